@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/werf/nelm-for-werf-helm/pkg/commands"
@@ -31,8 +34,24 @@ func main() {
 	rootCmd.AddCommand(commands.NewReleaseCommand())
 	rootCmd.AddCommand(commands.NewPlanCommand())
 
+	// Cancel running command on SIGINT/SIGTERM, so it can gracefully fail the release and
+	// release the lock. Second signal terminates immediately.
+	ctx, ctxCancelFn := context.WithCancelCause(context.Background())
+	defer ctxCancelFn(nil)
+
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signalCh
+		signal.Stop(signalCh)
+
+		logger.Warn(context.Background(), "Received %s, interrupting. Send it again to terminate immediately.", sig)
+		ctxCancelFn(fmt.Errorf("received %s", sig))
+	}()
+
 	// Execute the root command
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		// Log the error
 		logger.Error(context.Background(), "Error: %v", err)
 
@@ -0,0 +1,152 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/registry"
+	"github.com/werf/logboek"
+	"github.com/werf/logboek/pkg/types"
+)
+
+// Client is bound to a single Kubernetes cluster, chart registry, logger and release storage.
+// Unlike the package-level actions, it doesn't depend on any process-wide settings, so
+// multiple clients targeting different clusters can be used from one process, and a single
+// client can be used by multiple goroutines concurrently.
+func NewClient(opts ClientOptions) (*Client, error) {
+	if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return nil, fmt.Errorf("validate release storage options: %w", err)
+	}
+
+	if opts.LogRegistryStreamOut == nil {
+		opts.LogRegistryStreamOut = os.Stdout
+	}
+
+	helmRegistryClientOpts := []registry.ClientOption{
+		registry.ClientOptDebug(opts.LogDebug),
+		registry.ClientOptWriter(opts.LogRegistryStreamOut),
+	}
+
+	if opts.ChartRepositoryInsecure {
+		helmRegistryClientOpts = append(
+			helmRegistryClientOpts,
+			registry.ClientOptPlainHTTP(),
+		)
+	}
+
+	if opts.RegistryCredentialsPath != "" {
+		helmRegistryClientOpts = append(
+			helmRegistryClientOpts,
+			registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
+		)
+	}
+
+	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("construct registry client: %w", err)
+	}
+
+	return &Client{
+		chartRepositoryInsecure: opts.ChartRepositoryInsecure,
+		kubeConfigBase64:        opts.KubeConfigBase64,
+		kubeConfigPaths:         append([]string{}, opts.KubeConfigPaths...),
+		kubeContext:             opts.KubeContext,
+		logDebug:                opts.LogDebug,
+		logger:                  opts.Logger,
+		registryClient:          helmRegistryClient,
+		registryCredentialsPath: opts.RegistryCredentialsPath,
+		releaseStorageDriver:    opts.ReleaseStorageDriver,
+		releaseStoragePath:      opts.ReleaseStoragePath,
+	}, nil
+}
+
+type ClientOptions struct {
+	ChartRepositoryInsecure bool
+	KubeConfigBase64        string
+	KubeConfigPaths         []string
+	KubeContext             string
+	LogDebug                bool
+	LogRegistryStreamOut    io.Writer
+	// If not set, the logger from the context passed to the client methods is used.
+	Logger                  types.LoggerInterface
+	RegistryCredentialsPath string
+	ReleaseStorageDriver    ReleaseStorageDriver
+	ReleaseStoragePath      string
+}
+
+type Client struct {
+	chartRepositoryInsecure bool
+	kubeConfigBase64        string
+	kubeConfigPaths         []string
+	kubeContext             string
+	logDebug                bool
+	logger                  types.LoggerInterface
+	registryClient          *registry.Client
+	registryCredentialsPath string
+	releaseStorageDriver    ReleaseStorageDriver
+	releaseStoragePath      string
+}
+
+// Options owned by the client override the same options passed to the client methods.
+func (c *Client) Deploy(ctx context.Context, opts DeployOptions) error {
+	opts.ChartRepositoryInsecure = c.chartRepositoryInsecure
+	opts.KubeConfigBase64 = c.kubeConfigBase64
+	opts.KubeConfigPaths = c.kubeConfigPaths
+	opts.KubeContext = c.kubeContext
+	opts.LogDebug = c.logDebug
+	opts.RegistryClient = c.registryClient
+	opts.RegistryCredentialsPath = c.registryCredentialsPath
+	opts.ReleaseStorageDriver = c.releaseStorageDriver
+	opts.ReleaseStoragePath = c.releaseStoragePath
+
+	return Deploy(c.context(ctx), opts)
+}
+
+func (c *Client) Plan(ctx context.Context, opts PlanOptions) error {
+	opts.ChartRepositoryInsecure = c.chartRepositoryInsecure
+	opts.KubeConfigBase64 = c.kubeConfigBase64
+	opts.KubeConfigPaths = c.kubeConfigPaths
+	opts.KubeContext = c.kubeContext
+	opts.LogDebug = c.logDebug
+	opts.RegistryClient = c.registryClient
+	opts.RegistryCredentialsPath = c.registryCredentialsPath
+	opts.ReleaseStorageDriver = c.releaseStorageDriver
+	opts.ReleaseStoragePath = c.releaseStoragePath
+
+	return Plan(c.context(ctx), opts)
+}
+
+func (c *Client) Render(ctx context.Context, opts RenderOptions) error {
+	opts.ChartRepositoryInsecure = c.chartRepositoryInsecure
+	opts.KubeConfigBase64 = c.kubeConfigBase64
+	opts.KubeConfigPaths = c.kubeConfigPaths
+	opts.KubeContext = c.kubeContext
+	opts.LogDebug = c.logDebug
+	opts.RegistryClient = c.registryClient
+	opts.RegistryCredentialsPath = c.registryCredentialsPath
+	opts.ReleaseStorageDriver = c.releaseStorageDriver
+	opts.ReleaseStoragePath = c.releaseStoragePath
+
+	return Render(c.context(ctx), opts)
+}
+
+func (c *Client) Uninstall(ctx context.Context, opts UninstallOptions) error {
+	opts.KubeConfigBase64 = c.kubeConfigBase64
+	opts.KubeConfigPaths = c.kubeConfigPaths
+	opts.KubeContext = c.kubeContext
+	opts.LogDebug = c.logDebug
+	opts.ReleaseStorageDriver = c.releaseStorageDriver
+	opts.ReleaseStoragePath = c.releaseStoragePath
+
+	return Uninstall(c.context(ctx), opts)
+}
+
+func (c *Client) context(ctx context.Context) context.Context {
+	if c.logger == nil {
+		return ctx
+	}
+
+	return logboek.NewContext(ctx, c.logger)
+}
@@ -0,0 +1,85 @@
+package action_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+)
+
+var _ = Describe("Client", func() {
+	It("should run actions of multiple clients concurrently without sharing settings", func() {
+		const rendersPerClient = 5
+
+		tempDir := GinkgoT().TempDir()
+		chartDir, err := filepath.Abs(filepath.Join("testdata", "charts", "greeting"))
+		Expect(err).To(Succeed())
+
+		type testClient struct {
+			client   *action.Client
+			greeting string
+		}
+
+		var testClients []*testClient
+		for _, greeting := range []string{"hello", "bonjour"} {
+			client, err := action.NewClient(action.ClientOptions{
+				KubeContext:          "context-" + greeting,
+				LogRegistryStreamOut: io.Discard,
+				Logger:               logboek.NewLogger(io.Discard, io.Discard),
+			})
+			Expect(err).To(Succeed())
+
+			testClients = append(testClients, &testClient{
+				client:   client,
+				greeting: greeting,
+			})
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, len(testClients)*rendersPerClient)
+		for _, tc := range testClients {
+			for i := 0; i < rendersPerClient; i++ {
+				wg.Add(1)
+				go func(tc *testClient, i int) {
+					defer wg.Done()
+					defer GinkgoRecover()
+
+					errs <- tc.client.Render(context.Background(), action.RenderOptions{
+						ChartDirPath:     chartDir,
+						Local:            true,
+						OutputFilePath:   filepath.Join(tempDir, fmt.Sprintf("%s-%d.yaml", tc.greeting, i)),
+						OutputFileSave:   true,
+						ReleaseName:      fmt.Sprintf("%s-%d", tc.greeting, i),
+						ReleaseNamespace: "namespace-" + tc.greeting,
+						TempDirPath:      tempDir,
+						ValuesSets:       []string{"greeting=" + tc.greeting},
+					})
+				}(tc, i)
+			}
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			Expect(err).To(Succeed())
+		}
+
+		for _, tc := range testClients {
+			for i := 0; i < rendersPerClient; i++ {
+				output, err := os.ReadFile(filepath.Join(tempDir, fmt.Sprintf("%s-%d.yaml", tc.greeting, i)))
+				Expect(err).To(Succeed())
+				Expect(string(output)).To(ContainSubstring("greeting: " + tc.greeting))
+				Expect(string(output)).To(ContainSubstring(fmt.Sprintf("release: %s-%d", tc.greeting, i)))
+			}
+		}
+	})
+})
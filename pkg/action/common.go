@@ -1,16 +1,15 @@
 package action
 
 import (
-	"context"
-	"flag"
 	"fmt"
-	"io/ioutil"
 
-	"k8s.io/klog"
-	klog_v2 "k8s.io/klog/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
-	"github.com/werf/kubedog-for-werf-helm/pkg/display"
-	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
 )
 
 type LogColorMode string
@@ -31,71 +30,97 @@ const (
 	ReleaseStorageDriverConfigMap  ReleaseStorageDriver = "configmap"
 	ReleaseStorageDriverMemory     ReleaseStorageDriver = "memory"
 	ReleaseStorageDriverSQL        ReleaseStorageDriver = "sql"
+	// Native release storage drivers, not supported by Helm.
+	ReleaseStorageDriverFilesystem     ReleaseStorageDriver = "filesystem"
+	ReleaseStorageDriverChunkedSecrets ReleaseStorageDriver = "chunked-secrets"
 )
 
-func initKubedog(ctx context.Context) error {
-	flag.CommandLine.Parse([]string{})
+type OutputFormat string
 
-	display.SetOut(logboek.Context(ctx).OutStream())
-	display.SetErr(logboek.Context(ctx).ErrStream())
+const (
+	OutputFormatDefault  OutputFormat = ""
+	OutputFormatTable    OutputFormat = "table"
+	OutputFormatJSON     OutputFormat = "json"
+	OutputFormatYAML     OutputFormat = "yaml"
+	OutputFormatMarkdown OutputFormat = "markdown"
+)
 
-	if err := silenceKlog(ctx); err != nil {
-		return fmt.Errorf("silence klog: %w", err)
+// Helm action configuration panics on unknown drivers, so give it any valid one when the
+// release storage is native.
+func legacyReleaseStorageDriver(driver ReleaseStorageDriver) string {
+	switch driver {
+	case ReleaseStorageDriverFilesystem, ReleaseStorageDriverChunkedSecrets:
+		return string(ReleaseStorageDriverSecrets)
+	default:
+		return string(driver)
 	}
+}
 
-	if err := silenceKlogV2(ctx); err != nil {
-		return fmt.Errorf("silence klog v2: %w", err)
+func newReleaseStorage(driver ReleaseStorageDriver, storagePath, releaseNamespace string, legacyStorage rlsstor.LegacyStorage, clientFactory *kubeclnt.ClientFactory) rlsstor.ReleaseStorager {
+	switch driver {
+	case ReleaseStorageDriverFilesystem:
+		return rlsstor.NewFSReleaseStorage(storagePath, releaseNamespace)
+	case ReleaseStorageDriverChunkedSecrets:
+		return rlsstor.NewChunkedSecretReleaseStorage(clientFactory.Static(), releaseNamespace, rlsstor.ChunkedSecretReleaseStorageOptions{})
+	default:
+		return rlsstor.NewLegacyReleaseStorage(legacyStorage)
 	}
-
-	return nil
 }
 
-func silenceKlog(ctx context.Context) error {
-	fs := flag.NewFlagSet("klog", flag.PanicOnError)
-	klog.InitFlags(fs)
-
-	if err := fs.Set("logtostderr", "false"); err != nil {
-		return fmt.Errorf("set logtostderr: %w", err)
+func validateReleaseStorageOptions(driver ReleaseStorageDriver, storagePath string) error {
+	if driver == ReleaseStorageDriverFilesystem && storagePath == "" {
+		return fmt.Errorf("release storage path must be specified for %q release storage driver", driver)
 	}
 
-	if err := fs.Set("alsologtostderr", "false"); err != nil {
-		return fmt.Errorf("set alsologtostderr: %w", err)
-	}
+	return nil
+}
 
-	if err := fs.Set("stderrthreshold", "5"); err != nil {
-		return fmt.Errorf("set stderrthreshold: %w", err)
+func newAdoptResourceMatchers(selectors []string, releaseNamespace string) ([]*resrcmatcher.ResourceMatcher, error) {
+	var matchers []*resrcmatcher.ResourceMatcher
+	for _, selector := range selectors {
+		matcher, err := resrcmatcher.ParseResourceSelector(selector, resrcmatcher.ResourceSelectorOptions{
+			DefaultNamespace: releaseNamespace,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("parse adopt resource selector: %w", err)
+		}
+
+		matchers = append(matchers, matcher)
 	}
 
-	// Suppress info and warnings from client-go reflector
-	klog.SetOutputBySeverity("INFO", ioutil.Discard)
-	klog.SetOutputBySeverity("WARNING", ioutil.Discard)
-	klog.SetOutputBySeverity("ERROR", ioutil.Discard)
-	klog.SetOutputBySeverity("FATAL", logboek.Context(ctx).ErrStream())
-
-	return nil
+	return matchers, nil
 }
 
-func silenceKlogV2(ctx context.Context) error {
-	fs := flag.NewFlagSet("klog", flag.PanicOnError)
-	klog_v2.InitFlags(fs)
+func newRedactor(rules []string) (*redactr.Redactor, error) {
+	var redactionRules []*redactr.Rule
+	for _, rule := range rules {
+		r, err := redactr.ParseRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("parse redaction rule: %w", err)
+		}
 
-	if err := fs.Set("logtostderr", "false"); err != nil {
-		return fmt.Errorf("set logtostderr: %w", err)
+		redactionRules = append(redactionRules, r)
 	}
 
-	if err := fs.Set("alsologtostderr", "false"); err != nil {
-		return fmt.Errorf("set alsologtostderr: %w", err)
-	}
+	return redactr.NewRedactor(redactionRules, redactr.RedactorOptions{}), nil
+}
 
-	if err := fs.Set("stderrthreshold", "5"); err != nil {
-		return fmt.Errorf("set stderrthreshold: %w", err)
+// Masks sensitive values of the releases resources in errors, reports and logs.
+func newReleasesTextRedactor(redactor *redactr.Redactor, releases ...*rls.Release) *redactr.TextRedactor {
+	var objs []*unstructured.Unstructured
+	for _, rel := range releases {
+		if rel == nil {
+			continue
+		}
+
+		for _, res := range rel.HookResources() {
+			objs = append(objs, res.Unstructured())
+		}
+
+		for _, res := range rel.GeneralResources() {
+			objs = append(objs, res.Unstructured())
+		}
 	}
 
-	// Suppress info and warnings from client-go reflector
-	klog_v2.SetOutputBySeverity("INFO", ioutil.Discard)
-	klog_v2.SetOutputBySeverity("WARNING", ioutil.Discard)
-	klog_v2.SetOutputBySeverity("ERROR", ioutil.Discard)
-	klog_v2.SetOutputBySeverity("FATAL", logboek.Context(ctx).ErrStream())
-
-	return nil
+	return redactor.TextRedactor(objs...)
 }
@@ -17,7 +17,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
-	helm_v3 "github.com/werf/3p-helm-for-werf-helm/cmd/helm"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/registry"
@@ -36,8 +35,14 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/pln"
 	"github.com/werf/nelm-for-werf-helm/pkg/plnbuilder"
 	"github.com/werf/nelm-for-werf-helm/pkg/plnexectr"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnfile"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
 	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcpatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcprocssr"
 	"github.com/werf/nelm-for-werf-helm/pkg/rls"
@@ -50,15 +55,13 @@ import (
 
 const (
 	DefaultDeployReportFilename  = "deploy-report.json"
+	DefaultDeployJUnitFilename   = "deploy-junit.xml"
 	DefaultDeployGraphFilename   = "deploy-graph.dot"
 	DefaultRollbackGraphFilename = "rollback-graph.dot"
 )
 
 // FIXME(ilya-lesikov): this is old... need to check
-// 1. if last succeeded release was cleaned up because of release limit, werf will see
-// current release as first install. We might want to not delete last succeeded or last
-// uninstalled release ever.
-// 2. don't forget errs.FormatTemplatingError if any errors occurs
+// 1. don't forget errs.FormatTemplatingError if any errors occurs
 
 // FIXME(ilya-lesikov): this must be done a level higher
 // var logboekLogLevel level.Level
@@ -93,6 +96,7 @@ const (
 // logrus.StandardLogger().SetLevel(logrusLogLevel)
 
 type DeployOptions struct {
+	AdoptResources               []string
 	AutoRollback                 bool
 	ChartDirPath                 string
 	ChartRepositoryInsecure      bool
@@ -102,11 +106,16 @@ type DeployOptions struct {
 	DefaultValuesDisable         bool
 	DeployGraphPath              string
 	DeployGraphSave              bool
+	DeployJUnitPath              string
+	DeployJUnitSave              bool
 	DeployReportPath             string
 	DeployReportSave             bool
+	DeployReportVersion          int
+	ExcludeResources             []string
 	ExtraAnnotations             map[string]string
 	ExtraLabels                  map[string]string
 	ExtraRuntimeAnnotations      map[string]string
+	IncludeResources             []string
 	KubeConfigBase64             string
 	KubeConfigPaths              []string
 	KubeContext                  string
@@ -114,20 +123,29 @@ type DeployOptions struct {
 	LogDebug                     bool
 	LogRegistryStreamOut         io.Writer
 	NetworkParallelism           int
+	PlanFilePath                 string
 	ProgressTablePrint           bool
 	ProgressTablePrintInterval   time.Duration
+	ReadinessRulesFilePath       string
+	RedactionRules               []string
+	RegistryClient               *registry.Client
 	RegistryCredentialsPath      string
+	ReleaseHistoryFailedLimit    int
 	ReleaseHistoryLimit          int
 	ReleaseName                  string
 	ReleaseNamespace             string
 	ReleaseStorageDriver         ReleaseStorageDriver
+	ReleaseStoragePath           string
+	RetryAttempts                int
 	RollbackGraphPath            string
 	RollbackGraphSave            bool
 	SecretKeyIgnore              bool
 	SecretValuesPaths            []string
 	TempDirPath                  string
+	Timeout                      time.Duration
 	TrackCreationTimeout         time.Duration
 	TrackDeletionTimeout         time.Duration
+	TrackParallelism             int
 	TrackReadinessTimeout        time.Duration
 	ValuesFileSets               []string
 	ValuesFilesPaths             []string
@@ -166,6 +184,17 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		return fmt.Errorf("build deploy options: %w", err)
 	}
 
+	redactor, err := newRedactor(opts.RedactionRules)
+	if err != nil {
+		return fmt.Errorf("construct redactor: %w", err)
+	}
+
+	if opts.Timeout > 0 {
+		var ctxCancelFn context.CancelFunc
+		ctx, ctxCancelFn = context.WithTimeoutCause(ctx, opts.Timeout, fmt.Errorf("timed out after %s", opts.Timeout))
+		defer ctxCancelFn()
+	}
+
 	var kubeConfigPath string
 	if len(opts.KubeConfigPaths) > 0 {
 		kubeConfigPath = opts.KubeConfigPaths[0]
@@ -186,7 +215,7 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		return fmt.Errorf("construct kube config getter: %w", err)
 	}
 
-	helmSettings := helm_v3.Settings
+	helmSettings := cli.New()
 	*helmSettings.GetConfigP() = kubeConfigGetter
 	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
 	opts.ReleaseNamespace = helmSettings.Namespace()
@@ -201,35 +230,38 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		helmSettings.KubeConfig = kubeConfigPath
 	}
 
-	helmRegistryClientOpts := []registry.ClientOption{
-		registry.ClientOptDebug(opts.LogDebug),
-		registry.ClientOptWriter(opts.LogRegistryStreamOut),
-	}
+	helmRegistryClient := opts.RegistryClient
+	if helmRegistryClient == nil {
+		helmRegistryClientOpts := []registry.ClientOption{
+			registry.ClientOptDebug(opts.LogDebug),
+			registry.ClientOptWriter(opts.LogRegistryStreamOut),
+		}
 
-	if opts.ChartRepositoryInsecure {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptPlainHTTP(),
-		)
-	}
+		if opts.ChartRepositoryInsecure {
+			helmRegistryClientOpts = append(
+				helmRegistryClientOpts,
+				registry.ClientOptPlainHTTP(),
+			)
+		}
 
-	if opts.RegistryCredentialsPath != "" {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
-		)
-	}
+		if opts.RegistryCredentialsPath != "" {
+			helmRegistryClientOpts = append(
+				helmRegistryClientOpts,
+				registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
+			)
+		}
 
-	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
-	if err != nil {
-		return fmt.Errorf("construct registry client: %w", err)
+		helmRegistryClient, err = registry.NewClient(helmRegistryClientOpts...)
+		if err != nil {
+			return fmt.Errorf("construct registry client: %w", err)
+		}
 	}
 
 	helmActionConfig := &action.Configuration{}
 	if err := helmActionConfig.Init(
 		helmSettings.RESTClientGetter(),
 		opts.ReleaseNamespace,
-		string(opts.ReleaseStorageDriver),
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
 		func(format string, a ...interface{}) {
 			log.Default.Info(ctx, format, a...)
 		},
@@ -239,7 +271,6 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 	helmActionConfig.RegistryClient = helmRegistryClient
 
 	helmReleaseStorage := helmActionConfig.Releases
-	helmReleaseStorage.MaxHistory = opts.ReleaseHistoryLimit
 
 	helmChartPathOptions := action.ChartPathOptions{
 		InsecureSkipTLSverify: opts.ChartRepositorySkipTLSVerify,
@@ -247,7 +278,7 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 	}
 	helmChartPathOptions.SetRegistryClient(helmRegistryClient)
 
-	clientFactory, err := kubeclnt.NewClientFactory()
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
 	if err != nil {
 		return fmt.Errorf("construct kube client factory: %w", err)
 	}
@@ -303,137 +334,244 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 
 	log.Default.Info(ctx, "Constructing release history")
 	history, err := rlshistor.NewHistory(
+		ctx,
 		opts.ReleaseName,
 		opts.ReleaseNamespace,
-		helmReleaseStorage,
+		newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmReleaseStorage, clientFactory),
 		rlshistor.HistoryOptions{
-			Mapper:          clientFactory.Mapper(),
-			DiscoveryClient: clientFactory.Discovery(),
+			Mapper:                  clientFactory.Mapper(),
+			DiscoveryClient:         clientFactory.Discovery(),
+			SucceededRevisionsLimit: opts.ReleaseHistoryLimit,
+			FailedRevisionsLimit:    opts.ReleaseHistoryFailedLimit,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("construct release history: %w", err)
 	}
 
-	prevRelease, prevReleaseFound, err := history.LastRelease()
+	deployInfo, err := rlshistor.NewDeployInfo(history)
 	if err != nil {
-		return fmt.Errorf("get last release: %w", err)
+		return fmt.Errorf("get deploy info: %w", err)
 	}
 
-	prevDeployedRelease, prevDeployedReleaseFound, err := history.LastDeployedRelease()
-	if err != nil {
-		return fmt.Errorf("get last deployed release: %w", err)
+	prevRelease, prevReleaseFound := deployInfo.PrevRelease, deployInfo.PrevReleaseFound
+	prevDeployedRelease, prevDeployedReleaseFound := deployInfo.PrevDeployedRelease, deployInfo.PrevDeployedReleaseFound
+	newRevision, firstDeployed, deployType := deployInfo.NewRevision, deployInfo.FirstDeployed, deployInfo.DeployType
+
+	var readinessRules []*rdnsrule.ReadinessRule
+	if opts.ReadinessRulesFilePath != "" {
+		readinessRules, err = rdnsrule.LoadRules(opts.ReadinessRulesFilePath)
+		if err != nil {
+			return fmt.Errorf("load readiness rules: %w", err)
+		}
 	}
 
-	var newRevision int
-	var firstDeployed time.Time
+	var (
+		planFile                     *plnfile.PlanFile
+		notes                        string
+		newRel                       *rls.Release
+		standaloneCRDsInfos          []*resrcinfo.DeployableStandaloneCRDInfo
+		hookResourcesInfos           []*resrcinfo.DeployableHookResourceInfo
+		generalResourcesInfos        []*resrcinfo.DeployableGeneralResourceInfo
+		prevRelGeneralResourcesInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo
+	)
+
+	var prevRelGeneralResources []*resrc.GeneralResource
 	if prevReleaseFound {
-		newRevision = prevRelease.Revision() + 1
-		firstDeployed = prevRelease.FirstDeployed()
-	} else {
-		newRevision = 1
+		prevRelGeneralResources = prevRelease.GeneralResources()
 	}
 
-	var deployType helmcommon.DeployType
-	if prevReleaseFound && prevDeployedReleaseFound {
-		deployType = helmcommon.DeployTypeUpgrade
-	} else if prevReleaseFound {
-		deployType = helmcommon.DeployTypeInstall
-	} else {
-		deployType = helmcommon.DeployTypeInitial
-	}
+	includeResources := opts.IncludeResources
+	excludeResources := opts.ExcludeResources
 
-	log.Default.Info(ctx, "Constructing chart tree")
-	chartTree, err := chrttree.NewChartTree(
-		ctx,
-		opts.ChartDirPath,
-		opts.ReleaseName,
-		opts.ReleaseNamespace,
-		newRevision,
-		deployType,
-		helmActionConfig,
-		chrttree.ChartTreeOptions{
-			StringSetValues: opts.ValuesStringSets,
-			SetValues:       opts.ValuesSets,
-			FileValues:      opts.ValuesFileSets,
-			ValuesFiles:     opts.ValuesFilesPaths,
-			SubNotes:        opts.SubNotes,
+	if opts.PlanFilePath != "" {
+		log.Default.Info(ctx, "Loading plan from %q", opts.PlanFilePath)
+		planFile, err = plnfile.LoadPlanFile(opts.PlanFilePath)
+		if err != nil {
+			return fmt.Errorf("load plan file: %w", err)
+		}
+
+		if len(opts.AdoptResources) > 0 {
+			return fmt.Errorf("adopt resource selectors can't be used with plan file, adoptions are saved in the plan file")
+		}
+
+		if len(includeResources) > 0 || len(excludeResources) > 0 {
+			return fmt.Errorf("resource selectors can't be used with plan file, selectors saved in the plan file are used instead")
+		}
+
+		includeResources = planFile.IncludeResources
+		excludeResources = planFile.ExcludeResources
+
+		if planFile.ReleaseName != opts.ReleaseName || planFile.ReleaseNamespace != opts.ReleaseNamespace {
+			return fmt.Errorf("plan file is for release %q (namespace: %q), not for release %q (namespace: %q)", planFile.ReleaseName, planFile.ReleaseNamespace, opts.ReleaseName, opts.ReleaseNamespace)
+		}
+
+		if err := planFile.ValidateReleaseHistory(prevRelease); err != nil {
+			return fmt.Errorf("release history changed since planning: %w", err)
+		}
+
+		log.Default.Info(ctx, "Checking for resources changed since planning")
+		if err := planFile.ValidateLiveResources(ctx, clientFactory.KubeClient(), clientFactory.Mapper()); err != nil {
+			return fmt.Errorf("refusing to deploy outdated plan: %w", err)
+		}
+
+		log.Default.Info(ctx, "Constructing new release")
+		newRel, err = planFile.NewRelease(plnfile.PlanFileReleaseOptions{
 			Mapper:          clientFactory.Mapper(),
 			DiscoveryClient: clientFactory.Discovery(),
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("construct chart tree: %w", err)
-	}
+		})
+		if err != nil {
+			return fmt.Errorf("construct new release: %w", err)
+		}
 
-	notes := chartTree.Notes()
+		notes = newRel.Notes()
 
-	var prevRelGeneralResources []*resrc.GeneralResource
-	if prevReleaseFound {
-		prevRelGeneralResources = prevRelease.GeneralResources()
-	}
+		resourcesOpts := plnfile.PlanFileResourcesOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		}
 
-	log.Default.Info(ctx, "Processing resources")
-	resProcessor := resrcprocssr.NewDeployableResourcesProcessor(
-		deployType,
-		opts.ReleaseName,
-		opts.ReleaseNamespace,
-		chartTree.StandaloneCRDs(),
-		chartTree.HookResources(),
-		chartTree.GeneralResources(),
-		prevRelGeneralResources,
-		resrcprocssr.DeployableResourcesProcessorOptions{
-			NetworkParallelism: opts.NetworkParallelism,
-			ReleasableHookResourcePatchers: []resrcpatcher.ResourcePatcher{
-				resrcpatcher.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
-			},
-			ReleasableGeneralResourcePatchers: []resrcpatcher.ResourcePatcher{
-				resrcpatcher.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
-			},
-			DeployableStandaloneCRDsPatchers: []resrcpatcher.ResourcePatcher{
-				resrcpatcher.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
-				),
+		log.Default.Info(ctx, "Processing resources")
+		_, standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos, prevRelGeneralResourcesInfos, err = resrcinfo.BuildDeployableResourceInfos(
+			ctx,
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			planFile.DeployableStandaloneCRDs(resourcesOpts),
+			planFile.DeployableHookResources(resourcesOpts),
+			planFile.DeployableGeneralResources(resourcesOpts),
+			prevRelGeneralResources,
+			clientFactory.KubeClient(),
+			clientFactory.Mapper(),
+			opts.NetworkParallelism,
+		)
+		if err != nil {
+			return fmt.Errorf("build deployable resource infos: %w", err)
+		}
+
+		if err := planFile.ValidateDryApply(standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos); err != nil {
+			return fmt.Errorf("refusing to deploy outdated plan: %w", err)
+		}
+	} else {
+		log.Default.Info(ctx, "Constructing chart tree")
+		chartTree, err := chrttree.NewChartTree(
+			ctx,
+			opts.ChartDirPath,
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			newRevision,
+			deployType,
+			helmActionConfig,
+			chrttree.ChartTreeOptions{
+				StringSetValues: opts.ValuesStringSets,
+				SetValues:       opts.ValuesSets,
+				FileValues:      opts.ValuesFileSets,
+				ValuesFiles:     opts.ValuesFilesPaths,
+				SubNotes:        opts.SubNotes,
+				Mapper:          clientFactory.Mapper(),
+				DiscoveryClient: clientFactory.Discovery(),
+				HelmSettings:    helmSettings,
 			},
-			DeployableHookResourcePatchers: []resrcpatcher.ResourcePatcher{
-				resrcpatcher.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
-				),
+		)
+		if err != nil {
+			return fmt.Errorf("construct chart tree: %w", err)
+		}
+
+		notes = chartTree.Notes()
+
+		adoptResourceMatchers, err := newAdoptResourceMatchers(opts.AdoptResources, opts.ReleaseNamespace)
+		if err != nil {
+			return fmt.Errorf("construct adopt resource matchers: %w", err)
+		}
+
+		log.Default.Info(ctx, "Processing resources")
+		resProcessor := resrcprocssr.NewDeployableResourcesProcessor(
+			deployType,
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			chartTree.StandaloneCRDs(),
+			chartTree.HookResources(),
+			chartTree.GeneralResources(),
+			prevRelGeneralResources,
+			resrcprocssr.DeployableResourcesProcessorOptions{
+				NetworkParallelism: opts.NetworkParallelism,
+				ReleasableHookResourcePatchers: []resrcpatcher.ResourcePatcher{
+					resrcpatcher.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
+				},
+				ReleasableGeneralResourcePatchers: []resrcpatcher.ResourcePatcher{
+					resrcpatcher.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
+				},
+				DeployableStandaloneCRDsPatchers: []resrcpatcher.ResourcePatcher{
+					resrcpatcher.NewExtraMetadataPatcher(
+						lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
+					),
+				},
+				DeployableHookResourcePatchers: []resrcpatcher.ResourcePatcher{
+					resrcpatcher.NewExtraMetadataPatcher(
+						lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
+					),
+				},
+				DeployableGeneralResourcePatchers: []resrcpatcher.ResourcePatcher{
+					resrcpatcher.NewExtraMetadataPatcher(
+						lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
+					),
+				},
+				KubeClient:            clientFactory.KubeClient(),
+				Mapper:                clientFactory.Mapper(),
+				DiscoveryClient:       clientFactory.Discovery(),
+				AllowClusterAccess:    true,
+				AdoptResourceMatchers: adoptResourceMatchers,
 			},
-			DeployableGeneralResourcePatchers: []resrcpatcher.ResourcePatcher{
-				resrcpatcher.NewExtraMetadataPatcher(
-					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
-				),
+		)
+
+		if err := resProcessor.Process(ctx); err != nil {
+			return fmt.Errorf("process resources: %w", err)
+		}
+
+		log.Default.Info(ctx, "Constructing new release")
+		newRel, err = rls.NewRelease(
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			newRevision,
+			chartTree.ReleaseValues(),
+			chartTree.LegacyChart(),
+			resProcessor.ReleasableHookResources(),
+			resProcessor.ReleasableGeneralResources(),
+			notes,
+			rls.ReleaseOptions{
+				FirstDeployed: firstDeployed,
+				Mapper:        clientFactory.Mapper(),
 			},
-			KubeClient:         clientFactory.KubeClient(),
-			Mapper:             clientFactory.Mapper(),
-			DiscoveryClient:    clientFactory.Discovery(),
-			AllowClusterAccess: true,
-		},
-	)
+		)
+		if err != nil {
+			return fmt.Errorf("construct new release: %w", err)
+		}
 
-	if err := resProcessor.Process(ctx); err != nil {
-		return fmt.Errorf("process resources: %w", err)
+		standaloneCRDsInfos = resProcessor.DeployableStandaloneCRDsInfos()
+		hookResourcesInfos = resProcessor.DeployableHookResourcesInfos()
+		generalResourcesInfos = resProcessor.DeployableGeneralResourcesInfos()
+		prevRelGeneralResourcesInfos = resProcessor.DeployablePrevReleaseGeneralResourcesInfos()
 	}
 
-	log.Default.Info(ctx, "Constructing new release")
-	newRel, err := rls.NewRelease(
+	textRedactor := newReleasesTextRedactor(redactor, newRel, prevRelease)
+
+	resourceSelector, err := resrcmatcher.NewResourceSelector(includeResources, excludeResources, resrcmatcher.ResourceSelectorOptions{
+		DefaultNamespace: opts.ReleaseNamespace,
+	})
+	if err != nil {
+		return fmt.Errorf("construct resource selector: %w", err)
+	}
+
+	_, selectedHookResourcesInfos, selectedGeneralResourcesInfos, _, skippedResourcesIDs := plnbuilder.SelectResourcesInfos(
+		resourceSelector,
 		opts.ReleaseName,
 		opts.ReleaseNamespace,
-		newRevision,
-		chartTree.ReleaseValues(),
-		chartTree.LegacyChart(),
-		resProcessor.ReleasableHookResources(),
-		resProcessor.ReleasableGeneralResources(),
-		notes,
-		rls.ReleaseOptions{
-			FirstDeployed: firstDeployed,
-			Mapper:        clientFactory.Mapper(),
-		},
+		standaloneCRDsInfos,
+		hookResourcesInfos,
+		generalResourcesInfos,
+		prevRelGeneralResourcesInfos,
 	)
-	if err != nil {
-		return fmt.Errorf("construct new release: %w", err)
-	}
+
+	warnPartialApply(ctx, opts.ReleaseName, opts.ReleaseNamespace, skippedResourcesIDs)
 
 	taskStore := statestore.NewTaskStore()
 	logStore := kubeutil.NewConcurrent(
@@ -446,10 +584,10 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		deployType,
 		taskStore,
 		logStore,
-		resProcessor.DeployableStandaloneCRDsInfos(),
-		resProcessor.DeployableHookResourcesInfos(),
-		resProcessor.DeployableGeneralResourcesInfos(),
-		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+		standaloneCRDsInfos,
+		hookResourcesInfos,
+		generalResourcesInfos,
+		prevRelGeneralResourcesInfos,
 		newRel,
 		history,
 		clientFactory.KubeClient(),
@@ -463,6 +601,8 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 			CreationTimeout:     opts.TrackCreationTimeout,
 			ReadinessTimeout:    opts.TrackReadinessTimeout,
 			DeletionTimeout:     opts.TrackDeletionTimeout,
+			ResourceSelector:    resourceSelector,
+			ReadinessRules:      readinessRules,
 		},
 	)
 
@@ -488,6 +628,12 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		}
 	}
 
+	if planFile != nil {
+		if err := planFile.ValidatePlan(plan); err != nil {
+			return fmt.Errorf("refusing to deploy outdated plan: %w", err)
+		}
+	}
+
 	var releaseUpToDate bool
 	if prevReleaseFound {
 		releaseUpToDate, err = rlsdiff.ReleaseUpToDate(prevRelease, newRel)
@@ -502,13 +648,21 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 	}
 
 	if releaseUpToDate && planUseless {
-		if opts.DeployReportSave {
+		if opts.DeployReportSave || opts.DeployJUnitSave {
 			newRel.Skip()
 
-			report := reprt.NewReport(nil, nil, nil, newRel)
+			report := reprt.NewReport(nil, nil, nil, newRel, reprt.ReportOptions{TextRedactor: textRedactor, Version: opts.DeployReportVersion})
+
+			if opts.DeployReportSave {
+				if err := report.Save(opts.DeployReportPath); err != nil {
+					log.Default.Error(ctx, "Error: save deploy report: %s", err)
+				}
+			}
 
-			if err := report.Save(opts.DeployReportPath); err != nil {
-				log.Default.Error(ctx, "Error: save deploy report: %s", err)
+			if opts.DeployJUnitSave {
+				if err := report.SaveJUnit(opts.DeployJUnitPath); err != nil {
+					log.Default.Error(ctx, "Error: save deploy JUnit report: %s", err)
+				}
 			}
 		}
 
@@ -557,6 +711,11 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		plan,
 		plnexectr.PlanExecutorOptions{
 			NetworkParallelism: opts.NetworkParallelism,
+			TrackParallelism:   opts.TrackParallelism,
+			RetryPolicy: plnexectr.RetryPolicy{
+				Attempts: opts.RetryAttempts,
+			},
+			TextRedactor: textRedactor,
 		},
 	)
 
@@ -567,6 +726,15 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		criticalErrs = append(criticalErrs, fmt.Errorf("execute deploy plan: %w", planExecutionErr))
 	}
 
+	var interruptReason string
+	if ctx.Err() != nil {
+		interruptReason = context.Cause(ctx).Error()
+		log.Default.Warn(ctx, color.Style{color.Bold, color.Yellow}.Render(fmt.Sprintf("Release %q (namespace: %q) interrupted: %s", opts.ReleaseName, opts.ReleaseNamespace, interruptReason)))
+	}
+
+	// If interrupted, still need to mark the release failed, clean up and save the report.
+	finCtx := context.WithoutCancel(ctx)
+
 	var worthyCompletedOps []opertn.Operation
 	if ops, found, err := plan.WorthyCompletedOperations(); err != nil {
 		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get meaningful completed operations: %w", err))
@@ -588,6 +756,27 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		worthyFailedOps = ops
 	}
 
+	var executedOps []opertn.Operation
+	if ops, found, err := plan.ExecutedOperations(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get executed operations: %w", err))
+	} else if found {
+		executedOps = ops
+	}
+
+	var allOps []opertn.Operation
+	if ops, found, err := plan.Operations(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get operations: %w", err))
+	} else if found {
+		allOps = ops
+	}
+
+	var criticalPath []opertn.Operation
+	if ops, found, err := plan.CriticalPath(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get critical path: %w", err))
+	} else if found {
+		criticalPath = ops
+	}
+
 	var pendingReleaseCreated bool
 	if ops, found, err := plan.OperationsMatch(regexp.MustCompile(fmt.Sprintf(`^%s/%s$`, opertn.TypeCreatePendingReleaseOperation, newRel.ID()))); err != nil {
 		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get pending release operation: %w", err))
@@ -599,17 +788,21 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 
 	if planExecutionErr != nil && pendingReleaseCreated {
 		wcompops, wfailops, wcancops, criterrs, noncriterrs := runFailureDeployPlan(
-			ctx,
+			finCtx,
 			opts.ReleaseNamespace,
 			deployType,
 			plan,
 			taskStore,
-			resProcessor,
+			selectedHookResourcesInfos,
+			selectedGeneralResourcesInfos,
 			newRel,
 			prevRelease,
 			history,
 			clientFactory,
 			opts.NetworkParallelism,
+			opts.TrackParallelism,
+			opts.RetryAttempts,
+			textRedactor,
 		)
 
 		worthyCompletedOps = append(worthyCompletedOps, wcompops...)
@@ -620,7 +813,7 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 
 		if opts.AutoRollback && prevDeployedReleaseFound {
 			wcompops, wfailops, wcancops, notes, criterrs, noncriterrs = runRollbackPlan(
-				ctx,
+				finCtx,
 				taskStore,
 				logStore,
 				opts.ReleaseName,
@@ -637,9 +830,13 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 				opts.TrackCreationTimeout,
 				opts.TrackReadinessTimeout,
 				opts.TrackDeletionTimeout,
+				readinessRules,
 				opts.RollbackGraphSave,
 				opts.RollbackGraphPath,
 				opts.NetworkParallelism,
+				opts.TrackParallelism,
+				opts.RetryAttempts,
+				textRedactor,
 			)
 
 			worthyCompletedOps = append(worthyCompletedOps, wcompops...)
@@ -660,9 +857,18 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		worthyCanceledOps,
 		worthyFailedOps,
 		newRel,
+		reprt.ReportOptions{
+			CriticalPath:       criticalPath,
+			ExecutedOperations: executedOps,
+			InterruptReason:    interruptReason,
+			LogStore:           logStore,
+			Operations:         allOps,
+			TextRedactor:       textRedactor,
+			Version:            opts.DeployReportVersion,
+		},
 	)
 
-	report.Print(ctx)
+	report.Print(finCtx)
 
 	if opts.DeployReportSave {
 		if err := report.Save(opts.DeployReportPath); err != nil {
@@ -670,16 +876,24 @@ func Deploy(ctx context.Context, opts DeployOptions) error {
 		}
 	}
 
+	if opts.DeployJUnitSave {
+		if err := report.SaveJUnit(opts.DeployJUnitPath); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save deploy JUnit report: %w", err))
+		}
+	}
+
 	if len(criticalErrs) == 0 {
-		printNotes(ctx, notes)
+		printNotes(finCtx, notes)
 	}
 
 	if len(criticalErrs) > 0 {
-		return utls.Multierrorf("failed release %q (namespace: %q)", append(criticalErrs, nonCriticalErrs...), opts.ReleaseName, opts.ReleaseNamespace)
+		return textRedactor.RedactError(utls.Multierrorf("failed release %q (namespace: %q)", append(criticalErrs, nonCriticalErrs...), opts.ReleaseName, opts.ReleaseNamespace))
 	} else if len(nonCriticalErrs) > 0 {
-		return utls.Multierrorf("succeeded release %q (namespace: %q), but non-critical errors encountered", nonCriticalErrs, opts.ReleaseName, opts.ReleaseNamespace)
+		return textRedactor.RedactError(utls.Multierrorf("succeeded release %q (namespace: %q), but non-critical errors encountered", nonCriticalErrs, opts.ReleaseName, opts.ReleaseNamespace))
 	} else {
-		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Succeeded release %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)))
+		log.Default.Info(finCtx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Succeeded release %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)))
+
+		warnPartialApply(finCtx, opts.ReleaseName, opts.ReleaseNamespace, skippedResourcesIDs)
 
 		return nil
 	}
@@ -710,6 +924,16 @@ func applyDeployOptionsDefaults(
 		opts.RollbackGraphPath = filepath.Join(opts.TempDirPath, DefaultRollbackGraphFilename)
 	}
 
+	if opts.DeployReportVersion == 0 {
+		opts.DeployReportVersion = reprt.DefaultReportVersion
+	} else if !lo.Contains(reprt.SupportedReportVersions, opts.DeployReportVersion) {
+		return DeployOptions{}, fmt.Errorf("unsupported report version %d, supported versions: %v", opts.DeployReportVersion, reprt.SupportedReportVersions)
+	}
+
+	if opts.DeployJUnitPath == "" {
+		opts.DeployJUnitPath = filepath.Join(opts.TempDirPath, DefaultDeployJUnitFilename)
+	}
+
 	if opts.DeployReportPath == "" {
 		opts.DeployReportPath = filepath.Join(opts.TempDirPath, DefaultDeployReportFilename)
 	}
@@ -734,12 +958,20 @@ func applyDeployOptionsDefaults(
 		opts.NetworkParallelism = 30
 	}
 
+	if opts.RetryAttempts <= 0 {
+		opts.RetryAttempts = plnexectr.DefaultRetryPolicy.Attempts
+	}
+
 	if opts.ProgressTablePrintInterval <= 0 {
 		opts.ProgressTablePrintInterval = 5 * time.Second
 	}
 
-	if opts.ReleaseHistoryLimit <= 0 {
-		opts.ReleaseHistoryLimit = 10
+	if opts.ReleaseHistoryFailedLimit < 0 {
+		return DeployOptions{}, fmt.Errorf("failed revisions history limit can't be negative, use 0 for no limit")
+	}
+
+	if opts.ReleaseHistoryLimit < 0 {
+		return DeployOptions{}, fmt.Errorf("revisions history limit can't be negative, use 0 for no limit")
 	}
 
 	if opts.ReleaseName == "" {
@@ -750,6 +982,8 @@ func applyDeployOptionsDefaults(
 		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
 	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
 		return DeployOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return DeployOptions{}, err
 	}
 
 	return opts, nil
@@ -815,6 +1049,17 @@ func printNotes(ctx context.Context, notes string) {
 	})
 }
 
+func warnPartialApply(ctx context.Context, releaseName, releaseNamespace string, skippedResourcesIDs []*resrcid.ResourceID) {
+	if len(skippedResourcesIDs) == 0 {
+		return
+	}
+
+	log.Default.Warn(ctx, color.Style{color.Bold, color.Yellow}.Render(fmt.Sprintf("WARNING: release %q (namespace: %q) is only partially applied, %d resource(s) not selected and left as is:", releaseName, releaseNamespace, len(skippedResourcesIDs))))
+	for _, id := range skippedResourcesIDs {
+		log.Default.Warn(ctx, "- %s", id.HumanID())
+	}
+}
+
 func printTables(
 	ctx context.Context,
 	tablesBuilder *track.TablesBuilder,
@@ -860,11 +1105,15 @@ func runFailureDeployPlan(
 	deployType helmcommon.DeployType,
 	failedPlan *pln.Plan,
 	taskStore *statestore.TaskStore,
-	resProcessor *resrcprocssr.DeployableResourcesProcessor,
+	hookResourcesInfos []*resrcinfo.DeployableHookResourceInfo,
+	generalResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo,
 	newRel, prevRelease *rls.Release,
 	history *rlshistor.History,
 	clientFactory *kubeclnt.ClientFactory,
 	networkParallelism int,
+	trackParallelism int,
+	retryAttempts int,
+	textRedactor *redactr.TextRedactor,
 ) (
 	worthyCompletedOps []opertn.Operation,
 	worthyFailedOps []opertn.Operation,
@@ -878,8 +1127,8 @@ func runFailureDeployPlan(
 		deployType,
 		failedPlan,
 		taskStore,
-		resProcessor.DeployableHookResourcesInfos(),
-		resProcessor.DeployableGeneralResourcesInfos(),
+		hookResourcesInfos,
+		generalResourcesInfos,
 		newRel,
 		history,
 		clientFactory.KubeClient(),
@@ -906,6 +1155,11 @@ func runFailureDeployPlan(
 		failurePlan,
 		plnexectr.PlanExecutorOptions{
 			NetworkParallelism: networkParallelism,
+			TrackParallelism:   trackParallelism,
+			RetryPolicy: plnexectr.RetryPolicy{
+				Attempts: retryAttempts,
+			},
+			TextRedactor: textRedactor,
 		},
 	)
 
@@ -952,9 +1206,13 @@ func runRollbackPlan(
 	trackCreationTimeout time.Duration,
 	trackReadinessTimeout time.Duration,
 	trackDeletionTimeout time.Duration,
+	readinessRules []*rdnsrule.ReadinessRule,
 	saveRollbackGraph bool,
 	rollbackGraphPath string,
 	networkParallelism int,
+	trackParallelism int,
+	retryAttempts int,
+	textRedactor *redactr.TextRedactor,
 ) (
 	worthyCompletedOps []opertn.Operation,
 	worthyFailedOps []opertn.Operation,
@@ -1050,6 +1308,7 @@ func runRollbackPlan(
 			CreationTimeout:     trackCreationTimeout,
 			ReadinessTimeout:    trackReadinessTimeout,
 			DeletionTimeout:     trackDeletionTimeout,
+			ReadinessRules:      readinessRules,
 		},
 	)
 
@@ -1077,6 +1336,11 @@ func runRollbackPlan(
 		rollbackPlan,
 		plnexectr.PlanExecutorOptions{
 			NetworkParallelism: networkParallelism,
+			TrackParallelism:   trackParallelism,
+			RetryPolicy: plnexectr.RetryPolicy{
+				Attempts: retryAttempts,
+			},
+			TextRedactor: textRedactor,
 		},
 	)
 
@@ -1119,12 +1383,16 @@ func runRollbackPlan(
 			deployType,
 			rollbackPlan,
 			taskStore,
-			resProcessor,
+			resProcessor.DeployableHookResourcesInfos(),
+			resProcessor.DeployableGeneralResourcesInfos(),
 			rollbackRel,
 			failedRelease,
 			history,
 			clientFactory,
 			networkParallelism,
+			trackParallelism,
+			retryAttempts,
+			textRedactor,
 		)
 		worthyCompletedOps = append(worthyCompletedOps, wcompops...)
 		worthyFailedOps = append(worthyFailedOps, wfailops...)
@@ -0,0 +1,122 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gookit/color"
+
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsset"
+)
+
+const DefaultReleasesParallelism = 5
+
+type DeployManyOptions struct {
+	// Shared by all releases. Release name, namespace, chart and values are taken from the
+	// manifest, values from here are applied before the values from the manifest.
+	DeployOptions
+
+	ManifestPath        string
+	ReleasesParallelism int
+}
+
+// Deploys all releases from the release set manifest, each release only after the releases it
+// depends on succeeded. Independent releases are deployed in parallel. If a release fails,
+// releases depending on it are not deployed, and the failed release itself is rolled back if
+// automatic rollback is enabled. Releases deployed before the failure are never rolled back.
+func DeployMany(ctx context.Context, opts DeployManyOptions) error {
+	releaseSet, err := loadReleaseSet(opts.ManifestPath, opts.ReleaseNamespace)
+	if err != nil {
+		return err
+	}
+
+	if opts.ReleasesParallelism <= 0 {
+		opts.ReleasesParallelism = DefaultReleasesParallelism
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Deploying release set")+" %q (%d release(s))", opts.ManifestPath, len(releaseSet.Releases()))
+
+	results, err := releaseSet.Run(ctx, opts.ReleasesParallelism, func(ctx context.Context, rel *rlsset.Release) error {
+		deployOpts := opts.DeployOptions
+		deployOpts.ReleaseName = rel.Name
+		deployOpts.ReleaseNamespace = rel.Namespace
+		deployOpts.ChartDirPath = rel.ChartDirPath
+		deployOpts.ValuesFilesPaths = append(append([]string{}, opts.ValuesFilesPaths...), rel.ValuesFilesPaths...)
+		deployOpts.ValuesSets = append(append([]string{}, opts.ValuesSets...), rel.ValuesSets...)
+		deployOpts.ValuesStringSets = append(append([]string{}, opts.ValuesStringSets...), rel.ValuesStringSets...)
+
+		if opts.TempDirPath != "" {
+			deployOpts.TempDirPath = filepath.Join(opts.TempDirPath, rel.Namespace, rel.Name)
+			if err := os.MkdirAll(deployOpts.TempDirPath, 0o755); err != nil {
+				return fmt.Errorf("create temp dir: %w", err)
+			}
+		}
+
+		return Deploy(releaseLogContext(ctx, rel), deployOpts)
+	})
+	if err != nil {
+		return fmt.Errorf("deploy releases: %w", err)
+	}
+
+	return reportReleaseSetResults(ctx, results)
+}
+
+func loadReleaseSet(manifestPath, defaultNamespace string) (*rlsset.ReleaseSet, error) {
+	if manifestPath == "" {
+		return nil, fmt.Errorf("release set manifest path must be specified")
+	}
+
+	if defaultNamespace == "" {
+		defaultNamespace = "default"
+	}
+
+	releases, err := rlsset.LoadManifest(manifestPath, defaultNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("load release set manifest: %w", err)
+	}
+
+	releaseSet, err := rlsset.NewReleaseSet(releases)
+	if err != nil {
+		return nil, fmt.Errorf("construct release set: %w", err)
+	}
+
+	return releaseSet, nil
+}
+
+// Releases run in parallel, so their log lines are prefixed with the release to tell them apart.
+func releaseLogContext(ctx context.Context, rel *rlsset.Release) context.Context {
+	parentLogger := logboek.Context(ctx)
+
+	logger := parentLogger.NewSubLogger(parentLogger.OutStream(), parentLogger.ErrStream())
+	logger.Streams().SetPrefix(fmt.Sprintf("[%s] ", rel.ID()))
+
+	return logboek.NewContext(ctx, logger)
+}
+
+func reportReleaseSetResults(ctx context.Context, results []*rlsset.Result) error {
+	var failed, skipped int
+	log.Default.InfoBlock(ctx, color.Style{color.Bold, color.Blue}.Render("Releases summary")).Do(func() {
+		for _, result := range results {
+			switch result.Status {
+			case rlsset.ResultStatusSucceeded:
+				log.Default.Info(ctx, color.Style{color.Green}.Render("Succeeded")+" %q (namespace: %q)", result.Release.Name, result.Release.Namespace)
+			case rlsset.ResultStatusFailed:
+				failed++
+				log.Default.Info(ctx, color.Style{color.Red}.Render("Failed")+" %q (namespace: %q): %s", result.Release.Name, result.Release.Namespace, result.Err)
+			case rlsset.ResultStatusSkipped:
+				skipped++
+				log.Default.Info(ctx, color.Style{color.Yellow}.Render("Skipped")+" %q (namespace: %q): %s", result.Release.Name, result.Release.Namespace, result.Err)
+			}
+		}
+	})
+
+	if failed > 0 || skipped > 0 {
+		return fmt.Errorf("%d of %d release(s) failed, %d skipped", failed, len(results), skipped)
+	}
+
+	return nil
+}
@@ -7,24 +7,27 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
-	"time"
 
 	"github.com/gookit/color"
 	"github.com/samber/lo"
 
-	helm_v3 "github.com/werf/3p-helm-for-werf-helm/cmd/helm"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/registry"
 
 	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
+	kubeutil "github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
 	"github.com/werf/nelm-for-werf-helm/pkg/chrttree"
-	helmcommon "github.com/werf/nelm-for-werf-helm/pkg/common"
 	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
 	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnbuilder"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnfile"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcchanglog"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcpatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcprocssr"
 	"github.com/werf/nelm-for-werf-helm/pkg/rls"
@@ -34,6 +37,7 @@ import (
 )
 
 type PlanOptions struct {
+	AdoptResources               []string
 	ChartDirPath                 string
 	ChartRepositoryInsecure      bool
 	ChartRepositorySkipTLSVerify bool
@@ -41,19 +45,27 @@ type PlanOptions struct {
 	DefaultSecretValuesDisable   bool
 	DefaultValuesDisable         bool
 	ErrorIfChangesPlanned        bool
+	ExcludeResources             []string
 	ExtraAnnotations             map[string]string
 	ExtraLabels                  map[string]string
 	ExtraRuntimeAnnotations      map[string]string
+	IncludeResources             []string
 	KubeConfigBase64             string
 	KubeConfigPaths              []string
 	KubeContext                  string
 	LogDebug                     bool
 	LogRegistryStreamOut         io.Writer
 	NetworkParallelism           int
+	OutputFormat                 OutputFormat
+	OutputStream                 io.Writer
+	PlanFilePath                 string
+	RedactionRules               []string
+	RegistryClient               *registry.Client
 	RegistryCredentialsPath      string
 	ReleaseName                  string
 	ReleaseNamespace             string
 	ReleaseStorageDriver         ReleaseStorageDriver
+	ReleaseStoragePath           string
 	SecretKeyIgnore              bool
 	SecretValuesPaths            []string
 	TempDirPath                  string
@@ -113,7 +125,7 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 		return fmt.Errorf("construct kube config getter: %w", err)
 	}
 
-	helmSettings := helm_v3.Settings
+	helmSettings := cli.New()
 	*helmSettings.GetConfigP() = kubeConfigGetter
 	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
 	opts.ReleaseNamespace = helmSettings.Namespace()
@@ -127,35 +139,38 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 		helmSettings.KubeConfig = kubeConfigPath
 	}
 
-	helmRegistryClientOpts := []registry.ClientOption{
-		registry.ClientOptDebug(opts.LogDebug),
-		registry.ClientOptWriter(opts.LogRegistryStreamOut),
-	}
+	helmRegistryClient := opts.RegistryClient
+	if helmRegistryClient == nil {
+		helmRegistryClientOpts := []registry.ClientOption{
+			registry.ClientOptDebug(opts.LogDebug),
+			registry.ClientOptWriter(opts.LogRegistryStreamOut),
+		}
 
-	if opts.ChartRepositoryInsecure {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptPlainHTTP(),
-		)
-	}
+		if opts.ChartRepositoryInsecure {
+			helmRegistryClientOpts = append(
+				helmRegistryClientOpts,
+				registry.ClientOptPlainHTTP(),
+			)
+		}
 
-	if opts.RegistryCredentialsPath != "" {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
-		)
-	}
+		if opts.RegistryCredentialsPath != "" {
+			helmRegistryClientOpts = append(
+				helmRegistryClientOpts,
+				registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
+			)
+		}
 
-	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
-	if err != nil {
-		return fmt.Errorf("construct registry client: %w", err)
+		helmRegistryClient, err = registry.NewClient(helmRegistryClientOpts...)
+		if err != nil {
+			return fmt.Errorf("construct registry client: %w", err)
+		}
 	}
 
 	helmActionConfig := &action.Configuration{}
 	if err := helmActionConfig.Init(
 		helmSettings.RESTClientGetter(),
 		opts.ReleaseNamespace,
-		string(opts.ReleaseStorageDriver),
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
 		func(format string, a ...interface{}) {
 			log.Default.Info(ctx, format, a...)
 		},
@@ -172,7 +187,7 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 	}
 	helmChartPathOptions.SetRegistryClient(helmRegistryClient)
 
-	clientFactory, err := kubeclnt.NewClientFactory()
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
 	if err != nil {
 		return fmt.Errorf("construct kube client factory: %w", err)
 	}
@@ -206,9 +221,10 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 
 	log.Default.Info(ctx, "Constructing release history")
 	history, err := rlshistor.NewHistory(
+		ctx,
 		opts.ReleaseName,
 		opts.ReleaseNamespace,
-		helmReleaseStorage,
+		newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmReleaseStorage, clientFactory),
 		rlshistor.HistoryOptions{
 			Mapper:          clientFactory.Mapper(),
 			DiscoveryClient: clientFactory.Discovery(),
@@ -218,33 +234,14 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 		return fmt.Errorf("construct release history: %w", err)
 	}
 
-	prevRelease, prevReleaseFound, err := history.LastRelease()
+	deployInfo, err := rlshistor.NewDeployInfo(history)
 	if err != nil {
-		return fmt.Errorf("get last release: %w", err)
+		return fmt.Errorf("get deploy info: %w", err)
 	}
 
-	_, prevDeployedReleaseFound, err := history.LastDeployedRelease()
-	if err != nil {
-		return fmt.Errorf("get last deployed release: %w", err)
-	}
-
-	var newRevision int
-	var firstDeployed time.Time
-	if prevReleaseFound {
-		newRevision = prevRelease.Revision() + 1
-		firstDeployed = prevRelease.FirstDeployed()
-	} else {
-		newRevision = 1
-	}
-
-	var deployType helmcommon.DeployType
-	if prevReleaseFound && prevDeployedReleaseFound {
-		deployType = helmcommon.DeployTypeUpgrade
-	} else if prevReleaseFound {
-		deployType = helmcommon.DeployTypeInstall
-	} else {
-		deployType = helmcommon.DeployTypeInitial
-	}
+	prevRelease, prevReleaseFound := deployInfo.PrevRelease, deployInfo.PrevReleaseFound
+	prevDeployedRelease := deployInfo.PrevDeployedRelease
+	newRevision, firstDeployed, deployType := deployInfo.NewRevision, deployInfo.FirstDeployed, deployInfo.DeployType
 
 	log.Default.Info(ctx, "Constructing chart tree")
 	chartTree, err := chrttree.NewChartTree(
@@ -262,6 +259,7 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 			ValuesFiles:     opts.ValuesFilesPaths,
 			Mapper:          clientFactory.Mapper(),
 			DiscoveryClient: clientFactory.Discovery(),
+			HelmSettings:    helmSettings,
 		},
 	)
 	if err != nil {
@@ -277,6 +275,11 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 		prevRelFailed = prevRelease.Failed()
 	}
 
+	adoptResourceMatchers, err := newAdoptResourceMatchers(opts.AdoptResources, opts.ReleaseNamespace)
+	if err != nil {
+		return fmt.Errorf("construct adopt resource matchers: %w", err)
+	}
+
 	log.Default.Info(ctx, "Processing resources")
 	resProcessor := resrcprocssr.NewDeployableResourcesProcessor(
 		deployType,
@@ -312,10 +315,11 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 					opts.ExtraLabels,
 				),
 			},
-			KubeClient:         clientFactory.KubeClient(),
-			Mapper:             clientFactory.Mapper(),
-			DiscoveryClient:    clientFactory.Discovery(),
-			AllowClusterAccess: true,
+			KubeClient:            clientFactory.KubeClient(),
+			Mapper:                clientFactory.Mapper(),
+			DiscoveryClient:       clientFactory.Discovery(),
+			AllowClusterAccess:    true,
+			AdoptResourceMatchers: adoptResourceMatchers,
 		},
 	)
 
@@ -342,8 +346,30 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 		return fmt.Errorf("construct new release: %w", err)
 	}
 
+	resourceSelector, err := resrcmatcher.NewResourceSelector(opts.IncludeResources, opts.ExcludeResources, resrcmatcher.ResourceSelectorOptions{
+		DefaultNamespace: opts.ReleaseNamespace,
+	})
+	if err != nil {
+		return fmt.Errorf("construct resource selector: %w", err)
+	}
+
+	_, _, _, _, skippedResourcesIDs := plnbuilder.SelectResourcesInfos(
+		resourceSelector,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		resProcessor.DeployableStandaloneCRDsInfos(),
+		resProcessor.DeployableHookResourcesInfos(),
+		resProcessor.DeployableGeneralResourcesInfos(),
+		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+	)
+
+	redactor, err := newRedactor(opts.RedactionRules)
+	if err != nil {
+		return fmt.Errorf("construct redactor: %w", err)
+	}
+
 	log.Default.Info(ctx, "Calculating planned changes")
-	createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, planChangesPlanned := resrcchangcalc.CalculatePlannedChanges(
+	createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, transferredChanges, planChangesPlanned := resrcchangcalc.CalculatePlannedChanges(
 		opts.ReleaseName,
 		opts.ReleaseNamespace,
 		resProcessor.DeployableStandaloneCRDsInfos(),
@@ -351,6 +377,10 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 		resProcessor.DeployableGeneralResourcesInfos(),
 		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
 		prevRelFailed,
+		resrcchangcalc.CalculatePlannedChangesOptions{
+			ResourceSelector: resourceSelector,
+			Redactor:         redactor,
+		},
 	)
 
 	var releaseUpToDate bool
@@ -361,17 +391,100 @@ func Plan(ctx context.Context, opts PlanOptions) error {
 		}
 	}
 
-	resrcchanglog.LogPlannedChanges(
-		ctx,
-		opts.ReleaseName,
-		opts.ReleaseNamespace,
-		!releaseUpToDate,
-		createdChanges,
-		recreatedChanges,
-		updatedChanges,
-		appliedChanges,
-		deletedChanges,
-	)
+	switch opts.OutputFormat {
+	case OutputFormatJSON, OutputFormatMarkdown:
+		changesReport := resrcchanglog.NewPlannedChangesReport(
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			!releaseUpToDate,
+			createdChanges,
+			recreatedChanges,
+			updatedChanges,
+			appliedChanges,
+			deletedChanges,
+			transferredChanges,
+		)
+
+		if opts.OutputFormat == OutputFormatJSON {
+			if err := printJSON(changesReport, opts.OutputStream); err != nil {
+				return fmt.Errorf("print planned changes as JSON: %w", err)
+			}
+		} else if _, err := io.WriteString(opts.OutputStream, changesReport.Markdown()); err != nil {
+			return fmt.Errorf("print planned changes as Markdown: %w", err)
+		}
+	default:
+		resrcchanglog.LogPlannedChanges(
+			ctx,
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			!releaseUpToDate,
+			createdChanges,
+			recreatedChanges,
+			updatedChanges,
+			appliedChanges,
+			deletedChanges,
+			transferredChanges,
+		)
+	}
+
+	if opts.PlanFilePath != "" {
+		log.Default.Info(ctx, "Constructing new deploy plan")
+		deployPlanBuilder := plnbuilder.NewDeployPlanBuilder(
+			opts.ReleaseNamespace,
+			deployType,
+			statestore.NewTaskStore(),
+			kubeutil.NewConcurrent(
+				logstore.NewLogStore(),
+			),
+			resProcessor.DeployableStandaloneCRDsInfos(),
+			resProcessor.DeployableHookResourcesInfos(),
+			resProcessor.DeployableGeneralResourcesInfos(),
+			resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+			newRel,
+			history,
+			clientFactory.KubeClient(),
+			clientFactory.Static(),
+			clientFactory.Dynamic(),
+			clientFactory.Discovery(),
+			clientFactory.Mapper(),
+			plnbuilder.DeployPlanBuilderOptions{
+				PrevRelease:         prevRelease,
+				PrevDeployedRelease: prevDeployedRelease,
+				ResourceSelector:    resourceSelector,
+			},
+		)
+
+		plan, err := deployPlanBuilder.Build(ctx)
+		if err != nil {
+			return fmt.Errorf("build deploy plan: %w", err)
+		}
+
+		planFile, err := plnfile.NewPlanFile(
+			deployType,
+			newRel,
+			plan,
+			resProcessor.DeployableStandaloneCRDsInfos(),
+			resProcessor.DeployableHookResourcesInfos(),
+			resProcessor.DeployableGeneralResourcesInfos(),
+			resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+			plnfile.PlanFileOptions{
+				PrevRelease:      prevRelease,
+				IncludeResources: opts.IncludeResources,
+				ExcludeResources: opts.ExcludeResources,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("construct plan file: %w", err)
+		}
+
+		if err := planFile.Save(opts.PlanFilePath); err != nil {
+			return fmt.Errorf("save plan file: %w", err)
+		}
+
+		log.Default.Info(ctx, "Plan saved to %q", opts.PlanFilePath)
+	}
+
+	warnPartialApply(ctx, opts.ReleaseName, opts.ReleaseNamespace, skippedResourcesIDs)
 
 	if opts.ErrorIfChangesPlanned && (planChangesPlanned || !releaseUpToDate) {
 		return resrcchangcalc.ErrChangesPlanned
@@ -405,6 +518,16 @@ func applyPlanOptionsDefaults(opts PlanOptions, currentDir string, currentUser *
 		opts.NetworkParallelism = 30
 	}
 
+	if opts.OutputStream == nil {
+		opts.OutputStream = os.Stdout
+	}
+
+	switch opts.OutputFormat {
+	case OutputFormatDefault, OutputFormatJSON, OutputFormatMarkdown:
+	default:
+		return PlanOptions{}, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+	}
+
 	if opts.ReleaseName == "" {
 		return PlanOptions{}, fmt.Errorf("release name not specified")
 	}
@@ -413,6 +536,8 @@ func applyPlanOptionsDefaults(opts PlanOptions, currentDir string, currentUser *
 		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
 	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
 		return PlanOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return PlanOptions{}, err
 	}
 
 	return opts, nil
@@ -0,0 +1,73 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/gookit/color"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsset"
+)
+
+type PlanManyOptions struct {
+	// Shared by all releases. Release name, namespace, chart and values are taken from the
+	// manifest, values from here are applied before the values from the manifest.
+	PlanOptions
+
+	ManifestPath        string
+	ReleasesParallelism int
+}
+
+// Plans all releases from the release set manifest in the same order "release deploy-many"
+// would deploy them.
+func PlanMany(ctx context.Context, opts PlanManyOptions) error {
+	releaseSet, err := loadReleaseSet(opts.ManifestPath, opts.ReleaseNamespace)
+	if err != nil {
+		return err
+	}
+
+	if opts.ReleasesParallelism <= 0 {
+		opts.ReleasesParallelism = DefaultReleasesParallelism
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Planning release set")+" %q (%d release(s))", opts.ManifestPath, len(releaseSet.Releases()))
+
+	var changesPlanned atomic.Bool
+	results, err := releaseSet.Run(ctx, opts.ReleasesParallelism, func(ctx context.Context, rel *rlsset.Release) error {
+		planOpts := opts.PlanOptions
+		planOpts.ReleaseName = rel.Name
+		planOpts.ReleaseNamespace = rel.Namespace
+		planOpts.ChartDirPath = rel.ChartDirPath
+		planOpts.ValuesFilesPaths = append(append([]string{}, opts.ValuesFilesPaths...), rel.ValuesFilesPaths...)
+		planOpts.ValuesSets = append(append([]string{}, opts.ValuesSets...), rel.ValuesSets...)
+		planOpts.ValuesStringSets = append(append([]string{}, opts.ValuesStringSets...), rel.ValuesStringSets...)
+
+		if err := Plan(releaseLogContext(ctx, rel), planOpts); err != nil {
+			if errors.Is(err, resrcchangcalc.ErrChangesPlanned) {
+				changesPlanned.Store(true)
+				return nil
+			}
+
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("plan releases: %w", err)
+	}
+
+	if err := reportReleaseSetResults(ctx, results); err != nil {
+		return err
+	}
+
+	if changesPlanned.Load() {
+		return resrcchangcalc.ErrChangesPlanned
+	}
+
+	return nil
+}
@@ -0,0 +1,298 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/gookit/color"
+	"github.com/samber/lo"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchanglog"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+)
+
+type PlanUninstallOptions struct {
+	DeleteHooks            bool
+	DeleteReleaseNamespace bool
+	ErrorIfChangesPlanned  bool
+	KubeConfigBase64       string
+	KubeConfigPaths        []string
+	KubeContext            string
+	LogDebug               bool
+	NetworkParallelism     int
+	OutputFormat           OutputFormat
+	OutputStream           io.Writer
+	RedactionRules         []string
+	ReleaseName            string
+	ReleaseNamespace       string
+	ReleaseStorageDriver   ReleaseStorageDriver
+	ReleaseStoragePath     string
+	TempDirPath            string
+}
+
+func PlanUninstall(ctx context.Context, opts PlanUninstallOptions) error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current working directory: %w", err)
+	}
+
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyPlanUninstallOptionsDefaults(opts, currentDir, currentUser)
+	if err != nil {
+		return fmt.Errorf("build plan uninstall options: %w", err)
+	}
+
+	var kubeConfigPath string
+	if len(opts.KubeConfigPaths) > 0 {
+		kubeConfigPath = opts.KubeConfigPaths[0]
+	}
+
+	kubeConfigGetter, err := kube.NewKubeConfigGetter(
+		kube.KubeConfigGetterOptions{
+			KubeConfigOptions: kube.KubeConfigOptions{
+				Context:             opts.KubeContext,
+				ConfigPath:          kubeConfigPath,
+				ConfigDataBase64:    opts.KubeConfigBase64,
+				ConfigPathMergeList: opts.KubeConfigPaths,
+			},
+			Namespace: opts.ReleaseNamespace,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct kube config getter: %w", err)
+	}
+
+	helmSettings := cli.New()
+	*helmSettings.GetConfigP() = kubeConfigGetter
+	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
+	opts.ReleaseNamespace = helmSettings.Namespace()
+	helmSettings.Debug = opts.LogDebug
+
+	if opts.KubeContext != "" {
+		helmSettings.KubeContext = opts.KubeContext
+	}
+
+	if kubeConfigPath != "" {
+		helmSettings.KubeConfig = kubeConfigPath
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		helmSettings.RESTClientGetter(),
+		opts.ReleaseNamespace,
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Info(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+
+	helmReleaseStorage := helmActionConfig.Releases
+
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Planning removal of release")+" %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)
+
+	namespaceID := resrcid.NewResourceID(
+		opts.ReleaseNamespace,
+		"",
+		schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+		resrcid.ResourceIDOptions{Mapper: clientFactory.Mapper()},
+	)
+
+	if _, err := clientFactory.KubeClient().Get(
+		ctx,
+		namespaceID,
+		kubeclnt.KubeClientGetOptions{
+			TryCache: true,
+		},
+	); err != nil {
+		if api_errors.IsNotFound(err) {
+			log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("No changes planned: no release namespace %q found", opts.ReleaseNamespace)))
+
+			return nil
+		} else {
+			return fmt.Errorf("get release namespace: %w", err)
+		}
+	}
+
+	log.Default.Info(ctx, "Constructing release history")
+	history, err := rlshistor.NewHistory(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmReleaseStorage, clientFactory),
+		rlshistor.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct release history: %w", err)
+	}
+
+	lastRelease, lastReleaseFound, err := history.LastRelease()
+	if err != nil {
+		return fmt.Errorf("get last release: %w", err)
+	} else if !lastReleaseFound {
+		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("No changes planned: no release %q (namespace: %q) found", opts.ReleaseName, opts.ReleaseNamespace)))
+
+		return nil
+	}
+
+	hookResources := lastRelease.HookResources()
+	if !opts.DeleteHooks {
+		hookResources = lo.Filter(hookResources, func(res *resrc.HookResource, _ int) bool {
+			return res.OnPreDelete() || res.OnPostDelete()
+		})
+	}
+
+	log.Default.Info(ctx, "Processing resources")
+	_, _, hookResourcesInfos, _, prevReleaseGeneralResourceInfos, err := resrcinfo.BuildDeployableResourceInfos(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		nil,
+		hookResources,
+		nil,
+		lastRelease.GeneralResources(),
+		clientFactory.KubeClient(),
+		clientFactory.Mapper(),
+		opts.NetworkParallelism,
+	)
+	if err != nil {
+		return fmt.Errorf("build deployable resources infos: %w", err)
+	}
+
+	redactor, err := newRedactor(opts.RedactionRules)
+	if err != nil {
+		return fmt.Errorf("construct redactor: %w", err)
+	}
+
+	var releaseNamespaceID *resrcid.ResourceID
+	if opts.DeleteReleaseNamespace {
+		releaseNamespaceID = namespaceID
+	}
+
+	log.Default.Info(ctx, "Calculating planned changes")
+	createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, keptChanges, notOwnedChanges, _ := resrcchangcalc.CalculatePlannedUninstallChanges(
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		hookResourcesInfos,
+		prevReleaseGeneralResourceInfos,
+		resrcchangcalc.CalculatePlannedUninstallChangesOptions{
+			DeleteHooks:        opts.DeleteHooks,
+			ReleaseNamespaceID: releaseNamespaceID,
+			Redactor:           redactor,
+		},
+	)
+
+	switch opts.OutputFormat {
+	case OutputFormatJSON, OutputFormatMarkdown:
+		changesReport := resrcchanglog.NewPlannedUninstallChangesReport(
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			createdChanges,
+			recreatedChanges,
+			updatedChanges,
+			appliedChanges,
+			deletedChanges,
+			keptChanges,
+			notOwnedChanges,
+		)
+
+		if opts.OutputFormat == OutputFormatJSON {
+			if err := printJSON(changesReport, opts.OutputStream); err != nil {
+				return fmt.Errorf("print planned changes as JSON: %w", err)
+			}
+		} else if _, err := io.WriteString(opts.OutputStream, changesReport.Markdown()); err != nil {
+			return fmt.Errorf("print planned changes as Markdown: %w", err)
+		}
+	default:
+		resrcchanglog.LogPlannedUninstallChanges(
+			ctx,
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			createdChanges,
+			recreatedChanges,
+			updatedChanges,
+			appliedChanges,
+			deletedChanges,
+			keptChanges,
+			notOwnedChanges,
+		)
+	}
+
+	// Release is always deleted, so any found release means changes.
+	if opts.ErrorIfChangesPlanned {
+		return resrcchangcalc.ErrChangesPlanned
+	}
+
+	return nil
+}
+
+func applyPlanUninstallOptionsDefaults(opts PlanUninstallOptions, currentDir string, currentUser *user.User) (PlanUninstallOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return PlanUninstallOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = 30
+	}
+
+	if opts.OutputStream == nil {
+		opts.OutputStream = os.Stdout
+	}
+
+	switch opts.OutputFormat {
+	case OutputFormatDefault, OutputFormatJSON, OutputFormatMarkdown:
+	default:
+		return PlanUninstallOptions{}, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+	}
+
+	if opts.ReleaseName == "" {
+		return PlanUninstallOptions{}, fmt.Errorf("release name not specified")
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
+		return PlanUninstallOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return PlanUninstallOptions{}, err
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,209 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os/user"
+	"path/filepath"
+
+	"github.com/gookit/color"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchanglog"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+)
+
+const DefaultDriftIgnoreFieldManager = "kube-controller-manager"
+
+type ReleaseDriftOptions struct {
+	IgnoreFieldManagers  []string
+	KubeConfigBase64     string
+	KubeConfigPaths      []string
+	KubeContext          string
+	LogDebug             bool
+	NetworkParallelism   int
+	RedactionRules       []string
+	ReleaseName          string
+	ReleaseNamespace     string
+	ReleaseStorageDriver ReleaseStorageDriver
+	ReleaseStoragePath   string
+}
+
+// Returns resrcchangcalc.ErrDriftDetected if the live cluster differs from the last
+// deployed release, or resrcchangcalc.ErrDriftUnknown if some resources couldn't be compared.
+func ReleaseDrift(ctx context.Context, opts ReleaseDriftOptions) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseDriftOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return fmt.Errorf("build release drift options: %w", err)
+	}
+
+	var kubeConfigPath string
+	if len(opts.KubeConfigPaths) > 0 {
+		kubeConfigPath = opts.KubeConfigPaths[0]
+	}
+
+	kubeConfigGetter, err := kube.NewKubeConfigGetter(
+		kube.KubeConfigGetterOptions{
+			KubeConfigOptions: kube.KubeConfigOptions{
+				Context:             opts.KubeContext,
+				ConfigPath:          kubeConfigPath,
+				ConfigDataBase64:    opts.KubeConfigBase64,
+				ConfigPathMergeList: opts.KubeConfigPaths,
+			},
+			Namespace: opts.ReleaseNamespace,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct kube config getter: %w", err)
+	}
+
+	helmSettings := cli.New()
+	*helmSettings.GetConfigP() = kubeConfigGetter
+	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
+	opts.ReleaseNamespace = helmSettings.Namespace()
+	helmSettings.Debug = opts.LogDebug
+
+	if opts.KubeContext != "" {
+		helmSettings.KubeContext = opts.KubeContext
+	}
+
+	if kubeConfigPath != "" {
+		helmSettings.KubeConfig = kubeConfigPath
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		helmSettings.RESTClientGetter(),
+		opts.ReleaseNamespace,
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Info(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Checking drift of release")+" %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)
+
+	log.Default.Info(ctx, "Constructing release history")
+	history, err := rlshistor.NewHistory(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmActionConfig.Releases, clientFactory),
+		rlshistor.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct release history: %w", err)
+	}
+
+	deployedRelease, found, err := history.LastDeployedRelease()
+	if err != nil {
+		return fmt.Errorf("get last deployed release: %w", err)
+	} else if !found {
+		return fmt.Errorf("no deployed revisions of release %q (namespace: %q) found", opts.ReleaseName, opts.ReleaseNamespace)
+	}
+
+	log.Default.Info(ctx, "Processing resources")
+	_, _, _, generalResourcesInfos, _, err := resrcinfo.BuildDeployableResourceInfos(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		nil,
+		nil,
+		deployedRelease.GeneralResources(),
+		nil,
+		clientFactory.KubeClient(),
+		clientFactory.Mapper(),
+		opts.NetworkParallelism,
+	)
+	if err != nil {
+		return fmt.Errorf("build deployable resources infos: %w", err)
+	}
+
+	redactor, err := newRedactor(opts.RedactionRules)
+	if err != nil {
+		return fmt.Errorf("construct redactor: %w", err)
+	}
+
+	log.Default.Info(ctx, "Calculating drift")
+	modifiedDrifts, deletedDrifts, extraManagersDrifts, unknownDrifts, anyDriftDetected, err := resrcchangcalc.CalculateDrift(
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		generalResourcesInfos,
+		resrcchangcalc.CalculateDriftOptions{
+			IgnoreFieldManagers: opts.IgnoreFieldManagers,
+			Redactor:            redactor,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("calculate drift: %w", err)
+	}
+
+	resrcchanglog.LogDrift(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		deployedRelease.Revision(),
+		modifiedDrifts,
+		deletedDrifts,
+		extraManagersDrifts,
+		unknownDrifts,
+	)
+
+	if anyDriftDetected {
+		return resrcchangcalc.ErrDriftDetected
+	} else if len(unknownDrifts) > 0 {
+		return resrcchangcalc.ErrDriftUnknown
+	}
+
+	return nil
+}
+
+func applyReleaseDriftOptionsDefaults(opts ReleaseDriftOptions, currentUser *user.User) (ReleaseDriftOptions, error) {
+	if opts.IgnoreFieldManagers == nil {
+		opts.IgnoreFieldManagers = []string{DefaultDriftIgnoreFieldManager}
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = 30
+	}
+
+	if opts.ReleaseName == "" {
+		return ReleaseDriftOptions{}, fmt.Errorf("release name not specified")
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
+		return ReleaseDriftOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return ReleaseDriftOptions{}, err
+	}
+
+	return opts, nil
+}
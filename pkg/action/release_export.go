@@ -0,0 +1,154 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/gookit/color"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsarchiv"
+)
+
+type ReleaseExportOptions struct {
+	KubeConfigBase64     string
+	KubeConfigPaths      []string
+	KubeContext          string
+	LogDebug             bool
+	OutputFilePath       string
+	ReleaseName          string
+	ReleaseNamespace     string
+	ReleaseStorageDriver ReleaseStorageDriver
+	ReleaseStoragePath   string
+}
+
+// Saves all revisions of the release to a portable archive, which can be imported with
+// ReleaseImport into another cluster or namespace.
+func ReleaseExport(ctx context.Context, opts ReleaseExportOptions) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseExportOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return fmt.Errorf("build release export options: %w", err)
+	}
+
+	var kubeConfigPath string
+	if len(opts.KubeConfigPaths) > 0 {
+		kubeConfigPath = opts.KubeConfigPaths[0]
+	}
+
+	kubeConfigGetter, err := kube.NewKubeConfigGetter(
+		kube.KubeConfigGetterOptions{
+			KubeConfigOptions: kube.KubeConfigOptions{
+				Context:             opts.KubeContext,
+				ConfigPath:          kubeConfigPath,
+				ConfigDataBase64:    opts.KubeConfigBase64,
+				ConfigPathMergeList: opts.KubeConfigPaths,
+			},
+			Namespace: opts.ReleaseNamespace,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct kube config getter: %w", err)
+	}
+
+	helmSettings := cli.New()
+	*helmSettings.GetConfigP() = kubeConfigGetter
+	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
+	opts.ReleaseNamespace = helmSettings.Namespace()
+	helmSettings.Debug = opts.LogDebug
+
+	if opts.KubeContext != "" {
+		helmSettings.KubeContext = opts.KubeContext
+	}
+
+	if kubeConfigPath != "" {
+		helmSettings.KubeConfig = kubeConfigPath
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		helmSettings.RESTClientGetter(),
+		opts.ReleaseNamespace,
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Info(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Exporting release")+" %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)
+
+	legacyRels, err := newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmActionConfig.Releases, clientFactory).Releases(ctx, opts.ReleaseName)
+	if err != nil {
+		return fmt.Errorf("get releases: %w", err)
+	} else if len(legacyRels) == 0 {
+		return fmt.Errorf("release %q (namespace: %q) not found", opts.ReleaseName, opts.ReleaseNamespace)
+	}
+
+	// Releases contain Secrets and values in cleartext.
+	file, err := os.OpenFile(opts.OutputFilePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("create archive file: %w", err)
+	}
+	defer file.Close()
+
+	// Permissions of an existing file are not changed by opening it.
+	if err := file.Chmod(0o600); err != nil {
+		return fmt.Errorf("change archive file permissions: %w", err)
+	}
+
+	if err := rlsarchiv.Write(file, opts.ReleaseName, opts.ReleaseNamespace, legacyRels, time.Now()); err != nil {
+		return fmt.Errorf("write release archive: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close archive file: %w", err)
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Exported")+" %d revisions of release %q (namespace: %q) to %q", len(legacyRels), opts.ReleaseName, opts.ReleaseNamespace, opts.OutputFilePath)
+
+	return nil
+}
+
+func applyReleaseExportOptionsDefaults(opts ReleaseExportOptions, currentUser *user.User) (ReleaseExportOptions, error) {
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.ReleaseName == "" {
+		return ReleaseExportOptions{}, fmt.Errorf("release name not specified")
+	}
+
+	if opts.OutputFilePath == "" {
+		opts.OutputFilePath = opts.ReleaseName + ".tar.gz"
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
+		return ReleaseExportOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return ReleaseExportOptions{}, err
+	}
+
+	return opts, nil
+}
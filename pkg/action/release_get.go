@@ -0,0 +1,302 @@
+package action
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+)
+
+type ReleaseGetOptions struct {
+	KubeConfigBase64     string
+	KubeConfigPaths      []string
+	KubeContext          string
+	LogDebug             bool
+	OutputFormat         OutputFormat
+	OutputStream         io.Writer
+	ReleaseName          string
+	ReleaseNamespace     string
+	ReleaseStorageDriver ReleaseStorageDriver
+	ReleaseStoragePath   string
+	Revision             int
+}
+
+type ReleaseGetResult struct {
+	Name          string                 `json:"name"`
+	Namespace     string                 `json:"namespace"`
+	Revision      int                    `json:"revision"`
+	Status        string                 `json:"status"`
+	DeployType    string                 `json:"deployType,omitempty"`
+	FirstDeployed time.Time              `json:"firstDeployed"`
+	LastDeployed  time.Time              `json:"lastDeployed"`
+	Chart         string                 `json:"chart,omitempty"`
+	ChartVersion  string                 `json:"chartVersion,omitempty"`
+	AppVersion    string                 `json:"appVersion,omitempty"`
+	Values        map[string]interface{} `json:"values,omitempty"`
+	Notes         string                 `json:"notes,omitempty"`
+	Hooks         []string               `json:"hooks,omitempty"`
+	Manifests     []string               `json:"manifests,omitempty"`
+}
+
+func ReleaseGet(ctx context.Context, opts ReleaseGetOptions) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseGetOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return fmt.Errorf("build release get options: %w", err)
+	}
+
+	var kubeConfigPath string
+	if len(opts.KubeConfigPaths) > 0 {
+		kubeConfigPath = opts.KubeConfigPaths[0]
+	}
+
+	kubeConfigGetter, err := kube.NewKubeConfigGetter(
+		kube.KubeConfigGetterOptions{
+			KubeConfigOptions: kube.KubeConfigOptions{
+				Context:             opts.KubeContext,
+				ConfigPath:          kubeConfigPath,
+				ConfigDataBase64:    opts.KubeConfigBase64,
+				ConfigPathMergeList: opts.KubeConfigPaths,
+			},
+			Namespace: opts.ReleaseNamespace,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct kube config getter: %w", err)
+	}
+
+	helmSettings := cli.New()
+	*helmSettings.GetConfigP() = kubeConfigGetter
+	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
+	opts.ReleaseNamespace = helmSettings.Namespace()
+	helmSettings.Debug = opts.LogDebug
+
+	if opts.KubeContext != "" {
+		helmSettings.KubeContext = opts.KubeContext
+	}
+
+	if kubeConfigPath != "" {
+		helmSettings.KubeConfig = kubeConfigPath
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		helmSettings.RESTClientGetter(),
+		opts.ReleaseNamespace,
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Info(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	history, err := rlshistor.NewHistory(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmActionConfig.Releases, clientFactory),
+		rlshistor.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct release history: %w", err)
+	}
+
+	var rel *rls.Release
+	var found bool
+	if opts.Revision == 0 {
+		rel, found, err = history.LastRelease()
+		if err != nil {
+			return fmt.Errorf("get last release: %w", err)
+		} else if !found {
+			return fmt.Errorf("release %q (namespace: %q) not found", opts.ReleaseName, opts.ReleaseNamespace)
+		}
+	} else {
+		rel, found, err = history.Release(opts.Revision)
+		if err != nil {
+			return fmt.Errorf("get release revision %d: %w", opts.Revision, err)
+		} else if !found {
+			return fmt.Errorf("revision %d of release %q (namespace: %q) not found", opts.Revision, opts.ReleaseName, opts.ReleaseNamespace)
+		}
+	}
+
+	result, err := newReleaseGetResult(rel)
+	if err != nil {
+		return fmt.Errorf("build release get result: %w", err)
+	}
+
+	switch opts.OutputFormat {
+	case OutputFormatJSON:
+		if err := printJSON(result, opts.OutputStream); err != nil {
+			return fmt.Errorf("print release as JSON: %w", err)
+		}
+	case OutputFormatYAML:
+		if err := printYAML(result, opts.OutputStream); err != nil {
+			return fmt.Errorf("print release as YAML: %w", err)
+		}
+	default:
+		if err := printReleaseGetResultTable(result, opts.OutputStream); err != nil {
+			return fmt.Errorf("print release: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func applyReleaseGetOptionsDefaults(opts ReleaseGetOptions, currentUser *user.User) (ReleaseGetOptions, error) {
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.OutputStream == nil {
+		opts.OutputStream = os.Stdout
+	}
+
+	switch opts.OutputFormat {
+	case OutputFormatDefault:
+		opts.OutputFormat = OutputFormatTable
+	case OutputFormatTable, OutputFormatJSON, OutputFormatYAML:
+	default:
+		return ReleaseGetOptions{}, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+	}
+
+	if opts.ReleaseName == "" {
+		return ReleaseGetOptions{}, fmt.Errorf("release name not specified")
+	}
+
+	if opts.Revision < 0 {
+		return ReleaseGetOptions{}, fmt.Errorf("revision must be a positive number, got %d", opts.Revision)
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
+		return ReleaseGetOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return ReleaseGetOptions{}, err
+	}
+
+	return opts, nil
+}
+
+func newReleaseGetResult(rel *rls.Release) (*ReleaseGetResult, error) {
+	historyEntry := newReleaseHistoryEntry(rel)
+
+	result := &ReleaseGetResult{
+		Name:          rel.Name(),
+		Namespace:     rel.Namespace(),
+		Revision:      historyEntry.Revision,
+		Status:        historyEntry.Status,
+		DeployType:    historyEntry.DeployType,
+		FirstDeployed: historyEntry.FirstDeployed,
+		LastDeployed:  historyEntry.LastDeployed,
+		Chart:         historyEntry.Chart,
+		ChartVersion:  historyEntry.ChartVersion,
+		AppVersion:    historyEntry.AppVersion,
+		Values:        rel.Values(),
+		Notes:         rel.Notes(),
+	}
+
+	for _, res := range rel.HookResources() {
+		buf := &bytes.Buffer{}
+		if err := renderResource(res.Unstructured(), res.FilePath(), buf); err != nil {
+			return nil, fmt.Errorf("render hook resource %q: %w", res.HumanID(), err)
+		}
+
+		result.Hooks = append(result.Hooks, buf.String())
+	}
+
+	for _, res := range rel.GeneralResources() {
+		buf := &bytes.Buffer{}
+		if err := renderResource(res.Unstructured(), res.FilePath(), buf); err != nil {
+			return nil, fmt.Errorf("render general resource %q: %w", res.HumanID(), err)
+		}
+
+		result.Manifests = append(result.Manifests, buf.String())
+	}
+
+	return result, nil
+}
+
+func printReleaseGetResultTable(result *ReleaseGetResult, outStream io.Writer) error {
+	headerStyle := color.Style{color.Bold, color.Blue}
+
+	values := "-\n"
+	if len(result.Values) > 0 {
+		valuesBytes, err := yaml.Marshal(result.Values)
+		if err != nil {
+			return fmt.Errorf("marshal values to YAML: %w", err)
+		}
+
+		values = string(valuesBytes)
+	}
+
+	var out strings.Builder
+	fmt.Fprintln(&out, headerStyle.Render("Release"))
+	fmt.Fprintf(&out, "Name: %s\n", result.Name)
+	fmt.Fprintf(&out, "Namespace: %s\n", result.Namespace)
+	fmt.Fprintf(&out, "Revision: %d\n", result.Revision)
+	fmt.Fprintf(&out, "Status: %s\n", result.Status)
+	fmt.Fprintf(&out, "Deploy type: %s\n", valueOrDash(result.DeployType))
+	fmt.Fprintf(&out, "First deployed: %s\n", formatReleaseTime(result.FirstDeployed))
+	fmt.Fprintf(&out, "Last deployed: %s\n", formatReleaseTime(result.LastDeployed))
+	fmt.Fprintf(&out, "Chart: %s\n", valueOrDash(chartReference(result.Chart, result.ChartVersion)))
+	fmt.Fprintf(&out, "App version: %s\n", valueOrDash(result.AppVersion))
+
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, headerStyle.Render("Values"))
+	fmt.Fprint(&out, values)
+
+	if result.Notes != "" {
+		fmt.Fprintln(&out)
+		fmt.Fprintln(&out, headerStyle.Render("Notes"))
+		fmt.Fprintln(&out, result.Notes)
+	}
+
+	if len(result.Hooks) > 0 {
+		fmt.Fprintln(&out)
+		fmt.Fprintln(&out, headerStyle.Render("Hooks"))
+		fmt.Fprint(&out, strings.Join(result.Hooks, ""))
+	}
+
+	if len(result.Manifests) > 0 {
+		fmt.Fprintln(&out)
+		fmt.Fprintln(&out, headerStyle.Render("Manifests"))
+		fmt.Fprint(&out, strings.Join(result.Manifests, ""))
+	}
+
+	if _, err := io.WriteString(outStream, out.String()); err != nil {
+		return fmt.Errorf("write to output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	prtable "github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+)
+
+type ReleaseHistoryOptions struct {
+	KubeConfigBase64     string
+	KubeConfigPaths      []string
+	KubeContext          string
+	LogDebug             bool
+	OutputFormat         OutputFormat
+	OutputStream         io.Writer
+	ReleaseName          string
+	ReleaseNamespace     string
+	ReleaseStorageDriver ReleaseStorageDriver
+	ReleaseStoragePath   string
+}
+
+type ReleaseHistoryEntry struct {
+	Revision      int       `json:"revision"`
+	Status        string    `json:"status"`
+	DeployType    string    `json:"deployType,omitempty"`
+	FirstDeployed time.Time `json:"firstDeployed"`
+	LastDeployed  time.Time `json:"lastDeployed"`
+	Chart         string    `json:"chart,omitempty"`
+	ChartVersion  string    `json:"chartVersion,omitempty"`
+	AppVersion    string    `json:"appVersion,omitempty"`
+}
+
+func ReleaseHistory(ctx context.Context, opts ReleaseHistoryOptions) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseHistoryOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return fmt.Errorf("build release history options: %w", err)
+	}
+
+	var kubeConfigPath string
+	if len(opts.KubeConfigPaths) > 0 {
+		kubeConfigPath = opts.KubeConfigPaths[0]
+	}
+
+	kubeConfigGetter, err := kube.NewKubeConfigGetter(
+		kube.KubeConfigGetterOptions{
+			KubeConfigOptions: kube.KubeConfigOptions{
+				Context:             opts.KubeContext,
+				ConfigPath:          kubeConfigPath,
+				ConfigDataBase64:    opts.KubeConfigBase64,
+				ConfigPathMergeList: opts.KubeConfigPaths,
+			},
+			Namespace: opts.ReleaseNamespace,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct kube config getter: %w", err)
+	}
+
+	helmSettings := cli.New()
+	*helmSettings.GetConfigP() = kubeConfigGetter
+	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
+	opts.ReleaseNamespace = helmSettings.Namespace()
+	helmSettings.Debug = opts.LogDebug
+
+	if opts.KubeContext != "" {
+		helmSettings.KubeContext = opts.KubeContext
+	}
+
+	if kubeConfigPath != "" {
+		helmSettings.KubeConfig = kubeConfigPath
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		helmSettings.RESTClientGetter(),
+		opts.ReleaseNamespace,
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Info(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	history, err := rlshistor.NewHistory(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmActionConfig.Releases, clientFactory),
+		rlshistor.HistoryOptions{
+			Mapper:          clientFactory.Mapper(),
+			DiscoveryClient: clientFactory.Discovery(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct release history: %w", err)
+	}
+
+	if history.Empty() {
+		return fmt.Errorf("release %q (namespace: %q) not found", opts.ReleaseName, opts.ReleaseNamespace)
+	}
+
+	rels, err := history.Releases()
+	if err != nil {
+		return fmt.Errorf("get releases: %w", err)
+	}
+
+	var entries []*ReleaseHistoryEntry
+	for _, rel := range rels {
+		entries = append(entries, newReleaseHistoryEntry(rel))
+	}
+
+	switch opts.OutputFormat {
+	case OutputFormatJSON:
+		if err := printJSON(entries, opts.OutputStream); err != nil {
+			return fmt.Errorf("print release history as JSON: %w", err)
+		}
+	case OutputFormatYAML:
+		if err := printYAML(entries, opts.OutputStream); err != nil {
+			return fmt.Errorf("print release history as YAML: %w", err)
+		}
+	default:
+		table := prtable.NewWriter()
+		setReleaseTableStyle(table)
+		table.AppendHeader(prtable.Row{"Revision", "Status", "Deploy type", "First deployed", "Last deployed", "Chart", "App version"})
+
+		for _, entry := range entries {
+			table.AppendRow(prtable.Row{
+				entry.Revision,
+				entry.Status,
+				valueOrDash(entry.DeployType),
+				formatReleaseTime(entry.FirstDeployed),
+				formatReleaseTime(entry.LastDeployed),
+				valueOrDash(chartReference(entry.Chart, entry.ChartVersion)),
+				valueOrDash(entry.AppVersion),
+			})
+		}
+
+		if _, err := fmt.Fprintln(opts.OutputStream, table.Render()); err != nil {
+			return fmt.Errorf("print release history table: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func applyReleaseHistoryOptionsDefaults(opts ReleaseHistoryOptions, currentUser *user.User) (ReleaseHistoryOptions, error) {
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.OutputStream == nil {
+		opts.OutputStream = os.Stdout
+	}
+
+	switch opts.OutputFormat {
+	case OutputFormatDefault:
+		opts.OutputFormat = OutputFormatTable
+	case OutputFormatTable, OutputFormatJSON, OutputFormatYAML:
+	default:
+		return ReleaseHistoryOptions{}, fmt.Errorf("unknown output format %q", opts.OutputFormat)
+	}
+
+	if opts.ReleaseName == "" {
+		return ReleaseHistoryOptions{}, fmt.Errorf("release name not specified")
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
+		return ReleaseHistoryOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return ReleaseHistoryOptions{}, err
+	}
+
+	return opts, nil
+}
+
+func newReleaseHistoryEntry(rel *rls.Release) *ReleaseHistoryEntry {
+	entry := &ReleaseHistoryEntry{
+		Revision:      rel.Revision(),
+		Status:        string(rel.Status()),
+		DeployType:    string(rel.DeployType()),
+		FirstDeployed: rel.FirstDeployed(),
+		LastDeployed:  rel.LastDeployed(),
+	}
+
+	if rel.LegacyChart() != nil && rel.LegacyChart().Metadata != nil {
+		entry.Chart = rel.LegacyChart().Metadata.Name
+		entry.ChartVersion = rel.LegacyChart().Metadata.Version
+		entry.AppVersion = rel.LegacyChart().Metadata.AppVersion
+	}
+
+	return entry
+}
+
+func setReleaseTableStyle(table prtable.Writer) {
+	style := prtable.StyleDefault
+	style.Options = prtable.OptionsNoBordersAndSeparators
+	style.Format.Header = text.FormatUpper
+	table.SetStyle(style)
+}
+
+func printJSON(obj interface{}, outStream io.Writer) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal to JSON: %w", err)
+	}
+
+	if _, err := outStream.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write to output: %w", err)
+	}
+
+	return nil
+}
+
+func printYAML(obj interface{}, outStream io.Writer) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("marshal to YAML: %w", err)
+	}
+
+	if _, err := outStream.Write(data); err != nil {
+		return fmt.Errorf("write to output: %w", err)
+	}
+
+	return nil
+}
+
+func formatReleaseTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.RFC3339)
+}
+
+func chartReference(name, version string) string {
+	if name == "" {
+		return ""
+	} else if version == "" {
+		return name
+	}
+
+	return name + "-" + version
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,304 @@
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/gookit/color"
+	corev1 "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsarchiv"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+type ReleaseImportOptions struct {
+	AdoptResources       bool
+	InputFilePath        string
+	KubeConfigBase64     string
+	KubeConfigPaths      []string
+	KubeContext          string
+	LogDebug             bool
+	ReleaseNamespace     string
+	ReleaseStorageDriver ReleaseStorageDriver
+	ReleaseStoragePath   string
+}
+
+// Re-creates all revisions of the release from the archive made by ReleaseExport. If
+// ReleaseNamespace differs from the namespace the release was exported from, the release
+// is moved to the new namespace.
+func ReleaseImport(ctx context.Context, opts ReleaseImportOptions) error {
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyReleaseImportOptionsDefaults(opts, currentUser)
+	if err != nil {
+		return fmt.Errorf("build release import options: %w", err)
+	}
+
+	archive, err := readReleaseArchive(opts.InputFilePath)
+	if err != nil {
+		return fmt.Errorf("read release archive: %w", err)
+	}
+
+	releaseName := archive.Metadata.ReleaseName
+	if opts.ReleaseNamespace == "" {
+		opts.ReleaseNamespace = archive.Metadata.ReleaseNamespace
+	}
+
+	var kubeConfigPath string
+	if len(opts.KubeConfigPaths) > 0 {
+		kubeConfigPath = opts.KubeConfigPaths[0]
+	}
+
+	kubeConfigGetter, err := kube.NewKubeConfigGetter(
+		kube.KubeConfigGetterOptions{
+			KubeConfigOptions: kube.KubeConfigOptions{
+				Context:             opts.KubeContext,
+				ConfigPath:          kubeConfigPath,
+				ConfigDataBase64:    opts.KubeConfigBase64,
+				ConfigPathMergeList: opts.KubeConfigPaths,
+			},
+			Namespace: opts.ReleaseNamespace,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct kube config getter: %w", err)
+	}
+
+	helmSettings := cli.New()
+	*helmSettings.GetConfigP() = kubeConfigGetter
+	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
+	opts.ReleaseNamespace = helmSettings.Namespace()
+	helmSettings.Debug = opts.LogDebug
+
+	if opts.KubeContext != "" {
+		helmSettings.KubeContext = opts.KubeContext
+	}
+
+	if kubeConfigPath != "" {
+		helmSettings.KubeConfig = kubeConfigPath
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		helmSettings.RESTClientGetter(),
+		opts.ReleaseNamespace,
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Info(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Importing release")+" %q (namespace: %q)", releaseName, opts.ReleaseNamespace)
+
+	if opts.ReleaseStorageDriver != ReleaseStorageDriverFilesystem {
+		if err := createReleaseNamespaceIfNotExists(ctx, clientFactory, opts.ReleaseNamespace); err != nil {
+			return fmt.Errorf("create release namespace: %w", err)
+		}
+	}
+
+	releaseStorage := newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmActionConfig.Releases, clientFactory)
+
+	if existingRels, err := releaseStorage.Releases(ctx, releaseName); err != nil {
+		return fmt.Errorf("get existing releases: %w", err)
+	} else if len(existingRels) > 0 {
+		return fmt.Errorf("release %q (namespace: %q) already exists", releaseName, opts.ReleaseNamespace)
+	}
+
+	for _, legacyRel := range archive.Releases {
+		if err := rlsarchiv.RewriteNamespace(legacyRel, opts.ReleaseNamespace); err != nil {
+			return fmt.Errorf("rewrite release namespace: %w", err)
+		}
+	}
+
+	if err := createImportedReleases(ctx, releaseStorage, archive.Releases); err != nil {
+		return fmt.Errorf("create releases: %w", err)
+	}
+
+	if opts.AdoptResources {
+		log.Default.Info(ctx, "Constructing release history")
+		history, err := rlshistor.NewHistory(
+			ctx,
+			releaseName,
+			opts.ReleaseNamespace,
+			releaseStorage,
+			rlshistor.HistoryOptions{
+				Mapper:          clientFactory.Mapper(),
+				DiscoveryClient: clientFactory.Discovery(),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("construct release history: %w", err)
+		}
+
+		if deployedRelease, found, err := history.LastDeployedRelease(); err != nil {
+			return fmt.Errorf("get last deployed release: %w", err)
+		} else if found {
+			if err := adoptImportedReleaseResources(ctx, deployedRelease, archive.Metadata.ReleaseNamespace, clientFactory.KubeClient()); err != nil {
+				return fmt.Errorf("adopt release resources: %w", err)
+			}
+		}
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Imported")+" %d revisions of release %q (namespace: %q)", len(archive.Releases), releaseName, opts.ReleaseNamespace)
+
+	return nil
+}
+
+func applyReleaseImportOptionsDefaults(opts ReleaseImportOptions, currentUser *user.User) (ReleaseImportOptions, error) {
+	if opts.InputFilePath == "" {
+		return ReleaseImportOptions{}, fmt.Errorf("input file path not specified")
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
+		return ReleaseImportOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return ReleaseImportOptions{}, err
+	}
+
+	return opts, nil
+}
+
+func readReleaseArchive(path string) (*rlsarchiv.Archive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive file: %w", err)
+	}
+	defer file.Close()
+
+	archive, err := rlsarchiv.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("read archive file %q: %w", path, err)
+	}
+
+	return archive, nil
+}
+
+// Revisions are stored as they are in the archive. If any of them fails to be created, the
+// already created ones are deleted, so that the import can be retried.
+func createImportedReleases(ctx context.Context, releaseStorage rlsstor.ReleaseStorager, legacyRels []*legacyRelease.Release) error {
+	var createdRels []*legacyRelease.Release
+	for _, legacyRel := range legacyRels {
+		log.Default.Info(ctx, "Creating revision %d", legacyRel.Version)
+		if err := releaseStorage.Create(ctx, legacyRel); err != nil {
+			deleteImportedReleases(context.WithoutCancel(ctx), releaseStorage, createdRels)
+			return fmt.Errorf("create revision %d: %w", legacyRel.Version, err)
+		}
+
+		createdRels = append(createdRels, legacyRel)
+	}
+
+	return nil
+}
+
+func deleteImportedReleases(ctx context.Context, releaseStorage rlsstor.ReleaseStorager, legacyRels []*legacyRelease.Release) {
+	for _, legacyRel := range legacyRels {
+		log.Default.Info(ctx, "Deleting imported revision %d", legacyRel.Version)
+		if err := releaseStorage.Delete(ctx, legacyRel.Name, legacyRel.Version); err != nil {
+			log.Default.Warn(ctx, "Failed to delete imported revision %d of release %q (namespace: %q), delete it manually before retrying: %s", legacyRel.Version, legacyRel.Name, legacyRel.Namespace, err)
+		}
+	}
+}
+
+func createReleaseNamespaceIfNotExists(ctx context.Context, clientFactory *kubeclnt.ClientFactory, namespace string) error {
+	if _, err := clientFactory.Static().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err == nil {
+		return nil
+	} else if !api_errors.IsNotFound(err) {
+		return fmt.Errorf("get namespace %q: %w", namespace, err)
+	}
+
+	log.Default.Info(ctx, "Creating namespace %q", namespace)
+	if _, err := clientFactory.Static().CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+		},
+	}, metav1.CreateOptions{}); err != nil && !api_errors.IsAlreadyExists(err) {
+		return fmt.Errorf("create namespace %q: %w", namespace, err)
+	}
+
+	return nil
+}
+
+// Points release annotations of the live resources to the imported release. Resources
+// owned by other releases are left alone.
+func adoptImportedReleaseResources(ctx context.Context, rel *rls.Release, exportedFromNamespace string, kubeClient kubeclnt.KubeClienter) error {
+	for _, res := range rel.GeneralResources() {
+		if res.ManageableBy() != resrc.ManageableBySingleRelease {
+			continue
+		}
+
+		obj, err := kubeClient.Get(ctx, res.ResourceID, kubeclnt.KubeClientGetOptions{})
+		if err != nil {
+			if api_errors.IsNotFound(err) {
+				log.Default.Debug(ctx, "Skipping adoption of not found resource %q", res.HumanID())
+				continue
+			}
+
+			return fmt.Errorf("get resource %q: %w", res.HumanID(), err)
+		}
+
+		annos := obj.GetAnnotations()
+		if name, found := annos["meta.helm.sh/release-name"]; found && name != rel.Name() {
+			log.Default.Warn(ctx, "Skipping adoption of resource %q owned by release %q", res.HumanID(), name)
+			continue
+		}
+
+		if namespace, found := annos["meta.helm.sh/release-namespace"]; found && namespace != exportedFromNamespace && namespace != rel.Namespace() {
+			log.Default.Warn(ctx, "Skipping adoption of resource %q owned by release in namespace %q", res.HumanID(), namespace)
+			continue
+		}
+
+		unstruct := unstructured.Unstructured{Object: map[string]interface{}{}}
+		unstruct.SetAnnotations(map[string]string{
+			"meta.helm.sh/release-name":      rel.Name(),
+			"meta.helm.sh/release-namespace": rel.Namespace(),
+		})
+		unstruct.SetLabels(map[string]string{
+			"app.kubernetes.io/managed-by": "Helm",
+		})
+
+		patch, err := json.Marshal(unstruct.UnstructuredContent())
+		if err != nil {
+			return fmt.Errorf("marshal release metadata patch: %w", err)
+		}
+
+		log.Default.Info(ctx, "Adopting resource %q", res.HumanID())
+		if _, err := kubeClient.MergePatch(ctx, res.ResourceID, patch); err != nil {
+			return fmt.Errorf("patch resource %q: %w", res.HumanID(), err)
+		}
+	}
+
+	return nil
+}
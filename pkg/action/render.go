@@ -14,7 +14,6 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/yaml"
 
-	helm_v3 "github.com/werf/3p-helm-for-werf-helm/cmd/helm"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/chartutil"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
@@ -24,13 +23,13 @@ import (
 
 	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
 	"github.com/werf/nelm-for-werf-helm/pkg/chrttree"
-	helmcommon "github.com/werf/nelm-for-werf-helm/pkg/common"
 	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
 	"github.com/werf/nelm-for-werf-helm/pkg/log"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcpatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcprocssr"
 	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
 	"github.com/werf/nelm-for-werf-helm/pkg/secrets_manager"
 )
 
@@ -56,10 +55,12 @@ type RenderOptions struct {
 	LogDebug                     bool
 	LogRegistryStreamOut         io.Writer
 	NetworkParallelism           int
+	RegistryClient               *registry.Client
 	RegistryCredentialsPath      string
 	ReleaseName                  string
 	ReleaseNamespace             string
 	ReleaseStorageDriver         ReleaseStorageDriver
+	ReleaseStoragePath           string
 	OutputFilePath               string
 	OutputFileSave               bool
 	SecretKeyIgnore              bool
@@ -123,7 +124,7 @@ func Render(ctx context.Context, opts RenderOptions) error {
 		return fmt.Errorf("construct kube config getter: %w", err)
 	}
 
-	helmSettings := helm_v3.Settings
+	helmSettings := cli.New()
 	*helmSettings.GetConfigP() = kubeConfigGetter
 	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
 	opts.ReleaseNamespace = helmSettings.Namespace()
@@ -137,35 +138,38 @@ func Render(ctx context.Context, opts RenderOptions) error {
 		helmSettings.KubeConfig = kubeConfigPath
 	}
 
-	helmRegistryClientOpts := []registry.ClientOption{
-		registry.ClientOptDebug(opts.LogDebug),
-		registry.ClientOptWriter(opts.LogRegistryStreamOut),
-	}
+	helmRegistryClient := opts.RegistryClient
+	if helmRegistryClient == nil {
+		helmRegistryClientOpts := []registry.ClientOption{
+			registry.ClientOptDebug(opts.LogDebug),
+			registry.ClientOptWriter(opts.LogRegistryStreamOut),
+		}
 
-	if opts.ChartRepositoryInsecure {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptPlainHTTP(),
-		)
-	}
+		if opts.ChartRepositoryInsecure {
+			helmRegistryClientOpts = append(
+				helmRegistryClientOpts,
+				registry.ClientOptPlainHTTP(),
+			)
+		}
 
-	if opts.RegistryCredentialsPath != "" {
-		helmRegistryClientOpts = append(
-			helmRegistryClientOpts,
-			registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
-		)
-	}
+		if opts.RegistryCredentialsPath != "" {
+			helmRegistryClientOpts = append(
+				helmRegistryClientOpts,
+				registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
+			)
+		}
 
-	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
-	if err != nil {
-		return fmt.Errorf("construct registry client: %w", err)
+		helmRegistryClient, err = registry.NewClient(helmRegistryClientOpts...)
+		if err != nil {
+			return fmt.Errorf("construct registry client: %w", err)
+		}
 	}
 
 	helmActionConfig := &action.Configuration{}
 	if err := helmActionConfig.Init(
 		helmSettings.RESTClientGetter(),
 		opts.ReleaseNamespace,
-		string(opts.ReleaseStorageDriver),
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
 		func(format string, a ...interface{}) {
 			log.Default.Info(ctx, format, a...)
 		},
@@ -191,7 +195,7 @@ func Render(ctx context.Context, opts RenderOptions) error {
 			helmActionConfig.Capabilities.KubeVersion = *kubeVersion
 		}
 	} else {
-		clientFactory, err = kubeclnt.NewClientFactory()
+		clientFactory, err = kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
 		if err != nil {
 			return fmt.Errorf("construct kube client factory: %w", err)
 		}
@@ -231,52 +235,40 @@ func Render(ctx context.Context, opts RenderOptions) error {
 	}
 
 	var historyOptions rlshistor.HistoryOptions
-	if !opts.Local {
+	var releaseStorage rlsstor.ReleaseStorager
+	if opts.Local {
+		releaseStorage = rlsstor.NewLegacyReleaseStorage(helmReleaseStorage)
+	} else {
 		historyOptions.Mapper = clientFactory.Mapper()
 		historyOptions.DiscoveryClient = clientFactory.Discovery()
+		releaseStorage = newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmReleaseStorage, clientFactory)
 	}
 
 	history, err := rlshistor.NewHistory(
+		ctx,
 		opts.ReleaseName,
 		opts.ReleaseNamespace,
-		helmReleaseStorage,
+		releaseStorage,
 		historyOptions,
 	)
 	if err != nil {
 		return fmt.Errorf("construct release history: %w", err)
 	}
 
-	prevRelease, prevReleaseFound, err := history.LastRelease()
-	if err != nil {
-		return fmt.Errorf("get last release: %w", err)
-	}
-
-	_, prevDeployedReleaseFound, err := history.LastDeployedRelease()
+	deployInfo, err := rlshistor.NewDeployInfo(history)
 	if err != nil {
-		return fmt.Errorf("get last deployed release: %w", err)
+		return fmt.Errorf("get deploy info: %w", err)
 	}
 
-	var newRevision int
-	if prevReleaseFound {
-		newRevision = prevRelease.Revision() + 1
-	} else {
-		newRevision = 1
-	}
-
-	var deployType helmcommon.DeployType
-	if prevReleaseFound && prevDeployedReleaseFound {
-		deployType = helmcommon.DeployTypeUpgrade
-	} else if prevReleaseFound {
-		deployType = helmcommon.DeployTypeInstall
-	} else {
-		deployType = helmcommon.DeployTypeInitial
-	}
+	prevRelease, prevReleaseFound := deployInfo.PrevRelease, deployInfo.PrevReleaseFound
+	newRevision, deployType := deployInfo.NewRevision, deployInfo.DeployType
 
 	chartTreeOptions := chrttree.ChartTreeOptions{
 		StringSetValues: opts.ValuesStringSets,
 		SetValues:       opts.ValuesSets,
 		FileValues:      opts.ValuesFileSets,
 		ValuesFiles:     opts.ValuesFilesPaths,
+		HelmSettings:    helmSettings,
 	}
 	if !opts.Local {
 		chartTreeOptions.Mapper = clientFactory.Mapper()
@@ -460,6 +452,8 @@ func applyRenderOptionsDefaults(opts RenderOptions, currentDir string, currentUs
 
 	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
 		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return RenderOptions{}, err
 	}
 
 	return opts, nil
@@ -0,0 +1,675 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/samber/lo"
+	"github.com/xo/terminfo"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/registry"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
+	kubeutil "github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
+	helmcommon "github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/lock_manager"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnbuilder"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnexectr"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
+	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcpatcher"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcprocssr"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+	"github.com/werf/nelm-for-werf-helm/pkg/track"
+	"github.com/werf/nelm-for-werf-helm/pkg/utls"
+)
+
+const (
+	DefaultRollbackReportFilename = "rollback-report.json"
+	DefaultRollbackJUnitFilename  = "rollback-junit.xml"
+)
+
+type RollbackOptions struct {
+	ExtraAnnotations           map[string]string
+	ExtraLabels                map[string]string
+	ExtraRuntimeAnnotations    map[string]string
+	KubeConfigBase64           string
+	KubeConfigPaths            []string
+	KubeContext                string
+	LogColorMode               LogColorMode
+	LogDebug                   bool
+	LogRegistryStreamOut       io.Writer
+	NetworkParallelism         int
+	ProgressTablePrint         bool
+	ProgressTablePrintInterval time.Duration
+	ReadinessRulesFilePath     string
+	RedactionRules             []string
+	RegistryCredentialsPath    string
+	ReleaseHistoryFailedLimit  int
+	ReleaseHistoryLimit        int
+	ReleaseName                string
+	ReleaseNamespace           string
+	ReleaseStorageDriver       ReleaseStorageDriver
+	ReleaseStoragePath         string
+	RetryAttempts              int
+	Revision                   int
+	RollbackGraphPath          string
+	RollbackGraphSave          bool
+	RollbackJUnitPath          string
+	RollbackJUnitSave          bool
+	RollbackReportPath         string
+	RollbackReportSave         bool
+	RollbackReportVersion      int
+	TempDirPath                string
+	Timeout                    time.Duration
+	TrackCreationTimeout       time.Duration
+	TrackDeletionTimeout       time.Duration
+	TrackParallelism           int
+	TrackReadinessTimeout      time.Duration
+}
+
+func Rollback(ctx context.Context, opts RollbackOptions) error {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current working directory: %w", err)
+	}
+
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("get current user: %w", err)
+	}
+
+	opts, err = applyRollbackOptionsDefaults(opts, currentDir, currentUser)
+	if err != nil {
+		return fmt.Errorf("build rollback options: %w", err)
+	}
+
+	redactor, err := newRedactor(opts.RedactionRules)
+	if err != nil {
+		return fmt.Errorf("construct redactor: %w", err)
+	}
+
+	if opts.Timeout > 0 {
+		var ctxCancelFn context.CancelFunc
+		ctx, ctxCancelFn = context.WithTimeoutCause(ctx, opts.Timeout, fmt.Errorf("timed out after %s", opts.Timeout))
+		defer ctxCancelFn()
+	}
+
+	var kubeConfigPath string
+	if len(opts.KubeConfigPaths) > 0 {
+		kubeConfigPath = opts.KubeConfigPaths[0]
+	}
+
+	kubeConfigGetter, err := kube.NewKubeConfigGetter(
+		kube.KubeConfigGetterOptions{
+			KubeConfigOptions: kube.KubeConfigOptions{
+				Context:             opts.KubeContext,
+				ConfigPath:          kubeConfigPath,
+				ConfigDataBase64:    opts.KubeConfigBase64,
+				ConfigPathMergeList: opts.KubeConfigPaths,
+			},
+			Namespace: opts.ReleaseNamespace,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct kube config getter: %w", err)
+	}
+
+	helmSettings := cli.New()
+	*helmSettings.GetConfigP() = kubeConfigGetter
+	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
+	opts.ReleaseNamespace = helmSettings.Namespace()
+	helmSettings.MaxHistory = opts.ReleaseHistoryLimit
+	helmSettings.Debug = opts.LogDebug
+
+	if opts.KubeContext != "" {
+		helmSettings.KubeContext = opts.KubeContext
+	}
+
+	if kubeConfigPath != "" {
+		helmSettings.KubeConfig = kubeConfigPath
+	}
+
+	helmRegistryClientOpts := []registry.ClientOption{
+		registry.ClientOptDebug(opts.LogDebug),
+		registry.ClientOptWriter(opts.LogRegistryStreamOut),
+	}
+
+	if opts.RegistryCredentialsPath != "" {
+		helmRegistryClientOpts = append(
+			helmRegistryClientOpts,
+			registry.ClientOptCredentialsFile(opts.RegistryCredentialsPath),
+		)
+	}
+
+	helmRegistryClient, err := registry.NewClient(helmRegistryClientOpts...)
+	if err != nil {
+		return fmt.Errorf("construct registry client: %w", err)
+	}
+
+	helmActionConfig := &action.Configuration{}
+	if err := helmActionConfig.Init(
+		helmSettings.RESTClientGetter(),
+		opts.ReleaseNamespace,
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
+		func(format string, a ...interface{}) {
+			log.Default.Info(ctx, format, a...)
+		},
+	); err != nil {
+		return fmt.Errorf("helm action config init: %w", err)
+	}
+	helmActionConfig.RegistryClient = helmRegistryClient
+
+	helmReleaseStorage := helmActionConfig.Releases
+
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
+	if err != nil {
+		return fmt.Errorf("construct kube client factory: %w", err)
+	}
+
+	var lockManager *lock_manager.LockManager
+	if m, err := lock_manager.NewLockManager(
+		opts.ReleaseNamespace,
+		false,
+		clientFactory.Static(),
+		clientFactory.Dynamic(),
+	); err != nil {
+		return fmt.Errorf("construct lock manager: %w", err)
+	} else {
+		lockManager = m
+	}
+
+	log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Starting rollback of release")+" %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)
+
+	if lock, err := lockManager.LockRelease(ctx, opts.ReleaseName); err != nil {
+		return fmt.Errorf("lock release: %w", err)
+	} else {
+		defer lockManager.Unlock(lock)
+	}
+
+	log.Default.Info(ctx, "Constructing release history")
+	history, err := rlshistor.NewHistory(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmReleaseStorage, clientFactory),
+		rlshistor.HistoryOptions{
+			Mapper:                  clientFactory.Mapper(),
+			DiscoveryClient:         clientFactory.Discovery(),
+			SucceededRevisionsLimit: opts.ReleaseHistoryLimit,
+			FailedRevisionsLimit:    opts.ReleaseHistoryFailedLimit,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct release history: %w", err)
+	}
+
+	prevRelease, prevReleaseFound, err := history.LastRelease()
+	if err != nil {
+		return fmt.Errorf("get last release: %w", err)
+	} else if !prevReleaseFound {
+		return fmt.Errorf("release %q (namespace: %q) not found", opts.ReleaseName, opts.ReleaseNamespace)
+	}
+
+	prevDeployedRelease, _, err := history.LastDeployedRelease()
+	if err != nil {
+		return fmt.Errorf("get last deployed release: %w", err)
+	}
+
+	targetRelease, err := rollbackTargetRelease(history, prevRelease, opts.Revision)
+	if err != nil {
+		return fmt.Errorf("get rollback target release: %w", err)
+	}
+
+	log.Default.Info(ctx, "Rolling back to revision %d", targetRelease.Revision())
+
+	var readinessRules []*rdnsrule.ReadinessRule
+	if opts.ReadinessRulesFilePath != "" {
+		readinessRules, err = rdnsrule.LoadRules(opts.ReadinessRulesFilePath)
+		if err != nil {
+			return fmt.Errorf("load readiness rules: %w", err)
+		}
+	}
+
+	newRevision := prevRelease.Revision() + 1
+	deployType := helmcommon.DeployTypeRollback
+
+	log.Default.Info(ctx, "Processing resources")
+	resProcessor := resrcprocssr.NewDeployableResourcesProcessor(
+		deployType,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		nil,
+		targetRelease.HookResources(),
+		targetRelease.GeneralResources(),
+		prevRelease.GeneralResources(),
+		resrcprocssr.DeployableResourcesProcessorOptions{
+			NetworkParallelism: opts.NetworkParallelism,
+			ReleasableHookResourcePatchers: []resrcpatcher.ResourcePatcher{
+				resrcpatcher.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
+			},
+			ReleasableGeneralResourcePatchers: []resrcpatcher.ResourcePatcher{
+				resrcpatcher.NewExtraMetadataPatcher(opts.ExtraAnnotations, opts.ExtraLabels),
+			},
+			DeployableStandaloneCRDsPatchers: []resrcpatcher.ResourcePatcher{
+				resrcpatcher.NewExtraMetadataPatcher(
+					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
+				),
+			},
+			DeployableHookResourcePatchers: []resrcpatcher.ResourcePatcher{
+				resrcpatcher.NewExtraMetadataPatcher(
+					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
+				),
+			},
+			DeployableGeneralResourcePatchers: []resrcpatcher.ResourcePatcher{
+				resrcpatcher.NewExtraMetadataPatcher(
+					lo.Assign(opts.ExtraAnnotations, opts.ExtraRuntimeAnnotations), opts.ExtraLabels,
+				),
+			},
+			KubeClient:         clientFactory.KubeClient(),
+			Mapper:             clientFactory.Mapper(),
+			DiscoveryClient:    clientFactory.Discovery(),
+			AllowClusterAccess: true,
+		},
+	)
+
+	if err := resProcessor.Process(ctx); err != nil {
+		return fmt.Errorf("process resources: %w", err)
+	}
+
+	log.Default.Info(ctx, "Constructing rollback release")
+	newRel, err := rls.NewRelease(
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newRevision,
+		targetRelease.Values(),
+		targetRelease.LegacyChart(),
+		resProcessor.ReleasableHookResources(),
+		resProcessor.ReleasableGeneralResources(),
+		targetRelease.Notes(),
+		rls.ReleaseOptions{
+			FirstDeployed: prevRelease.FirstDeployed(),
+			Mapper:        clientFactory.Mapper(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("construct rollback release: %w", err)
+	}
+
+	textRedactor := newReleasesTextRedactor(redactor, newRel, prevRelease)
+
+	taskStore := statestore.NewTaskStore()
+	logStore := kubeutil.NewConcurrent(
+		logstore.NewLogStore(),
+	)
+
+	log.Default.Info(ctx, "Constructing rollback plan")
+	rollbackPlanBuilder := plnbuilder.NewDeployPlanBuilder(
+		opts.ReleaseNamespace,
+		deployType,
+		taskStore,
+		logStore,
+		nil,
+		resProcessor.DeployableHookResourcesInfos(),
+		resProcessor.DeployableGeneralResourcesInfos(),
+		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+		newRel,
+		history,
+		clientFactory.KubeClient(),
+		clientFactory.Static(),
+		clientFactory.Dynamic(),
+		clientFactory.Discovery(),
+		clientFactory.Mapper(),
+		plnbuilder.DeployPlanBuilderOptions{
+			PrevRelease:         prevRelease,
+			PrevDeployedRelease: prevDeployedRelease,
+			CreationTimeout:     opts.TrackCreationTimeout,
+			ReadinessTimeout:    opts.TrackReadinessTimeout,
+			DeletionTimeout:     opts.TrackDeletionTimeout,
+			ReadinessRules:      readinessRules,
+		},
+	)
+
+	plan, planBuildErr := rollbackPlanBuilder.Build(ctx)
+	if planBuildErr != nil {
+		if _, err := os.Create(opts.RollbackGraphPath); err != nil {
+			log.Default.Error(ctx, "Error: create rollback graph file: %s", err)
+			return fmt.Errorf("build rollback plan: %w", planBuildErr)
+		}
+
+		if err := plan.SaveDOT(opts.RollbackGraphPath); err != nil {
+			log.Default.Error(ctx, "Error: save rollback graph: %s", err)
+		}
+
+		log.Default.Warn(ctx, "Rollback graph saved to %q for debugging", opts.RollbackGraphPath)
+
+		return fmt.Errorf("build rollback plan: %w", planBuildErr)
+	}
+
+	if opts.RollbackGraphSave {
+		if err := plan.SaveDOT(opts.RollbackGraphPath); err != nil {
+			return fmt.Errorf("save rollback graph: %w", err)
+		}
+	}
+
+	tablesBuilder := track.NewTablesBuilder(
+		taskStore,
+		logStore,
+		track.TablesBuilderOptions{
+			DefaultNamespace: opts.ReleaseNamespace,
+			Colorize:         opts.LogColorMode == LogColorModeOn,
+		},
+	)
+
+	log.Default.Info(ctx, "Starting tracking")
+	stdoutTrackerStopCh := make(chan bool)
+	stdoutTrackerFinishedCh := make(chan bool)
+
+	if opts.ProgressTablePrint {
+		go func() {
+			ticker := time.NewTicker(opts.ProgressTablePrintInterval)
+			defer func() {
+				ticker.Stop()
+				stdoutTrackerFinishedCh <- true
+			}()
+
+			for {
+				select {
+				case <-ticker.C:
+					printTables(ctx, tablesBuilder)
+				case <-stdoutTrackerStopCh:
+					printTables(ctx, tablesBuilder)
+					return
+				}
+			}
+		}()
+	}
+
+	log.Default.Info(ctx, "Executing rollback plan")
+	planExecutor := plnexectr.NewPlanExecutor(
+		plan,
+		plnexectr.PlanExecutorOptions{
+			NetworkParallelism: opts.NetworkParallelism,
+			TrackParallelism:   opts.TrackParallelism,
+			RetryPolicy: plnexectr.RetryPolicy{
+				Attempts: opts.RetryAttempts,
+			},
+			TextRedactor: textRedactor,
+		},
+	)
+
+	var criticalErrs, nonCriticalErrs []error
+
+	planExecutionErr := planExecutor.Execute(ctx)
+	if planExecutionErr != nil {
+		criticalErrs = append(criticalErrs, fmt.Errorf("execute rollback plan: %w", planExecutionErr))
+	}
+
+	var interruptReason string
+	if ctx.Err() != nil {
+		interruptReason = context.Cause(ctx).Error()
+		log.Default.Warn(ctx, color.Style{color.Bold, color.Yellow}.Render(fmt.Sprintf("Rollback of release %q (namespace: %q) interrupted: %s", opts.ReleaseName, opts.ReleaseNamespace, interruptReason)))
+	}
+
+	// If interrupted, still need to mark the release failed, clean up and save the report.
+	finCtx := context.WithoutCancel(ctx)
+
+	var worthyCompletedOps []opertn.Operation
+	if ops, found, err := plan.WorthyCompletedOperations(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get meaningful completed operations: %w", err))
+	} else if found {
+		worthyCompletedOps = ops
+	}
+
+	var worthyCanceledOps []opertn.Operation
+	if ops, found, err := plan.WorthyCanceledOperations(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get meaningful canceled operations: %w", err))
+	} else if found {
+		worthyCanceledOps = ops
+	}
+
+	var worthyFailedOps []opertn.Operation
+	if ops, found, err := plan.WorthyFailedOperations(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get meaningful failed operations: %w", err))
+	} else if found {
+		worthyFailedOps = ops
+	}
+
+	var executedOps []opertn.Operation
+	if ops, found, err := plan.ExecutedOperations(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get executed operations: %w", err))
+	} else if found {
+		executedOps = ops
+	}
+
+	var allOps []opertn.Operation
+	if ops, found, err := plan.Operations(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get operations: %w", err))
+	} else if found {
+		allOps = ops
+	}
+
+	var criticalPath []opertn.Operation
+	if ops, found, err := plan.CriticalPath(); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get critical path: %w", err))
+	} else if found {
+		criticalPath = ops
+	}
+
+	var pendingReleaseCreated bool
+	if ops, found, err := plan.OperationsMatch(regexp.MustCompile(fmt.Sprintf(`^%s/%s$`, opertn.TypeCreatePendingReleaseOperation, newRel.ID()))); err != nil {
+		nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get pending release operation: %w", err))
+	} else if !found {
+		panic("no pending release operation found")
+	} else {
+		pendingReleaseCreated = ops[0].Status() == opertn.StatusCompleted
+	}
+
+	if planExecutionErr != nil && pendingReleaseCreated {
+		wcompops, wfailops, wcancops, criterrs, noncriterrs := runFailureDeployPlan(
+			finCtx,
+			opts.ReleaseNamespace,
+			deployType,
+			plan,
+			taskStore,
+			resProcessor.DeployableHookResourcesInfos(),
+			resProcessor.DeployableGeneralResourcesInfos(),
+			newRel,
+			prevRelease,
+			history,
+			clientFactory,
+			opts.NetworkParallelism,
+			opts.TrackParallelism,
+			opts.RetryAttempts,
+			textRedactor,
+		)
+
+		worthyCompletedOps = append(worthyCompletedOps, wcompops...)
+		worthyFailedOps = append(worthyFailedOps, wfailops...)
+		worthyCanceledOps = append(worthyCanceledOps, wcancops...)
+		criticalErrs = append(criticalErrs, criterrs...)
+		nonCriticalErrs = append(nonCriticalErrs, noncriterrs...)
+	}
+
+	if opts.ProgressTablePrint {
+		stdoutTrackerStopCh <- true
+		<-stdoutTrackerFinishedCh
+	}
+
+	report := reprt.NewReport(
+		worthyCompletedOps,
+		worthyCanceledOps,
+		worthyFailedOps,
+		newRel,
+		reprt.ReportOptions{
+			CriticalPath:       criticalPath,
+			ExecutedOperations: executedOps,
+			InterruptReason:    interruptReason,
+			LogStore:           logStore,
+			Operations:         allOps,
+			TextRedactor:       textRedactor,
+			Version:            opts.RollbackReportVersion,
+		},
+	)
+
+	report.Print(finCtx)
+
+	if opts.RollbackReportSave {
+		if err := report.Save(opts.RollbackReportPath); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save rollback report: %w", err))
+		}
+	}
+
+	if opts.RollbackJUnitSave {
+		if err := report.SaveJUnit(opts.RollbackJUnitPath); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save rollback JUnit report: %w", err))
+		}
+	}
+
+	if len(criticalErrs) == 0 {
+		printNotes(finCtx, newRel.Notes())
+	}
+
+	if len(criticalErrs) > 0 {
+		return textRedactor.RedactError(utls.Multierrorf("failed rollback of release %q (namespace: %q) to revision %d", append(criticalErrs, nonCriticalErrs...), opts.ReleaseName, opts.ReleaseNamespace, targetRelease.Revision()))
+	} else if len(nonCriticalErrs) > 0 {
+		return textRedactor.RedactError(utls.Multierrorf("succeeded rollback of release %q (namespace: %q) to revision %d, but non-critical errors encountered", nonCriticalErrs, opts.ReleaseName, opts.ReleaseNamespace, targetRelease.Revision()))
+	} else {
+		log.Default.Info(finCtx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Succeeded rollback of release %q (namespace: %q) to revision %d", opts.ReleaseName, opts.ReleaseNamespace, targetRelease.Revision())))
+
+		return nil
+	}
+}
+
+func applyRollbackOptionsDefaults(
+	opts RollbackOptions,
+	currentDir string,
+	currentUser *user.User,
+) (RollbackOptions, error) {
+	var err error
+	if opts.TempDirPath == "" {
+		opts.TempDirPath, err = os.MkdirTemp("", "")
+		if err != nil {
+			return RollbackOptions{}, fmt.Errorf("create temp dir: %w", err)
+		}
+	}
+
+	if opts.RollbackGraphPath == "" {
+		opts.RollbackGraphPath = filepath.Join(opts.TempDirPath, DefaultRollbackGraphFilename)
+	}
+
+	if opts.RollbackReportVersion == 0 {
+		opts.RollbackReportVersion = reprt.DefaultReportVersion
+	} else if !lo.Contains(reprt.SupportedReportVersions, opts.RollbackReportVersion) {
+		return RollbackOptions{}, fmt.Errorf("unsupported report version %d, supported versions: %v", opts.RollbackReportVersion, reprt.SupportedReportVersions)
+	}
+
+	if opts.RollbackJUnitPath == "" {
+		opts.RollbackJUnitPath = filepath.Join(opts.TempDirPath, DefaultRollbackJUnitFilename)
+	}
+
+	if opts.RollbackReportPath == "" {
+		opts.RollbackReportPath = filepath.Join(opts.TempDirPath, DefaultRollbackReportFilename)
+	}
+
+	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
+		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
+	}
+
+	if opts.LogRegistryStreamOut == nil {
+		opts.LogRegistryStreamOut = os.Stdout
+	}
+
+	if opts.LogColorMode == LogColorModeDefault {
+		if color.DetectColorLevel() == terminfo.ColorLevelNone {
+			opts.LogColorMode = LogColorModeOff
+		} else {
+			opts.LogColorMode = LogColorModeOn
+		}
+	}
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = 30
+	}
+
+	if opts.RetryAttempts <= 0 {
+		opts.RetryAttempts = plnexectr.DefaultRetryPolicy.Attempts
+	}
+
+	if opts.ProgressTablePrintInterval <= 0 {
+		opts.ProgressTablePrintInterval = 5 * time.Second
+	}
+
+	if opts.ReleaseHistoryFailedLimit < 0 {
+		return RollbackOptions{}, fmt.Errorf("failed revisions history limit can't be negative, use 0 for no limit")
+	}
+
+	if opts.ReleaseHistoryLimit < 0 {
+		return RollbackOptions{}, fmt.Errorf("revisions history limit can't be negative, use 0 for no limit")
+	}
+
+	if opts.ReleaseName == "" {
+		return RollbackOptions{}, fmt.Errorf("release name not specified")
+	}
+
+	if opts.Revision < 0 {
+		return RollbackOptions{}, fmt.Errorf("revision must be a positive number, got %d", opts.Revision)
+	}
+
+	if opts.ReleaseStorageDriver == ReleaseStorageDriverDefault {
+		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
+	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
+		return RollbackOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return RollbackOptions{}, err
+	}
+
+	return opts, nil
+}
+
+// If revision is not specified, then rollback to the last successfully deployed revision preceding
+// the last release.
+func rollbackTargetRelease(history *rlshistor.History, lastRelease *rls.Release, revision int) (*rls.Release, error) {
+	if revision != 0 {
+		rel, found, err := history.Release(revision)
+		if err != nil {
+			return nil, fmt.Errorf("get release revision %d: %w", revision, err)
+		} else if !found {
+			return nil, fmt.Errorf("revision %d not found in release history", revision)
+		}
+
+		return rel, nil
+	}
+
+	for rev := lastRelease.Revision() - 1; rev > 0; rev-- {
+		rel, found, err := history.Release(rev)
+		if err != nil {
+			return nil, fmt.Errorf("get release revision %d: %w", rev, err)
+		} else if !found {
+			continue
+		}
+
+		switch rel.Status() {
+		case release.StatusDeployed, release.StatusSuperseded:
+			return rel, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no successfully deployed revision found to rollback to")
+}
@@ -2,7 +2,6 @@ package action
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"os"
 	"os/user"
@@ -11,22 +10,37 @@ import (
 
 	"github.com/gookit/color"
 	"github.com/samber/lo"
+	"github.com/xo/terminfo"
 	api_errors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
-	helm_v3 "github.com/werf/3p-helm-for-werf-helm/cmd/helm"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
-	helm_kube "github.com/werf/3p-helm-for-werf-helm/pkg/kube"
-	"github.com/werf/3p-helm-for-werf-helm/pkg/storage/driver"
-	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
-	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
 
 	"github.com/werf/kubedog-for-werf-helm/pkg/kube"
-	"github.com/werf/logboek"
-	"github.com/werf/nelm-for-werf-helm/pkg/deploy"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
+	kubeutil "github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
 	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
 	"github.com/werf/nelm-for-werf-helm/pkg/lock_manager"
 	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnbuilder"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnexectr"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
+	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+	"github.com/werf/nelm-for-werf-helm/pkg/track"
+	"github.com/werf/nelm-for-werf-helm/pkg/utls"
+)
+
+const (
+	DefaultUninstallGraphFilename  = "uninstall-graph.dot"
+	DefaultUninstallReportFilename = "uninstall-report.json"
+	DefaultUninstallJUnitFilename  = "uninstall-junit.xml"
 )
 
 type UninstallOptions struct {
@@ -35,13 +49,30 @@ type UninstallOptions struct {
 	KubeConfigBase64           string
 	KubeConfigPaths            []string
 	KubeContext                string
+	LogColorMode               LogColorMode
 	LogDebug                   bool
+	NetworkParallelism         int
+	ProgressTablePrint         bool
 	ProgressTablePrintInterval time.Duration
-	ReleaseHistoryLimit        int
+	ReadinessRulesFilePath     string
+	RedactionRules             []string
 	ReleaseName                string
 	ReleaseNamespace           string
 	ReleaseStorageDriver       ReleaseStorageDriver
+	ReleaseStoragePath         string
+	RetryAttempts              int
 	TempDirPath                string
+	Timeout                    time.Duration
+	TrackDeletionTimeout       time.Duration
+	TrackParallelism           int
+	TrackReadinessTimeout      time.Duration
+	UninstallGraphPath         string
+	UninstallGraphSave         bool
+	UninstallJUnitPath         string
+	UninstallJUnitSave         bool
+	UninstallReportPath        string
+	UninstallReportSave        bool
+	UninstallReportVersion     int
 }
 
 func Uninstall(ctx context.Context, opts UninstallOptions) error {
@@ -60,6 +91,25 @@ func Uninstall(ctx context.Context, opts UninstallOptions) error {
 		return fmt.Errorf("build uninstall options: %w", err)
 	}
 
+	redactor, err := newRedactor(opts.RedactionRules)
+	if err != nil {
+		return fmt.Errorf("construct redactor: %w", err)
+	}
+
+	var readinessRules []*rdnsrule.ReadinessRule
+	if opts.ReadinessRulesFilePath != "" {
+		readinessRules, err = rdnsrule.LoadRules(opts.ReadinessRulesFilePath)
+		if err != nil {
+			return fmt.Errorf("load readiness rules: %w", err)
+		}
+	}
+
+	if opts.Timeout > 0 {
+		var ctxCancelFn context.CancelFunc
+		ctx, ctxCancelFn = context.WithTimeoutCause(ctx, opts.Timeout, fmt.Errorf("timed out after %s", opts.Timeout))
+		defer ctxCancelFn()
+	}
+
 	var kubeConfigPath string
 	if len(opts.KubeConfigPaths) > 0 {
 		kubeConfigPath = opts.KubeConfigPaths[0]
@@ -80,11 +130,10 @@ func Uninstall(ctx context.Context, opts UninstallOptions) error {
 		return fmt.Errorf("construct kube config getter: %w", err)
 	}
 
-	helmSettings := helm_v3.Settings
+	helmSettings := cli.New()
 	*helmSettings.GetConfigP() = kubeConfigGetter
 	*helmSettings.GetNamespaceP() = opts.ReleaseNamespace
 	opts.ReleaseNamespace = helmSettings.Namespace()
-	helmSettings.MaxHistory = opts.ReleaseHistoryLimit
 	helmSettings.Debug = opts.LogDebug
 
 	if opts.KubeContext != "" {
@@ -95,26 +144,11 @@ func Uninstall(ctx context.Context, opts UninstallOptions) error {
 		helmSettings.KubeConfig = kubeConfigPath
 	}
 
-	if err := kube.Init(kube.InitOptions{
-		KubeConfigOptions: kube.KubeConfigOptions{
-			Context:             opts.KubeContext,
-			ConfigPath:          kubeConfigPath,
-			ConfigDataBase64:    opts.KubeConfigBase64,
-			ConfigPathMergeList: opts.KubeConfigPaths,
-		},
-	}); err != nil {
-		return fmt.Errorf("initialize kubedog kube client: %w", err)
-	}
-
-	if err := initKubedog(ctx); err != nil {
-		return fmt.Errorf("initialize kubedog: %w", err)
-	}
-
 	helmActionConfig := &action.Configuration{}
 	if err := helmActionConfig.Init(
 		helmSettings.RESTClientGetter(),
 		opts.ReleaseNamespace,
-		string(opts.ReleaseStorageDriver),
+		legacyReleaseStorageDriver(opts.ReleaseStorageDriver),
 		func(format string, a ...interface{}) {
 			log.Default.Info(ctx, format, a...)
 		},
@@ -123,22 +157,12 @@ func Uninstall(ctx context.Context, opts UninstallOptions) error {
 	}
 
 	helmReleaseStorage := helmActionConfig.Releases
-	helmReleaseStorage.MaxHistory = opts.ReleaseHistoryLimit
 
-	clientFactory, err := kubeclnt.NewClientFactory()
+	clientFactory, err := kubeclnt.NewClientFactory(helmSettings.RESTClientGetter())
 	if err != nil {
 		return fmt.Errorf("construct kube client factory: %w", err)
 	}
 
-	helmKubeClient := helmActionConfig.KubeClient.(*helm_kube.Client)
-	helmKubeClient.Namespace = opts.ReleaseNamespace
-	helmKubeClient.ResourcesWaiter = deploy.NewResourcesWaiter(
-		helmKubeClient,
-		time.Now(),
-		opts.ProgressTablePrintInterval,
-		opts.ProgressTablePrintInterval,
-	)
-
 	namespaceID := resrcid.NewResourceID(
 		opts.ReleaseNamespace,
 		"",
@@ -162,17 +186,38 @@ func Uninstall(ctx context.Context, opts UninstallOptions) error {
 		}
 	}
 
+	var lockManager *lock_manager.LockManager
+	if m, err := lock_manager.NewLockManager(
+		opts.ReleaseNamespace,
+		false,
+		clientFactory.Static(),
+		clientFactory.Dynamic(),
+	); err != nil {
+		return fmt.Errorf("construct lock manager: %w", err)
+	} else {
+		lockManager = m
+	}
+
 	if err := func() error {
-		var releaseFound bool
-		if _, err := helmActionConfig.Releases.History(opts.ReleaseName); err != nil {
-			if !errors.Is(err, driver.ErrReleaseNotFound) {
-				return fmt.Errorf("get release history: %w", err)
-			}
-		} else {
-			releaseFound = true
+		log.Default.Info(ctx, "Constructing release history")
+		history, err := rlshistor.NewHistory(
+			ctx,
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			newReleaseStorage(opts.ReleaseStorageDriver, opts.ReleaseStoragePath, opts.ReleaseNamespace, helmReleaseStorage, clientFactory),
+			rlshistor.HistoryOptions{
+				Mapper:          clientFactory.Mapper(),
+				DiscoveryClient: clientFactory.Discovery(),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("construct release history: %w", err)
 		}
 
-		if !releaseFound {
+		lastRelease, lastReleaseFound, err := history.LastRelease()
+		if err != nil {
+			return fmt.Errorf("get last release: %w", err)
+		} else if !lastReleaseFound {
 			log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Skipped release %q (namespace: %q) removal: no release found", opts.ReleaseName, opts.ReleaseNamespace)))
 
 			return nil
@@ -180,39 +225,234 @@ func Uninstall(ctx context.Context, opts UninstallOptions) error {
 
 		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render("Deleting release")+" %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)
 
-		var lockManager *lock_manager.LockManager
-		if m, err := lock_manager.NewLockManager(
+		if lock, err := lockManager.LockRelease(ctx, opts.ReleaseName); err != nil {
+			return fmt.Errorf("lock release: %w", err)
+		} else {
+			defer lockManager.Unlock(lock)
+		}
+
+		hookResources := lastRelease.HookResources()
+		if !opts.DeleteHooks {
+			hookResources = lo.Filter(hookResources, func(res *resrc.HookResource, _ int) bool {
+				return res.OnPreDelete() || res.OnPostDelete()
+			})
+		}
+
+		log.Default.Info(ctx, "Processing resources")
+		_, _, hookResourcesInfos, _, prevReleaseGeneralResourceInfos, err := resrcinfo.BuildDeployableResourceInfos(
+			ctx,
+			opts.ReleaseName,
 			opts.ReleaseNamespace,
-			false,
+			nil,
+			hookResources,
+			nil,
+			lastRelease.GeneralResources(),
+			clientFactory.KubeClient(),
+			clientFactory.Mapper(),
+			opts.NetworkParallelism,
+		)
+		if err != nil {
+			return fmt.Errorf("build deployable resources infos: %w", err)
+		}
+
+		textRedactor := newReleasesTextRedactor(redactor, lastRelease)
+
+		taskStore := statestore.NewTaskStore()
+		logStore := kubeutil.NewConcurrent(
+			logstore.NewLogStore(),
+		)
+
+		log.Default.Info(ctx, "Constructing uninstall plan")
+		uninstallPlanBuilder := plnbuilder.NewUninstallPlanBuilder(
+			opts.ReleaseName,
+			opts.ReleaseNamespace,
+			taskStore,
+			logStore,
+			hookResourcesInfos,
+			prevReleaseGeneralResourceInfos,
+			lastRelease,
+			history,
+			clientFactory.KubeClient(),
 			clientFactory.Static(),
 			clientFactory.Dynamic(),
-		); err != nil {
-			return fmt.Errorf("construct lock manager: %w", err)
-		} else {
-			lockManager = m
+			clientFactory.Discovery(),
+			clientFactory.Mapper(),
+			plnbuilder.UninstallPlanBuilderOptions{
+				DeleteHooks:      opts.DeleteHooks,
+				ReadinessTimeout: opts.TrackReadinessTimeout,
+				DeletionTimeout:  opts.TrackDeletionTimeout,
+				ReadinessRules:   readinessRules,
+			},
+		)
+
+		plan, planBuildErr := uninstallPlanBuilder.Build(ctx)
+		if planBuildErr != nil {
+			if _, err := os.Create(opts.UninstallGraphPath); err != nil {
+				log.Default.Error(ctx, "Error: create uninstall graph file: %s", err)
+				return fmt.Errorf("build uninstall plan: %w", planBuildErr)
+			}
+
+			if err := plan.SaveDOT(opts.UninstallGraphPath); err != nil {
+				log.Default.Error(ctx, "Error: save uninstall graph: %s", err)
+			}
+
+			log.Default.Warn(ctx, "Uninstall graph saved to %q for debugging", opts.UninstallGraphPath)
+
+			return fmt.Errorf("build uninstall plan: %w", planBuildErr)
 		}
 
-		if lock, err := lockManager.LockRelease(ctx, opts.ReleaseName); err != nil {
-			return fmt.Errorf("lock release: %w", err)
-		} else {
-			defer lockManager.Unlock(lock)
+		if opts.UninstallGraphSave {
+			if err := plan.SaveDOT(opts.UninstallGraphPath); err != nil {
+				return fmt.Errorf("save uninstall graph: %w", err)
+			}
 		}
 
-		helmUninstallCmd := helm_v3.NewUninstallCmd(
-			helmActionConfig,
-			logboek.Context(ctx).OutStream(),
-			helm_v3.UninstallCmdOptions{
-				StagesSplitter:      deploy.NewStagesSplitter(),
-				DeleteHooks:         lo.ToPtr(opts.DeleteHooks),
-				DontFailIfNoRelease: lo.ToPtr(true),
+		tablesBuilder := track.NewTablesBuilder(
+			taskStore,
+			logStore,
+			track.TablesBuilderOptions{
+				DefaultNamespace: opts.ReleaseNamespace,
+				Colorize:         opts.LogColorMode == LogColorModeOn,
 			},
 		)
 
-		if err := helmUninstallCmd.RunE(helmUninstallCmd, []string{opts.ReleaseName}); err != nil {
-			return fmt.Errorf("run uninstall command: %w", err)
+		log.Default.Info(ctx, "Starting tracking")
+		stdoutTrackerStopCh := make(chan bool)
+		stdoutTrackerFinishedCh := make(chan bool)
+
+		if opts.ProgressTablePrint {
+			go func() {
+				ticker := time.NewTicker(opts.ProgressTablePrintInterval)
+				defer func() {
+					ticker.Stop()
+					stdoutTrackerFinishedCh <- true
+				}()
+
+				for {
+					select {
+					case <-ticker.C:
+						printTables(ctx, tablesBuilder)
+					case <-stdoutTrackerStopCh:
+						printTables(ctx, tablesBuilder)
+						return
+					}
+				}
+			}()
+		}
+
+		log.Default.Info(ctx, "Executing uninstall plan")
+		planExecutor := plnexectr.NewPlanExecutor(
+			plan,
+			plnexectr.PlanExecutorOptions{
+				NetworkParallelism: opts.NetworkParallelism,
+				TrackParallelism:   opts.TrackParallelism,
+				RetryPolicy: plnexectr.RetryPolicy{
+					Attempts: opts.RetryAttempts,
+				},
+				TextRedactor: textRedactor,
+			},
+		)
+
+		var criticalErrs, nonCriticalErrs []error
+
+		planExecutionErr := planExecutor.Execute(ctx)
+		if planExecutionErr != nil {
+			criticalErrs = append(criticalErrs, fmt.Errorf("execute uninstall plan: %w", planExecutionErr))
+		}
+
+		var interruptReason string
+		if ctx.Err() != nil {
+			interruptReason = context.Cause(ctx).Error()
+			log.Default.Warn(ctx, color.Style{color.Bold, color.Yellow}.Render(fmt.Sprintf("Uninstall of release %q (namespace: %q) interrupted: %s", opts.ReleaseName, opts.ReleaseNamespace, interruptReason)))
+		}
+
+		// If interrupted, still need to save the report.
+		finCtx := context.WithoutCancel(ctx)
+
+		var worthyCompletedOps []opertn.Operation
+		if ops, found, err := plan.WorthyCompletedOperations(); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get meaningful completed operations: %w", err))
+		} else if found {
+			worthyCompletedOps = ops
+		}
+
+		var worthyCanceledOps []opertn.Operation
+		if ops, found, err := plan.WorthyCanceledOperations(); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get meaningful canceled operations: %w", err))
+		} else if found {
+			worthyCanceledOps = ops
 		}
 
-		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Deleted release %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)))
+		var worthyFailedOps []opertn.Operation
+		if ops, found, err := plan.WorthyFailedOperations(); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get meaningful failed operations: %w", err))
+		} else if found {
+			worthyFailedOps = ops
+		}
+
+		var executedOps []opertn.Operation
+		if ops, found, err := plan.ExecutedOperations(); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get executed operations: %w", err))
+		} else if found {
+			executedOps = ops
+		}
+
+		var allOps []opertn.Operation
+		if ops, found, err := plan.Operations(); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get operations: %w", err))
+		} else if found {
+			allOps = ops
+		}
+
+		var criticalPath []opertn.Operation
+		if ops, found, err := plan.CriticalPath(); err != nil {
+			nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("get critical path: %w", err))
+		} else if found {
+			criticalPath = ops
+		}
+
+		if opts.ProgressTablePrint {
+			stdoutTrackerStopCh <- true
+			<-stdoutTrackerFinishedCh
+		}
+
+		report := reprt.NewReport(
+			worthyCompletedOps,
+			worthyCanceledOps,
+			worthyFailedOps,
+			lastRelease,
+			reprt.ReportOptions{
+				CriticalPath:       criticalPath,
+				ExecutedOperations: executedOps,
+				InterruptReason:    interruptReason,
+				LogStore:           logStore,
+				Operations:         allOps,
+				TextRedactor:       textRedactor,
+				Version:            opts.UninstallReportVersion,
+			},
+		)
+
+		report.Print(finCtx)
+
+		if opts.UninstallReportSave {
+			if err := report.Save(opts.UninstallReportPath); err != nil {
+				nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save uninstall report: %w", err))
+			}
+		}
+
+		if opts.UninstallJUnitSave {
+			if err := report.SaveJUnit(opts.UninstallJUnitPath); err != nil {
+				nonCriticalErrs = append(nonCriticalErrs, fmt.Errorf("save uninstall JUnit report: %w", err))
+			}
+		}
+
+		if len(criticalErrs) > 0 {
+			return textRedactor.RedactError(utls.Multierrorf("failed uninstall of release %q (namespace: %q)", append(criticalErrs, nonCriticalErrs...), opts.ReleaseName, opts.ReleaseNamespace))
+		} else if len(nonCriticalErrs) > 0 {
+			return textRedactor.RedactError(utls.Multierrorf("succeeded uninstall of release %q (namespace: %q), but non-critical errors encountered", nonCriticalErrs, opts.ReleaseName, opts.ReleaseNamespace))
+		}
+
+		log.Default.Info(finCtx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("Deleted release %q (namespace: %q)", opts.ReleaseName, opts.ReleaseNamespace)))
 
 		return nil
 	}(); err != nil {
@@ -247,16 +487,46 @@ func applyUninstallOptionsDefaults(opts UninstallOptions, currentDir string, cur
 		}
 	}
 
+	if opts.UninstallGraphPath == "" {
+		opts.UninstallGraphPath = filepath.Join(opts.TempDirPath, DefaultUninstallGraphFilename)
+	}
+
+	if opts.UninstallReportVersion == 0 {
+		opts.UninstallReportVersion = reprt.DefaultReportVersion
+	} else if !lo.Contains(reprt.SupportedReportVersions, opts.UninstallReportVersion) {
+		return UninstallOptions{}, fmt.Errorf("unsupported report version %d, supported versions: %v", opts.UninstallReportVersion, reprt.SupportedReportVersions)
+	}
+
+	if opts.UninstallJUnitPath == "" {
+		opts.UninstallJUnitPath = filepath.Join(opts.TempDirPath, DefaultUninstallJUnitFilename)
+	}
+
+	if opts.UninstallReportPath == "" {
+		opts.UninstallReportPath = filepath.Join(opts.TempDirPath, DefaultUninstallReportFilename)
+	}
+
 	if opts.KubeConfigBase64 == "" && len(opts.KubeConfigPaths) == 0 {
 		opts.KubeConfigPaths = []string{filepath.Join(currentUser.HomeDir, ".kube", "config")}
 	}
 
-	if opts.ProgressTablePrintInterval <= 0 {
-		opts.ProgressTablePrintInterval = 5 * time.Second
+	if opts.LogColorMode == LogColorModeDefault {
+		if color.DetectColorLevel() == terminfo.ColorLevelNone {
+			opts.LogColorMode = LogColorModeOff
+		} else {
+			opts.LogColorMode = LogColorModeOn
+		}
+	}
+
+	if opts.NetworkParallelism <= 0 {
+		opts.NetworkParallelism = 30
 	}
 
-	if opts.ReleaseHistoryLimit <= 0 {
-		opts.ReleaseHistoryLimit = 10
+	if opts.RetryAttempts <= 0 {
+		opts.RetryAttempts = plnexectr.DefaultRetryPolicy.Attempts
+	}
+
+	if opts.ProgressTablePrintInterval <= 0 {
+		opts.ProgressTablePrintInterval = 5 * time.Second
 	}
 
 	if opts.ReleaseName == "" {
@@ -267,6 +537,8 @@ func applyUninstallOptionsDefaults(opts UninstallOptions, currentDir string, cur
 		opts.ReleaseStorageDriver = ReleaseStorageDriverSecrets
 	} else if opts.ReleaseStorageDriver == ReleaseStorageDriverMemory {
 		return UninstallOptions{}, fmt.Errorf("memory release storage driver is not supported")
+	} else if err := validateReleaseStorageOptions(opts.ReleaseStorageDriver, opts.ReleaseStoragePath); err != nil {
+		return UninstallOptions{}, err
 	}
 
 	return opts, nil
@@ -10,11 +10,11 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/client-go/discovery"
 
-	helm_v3 "github.com/werf/3p-helm-for-werf-helm/cmd/helm"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/chart"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/chart/loader"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/chartutil"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/cli/values"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/getter"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/releaseutil"
@@ -33,7 +33,12 @@ func NewChartTree(ctx context.Context, chartPath, releaseName, releaseNamespace
 		ValueFiles:   opts.ValuesFiles,
 	}
 
-	getters := getter.All(helm_v3.Settings)
+	helmSettings := opts.HelmSettings
+	if helmSettings == nil {
+		helmSettings = cli.New()
+	}
+
+	getters := getter.All(helmSettings)
 
 	log.Default.Debug(ctx, "Merging values for chart tree at %q", chartPath)
 	releaseValues, err := valOpts.MergeValues(getters, loader.GlobalLoadOptions.ChartExtender)
@@ -176,6 +181,7 @@ type ChartTreeOptions struct {
 	FileValues      []string
 	ValuesFiles     []string
 	SubNotes        bool
+	HelmSettings    *cli.EnvSettings
 }
 
 type ChartTree struct {
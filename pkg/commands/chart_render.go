@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"context"
 	"fmt"
 	"github.com/werf/logboek"
 
@@ -25,7 +24,7 @@ func NewChartRenderCommand() *cobra.Command {
 				opts.ChartDirPath = ""
 			}
 
-			ctx := logboek.NewContext(context.Background(), logboek.DefaultLogger())
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
 			if err := action.Render(ctx, opts); err != nil {
 				return fmt.Errorf("render failed: %w", err)
 			}
@@ -52,6 +51,8 @@ func NewChartRenderCommand() *cobra.Command {
 	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
 	f.StringVar(&opts.RegistryCredentialsPath, "registry-credentials-path", "", "Registry credentials path")
 	f.StringVar(&opts.ReleaseNamespace, "namespace", "", "Release namespace")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
 	f.StringVar(&opts.OutputFilePath, "output-path", "", "Output file path")
 	f.BoolVar(&opts.OutputFileSave, "output", false, "Output file save")
 	f.BoolVar(&opts.SecretKeyIgnore, "ignore-secret-key", false, "Secret key ignore")
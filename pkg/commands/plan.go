@@ -12,6 +12,8 @@ func NewPlanCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(NewPlanDeployCommand())
+	cmd.AddCommand(NewPlanDeployManyCommand())
+	cmd.AddCommand(NewPlanUninstallCommand())
 
 	return cmd
 }
@@ -1,8 +1,9 @@
 package commands
 
 import (
-	"context"
 	"fmt"
+	"os"
+
 	"github.com/werf/logboek"
 
 	"github.com/spf13/cobra"
@@ -11,6 +12,7 @@ import (
 
 func NewPlanDeployCommand() *cobra.Command {
 	var opts action.PlanOptions
+	var outputFormat string
 
 	cmd := &cobra.Command{
 		Use:   "deploy [release-name] [chart-dir]",
@@ -25,7 +27,16 @@ func NewPlanDeployCommand() *cobra.Command {
 				opts.ChartDirPath = ""
 			}
 
-			ctx := logboek.NewContext(context.Background(), logboek.DefaultLogger())
+			opts.OutputFormat = action.OutputFormat(outputFormat)
+
+			logger := logboek.DefaultLogger()
+			if opts.OutputFormat != action.OutputFormatDefault {
+				// Keep stdout clean for the machine-readable output.
+				logger = logboek.NewLogger(os.Stderr, os.Stderr)
+				opts.LogRegistryStreamOut = os.Stderr
+			}
+
+			ctx := logboek.NewContext(cmd.Context(), logger)
 			if err := action.Plan(ctx, opts); err != nil {
 				return fmt.Errorf("plan failed: %w", err)
 			}
@@ -35,20 +46,28 @@ func NewPlanDeployCommand() *cobra.Command {
 
 	f := cmd.Flags()
 	// Define flags
+	f.StringArrayVar(&opts.AdoptResources, "adopt-resource", []string{}, "Take over existing resources matching the selector even if they belong to another release or to no release, e.g. \"kind=ConfigMap;name=legacy-*\"\n(can be set multiple times)")
 	f.BoolVar(&opts.ChartRepositoryInsecure, "plain-http", false, "use insecure HTTP connections for the chart download")
 	f.BoolVar(&opts.ChartRepositorySkipTLSVerify, "insecure-skip-tls-verify", false, "Skip TLS verification for chart repository")
 	f.BoolVar(&opts.ChartRepositorySkipUpdate, "skip-dependency-update", false, "Skip update of the chart repository")
 	f.BoolVar(&opts.DefaultSecretValuesDisable, "disable-default-secret-values", false, "Disable default secret values")
 	f.BoolVar(&opts.DefaultValuesDisable, "disable-default-values", false, "Disable default values")
 	f.BoolVar(&opts.ErrorIfChangesPlanned, "exit-on-changes", false, "Exit with error if changes are planned")
+	f.StringArrayVar(&opts.ExcludeResources, "exclude-resource", []string{}, "Don't deploy resources matching the selector, e.g. \"kind=Job;name=migrate-*\"\n(can be set multiple times)")
 	f.StringToStringVarP(&opts.ExtraAnnotations, "annotations", "a", map[string]string{}, "Extra annotations to add to the rendered manifests")
+	f.StringArrayVar(&opts.IncludeResources, "include-resource", []string{}, "Deploy only resources matching the selector. Selector keys: kind, name, namespace, group, version, labels, e.g. \"kind=Deployment;name=web-*;labels=app=web\"\n(can be set multiple times)")
 	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
 	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
 	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
 	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
 	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.StringVarP(&outputFormat, "output", "o", "", "Print planned changes in the specified format instead of logging them: json or markdown")
+	f.StringVar(&opts.PlanFilePath, "out", "", "Save the executable plan to the file, to be deployed later with \"release deploy --plan\"")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path in diffs of matching resources, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
 	f.StringVar(&opts.RegistryCredentialsPath, "registry-credentials-path", "", "Path to the registry credentials")
 	f.StringVar(&opts.ReleaseNamespace, "namespace", "default", "Namespace for the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
 	f.BoolVar(&opts.SecretKeyIgnore, "ignore-secret-key", false, "Ignore secret keys")
 	f.StringSliceVar(&opts.SecretValuesPaths, "secret-values", []string{}, "Paths to secret values files")
 	f.StringVar(&opts.TempDirPath, "temp-dir", "", "Path to the temporary directory")
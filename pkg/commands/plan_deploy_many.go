@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+)
+
+func NewPlanDeployManyCommand() *cobra.Command {
+	var opts action.PlanManyOptions
+
+	cmd := &cobra.Command{
+		Use:   "deploy-many [manifest]",
+		Short: "Plan deployment of multiple Helm charts",
+		Long:  "Plan deployment of all releases listed in the release set manifest, in the same order \"release deploy-many\" would deploy them.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ManifestPath = args[0]
+
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
+			if err := action.PlanMany(ctx, opts); err != nil {
+				return fmt.Errorf("plan failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.BoolVar(&opts.ChartRepositoryInsecure, "plain-http", false, "use insecure HTTP connections for the chart download")
+	f.BoolVar(&opts.ChartRepositorySkipTLSVerify, "insecure-skip-tls-verify", false, "Skip TLS verification for chart repository")
+	f.BoolVar(&opts.ChartRepositorySkipUpdate, "skip-dependency-update", false, "Skip update of the chart repository")
+	f.BoolVar(&opts.DefaultSecretValuesDisable, "disable-default-secret-values", false, "Disable default secret values")
+	f.BoolVar(&opts.DefaultValuesDisable, "disable-default-values", false, "Disable default values")
+	f.BoolVar(&opts.ErrorIfChangesPlanned, "exit-on-changes", false, "Exit with error if changes are planned for any release")
+	f.StringToStringVarP(&opts.ExtraAnnotations, "annotations", "a", map[string]string{}, "Extra annotations to add to the rendered manifests")
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
+	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path in diffs of matching resources, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
+	f.StringVar(&opts.RegistryCredentialsPath, "registry-credentials-path", "", "Path to the registry credentials")
+	f.IntVar(&opts.ReleasesParallelism, "releases-parallelism", action.DefaultReleasesParallelism, "Max number of releases planned at the same time")
+	f.StringVar(&opts.ReleaseNamespace, "namespace", "default", "Namespace for releases without namespace in the manifest")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+	f.BoolVar(&opts.SecretKeyIgnore, "ignore-secret-key", false, "Ignore secret keys")
+	f.StringSliceVar(&opts.SecretValuesPaths, "secret-values", []string{}, "Paths to secret values files")
+	f.StringVar(&opts.TempDirPath, "temp-dir", "", "Path to the temporary directory")
+	f.StringSliceVar(&opts.ValuesFileSets, "set-file", []string{}, "Values file sets for all releases")
+	f.StringSliceVarP(&opts.ValuesFilesPaths, "values", "f", []string{}, "Paths to values files for all releases, applied before values from the manifest\n(can be set multiple times)")
+	f.StringSliceVar(&opts.ValuesSets, "set", []string{}, "Values sets for all releases")
+	f.StringSliceVar(&opts.ValuesStringSets, "set-string", []string{}, "Values string sets for all releases")
+
+	return cmd
+}
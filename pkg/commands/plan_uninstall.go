@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/werf/logboek"
+
+	"github.com/spf13/cobra"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+)
+
+func NewPlanUninstallCommand() *cobra.Command {
+	var opts action.PlanUninstallOptions
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "uninstall [release-name]",
+		Short: "Plan a Helm release removal",
+		Long:  "Show what uninstalling the Helm release with the specified release name will delete.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ReleaseName = args[0]
+			opts.OutputFormat = action.OutputFormat(outputFormat)
+
+			logger := logboek.DefaultLogger()
+			if opts.OutputFormat != action.OutputFormatDefault {
+				// Keep stdout clean for the machine-readable output.
+				logger = logboek.NewLogger(os.Stderr, os.Stderr)
+			}
+
+			ctx := logboek.NewContext(cmd.Context(), logger)
+			if err := action.PlanUninstall(ctx, opts); err != nil {
+				return fmt.Errorf("plan failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.BoolVar(&opts.DeleteHooks, "delete-hooks", false, "Delete hooks")
+	f.BoolVar(&opts.DeleteReleaseNamespace, "delete-namespace", false, "Delete namespace of the release")
+	f.BoolVar(&opts.ErrorIfChangesPlanned, "exit-on-changes", false, "Exit with error if changes are planned")
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
+	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.StringVarP(&outputFormat, "output", "o", "", "Print planned changes in the specified format instead of logging them: json or markdown")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path in diffs of matching resources, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
+	f.StringVarP(&opts.ReleaseNamespace, "namespace", "n", "default", "Namespace of the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+	f.StringVar(&opts.TempDirPath, "temp-dir", "", "Path to the temporary directory")
+
+	return cmd
+}
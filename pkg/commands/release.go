@@ -12,7 +12,14 @@ func NewReleaseCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(NewReleaseDeployCommand())
+	cmd.AddCommand(NewReleaseDeployManyCommand())
 	cmd.AddCommand(NewReleaseUninstallCommand())
+	cmd.AddCommand(NewReleaseRollbackCommand())
+	cmd.AddCommand(NewReleaseHistoryCommand())
+	cmd.AddCommand(NewReleaseGetCommand())
+	cmd.AddCommand(NewReleaseDriftCommand())
+	cmd.AddCommand(NewReleaseExportCommand())
+	cmd.AddCommand(NewReleaseImportCommand())
 
 	return cmd
 }
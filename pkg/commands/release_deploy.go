@@ -1,13 +1,13 @@
 package commands
 
 import (
-	"context"
 	"fmt"
 	"github.com/werf/logboek"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/werf/nelm-for-werf-helm/pkg/action"
+	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
 )
 
 func NewReleaseDeployCommand() *cobra.Command {
@@ -27,7 +27,7 @@ func NewReleaseDeployCommand() *cobra.Command {
 				opts.ChartDirPath = ""
 			}
 
-			ctx := logboek.NewContext(context.Background(), logboek.DefaultLogger())
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
 			if err := action.Deploy(ctx, opts); err != nil {
 				return fmt.Errorf("deploy failed: %w", err)
 			}
@@ -37,6 +37,7 @@ func NewReleaseDeployCommand() *cobra.Command {
 
 	f := cmd.Flags()
 	// Define flags
+	f.StringArrayVar(&opts.AdoptResources, "adopt-resource", []string{}, "Take over existing resources matching the selector even if they belong to another release or to no release, e.g. \"kind=ConfigMap;name=legacy-*\"\n(can be set multiple times)")
 	f.BoolVar(&opts.AutoRollback, "atomic", false, "Enable automatic rollback on failure")
 	f.BoolVar(&opts.ChartRepositoryInsecure, "plain-http", false, "use insecure HTTP connections for the chart download")
 	f.BoolVar(&opts.ChartRepositorySkipTLSVerify, "insecure-skip-tls-verify", false, "Skip TLS verification for chart repository")
@@ -45,27 +46,41 @@ func NewReleaseDeployCommand() *cobra.Command {
 	f.BoolVar(&opts.DefaultValuesDisable, "disable-default-values", false, "Disable default values")
 	f.StringVar(&opts.DeployGraphPath, "graph-path", "", "Path to save the deploy graph")
 	f.BoolVar(&opts.DeployGraphSave, "graph", false, "Save the deploy graph")
+	f.StringVar(&opts.DeployJUnitPath, "junit-path", "", "Path to save the deploy outcome as a JUnit XML report")
+	f.BoolVar(&opts.DeployJUnitSave, "junit", false, "Save the deploy outcome as a JUnit XML report")
 	f.StringVar(&opts.DeployReportPath, "report-path", "", "Path to save the deploy report")
 	f.BoolVar(&opts.DeployReportSave, "report", false, "Save the deploy report")
+	f.IntVar(&opts.DeployReportVersion, "report-version", reprt.DefaultReportVersion, "Version of the deploy report format: 2 or 3. Version 3 also has errors, resources and timings of operations and tracking results")
+	f.StringArrayVar(&opts.ExcludeResources, "exclude-resource", []string{}, "Don't deploy resources matching the selector, e.g. \"kind=Job;name=migrate-*\"\n(can be set multiple times)")
 	f.StringToStringVarP(&opts.ExtraAnnotations, "annotations", "a", map[string]string{}, "Extra annotations to add to the rendered manifests")
 	f.StringToStringVarP(&opts.ExtraLabels, "labels", "l", map[string]string{}, "Extra labels to add to the rendered manifests")
 	f.StringToStringVar(&opts.ExtraRuntimeAnnotations, "runtime-annotations", map[string]string{}, "Extra runtime annotations to add to the rendered manifests")
+	f.StringArrayVar(&opts.IncludeResources, "include-resource", []string{}, "Deploy only resources matching the selector. Selector keys: kind, name, namespace, group, version, labels, e.g. \"kind=Deployment;name=web-*;labels=app=web\"\n(can be set multiple times)")
 	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
 	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
 	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
 	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.StringVar(&opts.PlanFilePath, "plan", "", "Deploy exactly the plan saved by \"plan deploy --out\" instead of rendering the chart")
 	f.BoolVar(&opts.ProgressTablePrint, "kubedog", false, "Print progress table")
 	f.DurationVar(&opts.ProgressTablePrintInterval, "kubedog-interval", 10*time.Second, "Progress table print interval")
+	f.StringVar(&opts.ReadinessRulesFilePath, "readiness-rules", "", "Path to the YAML file with readiness rules for custom resources, applied to resources without readiness annotations")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path of matching resources in errors, reports and logs, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
 	f.StringVar(&opts.RegistryCredentialsPath, "registry-credentials-path", "", "Path to the registry credentials")
-	f.IntVar(&opts.ReleaseHistoryLimit, "history-max", 10, "The maximum number of revisions saved per release. Use 0 for no limit")
+	f.IntVar(&opts.RetryAttempts, "retry-attempts", 5, "Max attempts for resource operations failed with transient Kubernetes API errors, 1 disables retries")
+	f.IntVar(&opts.ReleaseHistoryFailedLimit, "history-max-failed", 10, "The maximum number of failed revisions saved per release. Use 0 for no limit")
+	f.IntVar(&opts.ReleaseHistoryLimit, "history-max", 10, "The maximum number of successful revisions saved per release. Use 0 for no limit")
 	f.StringVar(&opts.ReleaseNamespace, "namespace", "default", "Namespace for the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
 	f.StringVar(&opts.RollbackGraphPath, "rollback-graph-path", "", "Path to save the rollback graph")
 	f.BoolVar(&opts.RollbackGraphSave, "rollback-graph", false, "Save the rollback graph")
 	f.BoolVar(&opts.SecretKeyIgnore, "ignore-secret-key", false, "Ignore secret keys")
 	f.StringSliceVar(&opts.SecretValuesPaths, "secret-values", []string{}, "Paths to secret values files")
 	f.StringVar(&opts.TempDirPath, "temp-dir", "", "Path to the temporary directory")
 	f.DurationVar(&opts.TrackCreationTimeout, "creation-timeout", 10*time.Minute, "Track creation timeout")
+	f.DurationVar(&opts.Timeout, "timeout", 0, "Timeout for the whole release, after which it is interrupted and marked failed (0 means no timeout)")
 	f.DurationVar(&opts.TrackDeletionTimeout, "deletion-timeout", 10*time.Minute, "Track deletion timeout")
+	f.IntVar(&opts.TrackParallelism, "track-parallelism", 0, "Tracking parallelism (defaults to network parallelism)")
 	f.DurationVar(&opts.TrackReadinessTimeout, "readiness-timeout", 10*time.Minute, "Track readiness timeout")
 	f.StringSliceVar(&opts.ValuesFileSets, "set-file", []string{}, "Values file sets")
 	f.StringSliceVarP(&opts.ValuesFilesPaths, "values", "f", []string{}, "Paths to values files\n(can be set multiple times)")
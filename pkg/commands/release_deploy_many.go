@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
+)
+
+func NewReleaseDeployManyCommand() *cobra.Command {
+	var opts action.DeployManyOptions
+
+	cmd := &cobra.Command{
+		Use:   "deploy-many [manifest]",
+		Short: "Deploy multiple Helm charts",
+		Long:  "Deploy all releases listed in the release set manifest, respecting dependencies between them. Independent releases are deployed in parallel, releases depending on a failed release are not deployed. There is no rollback of the release set as a whole: releases already deployed stay deployed when another release fails, and only the failed release itself is rolled back with --atomic.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ManifestPath = args[0]
+
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
+			if err := action.DeployMany(ctx, opts); err != nil {
+				return fmt.Errorf("deploy failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.BoolVar(&opts.AutoRollback, "atomic", false, "Enable automatic rollback of failed releases. Other releases of the set are not rolled back")
+	f.BoolVar(&opts.ChartRepositoryInsecure, "plain-http", false, "use insecure HTTP connections for the chart download")
+	f.BoolVar(&opts.ChartRepositorySkipTLSVerify, "insecure-skip-tls-verify", false, "Skip TLS verification for chart repository")
+	f.BoolVar(&opts.ChartRepositorySkipUpdate, "skip-dependency-update", false, "Skip update of the chart repository")
+	f.BoolVar(&opts.DefaultSecretValuesDisable, "disable-default-secret-values", false, "Disable default secret values")
+	f.BoolVar(&opts.DefaultValuesDisable, "disable-default-values", false, "Disable default values")
+	f.BoolVar(&opts.DeployGraphSave, "graph", false, "Save the deploy graph of each release to its temporary directory")
+	f.BoolVar(&opts.DeployJUnitSave, "junit", false, "Save the deploy outcome of each release as a JUnit XML report to its temporary directory")
+	f.BoolVar(&opts.DeployReportSave, "report", false, "Save the deploy report of each release to its temporary directory")
+	f.IntVar(&opts.DeployReportVersion, "report-version", reprt.DefaultReportVersion, "Version of the deploy report format: 2 or 3. Version 3 also has errors, resources and timings of operations and tracking results")
+	f.StringToStringVarP(&opts.ExtraAnnotations, "annotations", "a", map[string]string{}, "Extra annotations to add to the rendered manifests")
+	f.StringToStringVarP(&opts.ExtraLabels, "labels", "l", map[string]string{}, "Extra labels to add to the rendered manifests")
+	f.StringToStringVar(&opts.ExtraRuntimeAnnotations, "runtime-annotations", map[string]string{}, "Extra runtime annotations to add to the rendered manifests")
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.BoolVar(&opts.ProgressTablePrint, "kubedog", false, "Print progress table")
+	f.DurationVar(&opts.ProgressTablePrintInterval, "kubedog-interval", 10*time.Second, "Progress table print interval")
+	f.StringVar(&opts.ReadinessRulesFilePath, "readiness-rules", "", "Path to the YAML file with readiness rules for custom resources, applied to resources without readiness annotations")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path of matching resources in errors, reports and logs, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
+	f.StringVar(&opts.RegistryCredentialsPath, "registry-credentials-path", "", "Path to the registry credentials")
+	f.IntVar(&opts.ReleasesParallelism, "releases-parallelism", action.DefaultReleasesParallelism, "Max number of releases deployed at the same time")
+	f.IntVar(&opts.RetryAttempts, "retry-attempts", 5, "Max attempts for resource operations failed with transient Kubernetes API errors, 1 disables retries")
+	f.IntVar(&opts.ReleaseHistoryFailedLimit, "history-max-failed", 10, "The maximum number of failed revisions saved per release. Use 0 for no limit")
+	f.IntVar(&opts.ReleaseHistoryLimit, "history-max", 10, "The maximum number of successful revisions saved per release. Use 0 for no limit")
+	f.StringVar(&opts.ReleaseNamespace, "namespace", "default", "Namespace for releases without namespace in the manifest")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+	f.BoolVar(&opts.RollbackGraphSave, "rollback-graph", false, "Save the rollback graph of each release to its temporary directory")
+	f.BoolVar(&opts.SecretKeyIgnore, "ignore-secret-key", false, "Ignore secret keys")
+	f.StringSliceVar(&opts.SecretValuesPaths, "secret-values", []string{}, "Paths to secret values files")
+	f.StringVar(&opts.TempDirPath, "temp-dir", "", "Path to the temporary directory, each release gets a subdirectory \"<namespace>/<name>\"")
+	f.DurationVar(&opts.TrackCreationTimeout, "creation-timeout", 10*time.Minute, "Track creation timeout")
+	f.DurationVar(&opts.Timeout, "timeout", 0, "Timeout for each release, after which it is interrupted and marked failed (0 means no timeout)")
+	f.DurationVar(&opts.TrackDeletionTimeout, "deletion-timeout", 10*time.Minute, "Track deletion timeout")
+	f.IntVar(&opts.TrackParallelism, "track-parallelism", 0, "Tracking parallelism (defaults to network parallelism)")
+	f.DurationVar(&opts.TrackReadinessTimeout, "readiness-timeout", 10*time.Minute, "Track readiness timeout")
+	f.StringSliceVar(&opts.ValuesFileSets, "set-file", []string{}, "Values file sets for all releases")
+	f.StringSliceVarP(&opts.ValuesFilesPaths, "values", "f", []string{}, "Paths to values files for all releases, applied before values from the manifest\n(can be set multiple times)")
+	f.StringSliceVar(&opts.ValuesSets, "set", []string{}, "Values sets for all releases")
+	f.StringSliceVar(&opts.ValuesStringSets, "set-string", []string{}, "Values string sets for all releases")
+	f.BoolVar(&opts.SubNotes, "render-subchart-notes", false, "Render subchart notes along with the parent")
+
+	return cmd
+}
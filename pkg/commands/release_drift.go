@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+)
+
+func NewReleaseDriftCommand() *cobra.Command {
+	var opts action.ReleaseDriftOptions
+
+	cmd := &cobra.Command{
+		Use:   "drift [release-name]",
+		Short: "Detect drift of a Helm release",
+		Long:  "Compare the live cluster with the last deployed revision of the Helm release and show resources modified or deleted out-of-band. Exits with error if drift is detected or if some resources can't be compared.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ReleaseName = args[0]
+
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
+			if err := action.ReleaseDrift(ctx, opts); err != nil {
+				return fmt.Errorf("release drift failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.StringSliceVar(&opts.IgnoreFieldManagers, "ignore-field-manager", []string{action.DefaultDriftIgnoreFieldManager}, "Don't report this field manager as an extra manager of resources\n(can be set multiple times)")
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
+	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path in diffs of matching resources, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
+	f.StringVarP(&opts.ReleaseNamespace, "namespace", "n", "default", "Namespace of the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+
+	return cmd
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+)
+
+func NewReleaseExportCommand() *cobra.Command {
+	var opts action.ReleaseExportOptions
+
+	cmd := &cobra.Command{
+		Use:   "export [release-name]",
+		Short: "Export a Helm release history",
+		Long:  "Save all revisions of the Helm release to a portable archive, which can be imported into another cluster or namespace with \"release import\".",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ReleaseName = args[0]
+
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
+			if err := action.ReleaseExport(ctx, opts); err != nil {
+				return fmt.Errorf("release export failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
+	f.StringVarP(&opts.OutputFilePath, "output", "o", "", "Path to the archive file to create (default \"<release-name>.tar.gz\")")
+	f.StringVarP(&opts.ReleaseNamespace, "namespace", "n", "default", "Namespace of the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+
+	return cmd
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+)
+
+func NewReleaseHistoryCommand() *cobra.Command {
+	var opts action.ReleaseHistoryOptions
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "history [release-name]",
+		Short: "Show Helm release history",
+		Long:  "Show revisions of a Helm release with their status, deploy type, deploy timestamps and chart version.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ReleaseName = args[0]
+			opts.OutputFormat = action.OutputFormat(outputFormat)
+
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
+			if err := action.ReleaseHistory(ctx, opts); err != nil {
+				return fmt.Errorf("release history failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
+	f.StringVarP(&outputFormat, "output", "o", string(action.OutputFormatTable), "Output format: table, json or yaml")
+	f.StringVar(&opts.ReleaseNamespace, "namespace", "default", "Namespace for the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+
+	return cmd
+}
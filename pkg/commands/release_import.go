@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+)
+
+func NewReleaseImportCommand() *cobra.Command {
+	var opts action.ReleaseImportOptions
+
+	cmd := &cobra.Command{
+		Use:   "import [archive-path]",
+		Short: "Import a Helm release history",
+		Long:  "Re-create all revisions of the Helm release from the archive made by \"release export\", optionally moving the release to another namespace and adopting its existing resources.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.InputFilePath = args[0]
+
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
+			if err := action.ReleaseImport(ctx, opts); err != nil {
+				return fmt.Errorf("release import failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.BoolVar(&opts.AdoptResources, "adopt-resources", false, "Point release annotations of existing resources of the last deployed revision to the imported release, unless they belong to another release")
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
+	f.StringVarP(&opts.ReleaseNamespace, "namespace", "n", "", "Namespace to import the release to (default is the namespace the release was exported from)")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+
+	return cmd
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/werf/logboek"
+	"github.com/werf/nelm-for-werf-helm/pkg/action"
+	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
+)
+
+func NewReleaseRollbackCommand() *cobra.Command {
+	var opts action.RollbackOptions
+
+	cmd := &cobra.Command{
+		Use:   "rollback [release-name] [revision]",
+		Short: "Rollback a Helm release",
+		Long:  "Rollback a Helm release to the specified revision. If revision is not specified, rollback to the previous successfully deployed revision.",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ReleaseName = args[0]
+			if len(args) > 1 {
+				revision, err := strconv.Atoi(args[1])
+				if err != nil {
+					return fmt.Errorf("parse revision %q: %w", args[1], err)
+				}
+
+				opts.Revision = revision
+			}
+
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
+			if err := action.Rollback(ctx, opts); err != nil {
+				return fmt.Errorf("rollback failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	// Define flags
+	f.StringToStringVarP(&opts.ExtraAnnotations, "annotations", "a", map[string]string{}, "Extra annotations to add to the rolled back manifests")
+	f.StringToStringVarP(&opts.ExtraLabels, "labels", "l", map[string]string{}, "Extra labels to add to the rolled back manifests")
+	f.StringToStringVar(&opts.ExtraRuntimeAnnotations, "runtime-annotations", map[string]string{}, "Extra runtime annotations to add to the rolled back manifests")
+	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
+	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
+	f.StringVar(&opts.KubeContext, "kube-context", "", "Kube context to use")
+	f.BoolVar(&opts.LogDebug, "debug", false, "Enable debug logging")
+	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.BoolVar(&opts.ProgressTablePrint, "kubedog", false, "Print progress table")
+	f.DurationVar(&opts.ProgressTablePrintInterval, "kubedog-interval", 10*time.Second, "Progress table print interval")
+	f.StringVar(&opts.ReadinessRulesFilePath, "readiness-rules", "", "Path to the YAML file with readiness rules for custom resources, applied to resources without readiness annotations")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path of matching resources in errors, reports and logs, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
+	f.StringVar(&opts.RegistryCredentialsPath, "registry-credentials-path", "", "Path to the registry credentials")
+	f.IntVar(&opts.RetryAttempts, "retry-attempts", 5, "Max attempts for resource operations failed with transient Kubernetes API errors, 1 disables retries")
+	f.IntVar(&opts.ReleaseHistoryFailedLimit, "history-max-failed", 10, "The maximum number of failed revisions saved per release. Use 0 for no limit")
+	f.IntVar(&opts.ReleaseHistoryLimit, "history-max", 10, "The maximum number of successful revisions saved per release. Use 0 for no limit")
+	f.StringVar(&opts.ReleaseNamespace, "namespace", "default", "Namespace for the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
+	f.StringVar(&opts.RollbackGraphPath, "graph-path", "", "Path to save the rollback graph")
+	f.BoolVar(&opts.RollbackGraphSave, "graph", false, "Save the rollback graph")
+	f.StringVar(&opts.RollbackJUnitPath, "junit-path", "", "Path to save the rollback outcome as a JUnit XML report")
+	f.BoolVar(&opts.RollbackJUnitSave, "junit", false, "Save the rollback outcome as a JUnit XML report")
+	f.StringVar(&opts.RollbackReportPath, "report-path", "", "Path to save the rollback report")
+	f.BoolVar(&opts.RollbackReportSave, "report", false, "Save the rollback report")
+	f.IntVar(&opts.RollbackReportVersion, "report-version", reprt.DefaultReportVersion, "Version of the rollback report format: 2 or 3. Version 3 also has errors, resources and timings of operations and tracking results")
+	f.StringVar(&opts.TempDirPath, "temp-dir", "", "Path to the temporary directory")
+	f.DurationVar(&opts.TrackCreationTimeout, "creation-timeout", 10*time.Minute, "Track creation timeout")
+	f.DurationVar(&opts.Timeout, "timeout", 0, "Timeout for the whole release, after which it is interrupted and marked failed (0 means no timeout)")
+	f.DurationVar(&opts.TrackDeletionTimeout, "deletion-timeout", 10*time.Minute, "Track deletion timeout")
+	f.IntVar(&opts.TrackParallelism, "track-parallelism", 0, "Tracking parallelism (defaults to network parallelism)")
+	f.DurationVar(&opts.TrackReadinessTimeout, "readiness-timeout", 10*time.Minute, "Track readiness timeout")
+
+	return cmd
+}
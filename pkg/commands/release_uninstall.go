@@ -1,13 +1,13 @@
 package commands
 
 import (
-	"context"
 	"fmt"
 	"github.com/werf/logboek"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/werf/nelm-for-werf-helm/pkg/action"
+	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
 )
 
 func NewReleaseUninstallCommand() *cobra.Command {
@@ -20,7 +20,7 @@ func NewReleaseUninstallCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			opts.ReleaseName = args[0]
-			ctx := logboek.NewContext(context.Background(), logboek.DefaultLogger())
+			ctx := logboek.NewContext(cmd.Context(), logboek.DefaultLogger())
 
 			if err := action.Uninstall(ctx, opts); err != nil {
 				return fmt.Errorf("uninstall failed: %w", err)
@@ -32,15 +32,32 @@ func NewReleaseUninstallCommand() *cobra.Command {
 	f := cmd.Flags()
 	// Define flags
 	f.StringVarP(&opts.ReleaseNamespace, "namespace", "n", "default", "Namespace of the release")
+	f.StringVar((*string)(&opts.ReleaseStorageDriver), "release-storage", "", "Release storage driver: secrets, configmaps, sql, filesystem or chunked-secrets")
+	f.StringVar(&opts.ReleaseStoragePath, "release-storage-path", "", "Path to the directory with releases for the filesystem release storage driver")
 	f.BoolVar(&opts.DeleteHooks, "delete-hooks", false, "Delete hooks")
 	f.BoolVar(&opts.DeleteReleaseNamespace, "delete-namespace", false, "Delete namespace of the release")
 	f.StringVar(&opts.KubeConfigBase64, "kubeconfig-base64", "", "Base64 encoded kube config")
 	f.StringSliceVar(&opts.KubeConfigPaths, "kubeconfig", []string{}, "Paths to kube config files\n(can be set multiple times)")
 	f.StringVar(&opts.KubeContext, "kube-context", "", "Kubernetes context to use")
 	f.BoolVar(&opts.LogDebug, "debug", false, "enable verbose output")
+	f.IntVar(&opts.NetworkParallelism, "network-parallelism", 30, "Network parallelism")
+	f.BoolVar(&opts.ProgressTablePrint, "kubedog", false, "Print progress table")
 	f.DurationVar(&opts.ProgressTablePrintInterval, "kubedog-interval", 5*time.Second, "Progress print interval")
-	f.IntVar(&opts.ReleaseHistoryLimit, "keep-history-limit", 10, "Release history limit (0 to remove all history)")
+	f.StringVar(&opts.ReadinessRulesFilePath, "readiness-rules", "", "Path to the YAML file with readiness rules for custom resources, applied to hooks without readiness annotations")
+	f.StringArrayVar(&opts.RedactionRules, "redact", []string{}, "Mask values at the path of matching resources in errors, reports and logs, format: <Kind>[.<version>][.<group>]:<path>, e.g. \"ConfigMap:.data.password\"\n(can be set multiple times)")
+	f.IntVar(&opts.RetryAttempts, "retry-attempts", 5, "Max attempts for resource operations failed with transient Kubernetes API errors, 1 disables retries")
 	f.StringVar(&opts.TempDirPath, "temp-dir", "", "Path to the temporary directory")
+	f.DurationVar(&opts.Timeout, "timeout", 0, "Timeout for the whole uninstall, after which it is interrupted (0 means no timeout)")
+	f.DurationVar(&opts.TrackDeletionTimeout, "deletion-timeout", 10*time.Minute, "Track deletion timeout")
+	f.IntVar(&opts.TrackParallelism, "track-parallelism", 0, "Tracking parallelism (defaults to network parallelism)")
+	f.DurationVar(&opts.TrackReadinessTimeout, "readiness-timeout", 10*time.Minute, "Track readiness timeout")
+	f.StringVar(&opts.UninstallGraphPath, "graph-path", "", "Path to save the uninstall graph")
+	f.BoolVar(&opts.UninstallGraphSave, "graph", false, "Save the uninstall graph")
+	f.StringVar(&opts.UninstallJUnitPath, "junit-path", "", "Path to save the uninstall outcome as a JUnit XML report")
+	f.BoolVar(&opts.UninstallJUnitSave, "junit", false, "Save the uninstall outcome as a JUnit XML report")
+	f.StringVar(&opts.UninstallReportPath, "report-path", "", "Path to save the uninstall report")
+	f.BoolVar(&opts.UninstallReportSave, "report", false, "Save the uninstall report")
+	f.IntVar(&opts.UninstallReportVersion, "report-version", reprt.DefaultReportVersion, "Version of the uninstall report format: 2 or 3. Version 3 also has errors, resources and timings of operations and tracking results")
 
 	return cmd
 }
@@ -14,8 +14,16 @@ func NewExternalDependency(name, namespace string, gvk schema.GroupVersionKind,
 		Mapper:           opts.Mapper,
 	})
 
+	var resourceState ResourceState
+	if opts.ResourceState == "" {
+		resourceState = ResourceStatePresent
+	} else {
+		resourceState = opts.ResourceState
+	}
+
 	return &ExternalDependency{
-		ResourceID: resID,
+		ResourceID:    resID,
+		ResourceState: resourceState,
 	}
 }
 
@@ -23,8 +31,10 @@ type ExternalDependencyOptions struct {
 	DefaultNamespace string
 	FilePath         string
 	Mapper           meta.ResettableRESTMapper
+	ResourceState    ResourceState
 }
 
 type ExternalDependency struct {
 	*resrcid.ResourceID
+	ResourceState ResourceState
 }
@@ -0,0 +1,57 @@
+package kubeclnt
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// Whether the error is transient and the request might succeed if retried: conflicts,
+// throttling, server-side errors and timeouts, dropped connections and webhook timeouts.
+func IsRetriableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch {
+	case apierrors.IsConflict(err),
+		apierrors.IsTooManyRequests(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err),
+		apierrors.IsInternalError(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsUnexpectedServerError(err):
+		return true
+	}
+
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) && apiStatus.Status().Code >= 500 {
+		return true
+	}
+
+	if utilnet.IsConnectionReset(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsProbableEOF(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := err.Error()
+	if strings.Contains(msg, "failed calling webhook") &&
+		(strings.Contains(msg, "context deadline exceeded") || strings.Contains(msg, "timeout")) {
+		return true
+	}
+
+	return false
+}
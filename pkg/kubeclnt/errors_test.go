@@ -0,0 +1,56 @@
+package kubeclnt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+)
+
+var configMapsResource = schema.GroupResource{Resource: "configmaps"}
+
+var _ = DescribeTable("IsRetriableError",
+	func(err error, expected bool) {
+		Expect(kubeclnt.IsRetriableError(err)).To(Equal(expected))
+	},
+	Entry("nil", nil, false),
+
+	Entry("conflict", apierrors.NewConflict(configMapsResource, "config", errors.New("object was modified")), true),
+	Entry("too many requests", apierrors.NewTooManyRequests("slow down", 1), true),
+	Entry("server timeout", apierrors.NewServerTimeout(configMapsResource, "create", 1), true),
+	Entry("timeout", apierrors.NewTimeoutError("timed out", 1), true),
+	Entry("internal error", apierrors.NewInternalError(errors.New("etcd is down")), true),
+	Entry("service unavailable", apierrors.NewServiceUnavailable("try later"), true),
+	Entry("unexpected server error", apierrors.NewGenericServerResponse(http.StatusBadGateway, "create", configMapsResource, "config", "", 0, true), true),
+	Entry("other status with 5xx code", &apierrors.StatusError{ErrStatus: metav1.Status{Status: metav1.StatusFailure, Code: http.StatusGatewayTimeout}}, true),
+	Entry("wrapped conflict", fmt.Errorf("error updating: %w", apierrors.NewConflict(configMapsResource, "config", errors.New("object was modified"))), true),
+
+	Entry("connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true),
+	Entry("connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true),
+	Entry("unexpected EOF", fmt.Errorf("error reading response: %w", io.ErrUnexpectedEOF), true),
+	Entry("probable EOF", errors.New("http2: server sent GOAWAY and closed the connection"), true),
+	Entry("network timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, true),
+	Entry("webhook deadline exceeded", errors.New(`Internal error occurred: failed calling webhook "validate.example.com": context deadline exceeded`), true),
+	Entry("webhook timeout", errors.New(`failed calling webhook "validate.example.com": Post "https://webhook.svc:443": net/http: request canceled (Client.Timeout exceeded while awaiting headers), timeout`), true),
+
+	Entry("not found", apierrors.NewNotFound(configMapsResource, "config"), false),
+	Entry("already exists", apierrors.NewAlreadyExists(configMapsResource, "config"), false),
+	Entry("invalid", apierrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, "config", nil), false),
+	Entry("forbidden", apierrors.NewForbidden(configMapsResource, "config", errors.New("no access")), false),
+	Entry("bad request", apierrors.NewBadRequest("bad manifest"), false),
+	Entry("webhook denial", errors.New(`admission webhook "validate.example.com" denied the request: replicas must be positive`), false),
+	Entry("webhook failure without timeout", errors.New(`failed calling webhook "validate.example.com": no endpoints available`), false),
+	Entry("canceled context", context.Canceled, false),
+	Entry("arbitrary error", errors.New("template rendering failed"), false),
+)
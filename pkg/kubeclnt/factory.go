@@ -15,22 +15,17 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
-
-	helm_v3 "github.com/werf/3p-helm-for-werf-helm/cmd/helm"
 )
 
 var addToScheme sync.Once
 
-func NewClientFactory() (*ClientFactory, error) {
+func NewClientFactory(restClientGetter genericclioptions.RESTClientGetter) (*ClientFactory, error) {
 	addToScheme.Do(func() {
 		lo.Must0(apiextv1.AddToScheme(scheme.Scheme))
 		lo.Must0(apiextv1beta1.AddToScheme(scheme.Scheme))
 	})
 
-	var restClientGetter genericclioptions.RESTClientGetter
-	if getterPtr := helm_v3.Settings.GetConfigP(); getterPtr != nil {
-		restClientGetter = *getterPtr
-	} else {
+	if restClientGetter == nil {
 		restClientGetter = genericclioptions.NewConfigFlags(true)
 	}
 
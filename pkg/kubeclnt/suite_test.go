@@ -0,0 +1,13 @@
+package kubeclnt_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestKubeClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "kube client suite")
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*ApplyResourceOperation)(nil)
+var _ ResourceOperation = (*ApplyResourceOperation)(nil)
 
 const TypeApplyResourceOperation = "apply"
 const TypeExtraPostApplyResourceOperation = "extra-post-apply"
@@ -37,6 +37,8 @@ type ApplyResourceOperationOptions struct {
 }
 
 type ApplyResourceOperation struct {
+	executionRecord
+
 	resource     *resrcid.ResourceID
 	unstruct     *unstructured.Unstructured
 	kubeClient   kubeclnt.KubeClienter
@@ -46,6 +48,9 @@ type ApplyResourceOperation struct {
 }
 
 func (o *ApplyResourceOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	if _, err := o.kubeClient.Apply(ctx, o.resource, o.unstruct, kubeclnt.KubeClientApplyOptions{}); err != nil {
 		o.status = StatusFailed
 		return fmt.Errorf("error applying resource: %w", err)
@@ -67,6 +72,10 @@ func (o *ApplyResourceOperation) HumanID() string {
 	return "apply resource: " + o.resource.HumanID()
 }
 
+func (o *ApplyResourceOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *ApplyResourceOperation) Status() Status {
 	return o.status
 }
@@ -26,6 +26,8 @@ func NewCreatePendingReleaseOperation(
 }
 
 type CreatePendingReleaseOperation struct {
+	executionRecord
+
 	deployType common.DeployType
 	release    *rls.Release
 	history    rlshistor.Historier
@@ -33,6 +35,9 @@ type CreatePendingReleaseOperation struct {
 }
 
 func (o *CreatePendingReleaseOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	o.release.Pend(o.deployType)
 
 	if err := o.history.CreateRelease(ctx, o.release); err != nil {
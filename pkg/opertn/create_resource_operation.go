@@ -12,7 +12,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*CreateResourceOperation)(nil)
+var _ ResourceOperation = (*CreateResourceOperation)(nil)
 
 const TypeCreateResourceOperation = "create"
 const TypeExtraPostCreateResourceOperation = "extra-post-create"
@@ -40,6 +40,8 @@ type CreateResourceOperationOptions struct {
 }
 
 type CreateResourceOperation struct {
+	executionRecord
+
 	resource      *resrcid.ResourceID
 	unstruct      *unstructured.Unstructured
 	kubeClient    kubeclnt.KubeClienter
@@ -50,6 +52,9 @@ type CreateResourceOperation struct {
 }
 
 func (o *CreateResourceOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	if _, err := o.kubeClient.Create(ctx, o.resource, o.unstruct, kubeclnt.KubeClientCreateOptions{
 		ForceReplicas: o.forceReplicas,
 	}); err != nil {
@@ -81,6 +86,10 @@ func (o *CreateResourceOperation) HumanID() string {
 	return "create resource: " + o.resource.HumanID()
 }
 
+func (o *CreateResourceOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *CreateResourceOperation) Status() Status {
 	return o.status
 }
@@ -0,0 +1,65 @@
+package opertn
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+)
+
+var _ Operation = (*DeleteReleaseOperation)(nil)
+
+const TypeDeleteReleaseOperation = "delete-release"
+
+func NewDeleteReleaseOperation(
+	rel *rls.Release,
+	history rlshistor.Historier,
+) *DeleteReleaseOperation {
+	return &DeleteReleaseOperation{
+		release: rel,
+		history: history,
+	}
+}
+
+type DeleteReleaseOperation struct {
+	executionRecord
+
+	release *rls.Release
+	history rlshistor.Historier
+	status  Status
+}
+
+func (o *DeleteReleaseOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
+	if err := o.history.DeleteRelease(ctx, o.release); err != nil {
+		o.status = StatusFailed
+		return fmt.Errorf("error deleting release: %w", err)
+	}
+
+	o.status = StatusCompleted
+
+	return nil
+}
+
+func (o *DeleteReleaseOperation) ID() string {
+	return TypeDeleteReleaseOperation + "/" + o.release.ID()
+}
+
+func (o *DeleteReleaseOperation) HumanID() string {
+	return "delete release: " + o.release.HumanID()
+}
+
+func (o *DeleteReleaseOperation) Status() Status {
+	return o.status
+}
+
+func (o *DeleteReleaseOperation) Type() Type {
+	return TypeDeleteReleaseOperation
+}
+
+func (o *DeleteReleaseOperation) Empty() bool {
+	return false
+}
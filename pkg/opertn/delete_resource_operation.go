@@ -8,7 +8,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*DeleteResourceOperation)(nil)
+var _ ResourceOperation = (*DeleteResourceOperation)(nil)
 
 const TypeDeleteResourceOperation = "delete"
 const TypeExtraPostDeleteResourceOperation = "extra-post-delete"
@@ -30,6 +30,8 @@ type DeleteResourceOperationOptions struct {
 }
 
 type DeleteResourceOperation struct {
+	executionRecord
+
 	resource   *resrcid.ResourceID
 	kubeClient kubeclnt.KubeClienter
 	extraPost  bool
@@ -37,6 +39,9 @@ type DeleteResourceOperation struct {
 }
 
 func (o *DeleteResourceOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	if err := o.kubeClient.Delete(ctx, o.resource, kubeclnt.KubeClientDeleteOptions{}); err != nil {
 		o.status = StatusFailed
 		return fmt.Errorf("error deleting resource: %w", err)
@@ -59,6 +64,10 @@ func (o *DeleteResourceOperation) HumanID() string {
 	return "delete resource: " + o.resource.HumanID()
 }
 
+func (o *DeleteResourceOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *DeleteResourceOperation) Status() Status {
 	return o.status
 }
@@ -0,0 +1,44 @@
+package opertn
+
+import "time"
+
+// Embedded into operations to record when they were executed, how many attempts it took and
+// why they failed.
+type executionRecord struct {
+	startedAt time.Time
+	endedAt   time.Time
+	attempts  int
+	err       error
+}
+
+func (r *executionRecord) StartedAt() time.Time {
+	return r.startedAt
+}
+
+func (r *executionRecord) EndedAt() time.Time {
+	return r.endedAt
+}
+
+func (r *executionRecord) Attempts() int {
+	return r.attempts
+}
+
+func (r *executionRecord) Err() error {
+	return r.err
+}
+
+func (r *executionRecord) SetErr(err error) {
+	r.err = err
+}
+
+func (r *executionRecord) beginAttempt() {
+	if r.startedAt.IsZero() {
+		r.startedAt = time.Now()
+	}
+
+	r.attempts++
+}
+
+func (r *executionRecord) end() {
+	r.endedAt = time.Now()
+}
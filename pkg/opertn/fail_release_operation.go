@@ -23,12 +23,17 @@ func NewFailReleaseOperation(
 }
 
 type FailReleaseOperation struct {
+	executionRecord
+
 	release *rls.Release
 	history rlshistor.Historier
 	status  Status
 }
 
 func (o *FailReleaseOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	o.release.Fail()
 
 	if err := o.history.UpdateRelease(ctx, o.release); err != nil {
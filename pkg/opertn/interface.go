@@ -1,6 +1,11 @@
 package opertn
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+)
 
 type Operation interface {
 	Execute(ctx context.Context) error
@@ -9,6 +14,17 @@ type Operation interface {
 	Status() Status
 	Type() Type
 	Empty() bool
+	StartedAt() time.Time
+	EndedAt() time.Time
+	Attempts() int
+	Err() error
+	SetErr(err error)
+}
+
+// Operation on a single Kubernetes resource.
+type ResourceOperation interface {
+	Operation
+	ResourceID() *resrcid.ResourceID
 }
 
 type Status string
@@ -20,3 +36,11 @@ const (
 )
 
 type Type string
+
+func Duration(op Operation) time.Duration {
+	if op.StartedAt().IsZero() || op.EndedAt().IsZero() {
+		return 0
+	}
+
+	return op.EndedAt().Sub(op.StartedAt())
+}
@@ -0,0 +1,67 @@
+package opertn
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+)
+
+var _ Operation = (*PendUninstallReleaseOperation)(nil)
+
+const TypePendUninstallReleaseOperation = "pend-uninstall-release"
+
+func NewPendUninstallReleaseOperation(
+	rel *rls.Release,
+	history rlshistor.Historier,
+) *PendUninstallReleaseOperation {
+	return &PendUninstallReleaseOperation{
+		release: rel,
+		history: history,
+	}
+}
+
+type PendUninstallReleaseOperation struct {
+	executionRecord
+
+	release *rls.Release
+	history rlshistor.Historier
+	status  Status
+}
+
+func (o *PendUninstallReleaseOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
+	o.release.PendUninstall()
+
+	if err := o.history.UpdateRelease(ctx, o.release); err != nil {
+		o.status = StatusFailed
+		return fmt.Errorf("error updating release: %w", err)
+	}
+
+	o.status = StatusCompleted
+
+	return nil
+}
+
+func (o *PendUninstallReleaseOperation) ID() string {
+	return TypePendUninstallReleaseOperation + "/" + o.release.ID()
+}
+
+func (o *PendUninstallReleaseOperation) HumanID() string {
+	return "pend uninstall release: " + o.release.HumanID()
+}
+
+func (o *PendUninstallReleaseOperation) Status() Status {
+	return o.status
+}
+
+func (o *PendUninstallReleaseOperation) Type() Type {
+	return TypePendUninstallReleaseOperation
+}
+
+func (o *PendUninstallReleaseOperation) Empty() bool {
+	return false
+}
@@ -17,7 +17,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*RecreateResourceOperation)(nil)
+var _ ResourceOperation = (*RecreateResourceOperation)(nil)
 
 const TypeRecreateResourceOperation = "recreate"
 const TypeExtraPostRecreateResourceOperation = "extra-post-recreate"
@@ -55,6 +55,8 @@ type RecreateResourceOperationOptions struct {
 }
 
 type RecreateResourceOperation struct {
+	executionRecord
+
 	resource                *resrcid.ResourceID
 	unstruct                *unstructured.Unstructured
 	taskState               *util.Concurrent[*statestore.AbsenceTaskState]
@@ -71,6 +73,9 @@ type RecreateResourceOperation struct {
 }
 
 func (o *RecreateResourceOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	if err := o.kubeClient.Delete(ctx, o.resource, kubeclnt.KubeClientDeleteOptions{}); err != nil {
 		o.status = StatusFailed
 		return fmt.Errorf("error deleting resource: %w", err)
@@ -110,6 +115,10 @@ func (o *RecreateResourceOperation) HumanID() string {
 	return "recreate resource: " + o.resource.HumanID()
 }
 
+func (o *RecreateResourceOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *RecreateResourceOperation) Status() Status {
 	return o.status
 }
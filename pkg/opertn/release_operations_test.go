@@ -0,0 +1,99 @@
+package opertn_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/chart"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/release"
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+var _ = Describe("release operations", func() {
+	It("should store pending release, then mark it succeeded and supersede previous one", func() {
+		ctx := tstng.NewContext(GinkgoWriter)
+
+		cluster, err := tstng.NewFakeCluster(tstng.FakeClusterOptions{})
+		Expect(err).To(Succeed())
+
+		history, err := rlshistor.NewHistory(ctx, "test-release", "test-namespace", rlsstor.NewMemoryReleaseStorage(), rlshistor.HistoryOptions{
+			Mapper:          cluster.Mapper(),
+			DiscoveryClient: cluster.Discovery(),
+		})
+		Expect(err).To(Succeed())
+
+		prevRel := newRelease(cluster, 1)
+		Expect(opertn.NewCreatePendingReleaseOperation(prevRel, common.DeployTypeInitial, history).Execute(ctx)).To(Succeed())
+		Expect(opertn.NewSucceedReleaseOperation(prevRel, history).Execute(ctx)).To(Succeed())
+
+		newRel := newRelease(cluster, 2)
+		opCreatePending := opertn.NewCreatePendingReleaseOperation(newRel, common.DeployTypeUpgrade, history)
+		Expect(opCreatePending.Execute(ctx)).To(Succeed())
+		Expect(opCreatePending.Status()).To(Equal(opertn.StatusCompleted))
+		Expect(releaseStatus(history, 2)).To(Equal(release.StatusPendingUpgrade))
+
+		Expect(opertn.NewSucceedReleaseOperation(newRel, history).Execute(ctx)).To(Succeed())
+		Expect(opertn.NewSupersedeReleaseOperation(prevRel, history).Execute(ctx)).To(Succeed())
+
+		Expect(releaseStatus(history, 1)).To(Equal(release.StatusSuperseded))
+		Expect(releaseStatus(history, 2)).To(Equal(release.StatusDeployed))
+
+		deployInfo, err := rlshistor.NewDeployInfo(history)
+		Expect(err).To(Succeed())
+		Expect(deployInfo.NewRevision).To(Equal(3))
+		Expect(deployInfo.DeployType).To(Equal(common.DeployTypeUpgrade))
+	})
+
+	It("succeed operation should fail if release was never stored", func() {
+		ctx := tstng.NewContext(GinkgoWriter)
+
+		cluster, err := tstng.NewFakeCluster(tstng.FakeClusterOptions{})
+		Expect(err).To(Succeed())
+
+		history, err := rlshistor.NewHistory(ctx, "test-release", "test-namespace", rlsstor.NewMemoryReleaseStorage(), rlshistor.HistoryOptions{
+			Mapper:          cluster.Mapper(),
+			DiscoveryClient: cluster.Discovery(),
+		})
+		Expect(err).To(Succeed())
+
+		op := opertn.NewSucceedReleaseOperation(newRelease(cluster, 1), history)
+		Expect(op.Execute(ctx)).NotTo(Succeed())
+		Expect(op.Status()).To(Equal(opertn.StatusFailed))
+	})
+})
+
+func newRelease(cluster *tstng.FakeCluster, revision int) *rls.Release {
+	GinkgoHelper()
+
+	rel, err := rls.NewRelease(
+		"test-release",
+		"test-namespace",
+		revision,
+		nil,
+		&chart.Chart{Metadata: &chart.Metadata{Name: "test", Version: "0.1.0"}},
+		nil,
+		nil,
+		"",
+		rls.ReleaseOptions{
+			Mapper: cluster.Mapper(),
+		},
+	)
+	Expect(err).To(Succeed())
+
+	return rel
+}
+
+func releaseStatus(history *rlshistor.History, revision int) release.Status {
+	GinkgoHelper()
+
+	rel, found, err := history.Release(revision)
+	Expect(err).To(Succeed())
+	Expect(found).To(BeTrue())
+
+	return rel.Status()
+}
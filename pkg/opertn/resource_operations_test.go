@@ -0,0 +1,168 @@
+package opertn_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+var configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+var deploymentGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+var _ = Describe("resource operations", func() {
+	var (
+		ctx     context.Context
+		cluster *tstng.FakeCluster
+	)
+
+	BeforeEach(func() {
+		ctx = tstng.NewContext(GinkgoWriter)
+
+		var err error
+		cluster, err = tstng.NewFakeCluster(tstng.FakeClusterOptions{
+			Objects: []*unstructured.Unstructured{
+				configMap("existing", "old"),
+			},
+		})
+		Expect(err).To(Succeed())
+	})
+
+	It("create operation should create resource with forced replicas", func() {
+		replicas := 3
+		op := opertn.NewCreateResourceOperation(
+			resourceID(cluster, deploymentGVK, "app"),
+			deployment("app", 1),
+			cluster.KubeClient(),
+			opertn.CreateResourceOperationOptions{
+				ForceReplicas: &replicas,
+			},
+		)
+
+		Expect(op.Execute(ctx)).To(Succeed())
+		Expect(op.Status()).To(Equal(opertn.StatusCompleted))
+		Expect(op.Attempts()).To(Equal(1))
+
+		obj := liveObject(cluster, deploymentGVK, "app")
+		Expect(obj.Object["spec"]).To(HaveKeyWithValue("replicas", BeNumerically("==", 3)))
+	})
+
+	DescribeTable("apply-based operation should update existing resource",
+		func(newOp func(resID *resrcid.ResourceID, unstruct *unstructured.Unstructured, cluster *tstng.FakeCluster) (opertn.Operation, error)) {
+			op, err := newOp(resourceID(cluster, configMapGVK, "existing"), configMap("existing", "new"), cluster)
+			Expect(err).To(Succeed())
+
+			Expect(op.Execute(ctx)).To(Succeed())
+			Expect(op.Status()).To(Equal(opertn.StatusCompleted))
+
+			obj := liveObject(cluster, configMapGVK, "existing")
+			Expect(obj.Object["data"]).To(HaveKeyWithValue("greeting", "new"))
+			Expect(obj.GetResourceVersion()).To(Equal("2"))
+		},
+		Entry("update", func(resID *resrcid.ResourceID, unstruct *unstructured.Unstructured, cluster *tstng.FakeCluster) (opertn.Operation, error) {
+			return opertn.NewUpdateResourceOperation(resID, unstruct, cluster.KubeClient(), opertn.UpdateResourceOperationOptions{})
+		}),
+		Entry("apply", func(resID *resrcid.ResourceID, unstruct *unstructured.Unstructured, cluster *tstng.FakeCluster) (opertn.Operation, error) {
+			return opertn.NewApplyResourceOperation(resID, unstruct, cluster.KubeClient(), opertn.ApplyResourceOperationOptions{})
+		}),
+	)
+
+	It("delete operation should delete resource", func() {
+		op := opertn.NewDeleteResourceOperation(
+			resourceID(cluster, configMapGVK, "existing"),
+			cluster.KubeClient(),
+			opertn.DeleteResourceOperationOptions{},
+		)
+
+		Expect(op.Execute(ctx)).To(Succeed())
+		Expect(op.Status()).To(Equal(opertn.StatusCompleted))
+
+		_, found, err := cluster.Object(configMapGVK, "test-namespace", "existing")
+		Expect(err).To(Succeed())
+		Expect(found).To(BeFalse())
+	})
+
+	It("delete operation should succeed if resource doesn't exist", func() {
+		op := opertn.NewDeleteResourceOperation(
+			resourceID(cluster, configMapGVK, "missing"),
+			cluster.KubeClient(),
+			opertn.DeleteResourceOperationOptions{},
+		)
+
+		Expect(op.Execute(ctx)).To(Succeed())
+		Expect(op.Status()).To(Equal(opertn.StatusCompleted))
+	})
+
+	It("create operation should fail for kind unknown to cluster", func() {
+		unknownGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Unknown"}
+
+		unstruct := &unstructured.Unstructured{}
+		unstruct.SetGroupVersionKind(unknownGVK)
+		unstruct.SetName("unknown")
+		unstruct.SetNamespace("test-namespace")
+
+		op := opertn.NewCreateResourceOperation(
+			resourceID(cluster, unknownGVK, "unknown"),
+			unstruct,
+			cluster.KubeClient(),
+			opertn.CreateResourceOperationOptions{},
+		)
+
+		Expect(op.Execute(ctx)).NotTo(Succeed())
+		Expect(op.Status()).To(Equal(opertn.StatusFailed))
+	})
+})
+
+func resourceID(cluster *tstng.FakeCluster, gvk schema.GroupVersionKind, name string) *resrcid.ResourceID {
+	return resrcid.NewResourceID(name, "test-namespace", gvk, resrcid.ResourceIDOptions{
+		Mapper: cluster.Mapper(),
+	})
+}
+
+func liveObject(cluster *tstng.FakeCluster, gvk schema.GroupVersionKind, name string) *unstructured.Unstructured {
+	GinkgoHelper()
+
+	obj, found, err := cluster.Object(gvk, "test-namespace", name)
+	Expect(err).To(Succeed())
+	Expect(found).To(BeTrue())
+
+	return obj
+}
+
+func configMap(name, greeting string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "test-namespace",
+			},
+			"data": map[string]interface{}{
+				"greeting": greeting,
+			},
+		},
+	}
+}
+
+func deployment(name string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "test-namespace",
+			},
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+			},
+		},
+	}
+}
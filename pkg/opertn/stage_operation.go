@@ -13,11 +13,16 @@ func NewStageOperation(name string) *StageOperation {
 }
 
 type StageOperation struct {
+	executionRecord
+
 	name   string
 	status Status
 }
 
 func (o *StageOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	o.status = StatusCompleted
 	return nil
 }
@@ -23,12 +23,17 @@ func NewSucceedReleaseOperation(
 }
 
 type SucceedReleaseOperation struct {
+	executionRecord
+
 	release *rls.Release
 	history rlshistor.Historier
 	status  Status
 }
 
 func (o *SucceedReleaseOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	o.release.Succeed()
 
 	if err := o.history.UpdateRelease(ctx, o.release); err != nil {
@@ -23,12 +23,17 @@ func NewSupersedeReleaseOperation(
 }
 
 type SupersedeReleaseOperation struct {
+	executionRecord
+
 	release *rls.Release
 	history rlshistor.Historier
 	status  Status
 }
 
 func (o *SupersedeReleaseOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	o.release.Supersede()
 
 	if err := o.history.UpdateRelease(ctx, o.release); err != nil {
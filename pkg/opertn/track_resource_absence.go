@@ -14,7 +14,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*TrackResourceAbsenceOperation)(nil)
+var _ ResourceOperation = (*TrackResourceAbsenceOperation)(nil)
 
 const TypeTrackResourceAbsenceOperation = "track-resource-absence"
 
@@ -41,6 +41,8 @@ type TrackResourceAbsenceOperationOptions struct {
 }
 
 type TrackResourceAbsenceOperation struct {
+	executionRecord
+
 	resource      *resrcid.ResourceID
 	taskState     *util.Concurrent[*statestore.AbsenceTaskState]
 	dynamicClient dynamic.Interface
@@ -52,6 +54,9 @@ type TrackResourceAbsenceOperation struct {
 }
 
 func (o *TrackResourceAbsenceOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	tracker := dyntracker.NewDynamicAbsenceTracker(o.taskState, o.dynamicClient, o.mapper, dyntracker.DynamicAbsenceTrackerOptions{
 		Timeout:    o.timeout,
 		PollPeriod: o.pollPeriod,
@@ -74,6 +79,10 @@ func (o *TrackResourceAbsenceOperation) HumanID() string {
 	return "track resource absence: " + o.resource.HumanID()
 }
 
+func (o *TrackResourceAbsenceOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *TrackResourceAbsenceOperation) Status() Status {
 	return o.status
 }
@@ -14,7 +14,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*TrackResourcePresenceOperation)(nil)
+var _ ResourceOperation = (*TrackResourcePresenceOperation)(nil)
 
 const TypeTrackResourcePresenceOperation = "track-resource-presence"
 
@@ -41,6 +41,8 @@ type TrackResourcePresenceOperationOptions struct {
 }
 
 type TrackResourcePresenceOperation struct {
+	executionRecord
+
 	resource      *resrcid.ResourceID
 	taskState     *util.Concurrent[*statestore.PresenceTaskState]
 	dynamicClient dynamic.Interface
@@ -52,6 +54,9 @@ type TrackResourcePresenceOperation struct {
 }
 
 func (o *TrackResourcePresenceOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	tracker := dyntracker.NewDynamicPresenceTracker(o.taskState, o.dynamicClient, o.mapper, dyntracker.DynamicPresenceTrackerOptions{
 		Timeout:    o.timeout,
 		PollPeriod: o.pollPeriod,
@@ -74,6 +79,10 @@ func (o *TrackResourcePresenceOperation) HumanID() string {
 	return "track resource presence: " + o.resource.HumanID()
 }
 
+func (o *TrackResourcePresenceOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *TrackResourcePresenceOperation) Status() Status {
 	return o.status
 }
@@ -6,7 +6,10 @@ import (
 	"regexp"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -15,13 +18,16 @@ import (
 	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
 	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
 	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*TrackResourceReadinessOperation)(nil)
+var _ ResourceOperation = (*TrackResourceReadinessOperation)(nil)
 
 const TypeTrackResourceReadinessOperation = "track-resource-readiness"
 
+const readinessRulePollPeriod = 2 * time.Second
+
 func NewTrackResourceReadinessOperation(
 	resource *resrcid.ResourceID,
 	taskState *util.Concurrent[*statestore.ReadinessTaskState],
@@ -50,6 +56,7 @@ func NewTrackResourceReadinessOperation(
 		ignoreLogs:                               opts.IgnoreLogs,
 		ignoreLogsForContainers:                  opts.IgnoreLogsForContainers,
 		saveEvents:                               opts.SaveEvents,
+		readinessRule:                            opts.ReadinessRule,
 	}
 }
 
@@ -64,9 +71,13 @@ type TrackResourceReadinessOperationOptions struct {
 	IgnoreLogs                               bool
 	IgnoreLogsForContainers                  []string
 	SaveEvents                               bool
+	// If set, readiness is determined by the rule instead of the kind-specific tracker.
+	ReadinessRule *rdnsrule.ReadinessRule
 }
 
 type TrackResourceReadinessOperation struct {
+	executionRecord
+
 	resource                                 *resrcid.ResourceID
 	taskState                                *util.Concurrent[*statestore.ReadinessTaskState]
 	logStore                                 *util.Concurrent[*logstore.LogStore]
@@ -84,11 +95,25 @@ type TrackResourceReadinessOperation struct {
 	ignoreLogs                               bool
 	ignoreLogsForContainers                  []string
 	saveEvents                               bool
+	readinessRule                            *rdnsrule.ReadinessRule
 
 	status Status
 }
 
 func (o *TrackResourceReadinessOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
+	if o.readinessRule != nil {
+		if err := o.trackByRule(ctx); err != nil {
+			o.status = StatusFailed
+			return fmt.Errorf("track resource readiness by rule: %w", err)
+		}
+
+		o.status = StatusCompleted
+		return nil
+	}
+
 	tracker, err := dyntracker.NewDynamicReadinessTracker(ctx, o.taskState, o.logStore, o.staticClient, o.dynamicClient, o.discoveryClient, o.mapper, dyntracker.DynamicReadinessTrackerOptions{
 		Timeout:                                  o.timeout,
 		NoActivityTimeout:                        o.noActivityTimeout,
@@ -114,6 +139,88 @@ func (o *TrackResourceReadinessOperation) Execute(ctx context.Context) error {
 	return nil
 }
 
+func (o *TrackResourceReadinessOperation) trackByRule(ctx context.Context) error {
+	namespaced, err := util.IsNamespaced(o.resource.GroupVersionKind(), o.mapper)
+	if err != nil {
+		return fmt.Errorf("check if namespaced: %w", err)
+	}
+
+	gvr, err := util.GVRFromGVK(o.resource.GroupVersionKind(), o.mapper)
+	if err != nil {
+		return fmt.Errorf("get GroupVersionResource: %w", err)
+	}
+
+	var resourceClient dynamic.ResourceInterface
+	if namespaced {
+		resourceClient = o.dynamicClient.Resource(gvr).Namespace(o.resource.Namespace())
+	} else {
+		resourceClient = o.dynamicClient.Resource(gvr)
+	}
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	// Errors getting the resource might be transient, so they are retried until the timeout.
+	var lastGetErr error
+	if err := wait.PollUntilContextCancel(ctx, readinessRulePollPeriod, true, func(ctx context.Context) (bool, error) {
+		unstruct, err := resourceClient.Get(ctx, o.resource.Name(), metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				lastGetErr = err
+			}
+
+			return false, nil
+		}
+		lastGetErr = nil
+
+		if !rdnsrule.StatusObserved(unstruct) {
+			return false, nil
+		}
+
+		if failed, reason, err := o.readinessRule.Failed(unstruct); err != nil {
+			return false, fmt.Errorf("check if resource %q failed: %w", o.resource.HumanID(), err)
+		} else if failed {
+			failErr := fmt.Errorf("resource %q failed: %s", o.resource.HumanID(), reason)
+			o.setResourceStatus(statestore.ResourceStatusFailed, failErr)
+
+			return false, failErr
+		}
+
+		if ready, err := o.readinessRule.Ready(unstruct); err != nil {
+			return false, fmt.Errorf("check if resource %q ready: %w", o.resource.HumanID(), err)
+		} else if !ready {
+			return false, nil
+		}
+
+		o.setResourceStatus(statestore.ResourceStatusReady, nil)
+
+		return true, nil
+	}); err != nil {
+		if lastGetErr != nil {
+			return fmt.Errorf("poll resource %q: %w, last error getting resource: %s", o.resource.HumanID(), err, lastGetErr)
+		}
+
+		return fmt.Errorf("poll resource %q: %w", o.resource.HumanID(), err)
+	}
+
+	return nil
+}
+
+func (o *TrackResourceReadinessOperation) setResourceStatus(status statestore.ResourceStatus, err error) {
+	o.taskState.RWTransaction(func(ts *statestore.ReadinessTaskState) {
+		ts.ResourceState(ts.Name(), ts.Namespace(), ts.GroupVersionKind()).RWTransaction(func(rs *statestore.ResourceState) {
+			rs.SetStatus(status)
+
+			if err != nil {
+				rs.AddError(err, "", time.Now())
+			}
+		})
+	})
+}
+
 func (o *TrackResourceReadinessOperation) ID() string {
 	return TypeTrackResourceReadinessOperation + "/" + o.resource.ID()
 }
@@ -122,6 +229,10 @@ func (o *TrackResourceReadinessOperation) HumanID() string {
 	return "track resource readiness: " + o.resource.HumanID()
 }
 
+func (o *TrackResourceReadinessOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *TrackResourceReadinessOperation) Status() Status {
 	return o.status
 }
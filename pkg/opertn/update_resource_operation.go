@@ -11,7 +11,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 )
 
-var _ Operation = (*UpdateResourceOperation)(nil)
+var _ ResourceOperation = (*UpdateResourceOperation)(nil)
 
 const TypeUpdateResourceOperation = "update"
 const TypeExtraPostUpdateResourceOperation = "extra-post-update"
@@ -37,6 +37,8 @@ type UpdateResourceOperationOptions struct {
 }
 
 type UpdateResourceOperation struct {
+	executionRecord
+
 	resource     *resrcid.ResourceID
 	unstruct     *unstructured.Unstructured
 	kubeClient   kubeclnt.KubeClienter
@@ -46,6 +48,9 @@ type UpdateResourceOperation struct {
 }
 
 func (o *UpdateResourceOperation) Execute(ctx context.Context) error {
+	o.beginAttempt()
+	defer o.end()
+
 	if _, err := o.kubeClient.Apply(ctx, o.resource, o.unstruct, kubeclnt.KubeClientApplyOptions{}); err != nil {
 		o.status = StatusFailed
 		return fmt.Errorf("error applying resource: %w", err)
@@ -67,6 +72,10 @@ func (o *UpdateResourceOperation) HumanID() string {
 	return "update resource: " + o.resource.HumanID()
 }
 
+func (o *UpdateResourceOperation) ResourceID() *resrcid.ResourceID {
+	return o.resource
+}
+
 func (o *UpdateResourceOperation) Status() Status {
 	return o.status
 }
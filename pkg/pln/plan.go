@@ -186,6 +186,78 @@ func (p *Plan) WorthyCanceledOperations() (worthyCanceledOps []opertn.Operation,
 	return worthyCanceledOps, len(worthyCanceledOps) > 0, nil
 }
 
+func (p *Plan) ExecutedOperations() (executedOps []opertn.Operation, found bool, err error) {
+	ops, found, err := p.Operations()
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting operations: %w", err)
+	} else if !found {
+		return nil, false, nil
+	}
+
+	for _, op := range ops {
+		if op.Attempts() > 0 {
+			executedOps = append(executedOps, op)
+		}
+	}
+
+	return executedOps, len(executedOps) > 0, nil
+}
+
+// Critical path is the chain of executed operations, which actually delayed the end of
+// plan execution: starting from the last finished operation, on each step we go to the
+// predecessor which finished last, since the operation couldn't start before it. Empty
+// (stage) operations are traversed, but not included in the result.
+func (p *Plan) CriticalPath() (path []opertn.Operation, found bool, err error) {
+	executedOps, found, err := p.ExecutedOperations()
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting executed operations: %w", err)
+	} else if !found {
+		return nil, false, nil
+	}
+
+	predecessorMap, err := p.PredecessorMap()
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting predecessor map: %w", err)
+	}
+
+	current := lo.MaxBy(executedOps, func(a, b opertn.Operation) bool {
+		return a.EndedAt().After(b.EndedAt())
+	})
+
+	for current != nil {
+		if !current.Empty() {
+			path = append(path, current)
+		}
+
+		var latestPredecessor opertn.Operation
+		for predecessorID := range predecessorMap[current.ID()] {
+			predecessor := lo.Must(p.Operation(predecessorID))
+			if predecessor.Attempts() == 0 {
+				continue
+			}
+
+			if latestPredecessor == nil || predecessor.EndedAt().After(latestPredecessor.EndedAt()) {
+				latestPredecessor = predecessor
+			}
+		}
+
+		current = latestPredecessor
+	}
+
+	path = lo.Reverse(path)
+
+	return path, len(path) > 0, nil
+}
+
+func (p *Plan) Dependencies() (deps []graph.Edge[string], err error) {
+	deps, err = p.graph.Edges()
+	if err != nil {
+		return nil, fmt.Errorf("error getting edges: %w", err)
+	}
+
+	return deps, nil
+}
+
 func (p *Plan) PredecessorMap() (map[string]map[string]graph.Edge[string], error) {
 	return p.graph.PredecessorMap()
 }
@@ -25,9 +25,11 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/log"
 	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
 	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/rls"
 	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
 )
@@ -76,6 +78,18 @@ func NewDeployPlanBuilder(
 ) *DeployPlanBuilder {
 	plan := pln.NewPlan()
 
+	curReleaseExistResourcesUIDs, _ := CurrentReleaseExistingResourcesUIDs(standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos)
+
+	standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos, prevReleaseGeneralResourceInfos, _ = SelectResourcesInfos(
+		opts.ResourceSelector,
+		newRelease.Name(),
+		releaseNamespace,
+		standaloneCRDsInfos,
+		hookResourcesInfos,
+		generalResourcesInfos,
+		prevReleaseGeneralResourceInfos,
+	)
+
 	preHookResourcesInfos := lo.Filter(hookResourcesInfos, func(info *resrcinfo.DeployableHookResourceInfo, _ int) bool {
 		switch deployType {
 		case common.DeployTypeInitial, common.DeployTypeInstall:
@@ -117,8 +131,6 @@ func NewDeployPlanBuilder(
 		return res.ResourceID, false
 	})
 
-	curReleaseExistResourcesUIDs, _ := CurrentReleaseExistingResourcesUIDs(standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos)
-
 	return &DeployPlanBuilder{
 		taskStore:                       taskStore,
 		logStore:                        logStore,
@@ -144,6 +156,7 @@ func NewDeployPlanBuilder(
 		creationTimeout:                 opts.CreationTimeout,
 		readinessTimeout:                opts.ReadinessTimeout,
 		deletionTimeout:                 opts.DeletionTimeout,
+		readinessRules:                  opts.ReadinessRules,
 	}
 }
 
@@ -153,6 +166,9 @@ type DeployPlanBuilderOptions struct {
 	CreationTimeout     time.Duration
 	ReadinessTimeout    time.Duration
 	DeletionTimeout     time.Duration
+	ResourceSelector    *resrcmatcher.ResourceSelector
+	// Used for resources without their own readiness rule in annotations.
+	ReadinessRules []*rdnsrule.ReadinessRule
 }
 
 type DeployPlanBuilder struct {
@@ -179,6 +195,7 @@ type DeployPlanBuilder struct {
 	creationTimeout                 time.Duration
 	readinessTimeout                time.Duration
 	deletionTimeout                 time.Duration
+	readinessRules                  []*rdnsrule.ReadinessRule
 
 	plan *pln.Plan
 }
@@ -675,32 +692,47 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 		prevReleaseFailed = b.prevRelease.Failed()
 	}
 
-	for _, info := range infos {
-		var extraPost bool
-		if !pre {
-			_, extraPost = lo.Find(b.prePostHookResourcesIDs, func(rid *resrcid.ResourceID) bool {
-				return rid.ID() == info.ResourceID.ID()
-			})
-		}
+	hookOpsBuilder := &hookOperationsBuilder{
+		plan:                           b.plan,
+		taskStore:                      b.taskStore,
+		logStore:                       b.logStore,
+		releaseName:                    b.newRelease.Name(),
+		releaseNamespace:               b.releaseNamespace,
+		prePostHookResourcesIDs:        b.prePostHookResourcesIDs,
+		prevReleaseFailed:              prevReleaseFailed,
+		kubeClient:                     b.kubeClient,
+		staticClient:                   b.staticClient,
+		dynamicClient:                  b.dynamicClient,
+		discoveryClient:                b.discoveryClient,
+		mapper:                         b.mapper,
+		readinessTimeout:               b.readinessTimeout,
+		deletionTimeout:                b.deletionTimeout,
+		readinessRules:                 b.readinessRules,
+		newExternalDependencyOperation: b.newExternalDependencyOperation,
+		outOfStageOps:                  true,
+	}
+
+	return hookOpsBuilder.setup(infos, stageStartOpID, stageEndOpID, pre)
+}
+
+// TODO(ilya-lesikov): almost identical with setupHookOperations, refactor
+func (b *DeployPlanBuilder) setupGeneralOperations(infos []*resrcinfo.DeployableGeneralResourceInfo, stageStartOpID, stageEndOpID string) error {
+	var prevReleaseFailed bool
+	if b.prevRelease != nil {
+		prevReleaseFailed = b.prevRelease.Failed()
+	}
 
+	for _, info := range infos {
 		create := info.ShouldCreate()
 		recreate := info.ShouldRecreate()
 		update := info.ShouldUpdate()
 		apply := info.ShouldApply()
 		cleanup := info.ShouldCleanup(b.newRelease.Name(), b.releaseNamespace)
-		var trackReadiness bool
-		if track := info.ShouldTrackReadiness(prevReleaseFailed); track && !extraPost {
-			trackReadiness = true
-		}
+		trackReadiness := info.ShouldTrackReadiness(prevReleaseFailed)
 		_, manIntDepsSet := info.Resource().ManualInternalDependencies()
-		var externalDeps []*depnd.ExternalDependency
-		var extDepsSet bool
-		if !extraPost {
-			var err error
-			externalDeps, extDepsSet, err = info.Resource().ExternalDependencies()
-			if err != nil {
-				return fmt.Errorf("error getting external dependencies: %w", err)
-			}
+		externalDeps, extDepsSet, err := info.Resource().ExternalDependencies()
+		if err != nil {
+			return fmt.Errorf("error getting external dependencies: %w", err)
 		}
 		var forceReplicas *int
 		if r, set := info.Resource().DefaultReplicasOnCreation(); set {
@@ -716,7 +748,6 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 				opertn.CreateResourceOperationOptions{
 					ManageableBy:  info.Resource().ManageableBy(),
 					ForceReplicas: forceReplicas,
-					ExtraPost:     extraPost,
 				},
 			)
 		} else if recreate {
@@ -736,7 +767,6 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 					ManageableBy:         info.Resource().ManageableBy(),
 					ForceReplicas:        forceReplicas,
 					DeletionTrackTimeout: b.deletionTimeout,
-					ExtraPost:            extraPost,
 				},
 			)
 		} else if update {
@@ -747,7 +777,6 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 				b.kubeClient,
 				opertn.UpdateResourceOperationOptions{
 					ManageableBy: info.Resource().ManageableBy(),
-					ExtraPost:    extraPost,
 				},
 			)
 			if err != nil {
@@ -761,7 +790,6 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 				b.kubeClient,
 				opertn.ApplyResourceOperationOptions{
 					ManageableBy: info.Resource().ManageableBy(),
-					ExtraPost:    extraPost,
 				},
 			)
 			if err != nil {
@@ -787,48 +815,14 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 
 		if extDepsSet && opDeploy != nil {
 			for _, dep := range externalDeps {
-				taskState, taskStateFound := lo.Find(b.taskStore.PresenceTasksStates(), func(ts *util.Concurrent[*statestore.PresenceTaskState]) bool {
-					var found bool
-
-					ts.RTransaction(func(pts *statestore.PresenceTaskState) {
-						if pts.Name() == dep.Name() &&
-							pts.Namespace() == dep.Namespace() &&
-							pts.GroupVersionKind() == dep.GroupVersionKind() {
-							found = true
-						}
-					})
-
-					return found
-				})
-
-				if !taskStateFound {
-					taskState = util.NewConcurrent(
-						statestore.NewPresenceTaskState(
-							dep.Name(),
-							dep.Namespace(),
-							dep.GroupVersionKind(),
-							statestore.PresenceTaskStateOptions{},
-						),
-					)
-					b.taskStore.AddPresenceTaskState(taskState)
-				}
-
-				opTrackReadiness := opertn.NewTrackResourcePresenceOperation(
-					dep.ResourceID,
-					taskState,
-					b.dynamicClient,
-					b.mapper,
-					opertn.TrackResourcePresenceOperationOptions{
-						Timeout: b.readinessTimeout,
-					},
-				)
+				opTrackDep := b.newExternalDependencyOperation(dep)
 
 				b.plan.AddInStagedOperation(
-					opTrackReadiness,
+					opTrackDep,
 					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
 				)
 
-				lo.Must0(b.plan.AddDependency(opTrackReadiness.ID(), opDeploy.ID()))
+				lo.Must0(b.plan.AddDependency(opTrackDep.ID(), opDeploy.ID()))
 			}
 		}
 
@@ -843,6 +837,10 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 			if timeout, set := info.Resource().NoActivityTimeout(); set {
 				noActivityTimeout = *timeout
 			}
+			readinessRule, readinessRuleSet := info.Resource().ReadinessRule()
+			if !readinessRuleSet {
+				readinessRule, _ = rdnsrule.FindRule(b.readinessRules, info.GroupVersionKind())
+			}
 
 			taskState := util.NewConcurrent(
 				statestore.NewReadinessTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.ReadinessTaskStateOptions{
@@ -870,6 +868,7 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 					IgnoreLogs:                               info.Resource().SkipLogs(),
 					IgnoreLogsForContainers:                  skipLogsFor,
 					SaveEvents:                               info.Resource().ShowServiceMessages(),
+					ReadinessRule:                            readinessRule,
 				},
 			)
 			if manIntDepsSet {
@@ -894,9 +893,7 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 			cleanupOp := opertn.NewDeleteResourceOperation(
 				info.ResourceID,
 				b.kubeClient,
-				opertn.DeleteResourceOperationOptions{
-					ExtraPost: extraPost,
-				},
+				opertn.DeleteResourceOperationOptions{},
 			)
 
 			if trackReadiness {
@@ -941,254 +938,126 @@ func (b *DeployPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHoo
 	return nil
 }
 
-// TODO(ilya-lesikov): almost identical with setupHookOperations, refactor
-func (b *DeployPlanBuilder) setupGeneralOperations(infos []*resrcinfo.DeployableGeneralResourceInfo, stageStartOpID, stageEndOpID string) error {
-	var prevReleaseFailed bool
-	if b.prevRelease != nil {
-		prevReleaseFailed = b.prevRelease.Failed()
-	}
-
-	for _, info := range infos {
-		create := info.ShouldCreate()
-		recreate := info.ShouldRecreate()
-		update := info.ShouldUpdate()
-		apply := info.ShouldApply()
-		cleanup := info.ShouldCleanup(b.newRelease.Name(), b.releaseNamespace)
-		trackReadiness := info.ShouldTrackReadiness(prevReleaseFailed)
-		_, manIntDepsSet := info.Resource().ManualInternalDependencies()
-		externalDeps, extDepsSet, err := info.Resource().ExternalDependencies()
-		if err != nil {
-			return fmt.Errorf("error getting external dependencies: %w", err)
-		}
-		var forceReplicas *int
-		if r, set := info.Resource().DefaultReplicasOnCreation(); set {
-			forceReplicas = &r
-		}
+// Task states are shared between resources depending on the same external resource in the same
+// state.
+func (b *DeployPlanBuilder) newExternalDependencyOperation(dep *depnd.ExternalDependency) opertn.Operation {
+	switch dep.ResourceState {
+	case depnd.ResourceStatePresent:
+		taskState, taskStateFound := lo.Find(b.taskStore.PresenceTasksStates(), func(ts *util.Concurrent[*statestore.PresenceTaskState]) bool {
+			var found bool
+
+			ts.RTransaction(func(pts *statestore.PresenceTaskState) {
+				if pts.Name() == dep.Name() &&
+					pts.Namespace() == dep.Namespace() &&
+					pts.GroupVersionKind() == dep.GroupVersionKind() {
+					found = true
+				}
+			})
 
-		var opDeploy opertn.Operation
-		if create {
-			opDeploy = opertn.NewCreateResourceOperation(
-				info.ResourceID,
-				info.Resource().Unstructured(),
-				b.kubeClient,
-				opertn.CreateResourceOperationOptions{
-					ManageableBy:  info.Resource().ManageableBy(),
-					ForceReplicas: forceReplicas,
-				},
-			)
-		} else if recreate {
-			absenceTaskState := util.NewConcurrent(
-				statestore.NewAbsenceTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.AbsenceTaskStateOptions{}),
-			)
-			b.taskStore.AddAbsenceTaskState(absenceTaskState)
+			return found
+		})
 
-			opDeploy = opertn.NewRecreateResourceOperation(
-				info.ResourceID,
-				info.Resource().Unstructured(),
-				absenceTaskState,
-				b.kubeClient,
-				b.dynamicClient,
-				b.mapper,
-				opertn.RecreateResourceOperationOptions{
-					ManageableBy:         info.Resource().ManageableBy(),
-					ForceReplicas:        forceReplicas,
-					DeletionTrackTimeout: b.deletionTimeout,
-				},
-			)
-		} else if update {
-			var err error
-			opDeploy, err = opertn.NewUpdateResourceOperation(
-				info.ResourceID,
-				info.Resource().Unstructured(),
-				b.kubeClient,
-				opertn.UpdateResourceOperationOptions{
-					ManageableBy: info.Resource().ManageableBy(),
-				},
-			)
-			if err != nil {
-				return fmt.Errorf("error creating update resource operation: %w", err)
-			}
-		} else if apply {
-			var err error
-			opDeploy, err = opertn.NewApplyResourceOperation(
-				info.ResourceID,
-				info.Resource().Unstructured(),
-				b.kubeClient,
-				opertn.ApplyResourceOperationOptions{
-					ManageableBy: info.Resource().ManageableBy(),
-				},
+		if !taskStateFound {
+			taskState = util.NewConcurrent(
+				statestore.NewPresenceTaskState(
+					dep.Name(),
+					dep.Namespace(),
+					dep.GroupVersionKind(),
+					statestore.PresenceTaskStateOptions{},
+				),
 			)
-			if err != nil {
-				return fmt.Errorf("error creating apply resource operation: %w", err)
-			}
-		}
-
-		if opDeploy != nil {
-			if manIntDepsSet {
-				b.plan.AddStagedOperation(
-					opDeploy,
-					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
-					StageOpNamePrefixFinal+"/"+StageOpNameSuffixStart,
-				)
-			} else {
-				b.plan.AddStagedOperation(
-					opDeploy,
-					stageStartOpID,
-					stageEndOpID,
-				)
-			}
+			b.taskStore.AddPresenceTaskState(taskState)
 		}
 
-		if extDepsSet && opDeploy != nil {
-			for _, dep := range externalDeps {
-				taskState, taskStateFound := lo.Find(b.taskStore.PresenceTasksStates(), func(ts *util.Concurrent[*statestore.PresenceTaskState]) bool {
-					var found bool
-
-					ts.RTransaction(func(pts *statestore.PresenceTaskState) {
-						if pts.Name() == dep.Name() &&
-							pts.Namespace() == dep.Namespace() &&
-							pts.GroupVersionKind() == dep.GroupVersionKind() {
-							found = true
-						}
-					})
-
-					return found
-				})
-
-				if !taskStateFound {
-					taskState = util.NewConcurrent(
-						statestore.NewPresenceTaskState(
-							dep.Name(),
-							dep.Namespace(),
-							dep.GroupVersionKind(),
-							statestore.PresenceTaskStateOptions{},
-						),
-					)
-					b.taskStore.AddPresenceTaskState(taskState)
+		return opertn.NewTrackResourcePresenceOperation(
+			dep.ResourceID,
+			taskState,
+			b.dynamicClient,
+			b.mapper,
+			opertn.TrackResourcePresenceOperationOptions{
+				Timeout: b.readinessTimeout,
+			},
+		)
+	case depnd.ResourceStateReady:
+		taskState, taskStateFound := lo.Find(b.taskStore.ReadinessTasksStates(), func(ts *util.Concurrent[*statestore.ReadinessTaskState]) bool {
+			var found bool
+
+			ts.RTransaction(func(rts *statestore.ReadinessTaskState) {
+				if rts.Name() == dep.Name() &&
+					rts.Namespace() == dep.Namespace() &&
+					rts.GroupVersionKind() == dep.GroupVersionKind() {
+					found = true
 				}
+			})
 
-				opTrackReadiness := opertn.NewTrackResourcePresenceOperation(
-					dep.ResourceID,
-					taskState,
-					b.dynamicClient,
-					b.mapper,
-					opertn.TrackResourcePresenceOperationOptions{
-						Timeout: b.readinessTimeout,
-					},
-				)
-
-				b.plan.AddInStagedOperation(
-					opTrackReadiness,
-					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
-				)
-
-				lo.Must0(b.plan.AddDependency(opTrackReadiness.ID(), opDeploy.ID()))
-			}
-		}
-
-		var opTrackReadiness *opertn.TrackResourceReadinessOperation
-		if trackReadiness {
-			logRegex, _ := info.Resource().LogRegex()
-			logRegexesFor, _ := info.Resource().LogRegexesForContainers()
-			skipLogsFor, _ := info.Resource().SkipLogsForContainers()
-			showLogsOnlyFor, _ := info.Resource().ShowLogsOnlyForContainers()
-			ignoreReadinessProbes, _ := info.Resource().IgnoreReadinessProbeFailsForContainers()
-			var noActivityTimeout time.Duration
-			if timeout, set := info.Resource().NoActivityTimeout(); set {
-				noActivityTimeout = *timeout
-			}
+			return found
+		})
 
-			taskState := util.NewConcurrent(
-				statestore.NewReadinessTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.ReadinessTaskStateOptions{
-					FailMode:                info.Resource().FailMode(),
-					TotalAllowFailuresCount: info.Resource().FailuresAllowed(),
-				}),
+		if !taskStateFound {
+			taskState = util.NewConcurrent(
+				statestore.NewReadinessTaskState(
+					dep.Name(),
+					dep.Namespace(),
+					dep.GroupVersionKind(),
+					statestore.ReadinessTaskStateOptions{},
+				),
 			)
 			b.taskStore.AddReadinessTaskState(taskState)
-
-			opTrackReadiness = opertn.NewTrackResourceReadinessOperation(
-				info.ResourceID,
-				taskState,
-				b.logStore,
-				b.staticClient,
-				b.dynamicClient,
-				b.discoveryClient,
-				b.mapper,
-				opertn.TrackResourceReadinessOperationOptions{
-					Timeout:                                  b.readinessTimeout,
-					NoActivityTimeout:                        noActivityTimeout,
-					IgnoreReadinessProbeFailsByContainerName: ignoreReadinessProbes,
-					SaveLogsOnlyForContainers:                showLogsOnlyFor,
-					SaveLogsByRegex:                          logRegex,
-					SaveLogsByRegexForContainers:             logRegexesFor,
-					IgnoreLogs:                               info.Resource().SkipLogs(),
-					IgnoreLogsForContainers:                  skipLogsFor,
-					SaveEvents:                               info.Resource().ShowServiceMessages(),
-				},
-			)
-			if manIntDepsSet {
-				b.plan.AddStagedOperation(
-					opTrackReadiness,
-					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
-					StageOpNamePrefixFinal+"/"+StageOpNameSuffixStart,
-				)
-			} else {
-				b.plan.AddStagedOperation(
-					opTrackReadiness,
-					stageStartOpID,
-					stageEndOpID,
-				)
-			}
-			if opDeploy != nil {
-				lo.Must0(b.plan.AddDependency(opDeploy.ID(), opTrackReadiness.ID()))
-			}
 		}
 
-		if cleanup {
-			cleanupOp := opertn.NewDeleteResourceOperation(
-				info.ResourceID,
-				b.kubeClient,
-				opertn.DeleteResourceOperationOptions{},
-			)
+		readinessRule, _ := rdnsrule.FindRule(b.readinessRules, dep.GroupVersionKind())
+
+		return opertn.NewTrackResourceReadinessOperation(
+			dep.ResourceID,
+			taskState,
+			b.logStore,
+			b.staticClient,
+			b.dynamicClient,
+			b.discoveryClient,
+			b.mapper,
+			opertn.TrackResourceReadinessOperationOptions{
+				Timeout:       b.readinessTimeout,
+				IgnoreLogs:    true,
+				ReadinessRule: readinessRule,
+			},
+		)
+	case depnd.ResourceStateAbsent:
+		taskState, taskStateFound := lo.Find(b.taskStore.AbsenceTasksStates(), func(ts *util.Concurrent[*statestore.AbsenceTaskState]) bool {
+			var found bool
+
+			ts.RTransaction(func(ats *statestore.AbsenceTaskState) {
+				if ats.Name() == dep.Name() &&
+					ats.Namespace() == dep.Namespace() &&
+					ats.GroupVersionKind() == dep.GroupVersionKind() {
+					found = true
+				}
+			})
 
-			if trackReadiness {
-				b.plan.AddOperation(cleanupOp)
-				lo.Must0(b.plan.AddDependency(opTrackReadiness.ID(), cleanupOp.ID()))
-			} else if opDeploy != nil {
-				b.plan.AddOperation(cleanupOp)
-				lo.Must0(b.plan.AddDependency(opDeploy.ID(), cleanupOp.ID()))
-			} else {
-				b.plan.AddInStagedOperation(
-					cleanupOp,
-					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
-				)
-			}
+			return found
+		})
 
-			taskState := util.NewConcurrent(
+		if !taskStateFound {
+			taskState = util.NewConcurrent(
 				statestore.NewAbsenceTaskState(
-					info.Name(),
-					info.Namespace(),
-					info.GroupVersionKind(),
+					dep.Name(),
+					dep.Namespace(),
+					dep.GroupVersionKind(),
 					statestore.AbsenceTaskStateOptions{},
 				),
 			)
 			b.taskStore.AddAbsenceTaskState(taskState)
-
-			opTrackDeletion := opertn.NewTrackResourceAbsenceOperation(
-				info.ResourceID,
-				taskState,
-				b.dynamicClient,
-				b.mapper,
-				opertn.TrackResourceAbsenceOperationOptions{
-					Timeout: b.deletionTimeout,
-				},
-			)
-			b.plan.AddOperation(opTrackDeletion)
-			if err := b.plan.AddDependency(cleanupOp.ID(), opTrackDeletion.ID()); err != nil {
-				return fmt.Errorf("error adding dependency: %w", err)
-			}
 		}
-	}
 
-	return nil
+		return opertn.NewTrackResourceAbsenceOperation(
+			dep.ResourceID,
+			taskState,
+			b.dynamicClient,
+			b.mapper,
+			opertn.TrackResourceAbsenceOperationOptions{
+				Timeout: b.readinessTimeout,
+			},
+		)
+	default:
+		panic(fmt.Sprintf("unexpected resource state %q", dep.ResourceState))
+	}
 }
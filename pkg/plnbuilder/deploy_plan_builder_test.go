@@ -0,0 +1,192 @@
+package plnbuilder_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+type deployPlanCase struct {
+	chart string
+	// Values sets of releases deployed before, oldest first. Resources of these releases are
+	// applied to the cluster.
+	prevReleases [][]string
+	valuesSets   []string
+	objects      []*unstructured.Unstructured
+	deployType   common.DeployType
+}
+
+var _ = Describe("DeployPlanBuilder", func() {
+	DescribeTable("should build the same plan as before",
+		func(goldenName string, c deployPlanCase) {
+			ctx := tstng.NewContext(GinkgoWriter)
+
+			cluster, err := tstng.NewFakeCluster(tstng.FakeClusterOptions{
+				Objects: c.objects,
+			})
+			Expect(err).To(Succeed())
+
+			releaseStorage := rlsstor.NewMemoryReleaseStorage()
+			chartDirPath := filepath.Join("testdata", "charts", c.chart)
+
+			for _, valuesSets := range c.prevReleases {
+				deployRelease(ctx, chartDirPath, cluster, releaseStorage, valuesSets)
+			}
+
+			deployPlan, err := tstng.BuildDeployPlan(ctx, chartDirPath, cluster, releaseStorage, tstng.BuildDeployPlanOptions{
+				ValuesSets: c.valuesSets,
+			})
+			Expect(err).To(Succeed())
+			Expect(deployPlan.DeployType).To(Equal(c.deployType))
+			Expect(deployPlan.Release.Revision()).To(Equal(len(c.prevReleases) + 1))
+
+			expectGoldenPlan(goldenName, deployPlan.Plan)
+		},
+		Entry("in empty cluster", "initial-install", deployPlanCase{
+			chart:      "basic",
+			deployType: common.DeployTypeInitial,
+		}),
+		Entry("with outdated resource of the release in cluster", "initial-install-existing-resource", deployPlanCase{
+			chart: "basic",
+			objects: []*unstructured.Unstructured{
+				configMap("config", map[string]interface{}{
+					"meta.helm.sh/release-name":      "test-release",
+					"meta.helm.sh/release-namespace": "test-namespace",
+				}),
+			},
+			deployType: common.DeployTypeInitial,
+		}),
+		Entry("with resource not owned by any release in cluster", "initial-install-adoption", deployPlanCase{
+			chart: "basic",
+			objects: []*unstructured.Unstructured{
+				configMap("config", nil),
+			},
+			valuesSets: []string{"adopt=true"},
+			deployType: common.DeployTypeInitial,
+		}),
+		Entry("over deployed release", "upgrade", deployPlanCase{
+			chart:        "basic",
+			prevReleases: [][]string{nil},
+			valuesSets:   []string{"greeting=bye"},
+			deployType:   common.DeployTypeUpgrade,
+		}),
+		Entry("with resource removed from the chart", "upgrade-removed-resource", deployPlanCase{
+			chart:        "basic",
+			prevReleases: [][]string{nil},
+			valuesSets:   []string{"app.enabled=false"},
+			deployType:   common.DeployTypeUpgrade,
+		}),
+		Entry("with hooks in empty cluster", "initial-install-hooks", deployPlanCase{
+			chart:      "hooks",
+			deployType: common.DeployTypeInitial,
+		}),
+		Entry("with hooks over deployed release", "upgrade-hooks", deployPlanCase{
+			chart:        "hooks",
+			prevReleases: [][]string{nil, nil},
+			deployType:   common.DeployTypeUpgrade,
+		}),
+	)
+})
+
+var _ = Describe("UninstallPlanBuilder", func() {
+	DescribeTable("should build the same plan as before",
+		func(goldenName, chart string, deleteHooks bool) {
+			ctx := tstng.NewContext(GinkgoWriter)
+
+			cluster, err := tstng.NewFakeCluster(tstng.FakeClusterOptions{})
+			Expect(err).To(Succeed())
+
+			releaseStorage := rlsstor.NewMemoryReleaseStorage()
+			deployRelease(ctx, filepath.Join("testdata", "charts", chart), cluster, releaseStorage, nil)
+
+			plan, err := tstng.BuildUninstallPlan(ctx, cluster, releaseStorage, tstng.BuildUninstallPlanOptions{
+				DeleteHooks: deleteHooks,
+			})
+			Expect(err).To(Succeed())
+
+			expectGoldenPlan(goldenName, plan)
+		},
+		Entry("of release without hooks", "uninstall", "basic", false),
+		Entry("of release with hooks", "uninstall-hooks", "hooks", false),
+		Entry("of release with hooks, deleting hooks", "uninstall-delete-hooks", "hooks", true),
+	)
+})
+
+// Emulates successful deploy of the chart: resources of the release, except hooks deleted after
+// the deploy, are applied to the cluster and the release is saved as deployed.
+func deployRelease(ctx context.Context, chartDirPath string, cluster *tstng.FakeCluster, releaseStorage rlsstor.ReleaseStorager, valuesSets []string) {
+	GinkgoHelper()
+
+	deployPlan, err := tstng.BuildDeployPlan(ctx, chartDirPath, cluster, releaseStorage, tstng.BuildDeployPlanOptions{
+		ValuesSets: valuesSets,
+	})
+	Expect(err).To(Succeed())
+
+	for _, info := range deployPlan.HookResourcesInfos {
+		if info.ShouldCleanup("test-release", "test-namespace") {
+			continue
+		}
+
+		_, err := cluster.KubeClient().Apply(ctx, info.ResourceID, info.Resource().Unstructured(), kubeclnt.KubeClientApplyOptions{})
+		Expect(err).To(Succeed())
+	}
+
+	for _, info := range deployPlan.GeneralResourcesInfos {
+		_, err := cluster.KubeClient().Apply(ctx, info.ResourceID, info.Resource().Unstructured(), kubeclnt.KubeClientApplyOptions{})
+		Expect(err).To(Succeed())
+	}
+
+	deployPlan.Release.Pend(deployPlan.DeployType)
+	deployPlan.Release.Succeed()
+
+	legacyRel, err := rls.NewLegacyReleaseFromRelease(deployPlan.Release)
+	Expect(err).To(Succeed())
+	Expect(releaseStorage.Create(ctx, legacyRel)).To(Succeed())
+}
+
+func expectGoldenPlan(goldenName string, plan *pln.Plan) {
+	GinkgoHelper()
+
+	ops, err := tstng.PlanOperations(plan)
+	Expect(err).To(Succeed())
+	Expect(tstng.CompareGolden(filepath.Join("testdata", "golden", goldenName+".ops"), ops)).To(Succeed())
+
+	dot, err := tstng.PlanDOT(plan)
+	Expect(err).To(Succeed())
+	Expect(tstng.CompareGolden(filepath.Join("testdata", "golden", goldenName+".dot"), dot)).To(Succeed())
+}
+
+// Outdated ConfigMap. It's not owned by any release if there are no annotations.
+func configMap(name string, annotations map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": "test-namespace",
+	}
+	if annotations != nil {
+		metadata["annotations"] = annotations
+		metadata["labels"] = map[string]interface{}{
+			"app.kubernetes.io/managed-by": "Helm",
+		}
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   metadata,
+			"data": map[string]interface{}{
+				"greeting": "bye",
+			},
+		},
+	}
+}
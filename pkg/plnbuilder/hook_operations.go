@@ -0,0 +1,286 @@
+package plnbuilder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm-for-werf-helm/pkg/depnd"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+)
+
+// Sets up deploy, readiness tracking and cleanup operations of hook resources. Shared between
+// the deploy and uninstall plan builders.
+type hookOperationsBuilder struct {
+	plan                    *pln.Plan
+	taskStore               *statestore.TaskStore
+	logStore                *util.Concurrent[*logstore.LogStore]
+	releaseName             string
+	releaseNamespace        string
+	prePostHookResourcesIDs []*resrcid.ResourceID
+	prevReleaseFailed       bool
+	kubeClient              kubeclnt.KubeClienter
+	staticClient            kubernetes.Interface
+	dynamicClient           dynamic.Interface
+	discoveryClient         discovery.CachedDiscoveryInterface
+	mapper                  meta.ResettableRESTMapper
+	readinessTimeout        time.Duration
+	deletionTimeout         time.Duration
+	readinessRules          []*rdnsrule.ReadinessRule
+
+	// If set, external dependencies of hooks are tracked with operations returned by this
+	// function. Otherwise external dependencies are ignored.
+	newExternalDependencyOperation func(dep *depnd.ExternalDependency) opertn.Operation
+	// If set, operations of hooks with manual internal dependencies are placed between the end of
+	// the Init stage and the start of the Final stage instead of their own stage, and external
+	// dependencies and cleanups without deploy operations are started right after the Init stage.
+	outOfStageOps bool
+}
+
+func (b *hookOperationsBuilder) setup(infos []*resrcinfo.DeployableHookResourceInfo, stageStartOpID, stageEndOpID string, pre bool) error {
+	for _, info := range infos {
+		var extraPost bool
+		if !pre {
+			_, extraPost = lo.Find(b.prePostHookResourcesIDs, func(rid *resrcid.ResourceID) bool {
+				return rid.ID() == info.ResourceID.ID()
+			})
+		}
+
+		create := info.ShouldCreate()
+		recreate := info.ShouldRecreate()
+		update := info.ShouldUpdate()
+		apply := info.ShouldApply()
+		cleanup := info.ShouldCleanup(b.releaseName, b.releaseNamespace)
+		var trackReadiness bool
+		if track := info.ShouldTrackReadiness(b.prevReleaseFailed); track && !extraPost {
+			trackReadiness = true
+		}
+		var manIntDepsSet bool
+		if b.outOfStageOps {
+			_, manIntDepsSet = info.Resource().ManualInternalDependencies()
+		}
+		var externalDeps []*depnd.ExternalDependency
+		var extDepsSet bool
+		if !extraPost && b.newExternalDependencyOperation != nil {
+			var err error
+			externalDeps, extDepsSet, err = info.Resource().ExternalDependencies()
+			if err != nil {
+				return fmt.Errorf("error getting external dependencies: %w", err)
+			}
+		}
+		var forceReplicas *int
+		if r, set := info.Resource().DefaultReplicasOnCreation(); set {
+			forceReplicas = &r
+		}
+
+		var opDeploy opertn.Operation
+		if create {
+			opDeploy = opertn.NewCreateResourceOperation(
+				info.ResourceID,
+				info.Resource().Unstructured(),
+				b.kubeClient,
+				opertn.CreateResourceOperationOptions{
+					ManageableBy:  info.Resource().ManageableBy(),
+					ForceReplicas: forceReplicas,
+					ExtraPost:     extraPost,
+				},
+			)
+		} else if recreate {
+			absenceTaskState := util.NewConcurrent(
+				statestore.NewAbsenceTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.AbsenceTaskStateOptions{}),
+			)
+			b.taskStore.AddAbsenceTaskState(absenceTaskState)
+
+			opDeploy = opertn.NewRecreateResourceOperation(
+				info.ResourceID,
+				info.Resource().Unstructured(),
+				absenceTaskState,
+				b.kubeClient,
+				b.dynamicClient,
+				b.mapper,
+				opertn.RecreateResourceOperationOptions{
+					ManageableBy:         info.Resource().ManageableBy(),
+					ForceReplicas:        forceReplicas,
+					DeletionTrackTimeout: b.deletionTimeout,
+					ExtraPost:            extraPost,
+				},
+			)
+		} else if update {
+			var err error
+			opDeploy, err = opertn.NewUpdateResourceOperation(
+				info.ResourceID,
+				info.Resource().Unstructured(),
+				b.kubeClient,
+				opertn.UpdateResourceOperationOptions{
+					ManageableBy: info.Resource().ManageableBy(),
+					ExtraPost:    extraPost,
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("error creating update resource operation: %w", err)
+			}
+		} else if apply {
+			var err error
+			opDeploy, err = opertn.NewApplyResourceOperation(
+				info.ResourceID,
+				info.Resource().Unstructured(),
+				b.kubeClient,
+				opertn.ApplyResourceOperationOptions{
+					ManageableBy: info.Resource().ManageableBy(),
+					ExtraPost:    extraPost,
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("error creating apply resource operation: %w", err)
+			}
+		}
+
+		if opDeploy != nil {
+			b.addStagedOperation(opDeploy, stageStartOpID, stageEndOpID, manIntDepsSet)
+		}
+
+		if extDepsSet && opDeploy != nil {
+			for _, dep := range externalDeps {
+				opTrackDep := b.newExternalDependencyOperation(dep)
+
+				b.plan.AddInStagedOperation(
+					opTrackDep,
+					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
+				)
+
+				lo.Must0(b.plan.AddDependency(opTrackDep.ID(), opDeploy.ID()))
+			}
+		}
+
+		var opTrackReadiness *opertn.TrackResourceReadinessOperation
+		if trackReadiness {
+			logRegex, _ := info.Resource().LogRegex()
+			logRegexesFor, _ := info.Resource().LogRegexesForContainers()
+			skipLogsFor, _ := info.Resource().SkipLogsForContainers()
+			showLogsOnlyFor, _ := info.Resource().ShowLogsOnlyForContainers()
+			ignoreReadinessProbes, _ := info.Resource().IgnoreReadinessProbeFailsForContainers()
+			var noActivityTimeout time.Duration
+			if timeout, set := info.Resource().NoActivityTimeout(); set {
+				noActivityTimeout = *timeout
+			}
+			readinessRule, readinessRuleSet := info.Resource().ReadinessRule()
+			if !readinessRuleSet {
+				readinessRule, _ = rdnsrule.FindRule(b.readinessRules, info.GroupVersionKind())
+			}
+
+			taskState := util.NewConcurrent(
+				statestore.NewReadinessTaskState(info.Name(), info.Namespace(), info.GroupVersionKind(), statestore.ReadinessTaskStateOptions{
+					FailMode:                info.Resource().FailMode(),
+					TotalAllowFailuresCount: info.Resource().FailuresAllowed(),
+				}),
+			)
+			b.taskStore.AddReadinessTaskState(taskState)
+
+			opTrackReadiness = opertn.NewTrackResourceReadinessOperation(
+				info.ResourceID,
+				taskState,
+				b.logStore,
+				b.staticClient,
+				b.dynamicClient,
+				b.discoveryClient,
+				b.mapper,
+				opertn.TrackResourceReadinessOperationOptions{
+					Timeout:                                  b.readinessTimeout,
+					NoActivityTimeout:                        noActivityTimeout,
+					IgnoreReadinessProbeFailsByContainerName: ignoreReadinessProbes,
+					SaveLogsOnlyForContainers:                showLogsOnlyFor,
+					SaveLogsByRegex:                          logRegex,
+					SaveLogsByRegexForContainers:             logRegexesFor,
+					IgnoreLogs:                               info.Resource().SkipLogs(),
+					IgnoreLogsForContainers:                  skipLogsFor,
+					SaveEvents:                               info.Resource().ShowServiceMessages(),
+					ReadinessRule:                            readinessRule,
+				},
+			)
+			b.addStagedOperation(opTrackReadiness, stageStartOpID, stageEndOpID, manIntDepsSet)
+			if opDeploy != nil {
+				lo.Must0(b.plan.AddDependency(opDeploy.ID(), opTrackReadiness.ID()))
+			}
+		}
+
+		if cleanup {
+			cleanupOp := opertn.NewDeleteResourceOperation(
+				info.ResourceID,
+				b.kubeClient,
+				opertn.DeleteResourceOperationOptions{
+					ExtraPost: extraPost,
+				},
+			)
+
+			if trackReadiness {
+				b.plan.AddOperation(cleanupOp)
+				lo.Must0(b.plan.AddDependency(opTrackReadiness.ID(), cleanupOp.ID()))
+			} else if opDeploy != nil {
+				b.plan.AddOperation(cleanupOp)
+				lo.Must0(b.plan.AddDependency(opDeploy.ID(), cleanupOp.ID()))
+			} else if b.outOfStageOps {
+				b.plan.AddInStagedOperation(
+					cleanupOp,
+					StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
+				)
+			} else {
+				b.plan.AddStagedOperation(cleanupOp, stageStartOpID, stageEndOpID)
+			}
+
+			taskState := util.NewConcurrent(
+				statestore.NewAbsenceTaskState(
+					info.Name(),
+					info.Namespace(),
+					info.GroupVersionKind(),
+					statestore.AbsenceTaskStateOptions{},
+				),
+			)
+			b.taskStore.AddAbsenceTaskState(taskState)
+
+			opTrackDeletion := opertn.NewTrackResourceAbsenceOperation(
+				info.ResourceID,
+				taskState,
+				b.dynamicClient,
+				b.mapper,
+				opertn.TrackResourceAbsenceOperationOptions{
+					Timeout: b.deletionTimeout,
+				},
+			)
+			b.plan.AddOperation(opTrackDeletion)
+			if err := b.plan.AddDependency(cleanupOp.ID(), opTrackDeletion.ID()); err != nil {
+				return fmt.Errorf("error adding dependency: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *hookOperationsBuilder) addStagedOperation(op opertn.Operation, stageStartOpID, stageEndOpID string, outOfStage bool) {
+	if outOfStage {
+		b.plan.AddStagedOperation(
+			op,
+			StageOpNamePrefixInit+"/"+StageOpNameSuffixEnd,
+			StageOpNamePrefixFinal+"/"+StageOpNameSuffixStart,
+		)
+	} else {
+		b.plan.AddStagedOperation(
+			op,
+			stageStartOpID,
+			stageEndOpID,
+		)
+	}
+}
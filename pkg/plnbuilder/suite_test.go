@@ -0,0 +1,13 @@
+package plnbuilder_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPlanBuilder(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "plan builder suite")
+}
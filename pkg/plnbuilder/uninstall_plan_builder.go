@@ -0,0 +1,405 @@
+package plnbuilder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+)
+
+const StageOpNamePrefixHooksDeletion = opertn.TypeStageOperation + "/hooks-deletion"
+
+func NewUninstallPlanBuilder(
+	releaseName string,
+	releaseNamespace string,
+	taskStore *statestore.TaskStore,
+	logStore *util.Concurrent[*logstore.LogStore],
+	hookResourcesInfos []*resrcinfo.DeployableHookResourceInfo,
+	prevReleaseGeneralResourceInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo,
+	release *rls.Release,
+	history rlshistor.Historier,
+	kubeClient kubeclnt.KubeClienter,
+	staticClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
+	discoveryClient discovery.CachedDiscoveryInterface,
+	mapper meta.ResettableRESTMapper,
+	opts UninstallPlanBuilderOptions,
+) *UninstallPlanBuilder {
+	preHookResourcesInfos := lo.Filter(hookResourcesInfos, func(info *resrcinfo.DeployableHookResourceInfo, _ int) bool {
+		return info.Resource().OnPreDelete()
+	})
+
+	postHookResourcesInfos := lo.Filter(hookResourcesInfos, func(info *resrcinfo.DeployableHookResourceInfo, _ int) bool {
+		return info.Resource().OnPostDelete()
+	})
+
+	prePostHookResourcesIDs := lo.FilterMap(hookResourcesInfos, func(info *resrcinfo.DeployableHookResourceInfo, _ int) (*resrcid.ResourceID, bool) {
+		return info.ResourceID, info.Resource().OnPreDelete() && info.Resource().OnPostDelete()
+	})
+
+	var deletableHookResourcesInfos []*resrcinfo.DeployableHookResourceInfo
+	if opts.DeleteHooks {
+		deletableHookResourcesInfos = lo.Filter(hookResourcesInfos, func(info *resrcinfo.DeployableHookResourceInfo, _ int) bool {
+			if info.ShouldKeepOnDelete(releaseName, releaseNamespace) {
+				return false
+			}
+
+			if info.Resource().OnPreDelete() || info.Resource().OnPostDelete() {
+				return !info.ShouldCleanup(releaseName, releaseNamespace)
+			}
+
+			return info.LiveResource() != nil
+		})
+	}
+
+	return &UninstallPlanBuilder{
+		taskStore:                       taskStore,
+		logStore:                        logStore,
+		releaseName:                     releaseName,
+		releaseNamespace:                releaseNamespace,
+		preHookResourcesInfos:           preHookResourcesInfos,
+		postHookResourcesInfos:          postHookResourcesInfos,
+		prePostHookResourcesIDs:         prePostHookResourcesIDs,
+		deletableHookResourcesInfos:     deletableHookResourcesInfos,
+		prevReleaseGeneralResourceInfos: prevReleaseGeneralResourceInfos,
+		release:                         release,
+		history:                         history,
+		kubeClient:                      kubeClient,
+		staticClient:                    staticClient,
+		dynamicClient:                   dynamicClient,
+		discoveryClient:                 discoveryClient,
+		mapper:                          mapper,
+		readinessTimeout:                opts.ReadinessTimeout,
+		deletionTimeout:                 opts.DeletionTimeout,
+		readinessRules:                  opts.ReadinessRules,
+		plan:                            pln.NewPlan(),
+	}
+}
+
+type UninstallPlanBuilderOptions struct {
+	DeleteHooks      bool
+	ReadinessTimeout time.Duration
+	DeletionTimeout  time.Duration
+	// Readiness rules for hooks without readiness annotations.
+	ReadinessRules []*rdnsrule.ReadinessRule
+}
+
+type UninstallPlanBuilder struct {
+	taskStore                       *statestore.TaskStore
+	logStore                        *util.Concurrent[*logstore.LogStore]
+	releaseName                     string
+	releaseNamespace                string
+	preHookResourcesInfos           []*resrcinfo.DeployableHookResourceInfo
+	postHookResourcesInfos          []*resrcinfo.DeployableHookResourceInfo
+	prePostHookResourcesIDs         []*resrcid.ResourceID
+	deletableHookResourcesInfos     []*resrcinfo.DeployableHookResourceInfo
+	prevReleaseGeneralResourceInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo
+	release                         *rls.Release
+	history                         rlshistor.Historier
+	kubeClient                      kubeclnt.KubeClienter
+	staticClient                    kubernetes.Interface
+	dynamicClient                   dynamic.Interface
+	discoveryClient                 discovery.CachedDiscoveryInterface
+	mapper                          meta.ResettableRESTMapper
+	readinessTimeout                time.Duration
+	deletionTimeout                 time.Duration
+	readinessRules                  []*rdnsrule.ReadinessRule
+
+	plan *pln.Plan
+	// Start and end operations IDs of all stages in the order of execution.
+	stagesOpIDs []string
+}
+
+func (b *UninstallPlanBuilder) Build(ctx context.Context) (*pln.Plan, error) {
+	log.Default.Debug(ctx, "Setting up init operations")
+	if err := b.setupInitOperations(); err != nil {
+		return b.plan, fmt.Errorf("error setting up init operations: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Setting up pre delete hook resources operations")
+	if err := b.setupHookResourcesOperations(b.preHookResourcesInfos, StageOpNamePrefixHookCRDs, StageOpNamePrefixHookResources, true); err != nil {
+		return b.plan, fmt.Errorf("error setting up pre delete hooks operations: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Setting up general resources deletion operations")
+	if err := b.setupGeneralResourcesDeletionOperations(); err != nil {
+		return b.plan, fmt.Errorf("error setting up general resources deletion operations: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Setting up post delete hook resources operations")
+	if err := b.setupHookResourcesOperations(b.postHookResourcesInfos, StageOpNamePrefixPostHookCRDs, StageOpNamePrefixPostHookResources, false); err != nil {
+		return b.plan, fmt.Errorf("error setting up post delete hooks operations: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Setting up hook resources deletion operations")
+	if err := b.setupHookResourcesDeletionOperations(); err != nil {
+		return b.plan, fmt.Errorf("error setting up hook resources deletion operations: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Setting up finalization operations")
+	if err := b.setupFinalizationOperations(); err != nil {
+		return b.plan, fmt.Errorf("error setting up finalization operations: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Connecting stages")
+	if err := b.connectStages(); err != nil {
+		return b.plan, fmt.Errorf("error connecting stages: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Connecting internal dependencies")
+	if err := b.connectInternalDependencies(); err != nil {
+		return b.plan, fmt.Errorf("error connecting internal dependencies: %w", err)
+	}
+
+	log.Default.Debug(ctx, "Optimizing plan")
+	if err := b.plan.Optimize(); err != nil {
+		return b.plan, fmt.Errorf("error optimizing plan: %w", err)
+	}
+
+	return b.plan, nil
+}
+
+func (b *UninstallPlanBuilder) setupInitOperations() error {
+	stageStartOpID, stageEndOpID := b.registerStage(StageOpNamePrefixInit)
+
+	opPendUninstallRel := opertn.NewPendUninstallReleaseOperation(b.release, b.history)
+	b.plan.AddStagedOperation(opPendUninstallRel, stageStartOpID, stageEndOpID)
+
+	return nil
+}
+
+func (b *UninstallPlanBuilder) setupHookResourcesOperations(infos []*resrcinfo.DeployableHookResourceInfo, crdsStageOpNamePrefix, resourcesStageOpNamePrefix string, pre bool) error {
+	weighedInfos := lo.GroupBy(infos, func(info *resrcinfo.DeployableHookResourceInfo) int {
+		return info.Resource().Weight()
+	})
+
+	weights := lo.Keys(weighedInfos)
+	sort.Ints(weights)
+
+	for _, weight := range weights {
+		crdInfos := lo.Filter(weighedInfos[weight], func(info *resrcinfo.DeployableHookResourceInfo, _ int) bool {
+			return resrc.IsCRDFromGK(info.GroupVersionKind().GroupKind())
+		})
+		crdsStageStartOpID, crdsStageEndOpID := b.registerStage(fmt.Sprintf("%s/weight:%d", crdsStageOpNamePrefix, weight))
+
+		if err := b.setupHookOperations(crdInfos, crdsStageStartOpID, crdsStageEndOpID, pre); err != nil {
+			return fmt.Errorf("error setting up hook crds operations: %w", err)
+		}
+
+		resourceInfos := lo.Filter(weighedInfos[weight], func(info *resrcinfo.DeployableHookResourceInfo, _ int) bool {
+			return !resrc.IsCRDFromGK(info.GroupVersionKind().GroupKind())
+		})
+		resourcesStageStartOpID, resourcesStageEndOpID := b.registerStage(fmt.Sprintf("%s/weight:%d", resourcesStageOpNamePrefix, weight))
+
+		if err := b.setupHookOperations(resourceInfos, resourcesStageStartOpID, resourcesStageEndOpID, pre); err != nil {
+			return fmt.Errorf("error setting up hook resources operations: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// General resources are deleted in the reverse order of their deployment: higher weights
+// first, and within the same weight custom resources before their CRDs.
+func (b *UninstallPlanBuilder) setupGeneralResourcesDeletionOperations() error {
+	infos := lo.Filter(b.prevReleaseGeneralResourceInfos, func(info *resrcinfo.DeployablePrevReleaseGeneralResourceInfo, _ int) bool {
+		return info.ShouldDelete(nil, b.releaseName, b.releaseNamespace)
+	})
+
+	weighedInfos := lo.GroupBy(infos, func(info *resrcinfo.DeployablePrevReleaseGeneralResourceInfo) int {
+		return info.Resource().Weight()
+	})
+
+	weights := lo.Keys(weighedInfos)
+	sort.Sort(sort.Reverse(sort.IntSlice(weights)))
+
+	for _, weight := range weights {
+		resourceInfos := lo.Filter(weighedInfos[weight], func(info *resrcinfo.DeployablePrevReleaseGeneralResourceInfo, _ int) bool {
+			return !resrc.IsCRDFromGK(info.GroupVersionKind().GroupKind())
+		})
+		resourcesStageStartOpID, resourcesStageEndOpID := b.registerStage(fmt.Sprintf("%s/weight:%d", StageOpNamePrefixGeneralResources, weight))
+
+		for _, info := range resourceInfos {
+			if err := b.setupDeletionOperations(info.ResourceID, resourcesStageStartOpID, resourcesStageEndOpID); err != nil {
+				return fmt.Errorf("error setting up general resource deletion operations: %w", err)
+			}
+		}
+
+		crdInfos := lo.Filter(weighedInfos[weight], func(info *resrcinfo.DeployablePrevReleaseGeneralResourceInfo, _ int) bool {
+			return resrc.IsCRDFromGK(info.GroupVersionKind().GroupKind())
+		})
+		crdsStageStartOpID, crdsStageEndOpID := b.registerStage(fmt.Sprintf("%s/weight:%d", StageOpNamePrefixGeneralCRDs, weight))
+
+		for _, info := range crdInfos {
+			if err := b.setupDeletionOperations(info.ResourceID, crdsStageStartOpID, crdsStageEndOpID); err != nil {
+				return fmt.Errorf("error setting up general crd deletion operations: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *UninstallPlanBuilder) setupHookResourcesDeletionOperations() error {
+	stageStartOpID, stageEndOpID := b.registerStage(StageOpNamePrefixHooksDeletion)
+
+	for _, info := range b.deletableHookResourcesInfos {
+		if err := b.setupDeletionOperations(info.ResourceID, stageStartOpID, stageEndOpID); err != nil {
+			return fmt.Errorf("error setting up hook resource deletion operations: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *UninstallPlanBuilder) setupFinalizationOperations() error {
+	stageStartOpID, stageEndOpID := b.registerStage(StageOpNamePrefixFinal)
+
+	releases, err := b.history.Releases()
+	if err != nil {
+		return fmt.Errorf("error getting releases: %w", err)
+	}
+
+	for _, rel := range releases {
+		opDeleteRel := opertn.NewDeleteReleaseOperation(rel, b.history)
+		b.plan.AddStagedOperation(opDeleteRel, stageStartOpID, stageEndOpID)
+	}
+
+	return nil
+}
+
+// If resource A depends on resource B, then B can only be deleted after A is gone.
+// Dependencies are reversed only between resources deleted in the same stage, since
+// resources from different stages are already ordered by the stages.
+func (b *UninstallPlanBuilder) connectInternalDependencies() error {
+	for _, info := range b.prevReleaseGeneralResourceInfos {
+		opTrackDeletion, found := b.plan.Operation(opertn.TypeTrackResourceAbsenceOperation + "/" + info.ID())
+		if !found {
+			continue
+		}
+
+		autoInternalDeps, _ := info.Resource().AutoInternalDependencies()
+		manualInternalDeps, _ := info.Resource().ManualInternalDependencies()
+
+		for _, dep := range lo.Union(autoInternalDeps, manualInternalDeps) {
+			for _, depInfo := range b.prevReleaseGeneralResourceInfos {
+				if depInfo.ID() == info.ID() ||
+					depInfo.Resource().Weight() != info.Resource().Weight() ||
+					resrc.IsCRDFromGK(depInfo.GroupVersionKind().GroupKind()) != resrc.IsCRDFromGK(info.GroupVersionKind().GroupKind()) ||
+					!dep.Match(depInfo.ResourceID) {
+					continue
+				}
+
+				opDeleteDep, found := b.plan.Operation(opertn.TypeDeleteResourceOperation + "/" + depInfo.ID())
+				if !found {
+					continue
+				}
+
+				if err := b.plan.AddDependency(opTrackDeletion.ID(), opDeleteDep.ID()); err != nil {
+					return fmt.Errorf("error adding dependency: %w", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *UninstallPlanBuilder) connectStages() error {
+	stagesOpIDs := lo.Filter(b.stagesOpIDs, func(opID string, _ int) bool {
+		_, found := b.plan.Operation(opID)
+		return found
+	})
+
+	for i := 1; i < len(stagesOpIDs); i++ {
+		if err := b.plan.AddDependency(stagesOpIDs[i-1], stagesOpIDs[i]); err != nil {
+			return fmt.Errorf("error adding dependency: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *UninstallPlanBuilder) registerStage(stageOpNamePrefix string) (stageStartOpID, stageEndOpID string) {
+	stageStartOpID = stageOpNamePrefix + "/" + StageOpNameSuffixStart
+	stageEndOpID = stageOpNamePrefix + "/" + StageOpNameSuffixEnd
+	b.stagesOpIDs = append(b.stagesOpIDs, stageStartOpID, stageEndOpID)
+
+	return stageStartOpID, stageEndOpID
+}
+
+func (b *UninstallPlanBuilder) setupDeletionOperations(resID *resrcid.ResourceID, stageStartOpID, stageEndOpID string) error {
+	opDelete := opertn.NewDeleteResourceOperation(
+		resID,
+		b.kubeClient,
+		opertn.DeleteResourceOperationOptions{},
+	)
+	b.plan.AddStagedOperation(opDelete, stageStartOpID, stageEndOpID)
+
+	taskState := util.NewConcurrent(
+		statestore.NewAbsenceTaskState(
+			resID.Name(),
+			resID.Namespace(),
+			resID.GroupVersionKind(),
+			statestore.AbsenceTaskStateOptions{},
+		),
+	)
+	b.taskStore.AddAbsenceTaskState(taskState)
+
+	opTrackDeletion := opertn.NewTrackResourceAbsenceOperation(
+		resID,
+		taskState,
+		b.dynamicClient,
+		b.mapper,
+		opertn.TrackResourceAbsenceOperationOptions{
+			Timeout: b.deletionTimeout,
+		},
+	)
+	b.plan.AddOutStagedOperation(opTrackDeletion, stageEndOpID)
+	if err := b.plan.AddDependency(opDelete.ID(), opTrackDeletion.ID()); err != nil {
+		return fmt.Errorf("error adding dependency: %w", err)
+	}
+
+	return nil
+}
+
+func (b *UninstallPlanBuilder) setupHookOperations(infos []*resrcinfo.DeployableHookResourceInfo, stageStartOpID, stageEndOpID string, pre bool) error {
+	hookOpsBuilder := &hookOperationsBuilder{
+		plan:                    b.plan,
+		taskStore:               b.taskStore,
+		logStore:                b.logStore,
+		releaseName:             b.releaseName,
+		releaseNamespace:        b.releaseNamespace,
+		prePostHookResourcesIDs: b.prePostHookResourcesIDs,
+		kubeClient:              b.kubeClient,
+		staticClient:            b.staticClient,
+		dynamicClient:           b.dynamicClient,
+		discoveryClient:         b.discoveryClient,
+		mapper:                  b.mapper,
+		readinessTimeout:        b.readinessTimeout,
+		deletionTimeout:         b.deletionTimeout,
+		readinessRules:          b.readinessRules,
+	}
+
+	return hookOpsBuilder.setup(infos, stageStartOpID, stageEndOpID, pre)
+}
@@ -1,9 +1,12 @@
 package plnbuilder
 
 import (
+	"github.com/samber/lo"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
 )
 
 func CurrentReleaseExistingResourcesUIDs(
@@ -31,3 +34,65 @@ func CurrentReleaseExistingResourcesUIDs(
 
 	return existingUIDs, len(existingUIDs) > 0
 }
+
+// Filters resources infos with the selector. Skipped resources are the unselected ones that
+// would otherwise be deployed or deleted, each reported once.
+func SelectResourcesInfos(
+	selector *resrcmatcher.ResourceSelector,
+	releaseName string,
+	releaseNamespace string,
+	standaloneCRDsInfos []*resrcinfo.DeployableStandaloneCRDInfo,
+	hookResourcesInfos []*resrcinfo.DeployableHookResourceInfo,
+	generalResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo,
+	prevReleaseGeneralResourceInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo,
+) (
+	selectedStandaloneCRDsInfos []*resrcinfo.DeployableStandaloneCRDInfo,
+	selectedHookResourcesInfos []*resrcinfo.DeployableHookResourceInfo,
+	selectedGeneralResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo,
+	selectedPrevReleaseGeneralResourceInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo,
+	skippedResourcesIDs []*resrcid.ResourceID,
+) {
+	if selector == nil || selector.Empty() {
+		return standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos, prevReleaseGeneralResourceInfos, nil
+	}
+
+	for _, info := range standaloneCRDsInfos {
+		if selector.Select(info.ResourceID, info.Resource().Unstructured().GetLabels()) {
+			selectedStandaloneCRDsInfos = append(selectedStandaloneCRDsInfos, info)
+		} else {
+			skippedResourcesIDs = append(skippedResourcesIDs, info.ResourceID)
+		}
+	}
+
+	for _, info := range hookResourcesInfos {
+		if selector.Select(info.ResourceID, info.Resource().Unstructured().GetLabels()) {
+			selectedHookResourcesInfos = append(selectedHookResourcesInfos, info)
+		} else {
+			skippedResourcesIDs = append(skippedResourcesIDs, info.ResourceID)
+		}
+	}
+
+	for _, info := range generalResourcesInfos {
+		if selector.Select(info.ResourceID, info.Resource().Unstructured().GetLabels()) {
+			selectedGeneralResourcesInfos = append(selectedGeneralResourcesInfos, info)
+		} else {
+			skippedResourcesIDs = append(skippedResourcesIDs, info.ResourceID)
+		}
+	}
+
+	curReleaseExistingResourcesUIDs, _ := CurrentReleaseExistingResourcesUIDs(standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos)
+
+	for _, info := range prevReleaseGeneralResourceInfos {
+		if selector.Select(info.ResourceID, info.Resource().Unstructured().GetLabels()) {
+			selectedPrevReleaseGeneralResourceInfos = append(selectedPrevReleaseGeneralResourceInfos, info)
+		} else if info.ShouldDelete(curReleaseExistingResourcesUIDs, releaseName, releaseNamespace) {
+			skippedResourcesIDs = append(skippedResourcesIDs, info.ResourceID)
+		}
+	}
+
+	skippedResourcesIDs = lo.UniqBy(skippedResourcesIDs, func(id *resrcid.ResourceID) string {
+		return id.ID()
+	})
+
+	return selectedStandaloneCRDsInfos, selectedHookResourcesInfos, selectedGeneralResourcesInfos, selectedPrevReleaseGeneralResourceInfos, skippedResourcesIDs
+}
@@ -5,88 +5,144 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/dominikbraun/graph"
 	"github.com/samber/lo"
-	"github.com/sourcegraph/conc/pool"
 
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
 	"github.com/werf/nelm-for-werf-helm/pkg/log"
 	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
 	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
 	"github.com/werf/nelm-for-werf-helm/pkg/utls"
 )
 
 func NewPlanExecutor(plan *pln.Plan, opts PlanExecutorOptions) *PlanExecutor {
+	networkParallelism := lo.Max([]int{opts.NetworkParallelism, 1})
+
+	trackParallelism := opts.TrackParallelism
+	if trackParallelism <= 0 {
+		trackParallelism = networkParallelism
+	}
+
 	return &PlanExecutor{
 		plan:               plan,
-		networkParallelism: lo.Max([]int{opts.NetworkParallelism, 1}),
+		networkParallelism: networkParallelism,
+		trackParallelism:   trackParallelism,
+		retryPolicy:        opts.RetryPolicy.withDefaults(),
+		textRedactor:       opts.TextRedactor,
 	}
 }
 
 type PlanExecutorOptions struct {
 	NetworkParallelism int
+	TrackParallelism   int
+	RetryPolicy        RetryPolicy
+	// Masks sensitive values in the logged errors.
+	TextRedactor *redactr.TextRedactor
 }
 
 type PlanExecutor struct {
 	plan               *pln.Plan
 	networkParallelism int
+	trackParallelism   int
+	retryPolicy        RetryPolicy
+	textRedactor       *redactr.TextRedactor
+}
+
+type operationResult struct {
+	opID     string
+	err      error
+	executed bool
 }
 
 func (e *PlanExecutor) Execute(parentCtx context.Context) error {
 	ctx, ctxCancelFn := context.WithCancel(parentCtx)
+	defer ctxCancelFn()
 
-	opsMap, err := e.plan.PredecessorMap()
+	predecessorMap, err := e.plan.PredecessorMap()
 	if err != nil {
 		return fmt.Errorf("error getting plan predecessor map: %w", err)
 	}
 
-	workerPool := pool.New().WithContext(ctx).WithMaxGoroutines(e.networkParallelism).WithCancelOnError().WithFirstError()
-	completedOpsIDsCh := make(chan string, 100000)
+	pendingDepsCount := make(map[string]int, len(predecessorMap))
+	successors := make(map[string][]string, len(predecessorMap))
+	var readyOpsIDs []string
+	for opID, predecessors := range predecessorMap {
+		pendingDepsCount[opID] = len(predecessors)
+		for predecessorID := range predecessors {
+			successors[predecessorID] = append(successors[predecessorID], opID)
+		}
 
-	for i := 0; len(opsMap) > 0; i++ {
-		if i > 0 {
-			if ctx.Err() != nil {
-				break
-			}
+		if len(predecessors) == 0 {
+			readyOpsIDs = append(readyOpsIDs, opID)
+		}
+	}
 
-			var gotCompletedOpID bool
-			for len(completedOpsIDsCh) > 0 {
-				completedOpID := <-completedOpsIDsCh
-				gotCompletedOpID = true
-				for _, edgeMap := range opsMap {
-					delete(edgeMap, completedOpID)
-				}
+	networkSemaphore := make(chan struct{}, e.networkParallelism)
+	trackSemaphore := make(chan struct{}, e.trackParallelism)
+	// Buffered for all operations, so that workers never block on reporting results.
+	resultsCh := make(chan operationResult, len(predecessorMap))
+
+	var runningOpsCount int
+	var firstErr error
+	for {
+		if ctx.Err() == nil {
+			for _, opID := range readyOpsIDs {
+				op := lo.Must(e.plan.Operation(opID))
+				e.execOperation(ctx, op, e.semaphoreFor(op, networkSemaphore, trackSemaphore), resultsCh)
+				runningOpsCount++
 			}
-			if !gotCompletedOpID {
-				time.Sleep(100 * time.Millisecond)
-				continue
+		}
+		readyOpsIDs = nil
+
+		if runningOpsCount == 0 {
+			break
+		}
+
+		result := <-resultsCh
+		runningOpsCount--
+
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
 			}
+
+			ctxCancelFn()
+			continue
+		} else if !result.executed {
+			continue
 		}
 
-		executableOpsIDs := e.findExecutableOpsIDs(opsMap)
-		for _, opID := range executableOpsIDs {
-			opID := opID
-			delete(opsMap, opID)
-			e.execOperation(opID, completedOpsIDsCh, workerPool, ctxCancelFn)
+		for _, successorID := range successors[result.opID] {
+			pendingDepsCount[successorID]--
+			if pendingDepsCount[successorID] == 0 {
+				readyOpsIDs = append(readyOpsIDs, successorID)
+			}
 		}
 	}
 
-	if err := workerPool.Wait(); err != nil {
-		return fmt.Errorf("error waiting for operations completion: %w", err)
+	if parentCtx.Err() != nil {
+		return fmt.Errorf("plan execution interrupted: %w", context.Cause(parentCtx))
 	}
 
-	return nil
+	return firstErr
 }
 
-func (e *PlanExecutor) execOperation(opID string, completedOpsIDsCh chan string, workerPool *pool.ContextPool, ctxCancelFn context.CancelFunc) {
-	workerPool.Go(func(ctx context.Context) error {
-		failed := true
-		defer func() {
-			if failed {
-				ctxCancelFn()
+func (e *PlanExecutor) execOperation(ctx context.Context, op opertn.Operation, semaphore chan struct{}, resultsCh chan<- operationResult) {
+	go func() {
+		if semaphore != nil {
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				resultsCh <- operationResult{opID: op.ID()}
+				return
 			}
-		}()
+		}
 
-		op := lo.Must(e.plan.Operation(opID))
+		if ctx.Err() != nil {
+			resultsCh <- operationResult{opID: op.ID()}
+			return
+		}
 
 		switch op.Type() {
 		case opertn.TypeCreateResourceOperation,
@@ -102,24 +158,51 @@ func (e *PlanExecutor) execOperation(opID string, completedOpsIDsCh chan string,
 			log.Default.Debug(ctx, utls.Capitalize(op.HumanID()))
 		}
 
-		if err := op.Execute(ctx); err != nil {
-			return fmt.Errorf("error executing operation: %w", err)
+		result := operationResult{
+			opID:     op.ID(),
+			executed: true,
 		}
 
-		completedOpsIDsCh <- opID
+		if err := e.executeWithRetries(ctx, op); err != nil {
+			op.SetErr(err)
+			result.err = fmt.Errorf("error executing operation %q: %w", op.HumanID(), err)
+		}
 
-		failed = false
-		return nil
-	})
+		resultsCh <- result
+	}()
 }
 
-func (e *PlanExecutor) findExecutableOpsIDs(opsMap map[string]map[string]graph.Edge[string]) []string {
-	var executableOpsIDs []string
-	for opID, edgeMap := range opsMap {
-		if len(edgeMap) == 0 {
-			executableOpsIDs = append(executableOpsIDs, opID)
+func (e *PlanExecutor) executeWithRetries(ctx context.Context, op opertn.Operation) error {
+	for attempt := 1; ; attempt++ {
+		err := op.Execute(ctx)
+		if err == nil ||
+			attempt >= e.retryPolicy.Attempts ||
+			!retriableOperation(op) ||
+			!kubeclnt.IsRetriableError(err) ||
+			ctx.Err() != nil {
+			return err
+		}
+
+		backoff := e.retryPolicy.backoff(attempt)
+		log.Default.Warn(ctx, "Retrying %s in %s (attempt %d/%d), got transient error: %s", op.HumanID(), backoff.Round(time.Millisecond), attempt+1, e.retryPolicy.Attempts, e.textRedactor.Redact(err.Error()))
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
 		}
 	}
+}
 
-	return executableOpsIDs
+func (e *PlanExecutor) semaphoreFor(op opertn.Operation, networkSemaphore, trackSemaphore chan struct{}) chan struct{} {
+	switch op.Type() {
+	case opertn.TypeStageOperation:
+		return nil
+	case opertn.TypeTrackResourceReadinessOperation,
+		opertn.TypeTrackResourcePresenceOperation,
+		opertn.TypeTrackResourceAbsenceOperation:
+		return trackSemaphore
+	default:
+		return networkSemaphore
+	}
 }
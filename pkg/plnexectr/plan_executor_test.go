@@ -0,0 +1,324 @@
+package plnexectr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+var _ = Describe("PlanExecutor", func() {
+	var (
+		ctx      context.Context
+		mu       sync.Mutex
+		executed []string
+	)
+
+	BeforeEach(func() {
+		ctx = tstng.NewContext(GinkgoWriter)
+		executed = nil
+	})
+
+	record := func(ctx context.Context, op *fakeOperation) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		executed = append(executed, op.id)
+
+		return nil
+	}
+
+	It("should execute operations after their dependencies", func() {
+		plan := newPlan(
+			[]*fakeOperation{
+				newFakeOperation("a", opertn.TypeCreateResourceOperation, record),
+				newFakeOperation("b", opertn.TypeCreateResourceOperation, record),
+				newFakeOperation("c", opertn.TypeTrackResourceReadinessOperation, record),
+				newFakeOperation("d", opertn.TypeDeleteResourceOperation, record),
+				newFakeOperation("e", opertn.TypeCreateResourceOperation, record),
+			},
+			[2]string{"a", "b"},
+			[2]string{"a", "c"},
+			[2]string{"b", "d"},
+			[2]string{"c", "d"},
+		)
+
+		Expect(NewPlanExecutor(plan, PlanExecutorOptions{NetworkParallelism: 3}).Execute(ctx)).To(Succeed())
+
+		Expect(executed).To(ConsistOf("a", "b", "c", "d", "e"))
+		Expect(lo.IndexOf(executed, "a")).To(BeNumerically("<", lo.IndexOf(executed, "b")))
+		Expect(lo.IndexOf(executed, "a")).To(BeNumerically("<", lo.IndexOf(executed, "c")))
+		Expect(lo.IndexOf(executed, "b")).To(BeNumerically("<", lo.IndexOf(executed, "d")))
+		Expect(lo.IndexOf(executed, "c")).To(BeNumerically("<", lo.IndexOf(executed, "d")))
+		expectStatuses(plan, opertn.StatusCompleted, "a", "b", "c", "d", "e")
+	})
+
+	It("should run no more network operations at a time than the network parallelism", func() {
+		var running, maxRunning int
+		slow := func(ctx context.Context, op *fakeOperation) error {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			return nil
+		}
+
+		plan := newPlan([]*fakeOperation{
+			newFakeOperation("a", opertn.TypeCreateResourceOperation, slow),
+			newFakeOperation("b", opertn.TypeCreateResourceOperation, slow),
+			newFakeOperation("c", opertn.TypeCreateResourceOperation, slow),
+			newFakeOperation("d", opertn.TypeCreateResourceOperation, slow),
+		})
+
+		Expect(NewPlanExecutor(plan, PlanExecutorOptions{NetworkParallelism: 2}).Execute(ctx)).To(Succeed())
+		Expect(maxRunning).To(Equal(2))
+	})
+
+	It("should cancel remaining operations after a failure", func() {
+		failure := errors.New("boom")
+		slowStarted := make(chan struct{})
+
+		var slowCanceled bool
+		plan := newPlan(
+			[]*fakeOperation{
+				newFakeOperation("failing", opertn.TypeStageOperation, func(ctx context.Context, op *fakeOperation) error {
+					<-slowStarted
+					return failure
+				}),
+				newFakeOperation("after-failing", opertn.TypeCreateResourceOperation, record),
+				newFakeOperation("slow", opertn.TypeTrackResourceReadinessOperation, func(ctx context.Context, op *fakeOperation) error {
+					close(slowStarted)
+
+					select {
+					case <-ctx.Done():
+						slowCanceled = true
+						return nil
+					case <-time.After(10 * time.Second):
+						return nil
+					}
+				}),
+				newFakeOperation("after-slow", opertn.TypeCreateResourceOperation, record),
+			},
+			[2]string{"failing", "after-failing"},
+			[2]string{"slow", "after-slow"},
+		)
+
+		err := NewPlanExecutor(plan, PlanExecutorOptions{}).Execute(ctx)
+		Expect(err).To(MatchError(failure))
+		Expect(err).To(MatchError(ContainSubstring(`error executing operation "failing"`)))
+
+		Expect(slowCanceled).To(BeTrue())
+		Expect(executed).To(BeEmpty())
+		expectStatuses(plan, opertn.StatusFailed, "failing")
+		expectStatuses(plan, opertn.StatusUnknown, "after-failing", "after-slow")
+
+		failingOp := lo.Must(plan.Operation("failing"))
+		Expect(failingOp.Err()).To(MatchError(failure))
+	})
+
+	It("should not start operations waiting for parallelism slots after a failure", func() {
+		failure := errors.New("boom")
+		holdingStarted := make(chan struct{})
+
+		var holding int
+		holdSlot := func(ctx context.Context, op *fakeOperation) error {
+			mu.Lock()
+			holding++
+			mu.Unlock()
+
+			close(holdingStarted)
+			<-ctx.Done()
+
+			return nil
+		}
+
+		plan := newPlan([]*fakeOperation{
+			newFakeOperation("failing", opertn.TypeStageOperation, func(ctx context.Context, op *fakeOperation) error {
+				<-holdingStarted
+				return failure
+			}),
+			newFakeOperation("holding-1", opertn.TypeCreateResourceOperation, holdSlot),
+			newFakeOperation("holding-2", opertn.TypeCreateResourceOperation, holdSlot),
+		})
+
+		// Which of the network operations gets the only slot isn't known, but the other one must
+		// never start.
+		err := NewPlanExecutor(plan, PlanExecutorOptions{NetworkParallelism: 1}).Execute(ctx)
+		Expect(err).To(MatchError(failure))
+		Expect(holding).To(Equal(1))
+	})
+
+	It("should stop once interrupted", func() {
+		interruptCtx, interrupt := context.WithCancelCause(ctx)
+		defer interrupt(nil)
+
+		plan := newPlan(
+			[]*fakeOperation{
+				newFakeOperation("a", opertn.TypeCreateResourceOperation, func(ctx context.Context, op *fakeOperation) error {
+					interrupt(errors.New("got signal"))
+					<-ctx.Done()
+					return nil
+				}),
+				newFakeOperation("b", opertn.TypeCreateResourceOperation, record),
+			},
+			[2]string{"a", "b"},
+		)
+
+		err := NewPlanExecutor(plan, PlanExecutorOptions{}).Execute(interruptCtx)
+		Expect(err).To(MatchError(ContainSubstring("plan execution interrupted: got signal")))
+		Expect(executed).To(BeEmpty())
+		expectStatuses(plan, opertn.StatusUnknown, "b")
+	})
+
+	It("should not start anything if interrupted before execution", func() {
+		interruptCtx, interrupt := context.WithCancel(ctx)
+		interrupt()
+
+		plan := newPlan([]*fakeOperation{
+			newFakeOperation("a", opertn.TypeCreateResourceOperation, record),
+			newFakeOperation("b", opertn.TypeStageOperation, record),
+		})
+
+		Expect(NewPlanExecutor(plan, PlanExecutorOptions{}).Execute(interruptCtx)).To(MatchError(ContainSubstring("plan execution interrupted")))
+		Expect(executed).To(BeEmpty())
+	})
+})
+
+func newPlan(ops []*fakeOperation, deps ...[2]string) *pln.Plan {
+	GinkgoHelper()
+
+	plan := pln.NewPlan()
+	for _, op := range ops {
+		plan.AddOperation(op)
+	}
+
+	for _, dep := range deps {
+		Expect(plan.AddDependency(dep[0], dep[1])).To(Succeed())
+	}
+
+	return plan
+}
+
+func expectStatuses(plan *pln.Plan, status opertn.Status, opIDs ...string) {
+	GinkgoHelper()
+
+	for _, opID := range opIDs {
+		op, found := plan.Operation(opID)
+		Expect(found).To(BeTrue())
+		Expect(op.Status()).To(Equal(status), "status of operation %q", opID)
+	}
+}
+
+var _ opertn.Operation = (*fakeOperation)(nil)
+
+func newFakeOperation(id string, opType opertn.Type, execute func(ctx context.Context, op *fakeOperation) error) *fakeOperation {
+	return &fakeOperation{
+		id:      id,
+		opType:  opType,
+		execute: execute,
+	}
+}
+
+// Runs the given function instead of doing anything with the cluster.
+type fakeOperation struct {
+	id      string
+	opType  opertn.Type
+	execute func(ctx context.Context, op *fakeOperation) error
+
+	mu        sync.Mutex
+	status    opertn.Status
+	startedAt time.Time
+	endedAt   time.Time
+	attempts  int
+	err       error
+}
+
+func (o *fakeOperation) Execute(ctx context.Context) error {
+	o.mu.Lock()
+	if o.startedAt.IsZero() {
+		o.startedAt = time.Now()
+	}
+	o.attempts++
+	o.mu.Unlock()
+
+	err := o.execute(ctx, o)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.endedAt = time.Now()
+	if err != nil {
+		o.status = opertn.StatusFailed
+	} else {
+		o.status = opertn.StatusCompleted
+	}
+
+	return err
+}
+
+func (o *fakeOperation) ID() string {
+	return o.id
+}
+
+func (o *fakeOperation) HumanID() string {
+	return o.id
+}
+
+func (o *fakeOperation) Status() opertn.Status {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.status
+}
+
+func (o *fakeOperation) Type() opertn.Type {
+	return o.opType
+}
+
+func (o *fakeOperation) Empty() bool {
+	return false
+}
+
+func (o *fakeOperation) StartedAt() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.startedAt
+}
+
+func (o *fakeOperation) EndedAt() time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.endedAt
+}
+
+func (o *fakeOperation) Attempts() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.attempts
+}
+
+func (o *fakeOperation) Err() error {
+	return o.err
+}
+
+func (o *fakeOperation) SetErr(err error) {
+	o.err = err
+}
@@ -0,0 +1,69 @@
+package plnexectr
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+)
+
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:       5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+// Applied to resource operations failed with transient Kubernetes API errors.
+type RetryPolicy struct {
+	// Total number of attempts, including the first one. 1 disables retries.
+	Attempts       int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = DefaultRetryPolicy.Attempts
+	}
+
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+
+	return p
+}
+
+// Exponential backoff with up to 20% of jitter, so that parallel retries don't hit the
+// API server at the same time.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	return backoff + time.Duration(rand.Int63n(int64(backoff)/5+1))
+}
+
+func retriableOperation(op opertn.Operation) bool {
+	switch op.Type() {
+	case opertn.TypeCreateResourceOperation,
+		opertn.TypeUpdateResourceOperation,
+		opertn.TypeApplyResourceOperation,
+		opertn.TypeDeleteResourceOperation,
+		opertn.TypeExtraPostCreateResourceOperation,
+		opertn.TypeExtraPostApplyResourceOperation,
+		opertn.TypeExtraPostUpdateResourceOperation,
+		opertn.TypeExtraPostDeleteResourceOperation:
+		return true
+	default:
+		return false
+	}
+}
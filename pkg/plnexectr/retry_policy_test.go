@@ -0,0 +1,121 @@
+package plnexectr
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+var _ = Describe("RetryPolicy", func() {
+	DescribeTable("backoff should grow exponentially up to the max backoff, with jitter",
+		func(attempt int, expected time.Duration) {
+			policy := RetryPolicy{
+				Attempts:       10,
+				InitialBackoff: time.Second,
+				MaxBackoff:     10 * time.Second,
+			}
+
+			for i := 0; i < 20; i++ {
+				backoff := policy.backoff(attempt)
+				Expect(backoff).To(BeNumerically(">=", expected))
+				Expect(backoff).To(BeNumerically("<=", expected+expected/5))
+			}
+		},
+		Entry("first attempt", 1, time.Second),
+		Entry("second attempt", 2, 2*time.Second),
+		Entry("third attempt", 3, 4*time.Second),
+		Entry("fourth attempt", 4, 8*time.Second),
+		Entry("capped", 5, 10*time.Second),
+		Entry("capped long after", 50, 10*time.Second),
+	)
+
+	DescribeTable("withDefaults should replace only unset fields",
+		func(policy, expected RetryPolicy) {
+			Expect(policy.withDefaults()).To(Equal(expected))
+		},
+		Entry("empty", RetryPolicy{}, DefaultRetryPolicy),
+		Entry("negative", RetryPolicy{Attempts: -1, InitialBackoff: -time.Second, MaxBackoff: -time.Second}, DefaultRetryPolicy),
+		Entry("partially set",
+			RetryPolicy{Attempts: 1},
+			RetryPolicy{Attempts: 1, InitialBackoff: DefaultRetryPolicy.InitialBackoff, MaxBackoff: DefaultRetryPolicy.MaxBackoff},
+		),
+		Entry("fully set",
+			RetryPolicy{Attempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Minute},
+			RetryPolicy{Attempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Minute},
+		),
+	)
+
+	DescribeTable("retriableOperation should allow retries only of idempotent resource operations",
+		func(opType string, expected bool) {
+			Expect(retriableOperation(newFakeOperation("op", opertn.Type(opType), nil))).To(Equal(expected))
+		},
+		Entry(nil, opertn.TypeCreateResourceOperation, true),
+		Entry(nil, opertn.TypeUpdateResourceOperation, true),
+		Entry(nil, opertn.TypeApplyResourceOperation, true),
+		Entry(nil, opertn.TypeDeleteResourceOperation, true),
+		Entry(nil, opertn.TypeExtraPostCreateResourceOperation, true),
+		Entry(nil, opertn.TypeExtraPostApplyResourceOperation, true),
+		Entry(nil, opertn.TypeExtraPostUpdateResourceOperation, true),
+		Entry(nil, opertn.TypeExtraPostDeleteResourceOperation, true),
+		Entry(nil, opertn.TypeRecreateResourceOperation, false),
+		Entry(nil, opertn.TypeExtraPostRecreateResourceOperation, false),
+		Entry(nil, opertn.TypeTrackResourceReadinessOperation, false),
+		Entry(nil, opertn.TypeStageOperation, false),
+	)
+
+	DescribeTable("executor should retry operations according to the policy",
+		func(opType string, errs []error, expectedAttempts int, expectSuccess bool) {
+			ctx := tstng.NewContext(GinkgoWriter)
+
+			op := newFakeOperation("op", opertn.Type(opType), func(ctx context.Context, op *fakeOperation) error {
+				attempt := op.Attempts()
+				if attempt > len(errs) {
+					return nil
+				}
+
+				return errs[attempt-1]
+			})
+
+			err := NewPlanExecutor(newPlan([]*fakeOperation{op}), PlanExecutorOptions{
+				RetryPolicy: RetryPolicy{
+					Attempts:       3,
+					InitialBackoff: time.Millisecond,
+					MaxBackoff:     time.Millisecond,
+				},
+			}).Execute(ctx)
+
+			if expectSuccess {
+				Expect(err).To(Succeed())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+
+			Expect(op.Attempts()).To(Equal(expectedAttempts))
+		},
+		Entry("succeeded at once",
+			opertn.TypeCreateResourceOperation, nil, 1, true,
+		),
+		Entry("succeeded after retriable errors",
+			opertn.TypeCreateResourceOperation, []error{conflictErr, conflictErr}, 3, true,
+		),
+		Entry("retriable errors until attempts are exhausted",
+			opertn.TypeApplyResourceOperation, []error{conflictErr, conflictErr, conflictErr, conflictErr}, 3, false,
+		),
+		Entry("non-retriable error",
+			opertn.TypeCreateResourceOperation, []error{errors.New("invalid manifest"), conflictErr}, 1, false,
+		),
+		Entry("retriable error of non-retriable operation",
+			opertn.TypeRecreateResourceOperation, []error{conflictErr}, 1, false,
+		),
+	)
+})
+
+var conflictErr = apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "config", errors.New("object was modified"))
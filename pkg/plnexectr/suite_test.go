@@ -0,0 +1,13 @@
+package plnexectr
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPlanExecutor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "plan executor suite")
+}
@@ -0,0 +1,482 @@
+package plnfile
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+)
+
+const PlanFileAPIVersion = "v1"
+
+func NewPlanFile(
+	deployType common.DeployType,
+	newRelease *rls.Release,
+	plan *pln.Plan,
+	standaloneCRDsInfos []*resrcinfo.DeployableStandaloneCRDInfo,
+	hookResourcesInfos []*resrcinfo.DeployableHookResourceInfo,
+	generalResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo,
+	prevReleaseGeneralResourceInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo,
+	opts PlanFileOptions,
+) (*PlanFile, error) {
+	legacyRel, err := rls.NewLegacyReleaseFromRelease(newRelease)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing legacy release from release: %w", err)
+	}
+
+	var prevReleaseRevision int
+	if opts.PrevRelease != nil {
+		prevReleaseRevision = opts.PrevRelease.Revision()
+	}
+
+	planFile := &PlanFile{
+		APIVersion:          PlanFileAPIVersion,
+		ReleaseName:         newRelease.Name(),
+		ReleaseNamespace:    newRelease.Namespace(),
+		DeployType:          deployType,
+		PrevReleaseRevision: prevReleaseRevision,
+		Release:             legacyRel,
+		ReleaseLabels:       legacyRel.Labels,
+		IncludeResources:    opts.IncludeResources,
+		ExcludeResources:    opts.ExcludeResources,
+	}
+
+	for _, info := range standaloneCRDsInfos {
+		planFile.StandaloneCRDs = append(planFile.StandaloneCRDs, newPlanFileResource(info.Resource().Unstructured(), info.FilePath(), info.DryApplyResource()))
+		planFile.LiveResources = append(planFile.LiveResources, newPlanFileLiveResource(info.ResourceID, info.LiveResource()))
+	}
+
+	for _, info := range hookResourcesInfos {
+		planFile.HookResources = append(planFile.HookResources, newPlanFileResource(info.Resource().Unstructured(), info.FilePath(), info.DryApplyResource()))
+		planFile.LiveResources = append(planFile.LiveResources, newPlanFileLiveResource(info.ResourceID, info.LiveResource()))
+	}
+
+	for _, info := range generalResourcesInfos {
+		planFile.GeneralResources = append(planFile.GeneralResources, newPlanFileResource(info.Resource().Unstructured(), info.FilePath(), info.DryApplyResource()))
+		planFile.LiveResources = append(planFile.LiveResources, newPlanFileLiveResource(info.ResourceID, info.LiveResource()))
+	}
+
+	for _, info := range prevReleaseGeneralResourceInfos {
+		planFile.LiveResources = append(planFile.LiveResources, newPlanFileLiveResource(info.ResourceID, info.LiveResource()))
+	}
+
+	planFile.LiveResources = lo.UniqBy(planFile.LiveResources, func(r *PlanFileLiveResource) string {
+		return r.ID()
+	})
+
+	planFile.Operations, planFile.Dependencies, err = planOperationsAndDependencies(plan)
+	if err != nil {
+		return nil, fmt.Errorf("error getting plan operations and dependencies: %w", err)
+	}
+
+	return planFile, nil
+}
+
+type PlanFileOptions struct {
+	PrevRelease      *rls.Release
+	IncludeResources []string
+	ExcludeResources []string
+}
+
+func LoadPlanFile(path string) (*PlanFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening plan file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading compressed plan file %q: %w", path, err)
+	}
+	defer gzipReader.Close()
+
+	planFile := &PlanFile{}
+	if err := json.NewDecoder(gzipReader).Decode(planFile); err != nil {
+		return nil, fmt.Errorf("error decoding plan file %q: %w", path, err)
+	}
+
+	if planFile.APIVersion != PlanFileAPIVersion {
+		return nil, fmt.Errorf("unsupported plan file version %q, expected %q", planFile.APIVersion, PlanFileAPIVersion)
+	}
+
+	return planFile, nil
+}
+
+type PlanFile struct {
+	APIVersion          string                  `json:"apiVersion"`
+	ReleaseName         string                  `json:"releaseName"`
+	ReleaseNamespace    string                  `json:"releaseNamespace"`
+	DeployType          common.DeployType       `json:"deployType"`
+	PrevReleaseRevision int                     `json:"prevReleaseRevision,omitempty"`
+	Release             *legacyRelease.Release  `json:"release"`
+	ReleaseLabels       map[string]string       `json:"releaseLabels,omitempty"`
+	IncludeResources    []string                `json:"includeResources,omitempty"`
+	ExcludeResources    []string                `json:"excludeResources,omitempty"`
+	StandaloneCRDs      []*PlanFileResource     `json:"standaloneCRDs,omitempty"`
+	HookResources       []*PlanFileResource     `json:"hookResources,omitempty"`
+	GeneralResources    []*PlanFileResource     `json:"generalResources,omitempty"`
+	LiveResources       []*PlanFileLiveResource `json:"liveResources,omitempty"`
+	Operations          []string                `json:"operations"`
+	Dependencies        []*PlanFileDependency   `json:"dependencies,omitempty"`
+}
+
+type PlanFileResource struct {
+	FilePath string                 `json:"filePath,omitempty"`
+	Object   map[string]interface{} `json:"object"`
+	DryApply map[string]interface{} `json:"dryApply,omitempty"`
+}
+
+type PlanFileLiveResource struct {
+	APIVersion      string `json:"apiVersion"`
+	Kind            string `json:"kind"`
+	Namespace       string `json:"namespace,omitempty"`
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+func (r *PlanFileLiveResource) ID() string {
+	return fmt.Sprintf("%s:%s:%s:%s", r.Namespace, r.APIVersion, r.Kind, r.Name)
+}
+
+type PlanFileDependency struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// The plan file has rendered Secrets and release values in cleartext, so it's only readable by
+// the owner.
+func (f *PlanFile) Save(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening plan file %q for writing: %w", path, err)
+	}
+	defer file.Close()
+
+	// Permissions of an existing file are not changed by opening it.
+	if err := file.Chmod(0o600); err != nil {
+		return fmt.Errorf("error changing permissions of plan file %q: %w", path, err)
+	}
+
+	gzipWriter := gzip.NewWriter(file)
+
+	if err := json.NewEncoder(gzipWriter).Encode(f); err != nil {
+		return fmt.Errorf("error encoding plan file %q: %w", path, err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("error flushing plan file %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func (f *PlanFile) NewRelease(opts PlanFileReleaseOptions) (*rls.Release, error) {
+	legacyRel := *f.Release
+	legacyRel.Labels = f.ReleaseLabels
+
+	rel, err := rls.NewReleaseFromLegacyRelease(&legacyRel, rls.ReleaseFromLegacyReleaseOptions{
+		Mapper:          opts.Mapper,
+		DiscoveryClient: opts.DiscoveryClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error constructing release from plan file: %w", err)
+	}
+
+	return rel, nil
+}
+
+type PlanFileReleaseOptions struct {
+	Mapper          meta.ResettableRESTMapper
+	DiscoveryClient discovery.CachedDiscoveryInterface
+}
+
+func (f *PlanFile) DeployableStandaloneCRDs(opts PlanFileResourcesOptions) []*resrc.StandaloneCRD {
+	var crds []*resrc.StandaloneCRD
+	for _, res := range f.StandaloneCRDs {
+		crds = append(crds, resrc.NewStandaloneCRD(&unstructured.Unstructured{Object: res.Object}, resrc.StandaloneCRDOptions{
+			FilePath:         res.FilePath,
+			DefaultNamespace: f.ReleaseNamespace,
+			Mapper:           opts.Mapper,
+		}))
+	}
+
+	return crds
+}
+
+func (f *PlanFile) DeployableHookResources(opts PlanFileResourcesOptions) []*resrc.HookResource {
+	var hooks []*resrc.HookResource
+	for _, res := range f.HookResources {
+		hooks = append(hooks, resrc.NewHookResource(&unstructured.Unstructured{Object: res.Object}, resrc.HookResourceOptions{
+			FilePath:         res.FilePath,
+			DefaultNamespace: f.ReleaseNamespace,
+			Mapper:           opts.Mapper,
+			DiscoveryClient:  opts.DiscoveryClient,
+		}))
+	}
+
+	return hooks
+}
+
+func (f *PlanFile) DeployableGeneralResources(opts PlanFileResourcesOptions) []*resrc.GeneralResource {
+	var generals []*resrc.GeneralResource
+	for _, res := range f.GeneralResources {
+		generals = append(generals, resrc.NewGeneralResource(&unstructured.Unstructured{Object: res.Object}, resrc.GeneralResourceOptions{
+			FilePath:         res.FilePath,
+			DefaultNamespace: f.ReleaseNamespace,
+			Mapper:           opts.Mapper,
+			DiscoveryClient:  opts.DiscoveryClient,
+		}))
+	}
+
+	return generals
+}
+
+type PlanFileResourcesOptions struct {
+	Mapper          meta.ResettableRESTMapper
+	DiscoveryClient discovery.CachedDiscoveryInterface
+}
+
+func (f *PlanFile) ValidateReleaseHistory(prevRelease *rls.Release) error {
+	var prevReleaseRevision int
+	if prevRelease != nil {
+		prevReleaseRevision = prevRelease.Revision()
+	}
+
+	if prevReleaseRevision != f.PrevReleaseRevision {
+		return fmt.Errorf("plan was made against release revision %d, but the last revision now is %d", f.PrevReleaseRevision, prevReleaseRevision)
+	}
+
+	return nil
+}
+
+func (f *PlanFile) ValidateLiveResources(ctx context.Context, kubeClient kubeclnt.KubeClienter, mapper meta.ResettableRESTMapper) error {
+	var drifted []string
+	for _, liveRes := range f.LiveResources {
+		gv, err := schema.ParseGroupVersion(liveRes.APIVersion)
+		if err != nil {
+			return fmt.Errorf("error parsing apiVersion %q: %w", liveRes.APIVersion, err)
+		}
+
+		resID := resrcid.NewResourceID(liveRes.Name, liveRes.Namespace, gv.WithKind(liveRes.Kind), resrcid.ResourceIDOptions{
+			DefaultNamespace: f.ReleaseNamespace,
+			Mapper:           mapper,
+		})
+
+		var resourceVersion string
+		if obj, err := kubeClient.Get(ctx, resID, kubeclnt.KubeClientGetOptions{}); err != nil {
+			if !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+				return fmt.Errorf("error getting resource %q: %w", resID.HumanID(), err)
+			}
+		} else {
+			resourceVersion = obj.GetResourceVersion()
+		}
+
+		if resourceVersion != liveRes.ResourceVersion {
+			drifted = append(drifted, fmt.Sprintf("%s (planned resourceVersion: %q, live resourceVersion: %q)", resID.HumanID(), liveRes.ResourceVersion, resourceVersion))
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("resources changed since planning: %v", drifted)
+	}
+
+	return nil
+}
+
+// Compares dry-apply results of existing resources now with the ones saved during planning.
+// Resources which are yet to be created are skipped, since their dry-apply results contain
+// server-allocated values (e.g. Service clusterIP) which change on every dry-apply.
+func (f *PlanFile) ValidateDryApply(
+	standaloneCRDsInfos []*resrcinfo.DeployableStandaloneCRDInfo,
+	hookResourcesInfos []*resrcinfo.DeployableHookResourceInfo,
+	generalResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo,
+) error {
+	plannedDryApplies := map[string]map[string]interface{}{}
+	for _, res := range lo.Flatten([][]*PlanFileResource{f.StandaloneCRDs, f.HookResources, f.GeneralResources}) {
+		plannedDryApplies[planFileResourceKey(&unstructured.Unstructured{Object: res.Object})] = res.DryApply
+	}
+
+	type dryAppliedResource struct {
+		humanID  string
+		unstruct *unstructured.Unstructured
+		dryApply *resrc.RemoteResource
+		live     *resrc.RemoteResource
+	}
+
+	var resources []*dryAppliedResource
+	for _, info := range standaloneCRDsInfos {
+		resources = append(resources, &dryAppliedResource{info.HumanID(), info.Resource().Unstructured(), info.DryApplyResource(), info.LiveResource()})
+	}
+	for _, info := range hookResourcesInfos {
+		resources = append(resources, &dryAppliedResource{info.HumanID(), info.Resource().Unstructured(), info.DryApplyResource(), info.LiveResource()})
+	}
+	for _, info := range generalResourcesInfos {
+		resources = append(resources, &dryAppliedResource{info.HumanID(), info.Resource().Unstructured(), info.DryApplyResource(), info.LiveResource()})
+	}
+
+	var drifted []string
+	for _, res := range resources {
+		if res.live == nil {
+			continue
+		}
+
+		var dryApply map[string]interface{}
+		if res.dryApply != nil {
+			dryApply = res.dryApply.Unstructured().UnstructuredContent()
+		}
+
+		equal, err := dryAppliesEqual(plannedDryApplies[planFileResourceKey(res.unstruct)], dryApply)
+		if err != nil {
+			return fmt.Errorf("error comparing dry-apply results for resource %q: %w", res.humanID, err)
+		}
+
+		if !equal {
+			drifted = append(drifted, res.humanID)
+		}
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("dry-apply results changed since planning: %v", drifted)
+	}
+
+	return nil
+}
+
+func (f *PlanFile) ValidatePlan(plan *pln.Plan) error {
+	operations, dependencies, err := planOperationsAndDependencies(plan)
+	if err != nil {
+		return fmt.Errorf("error getting plan operations and dependencies: %w", err)
+	}
+
+	if missing, extra := lo.Difference(f.Operations, operations); len(missing) > 0 || len(extra) > 0 {
+		return fmt.Errorf("operations differ from planned ones: missing %v, unexpected %v", missing, extra)
+	}
+
+	depID := func(dep *PlanFileDependency) string {
+		return dep.From + " -> " + dep.To
+	}
+
+	if missing, extra := lo.Difference(lo.Map(f.Dependencies, func(dep *PlanFileDependency, _ int) string {
+		return depID(dep)
+	}), lo.Map(dependencies, func(dep *PlanFileDependency, _ int) string {
+		return depID(dep)
+	})); len(missing) > 0 || len(extra) > 0 {
+		return fmt.Errorf("operation dependencies differ from planned ones: missing %v, unexpected %v", missing, extra)
+	}
+
+	return nil
+}
+
+func newPlanFileResource(unstruct *unstructured.Unstructured, filePath string, dryApplyResource *resrc.RemoteResource) *PlanFileResource {
+	res := &PlanFileResource{
+		FilePath: filePath,
+		Object:   unstruct.UnstructuredContent(),
+	}
+
+	if dryApplyResource != nil {
+		res.DryApply = dryApplyResource.Unstructured().UnstructuredContent()
+	}
+
+	return res
+}
+
+func newPlanFileLiveResource(resID *resrcid.ResourceID, liveResource *resrc.RemoteResource) *PlanFileLiveResource {
+	res := &PlanFileLiveResource{
+		APIVersion: resID.GroupVersionKind().GroupVersion().String(),
+		Kind:       resID.GroupVersionKind().Kind,
+		Namespace:  resID.Namespace(),
+		Name:       resID.Name(),
+	}
+
+	if liveResource != nil {
+		res.ResourceVersion = liveResource.Unstructured().GetResourceVersion()
+	}
+
+	return res
+}
+
+func planOperationsAndDependencies(plan *pln.Plan) (operations []string, dependencies []*PlanFileDependency, err error) {
+	ops, _, err := plan.Operations()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting operations: %w", err)
+	}
+
+	for _, op := range ops {
+		operations = append(operations, op.ID())
+	}
+	sort.Strings(operations)
+
+	deps, err := plan.Dependencies()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting dependencies: %w", err)
+	}
+
+	for _, dep := range deps {
+		dependencies = append(dependencies, &PlanFileDependency{
+			From: dep.Source,
+			To:   dep.Target,
+		})
+	}
+	sort.SliceStable(dependencies, func(i, j int) bool {
+		if dependencies[i].From == dependencies[j].From {
+			return dependencies[i].To < dependencies[j].To
+		}
+
+		return dependencies[i].From < dependencies[j].From
+	})
+
+	return operations, dependencies, nil
+}
+
+func planFileResourceKey(unstruct *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s:%s:%s:%s", unstruct.GetNamespace(), unstruct.GetAPIVersion(), unstruct.GetKind(), unstruct.GetName())
+}
+
+func dryAppliesEqual(planned, current map[string]interface{}) (bool, error) {
+	if planned == nil || current == nil {
+		return planned == nil && current == nil, nil
+	}
+
+	// Compare JSON representations, since numbers in the decoded plan file are float64 while
+	// in the API responses they are int64.
+	plannedJSON, err := json.Marshal(withoutVolatileMetadata(planned))
+	if err != nil {
+		return false, fmt.Errorf("error marshalling planned dry-apply result: %w", err)
+	}
+
+	currentJSON, err := json.Marshal(withoutVolatileMetadata(current))
+	if err != nil {
+		return false, fmt.Errorf("error marshalling current dry-apply result: %w", err)
+	}
+
+	return string(plannedJSON) == string(currentJSON), nil
+}
+
+func withoutVolatileMetadata(obj map[string]interface{}) map[string]interface{} {
+	unstruct := (&unstructured.Unstructured{Object: obj}).DeepCopy()
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "creationTimestamp", "generation"} {
+		unstructured.RemoveNestedField(unstruct.Object, "metadata", field)
+	}
+
+	return unstruct.Object
+}
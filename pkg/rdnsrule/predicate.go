@@ -0,0 +1,168 @@
+package rdnsrule
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const defaultConditionStatus = "True"
+
+// Holds either when the resource has the status condition of the specified type and status,
+// or when the JSONPath expression evaluated against the resource yields the expected value.
+// If no value is expected, any non-empty result of the JSONPath expression satisfies the
+// predicate.
+func NewPredicate(opts PredicateOptions) (*Predicate, error) {
+	if (opts.ConditionType == "") == (opts.JSONPath == "") {
+		return nil, fmt.Errorf("exactly one of condition type or JSONPath expression must be specified")
+	}
+
+	if opts.ConditionType != "" {
+		if opts.Value != "" {
+			return nil, fmt.Errorf("expected value can be specified only along with JSONPath expression")
+		}
+
+		conditionStatus := opts.ConditionStatus
+		if conditionStatus == "" {
+			conditionStatus = defaultConditionStatus
+		}
+
+		return &Predicate{
+			conditionType:   opts.ConditionType,
+			conditionStatus: conditionStatus,
+		}, nil
+	}
+
+	if opts.ConditionStatus != "" {
+		return nil, fmt.Errorf("condition status can be specified only along with condition type")
+	}
+
+	expression := opts.JSONPath
+	if !strings.HasPrefix(expression, "{") {
+		expression = "{" + expression + "}"
+	}
+
+	jsonPath := jsonpath.New("readiness").AllowMissingKeys(true)
+	if err := jsonPath.Parse(expression); err != nil {
+		return nil, fmt.Errorf("error parsing JSONPath expression %q: %w", opts.JSONPath, err)
+	}
+
+	return &Predicate{
+		jsonPathExpression: opts.JSONPath,
+		jsonPath:           jsonPath,
+		value:              opts.Value,
+	}, nil
+}
+
+type PredicateOptions struct {
+	ConditionType   string
+	ConditionStatus string
+	JSONPath        string
+	Value           string
+}
+
+type Predicate struct {
+	conditionType   string
+	conditionStatus string
+
+	jsonPathExpression string
+	jsonPath           *jsonpath.JSONPath
+	value              string
+}
+
+// If the predicate holds, also returns a human-readable explanation why.
+func (p *Predicate) Holds(unstruct *unstructured.Unstructured) (holds bool, explanation string, err error) {
+	if p.conditionType != "" {
+		return p.conditionHolds(unstruct)
+	}
+
+	return p.jsonPathHolds(unstruct)
+}
+
+func (p *Predicate) String() string {
+	if p.conditionType != "" {
+		return fmt.Sprintf("condition %q is %q", p.conditionType, p.conditionStatus)
+	}
+
+	if p.value == "" {
+		return fmt.Sprintf("%q is not empty", p.jsonPathExpression)
+	}
+
+	return fmt.Sprintf("%q is %q", p.jsonPathExpression, p.value)
+}
+
+func (p *Predicate) conditionHolds(unstruct *unstructured.Unstructured) (holds bool, explanation string, err error) {
+	if !StatusObserved(unstruct) {
+		return false, "", nil
+	}
+
+	conditions, found, err := unstructured.NestedSlice(unstruct.Object, "status", "conditions")
+	if err != nil {
+		return false, "", fmt.Errorf("error getting status conditions: %w", err)
+	} else if !found {
+		return false, "", nil
+	}
+
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if fmt.Sprint(condition["type"]) != p.conditionType || fmt.Sprint(condition["status"]) != p.conditionStatus {
+			continue
+		}
+
+		if observedGeneration, ok := condition["observedGeneration"].(int64); ok && observedGeneration < unstruct.GetGeneration() {
+			continue
+		}
+
+		explanation = p.String()
+		if reason, ok := condition["reason"].(string); ok && reason != "" {
+			explanation += ", reason: " + reason
+		}
+		if message, ok := condition["message"].(string); ok && message != "" {
+			explanation += ", message: " + message
+		}
+
+		return true, explanation, nil
+	}
+
+	return false, "", nil
+}
+
+func (p *Predicate) jsonPathHolds(unstruct *unstructured.Unstructured) (holds bool, explanation string, err error) {
+	results, err := p.jsonPath.FindResults(unstruct.Object)
+	if err != nil {
+		return false, "", fmt.Errorf("error evaluating JSONPath expression %q: %w", p.jsonPathExpression, err)
+	}
+
+	for _, result := range results {
+		for _, r := range result {
+			if !r.IsValid() || !r.CanInterface() || r.Interface() == nil {
+				continue
+			}
+
+			value := fmt.Sprint(r.Interface())
+
+			if p.value == "" && value != "" || p.value != "" && value == p.value {
+				return true, p.String(), nil
+			}
+		}
+	}
+
+	return false, "", nil
+}
+
+// False if the controller hasn't observed the latest generation of the resource yet, so its
+// status is stale. Resources without status.observedGeneration are always considered observed.
+func StatusObserved(unstruct *unstructured.Unstructured) bool {
+	observedGeneration, found, err := unstructured.NestedInt64(unstruct.Object, "status", "observedGeneration")
+	if err != nil || !found {
+		return true
+	}
+
+	return observedGeneration >= unstruct.GetGeneration()
+}
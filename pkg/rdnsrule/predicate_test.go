@@ -0,0 +1,198 @@
+package rdnsrule_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
+)
+
+func newResource(generation int64, status map[string]interface{}) *unstructured.Unstructured {
+	unstruct := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Database",
+			"metadata": map[string]interface{}{
+				"name":       "db",
+				"generation": generation,
+			},
+		},
+	}
+
+	if status != nil {
+		unstruct.Object["status"] = status
+	}
+
+	return unstruct
+}
+
+func readyCondition(status string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "Ready",
+		"status":  status,
+		"reason":  "Provisioned",
+		"message": "database is up",
+	}
+}
+
+var _ = Describe("Predicate", func() {
+	DescribeTable("condition predicate",
+		func(opts rdnsrule.PredicateOptions, unstruct *unstructured.Unstructured, expectHolds bool) {
+			predicate, err := rdnsrule.NewPredicate(opts)
+			Expect(err).To(Succeed())
+
+			holds, explanation, err := predicate.Holds(unstruct)
+			Expect(err).To(Succeed())
+			Expect(holds).To(Equal(expectHolds))
+			if expectHolds {
+				Expect(explanation).To(ContainSubstring(`condition "Ready"`))
+			}
+		},
+		Entry("holds when condition is True by default",
+			rdnsrule.PredicateOptions{ConditionType: "Ready"},
+			newResource(1, map[string]interface{}{
+				"conditions": []interface{}{readyCondition("True")},
+			}),
+			true,
+		),
+		Entry("doesn't hold when condition has other status",
+			rdnsrule.PredicateOptions{ConditionType: "Ready"},
+			newResource(1, map[string]interface{}{
+				"conditions": []interface{}{readyCondition("False")},
+			}),
+			false,
+		),
+		Entry("holds when condition has the specified status",
+			rdnsrule.PredicateOptions{ConditionType: "Ready", ConditionStatus: "False"},
+			newResource(1, map[string]interface{}{
+				"conditions": []interface{}{readyCondition("False")},
+			}),
+			true,
+		),
+		Entry("doesn't hold without the condition",
+			rdnsrule.PredicateOptions{ConditionType: "Ready"},
+			newResource(1, map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Synced", "status": "True"},
+				},
+			}),
+			false,
+		),
+		Entry("doesn't hold without status",
+			rdnsrule.PredicateOptions{ConditionType: "Ready"},
+			newResource(1, nil),
+			false,
+		),
+		Entry("holds when status observed the current generation",
+			rdnsrule.PredicateOptions{ConditionType: "Ready"},
+			newResource(2, map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions":         []interface{}{readyCondition("True")},
+			}),
+			true,
+		),
+		Entry("doesn't hold when status observed an older generation",
+			rdnsrule.PredicateOptions{ConditionType: "Ready"},
+			newResource(2, map[string]interface{}{
+				"observedGeneration": int64(1),
+				"conditions":         []interface{}{readyCondition("True")},
+			}),
+			false,
+		),
+		Entry("doesn't hold when condition observed an older generation",
+			rdnsrule.PredicateOptions{ConditionType: "Ready"},
+			newResource(2, map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True", "observedGeneration": int64(1)},
+				},
+			}),
+			false,
+		),
+	)
+
+	DescribeTable("JSONPath predicate",
+		func(opts rdnsrule.PredicateOptions, unstruct *unstructured.Unstructured, expectHolds bool) {
+			predicate, err := rdnsrule.NewPredicate(opts)
+			Expect(err).To(Succeed())
+
+			holds, _, err := predicate.Holds(unstruct)
+			Expect(err).To(Succeed())
+			Expect(holds).To(Equal(expectHolds))
+		},
+		Entry("holds when value matches",
+			rdnsrule.PredicateOptions{JSONPath: ".status.phase", Value: "Healthy"},
+			newResource(1, map[string]interface{}{"phase": "Healthy"}),
+			true,
+		),
+		Entry("doesn't hold when value differs",
+			rdnsrule.PredicateOptions{JSONPath: ".status.phase", Value: "Healthy"},
+			newResource(1, map[string]interface{}{"phase": "Degraded"}),
+			false,
+		),
+		Entry("holds on any non-empty value without expected value",
+			rdnsrule.PredicateOptions{JSONPath: ".status.endpoint"},
+			newResource(1, map[string]interface{}{"endpoint": "db.example.com"}),
+			true,
+		),
+		Entry("doesn't hold on empty value without expected value",
+			rdnsrule.PredicateOptions{JSONPath: ".status.endpoint"},
+			newResource(1, map[string]interface{}{"endpoint": ""}),
+			false,
+		),
+		Entry("doesn't hold on missing keys",
+			rdnsrule.PredicateOptions{JSONPath: ".status.phase", Value: "Healthy"},
+			newResource(1, nil),
+			false,
+		),
+		Entry("accepts expression in braces",
+			rdnsrule.PredicateOptions{JSONPath: "{.status.phase}", Value: "Healthy"},
+			newResource(1, map[string]interface{}{"phase": "Healthy"}),
+			true,
+		),
+		Entry("holds when any of multiple results matches",
+			rdnsrule.PredicateOptions{JSONPath: ".status.replicas[*].state", Value: "ready"},
+			newResource(1, map[string]interface{}{
+				"replicas": []interface{}{
+					map[string]interface{}{"state": "starting"},
+					map[string]interface{}{"state": "ready"},
+				},
+			}),
+			true,
+		),
+		Entry("compares non-string values by their string form",
+			rdnsrule.PredicateOptions{JSONPath: ".status.readyReplicas", Value: "3"},
+			newResource(1, map[string]interface{}{"readyReplicas": int64(3)}),
+			true,
+		),
+		Entry("filters list items",
+			rdnsrule.PredicateOptions{JSONPath: `.status.conditions[?(@.type=="Ready")].status`, Value: "True"},
+			newResource(1, map[string]interface{}{
+				"conditions": []interface{}{readyCondition("True")},
+			}),
+			true,
+		),
+	)
+
+	DescribeTable("should reject invalid options",
+		func(opts rdnsrule.PredicateOptions) {
+			_, err := rdnsrule.NewPredicate(opts)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("without condition type and JSONPath", rdnsrule.PredicateOptions{}),
+		Entry("with both condition type and JSONPath", rdnsrule.PredicateOptions{ConditionType: "Ready", JSONPath: ".status.phase"}),
+		Entry("with value for condition", rdnsrule.PredicateOptions{ConditionType: "Ready", Value: "True"}),
+		Entry("with status for JSONPath", rdnsrule.PredicateOptions{JSONPath: ".status.phase", ConditionStatus: "True"}),
+		Entry("with invalid JSONPath", rdnsrule.PredicateOptions{JSONPath: ".status[phase"}),
+	)
+
+	DescribeTable("StatusObserved",
+		func(unstruct *unstructured.Unstructured, expectObserved bool) {
+			Expect(rdnsrule.StatusObserved(unstruct)).To(Equal(expectObserved))
+		},
+		Entry("without observedGeneration", newResource(3, map[string]interface{}{"phase": "Healthy"}), true),
+		Entry("with current observedGeneration", newResource(3, map[string]interface{}{"observedGeneration": int64(3)}), true),
+		Entry("with newer observedGeneration", newResource(3, map[string]interface{}{"observedGeneration": int64(4)}), true),
+		Entry("with older observedGeneration", newResource(3, map[string]interface{}{"observedGeneration": int64(2)}), false),
+	)
+})
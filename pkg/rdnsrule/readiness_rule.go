@@ -0,0 +1,159 @@
+package rdnsrule
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Declarative readiness of a resource: the resource is ready when the ready predicate holds,
+// and failed when the failed predicate holds. Empty version matches any version.
+func NewReadinessRule(group, version, kind string, ready *Predicate, opts ReadinessRuleOptions) *ReadinessRule {
+	return &ReadinessRule{
+		group:   group,
+		version: version,
+		kind:    kind,
+		ready:   ready,
+		failed:  opts.Failed,
+	}
+}
+
+type ReadinessRuleOptions struct {
+	Failed *Predicate
+}
+
+type ReadinessRule struct {
+	group   string
+	version string
+	kind    string
+	ready   *Predicate
+	failed  *Predicate
+}
+
+func (r *ReadinessRule) Match(gvk schema.GroupVersionKind) bool {
+	return r.group == gvk.Group && r.kind == gvk.Kind && (r.version == "" || r.version == gvk.Version)
+}
+
+func (r *ReadinessRule) Ready(unstruct *unstructured.Unstructured) (ready bool, err error) {
+	ready, _, err = r.ready.Holds(unstruct)
+	if err != nil {
+		return false, fmt.Errorf("error checking ready predicate: %w", err)
+	}
+
+	return ready, nil
+}
+
+func (r *ReadinessRule) Failed(unstruct *unstructured.Unstructured) (failed bool, reason string, err error) {
+	if r.failed == nil {
+		return false, "", nil
+	}
+
+	failed, reason, err = r.failed.Holds(unstruct)
+	if err != nil {
+		return false, "", fmt.Errorf("error checking failed predicate: %w", err)
+	}
+
+	return failed, reason, nil
+}
+
+// Returns the first rule matching the GroupVersionKind.
+func FindRule(rules []*ReadinessRule, gvk schema.GroupVersionKind) (rule *ReadinessRule, found bool) {
+	for _, rule := range rules {
+		if rule.Match(gvk) {
+			return rule, true
+		}
+	}
+
+	return nil, false
+}
+
+// Reads rules from a YAML file like:
+//
+//	rules:
+//	- group: cert-manager.io
+//	  kind: Certificate
+//	  ready:
+//	    condition: Ready
+//	  failed:
+//	    condition: Ready
+//	    status: "False"
+//	- group: argoproj.io
+//	  kind: Rollout
+//	  ready:
+//	    jsonPath: .status.phase
+//	    value: Healthy
+//	  failed:
+//	    jsonPath: .status.phase
+//	    value: Degraded
+func LoadRules(path string) ([]*ReadinessRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading readiness rules file %q: %w", path, err)
+	}
+
+	var file rulesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("error unmarshalling readiness rules file %q: %w", path, err)
+	}
+
+	var rules []*ReadinessRule
+	for i, r := range file.Rules {
+		if r.Kind == "" {
+			return nil, fmt.Errorf("error in readiness rule %d: kind must be specified", i)
+		}
+
+		if r.Ready == nil {
+			return nil, fmt.Errorf("error in readiness rule %d for kind %q: ready predicate must be specified", i, r.Kind)
+		}
+
+		ready, err := r.Ready.predicate()
+		if err != nil {
+			return nil, fmt.Errorf("error in ready predicate of readiness rule %d for kind %q: %w", i, r.Kind, err)
+		}
+
+		var failed *Predicate
+		if r.Failed != nil {
+			failed, err = r.Failed.predicate()
+			if err != nil {
+				return nil, fmt.Errorf("error in failed predicate of readiness rule %d for kind %q: %w", i, r.Kind, err)
+			}
+		}
+
+		rules = append(rules, NewReadinessRule(r.Group, r.Version, r.Kind, ready, ReadinessRuleOptions{
+			Failed: failed,
+		}))
+	}
+
+	return rules, nil
+}
+
+type rulesFile struct {
+	Rules []rulesFileRule `json:"rules"`
+}
+
+type rulesFileRule struct {
+	Group   string                  `json:"group"`
+	Version string                  `json:"version"`
+	Kind    string                  `json:"kind"`
+	Ready   *rulesFileRulePredicate `json:"ready"`
+	Failed  *rulesFileRulePredicate `json:"failed"`
+}
+
+type rulesFileRulePredicate struct {
+	Condition string `json:"condition"`
+	Status    string `json:"status"`
+	JSONPath  string `json:"jsonPath"`
+	Value     string `json:"value"`
+}
+
+func (p *rulesFileRulePredicate) predicate() (*Predicate, error) {
+	return NewPredicate(PredicateOptions{
+		ConditionType:   p.Condition,
+		ConditionStatus: p.Status,
+		JSONPath:        p.JSONPath,
+		Value:           p.Value,
+	})
+}
@@ -0,0 +1,13 @@
+package rdnsrule_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReadinessRule(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "readiness rule suite")
+}
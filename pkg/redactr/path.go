@@ -0,0 +1,96 @@
+package redactr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type pathSegmentType string
+
+const (
+	pathSegmentTypeKey      pathSegmentType = "key"
+	pathSegmentTypeAnyKey   pathSegmentType = "any-key"
+	pathSegmentTypeIndex    pathSegmentType = "index"
+	pathSegmentTypeAnyIndex pathSegmentType = "any-index"
+)
+
+type pathSegment struct {
+	segmentType pathSegmentType
+	key         string
+	index       int
+}
+
+func ValidatePath(path string) error {
+	_, err := parsePath(path)
+	return err
+}
+
+// Supported subset of JSONPath: ".spec.template.spec.containers[*].env[*].value",
+// ".data.*", ".data['config.yaml']", ".items[0].value". Leading "$" is optional.
+func parsePath(path string) ([]pathSegment, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	if path == "" || path == "." {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	var segments []pathSegment
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+
+			end := i
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+
+			key := path[i:end]
+			if key == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key at position %d", path, i)
+			}
+
+			if key == "*" {
+				segments = append(segments, pathSegment{segmentType: pathSegmentTypeAnyKey})
+			} else {
+				segments = append(segments, pathSegment{segmentType: pathSegmentTypeKey, key: key})
+			}
+
+			i = end
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid path %q: unclosed bracket at position %d", path, i)
+			}
+			end += i
+
+			content := strings.TrimSpace(path[i+1 : end])
+			switch {
+			case content == "*":
+				segments = append(segments, pathSegment{segmentType: pathSegmentTypeAnyIndex})
+			case len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0]:
+				segments = append(segments, pathSegment{segmentType: pathSegmentTypeKey, key: content[1 : len(content)-1]})
+			default:
+				index, err := strconv.Atoi(content)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid path %q: unexpected %q in brackets, expected index, \"*\" or quoted key", path, content)
+				}
+
+				segments = append(segments, pathSegment{segmentType: pathSegmentTypeIndex, index: index})
+			}
+
+			i = end + 1
+		default:
+			if i == 0 {
+				path = "." + path
+				continue
+			}
+
+			return nil, fmt.Errorf("invalid path %q: unexpected character %q at position %d", path, path[i], i)
+		}
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,60 @@
+package redactr
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parsePath", func() {
+	DescribeTable("parses supported JSONPath subset",
+		func(path string, expected []pathSegment) {
+			segments, err := parsePath(path)
+			Expect(err).To(Succeed())
+			Expect(segments).To(Equal(expected))
+		},
+		Entry("single key", ".data", []pathSegment{
+			{segmentType: pathSegmentTypeKey, key: "data"},
+		}),
+		Entry("leading dollar", "$.data.password", []pathSegment{
+			{segmentType: pathSegmentTypeKey, key: "data"},
+			{segmentType: pathSegmentTypeKey, key: "password"},
+		}),
+		Entry("no leading dot", "spec.password", []pathSegment{
+			{segmentType: pathSegmentTypeKey, key: "spec"},
+			{segmentType: pathSegmentTypeKey, key: "password"},
+		}),
+		Entry("any key", ".data.*", []pathSegment{
+			{segmentType: pathSegmentTypeKey, key: "data"},
+			{segmentType: pathSegmentTypeAnyKey},
+		}),
+		Entry("quoted key with dots", ".data['config.yaml']", []pathSegment{
+			{segmentType: pathSegmentTypeKey, key: "data"},
+			{segmentType: pathSegmentTypeKey, key: "config.yaml"},
+		}),
+		Entry("double quoted key", `.data["config.yaml"]`, []pathSegment{
+			{segmentType: pathSegmentTypeKey, key: "data"},
+			{segmentType: pathSegmentTypeKey, key: "config.yaml"},
+		}),
+		Entry("index and any index", ".spec.containers[*].env[0].value", []pathSegment{
+			{segmentType: pathSegmentTypeKey, key: "spec"},
+			{segmentType: pathSegmentTypeKey, key: "containers"},
+			{segmentType: pathSegmentTypeAnyIndex},
+			{segmentType: pathSegmentTypeKey, key: "env"},
+			{segmentType: pathSegmentTypeIndex, index: 0},
+			{segmentType: pathSegmentTypeKey, key: "value"},
+		}),
+	)
+
+	DescribeTable("rejects invalid paths",
+		func(path string) {
+			_, err := parsePath(path)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("only dot", "."),
+		Entry("empty key", ".data..password"),
+		Entry("unclosed bracket", ".data['password'"),
+		Entry("negative index", ".items[-1]"),
+		Entry("unquoted key in brackets", ".data[password]"),
+	)
+})
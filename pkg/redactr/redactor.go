@@ -0,0 +1,420 @@
+package redactr
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const AnnotationKeySensitivePaths = "werf.io/sensitive-paths"
+
+const RedactedValuePrefix = "<redacted"
+
+const annotationKeyLastAppliedConfiguration = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Shorter values are not redacted in arbitrary text, otherwise values like "1" or "true"
+// would be masked everywhere.
+const minTextSensitiveValueLength = 4
+
+var DefaultRules = []*Rule{
+	lo.Must(ParseRule("Secret:.data")),
+	lo.Must(ParseRule("Secret:.stringData")),
+}
+
+var versionRegex = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// Generated once per process, so that equal values are masked equally within
+// the process (and their changes are still visible in diffs), but masked values
+// can't be compared across runs.
+var hashKey = lo.Must(randomHashKey())
+
+// Rule format: "<Kind>[.<version>][.<group>]:<path>", e.g. "Secret:.data",
+// "Deployment.apps:.spec.template.spec.containers[*].env[*].value",
+// "*.example.com:.spec.password". Kind, version and group support globs.
+func ParseRule(rule string) (*Rule, error) {
+	gvkPart, pathPart, found := strings.Cut(rule, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid redaction rule %q, expected <Kind>[.<version>][.<group>]:<path>", rule)
+	}
+
+	gvkPart = strings.TrimSpace(gvkPart)
+	if gvkPart == "" {
+		return nil, fmt.Errorf("invalid redaction rule %q, kind not specified", rule)
+	}
+
+	segments, err := parsePath(pathPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction rule %q: %w", rule, err)
+	}
+
+	kind, groupVersion, _ := strings.Cut(gvkPart, ".")
+
+	var version, group string
+	if v, g, _ := strings.Cut(groupVersion, "."); versionRegex.MatchString(v) {
+		version = v
+		group = g
+	} else {
+		group = groupVersion
+	}
+
+	return &Rule{
+		kind:     kind,
+		version:  version,
+		group:    group,
+		anyGroup: groupVersion == "",
+		path:     segments,
+	}, nil
+}
+
+type Rule struct {
+	kind     string
+	version  string
+	group    string
+	anyGroup bool
+	path     []pathSegment
+}
+
+func (r *Rule) Match(gvk schema.GroupVersionKind) bool {
+	if !matchValue(r.kind, gvk.Kind) {
+		return false
+	}
+
+	if r.version != "" && !matchValue(r.version, gvk.Version) {
+		return false
+	}
+
+	if !r.anyGroup && !matchValue(r.group, gvk.Group) {
+		return false
+	}
+
+	return true
+}
+
+func NewRedactor(rules []*Rule, opts RedactorOptions) *Redactor {
+	if !opts.NoDefaultRules {
+		rules = append(append([]*Rule{}, DefaultRules...), rules...)
+	}
+
+	return &Redactor{
+		rules: rules,
+	}
+}
+
+type RedactorOptions struct {
+	NoDefaultRules bool
+}
+
+type Redactor struct {
+	rules []*Rule
+}
+
+// Returns redacted copy of the object. Sensitive paths are also collected from the
+// related objects, so that different versions of the same resource (e.g. live and
+// desired) are redacted the same way.
+func (r *Redactor) Redact(obj *unstructured.Unstructured, related ...*unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+
+	var paths [][]pathSegment
+	for _, o := range append([]*unstructured.Unstructured{obj}, related...) {
+		if o == nil {
+			continue
+		}
+
+		for _, rule := range r.rules {
+			if rule.Match(o.GroupVersionKind()) {
+				paths = append(paths, rule.path)
+			}
+		}
+
+		paths = append(paths, annotationPaths(o)...)
+	}
+
+	obj = obj.DeepCopy()
+	for _, p := range paths {
+		redactPath(obj.Object, p)
+	}
+
+	redactLastAppliedConfiguration(obj, paths)
+
+	return obj
+}
+
+// Returns redactor of the values at the sensitive paths of the objects, to be used on
+// arbitrary text, like errors returned by Kubernetes API, reports and logs.
+func (r *Redactor) TextRedactor(objs ...*unstructured.Unstructured) *TextRedactor {
+	valuesSet := map[string]struct{}{}
+	for _, obj := range objs {
+		if obj == nil {
+			continue
+		}
+
+		paths := annotationPaths(obj)
+		for _, rule := range r.rules {
+			if rule.Match(obj.GroupVersionKind()) {
+				paths = append(paths, rule.path)
+			}
+		}
+
+		for _, p := range paths {
+			walkPath(obj.Object, p, func(value interface{}) {
+				collectValues(value, valuesSet)
+			})
+		}
+	}
+
+	values := lo.Keys(valuesSet)
+	// Longer values first, so that values containing other values are fully redacted.
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) == len(values[j]) {
+			return values[i] < values[j]
+		}
+
+		return len(values[i]) > len(values[j])
+	})
+
+	var oldNew []string
+	for _, value := range values {
+		oldNew = append(oldNew, value, redactValue(value).(string))
+	}
+
+	return &TextRedactor{
+		replacer: strings.NewReplacer(oldNew...),
+	}
+}
+
+// Nil TextRedactor returns the text as is.
+type TextRedactor struct {
+	replacer *strings.Replacer
+}
+
+func (r *TextRedactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+
+	return r.replacer.Replace(text)
+}
+
+// Returned error has the redacted message, but still unwraps to the original error.
+func (r *TextRedactor) RedactError(err error) error {
+	if r == nil || err == nil {
+		return err
+	}
+
+	return &redactedError{
+		err:     err,
+		message: r.Redact(err.Error()),
+	}
+}
+
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+func annotationPaths(obj *unstructured.Unstructured) [][]pathSegment {
+	value, found := obj.GetAnnotations()[AnnotationKeySensitivePaths]
+	if !found {
+		return nil
+	}
+
+	var paths [][]pathSegment
+	for _, p := range strings.Split(value, ",") {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+
+		// Invalid paths are rejected on resource validation, here we just skip them.
+		if segments, err := parsePath(p); err == nil {
+			paths = append(paths, segments)
+		}
+	}
+
+	return paths
+}
+
+// The annotation contains the whole previously applied object, so the same sensitive paths
+// are redacted in it too. If it can't be parsed, it is redacted completely.
+func redactLastAppliedConfiguration(obj *unstructured.Unstructured, paths [][]pathSegment) {
+	annotations := obj.GetAnnotations()
+
+	value, found := annotations[annotationKeyLastAppliedConfiguration]
+	if !found {
+		return
+	}
+
+	var lastApplied map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &lastApplied); err != nil || lastApplied == nil {
+		annotations[annotationKeyLastAppliedConfiguration] = redactValue(value).(string)
+		obj.SetAnnotations(annotations)
+
+		return
+	}
+
+	lastAppliedPaths := append(append([][]pathSegment{}, paths...), annotationPaths(&unstructured.Unstructured{Object: lastApplied})...)
+	for _, p := range lastAppliedPaths {
+		redactPath(lastApplied, p)
+	}
+
+	if data, err := json.Marshal(lastApplied); err != nil {
+		annotations[annotationKeyLastAppliedConfiguration] = redactValue(value).(string)
+	} else {
+		annotations[annotationKeyLastAppliedConfiguration] = string(data)
+	}
+
+	obj.SetAnnotations(annotations)
+}
+
+func redactPath(node interface{}, segments []pathSegment) interface{} {
+	if len(segments) == 0 {
+		return redactValue(node)
+	}
+
+	segment := segments[0]
+
+	switch segment.segmentType {
+	case pathSegmentTypeKey:
+		if m, ok := node.(map[string]interface{}); ok {
+			if value, found := m[segment.key]; found {
+				m[segment.key] = redactPath(value, segments[1:])
+			}
+		}
+	case pathSegmentTypeAnyKey:
+		if m, ok := node.(map[string]interface{}); ok {
+			for key, value := range m {
+				m[key] = redactPath(value, segments[1:])
+			}
+		}
+	case pathSegmentTypeIndex:
+		if l, ok := node.([]interface{}); ok && segment.index < len(l) {
+			l[segment.index] = redactPath(l[segment.index], segments[1:])
+		}
+	case pathSegmentTypeAnyIndex:
+		if l, ok := node.([]interface{}); ok {
+			for i, value := range l {
+				l[i] = redactPath(value, segments[1:])
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unexpected path segment type %q", segment.segmentType))
+	}
+
+	return node
+}
+
+func walkPath(node interface{}, segments []pathSegment, fn func(value interface{})) {
+	if len(segments) == 0 {
+		fn(node)
+		return
+	}
+
+	segment := segments[0]
+
+	switch segment.segmentType {
+	case pathSegmentTypeKey:
+		if m, ok := node.(map[string]interface{}); ok {
+			if value, found := m[segment.key]; found {
+				walkPath(value, segments[1:], fn)
+			}
+		}
+	case pathSegmentTypeAnyKey:
+		if m, ok := node.(map[string]interface{}); ok {
+			for _, value := range m {
+				walkPath(value, segments[1:], fn)
+			}
+		}
+	case pathSegmentTypeIndex:
+		if l, ok := node.([]interface{}); ok && segment.index < len(l) {
+			walkPath(l[segment.index], segments[1:], fn)
+		}
+	case pathSegmentTypeAnyIndex:
+		if l, ok := node.([]interface{}); ok {
+			for _, value := range l {
+				walkPath(value, segments[1:], fn)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unexpected path segment type %q", segment.segmentType))
+	}
+}
+
+func collectValues(value interface{}, values map[string]struct{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, val := range v {
+			collectValues(val, values)
+		}
+	case []interface{}:
+		for _, val := range v {
+			collectValues(val, values)
+		}
+	case nil:
+	default:
+		if str := fmt.Sprint(v); len(str) >= minTextSensitiveValueLength {
+			values[str] = struct{}{}
+		}
+	}
+}
+
+// Keys of maps are preserved, so that it's still visible which keys changed.
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = redactValue(val)
+		}
+
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redactValue(val)
+		}
+
+		return v
+	case nil:
+		return nil
+	default:
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write([]byte(fmt.Sprint(v)))
+
+		return fmt.Sprintf("%s %s>", RedactedValuePrefix, hex.EncodeToString(mac.Sum(nil))[:8])
+	}
+}
+
+func matchValue(pattern, value string) bool {
+	if matched, err := path.Match(pattern, value); err == nil && matched {
+		return true
+	}
+
+	return pattern == value
+}
+
+func randomHashKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("error generating random key: %w", err)
+	}
+
+	return key, nil
+}
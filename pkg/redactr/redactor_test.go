@@ -0,0 +1,228 @@
+package redactr
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("ParseRule", func() {
+	DescribeTable("matches resources by kind, version and group",
+		func(rule string, gvk schema.GroupVersionKind, expectMatch bool) {
+			r, err := ParseRule(rule)
+			Expect(err).To(Succeed())
+			Expect(r.Match(gvk)).To(Equal(expectMatch))
+		},
+		Entry("kind in any group", "Secret:.data", schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, true),
+		Entry("other kind", "Secret:.data", schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, false),
+		Entry("kind and group", "Deployment.apps:.spec", schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, true),
+		Entry("kind and other group", "Deployment.apps:.spec", schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}, false),
+		Entry("kind, version and group", "Deployment.v1.apps:.spec", schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, true),
+		Entry("kind and other version", "Deployment.v1beta1.apps:.spec", schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, false),
+		Entry("kind glob", "*.example.com:.spec.password", schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Database"}, true),
+		Entry("kind and group globs", "*.*.example.com:.spec.password", schema.GroupVersionKind{Group: "db.example.com", Version: "v1", Kind: "Database"}, true),
+	)
+
+	DescribeTable("rejects invalid rules",
+		func(rule string) {
+			_, err := ParseRule(rule)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("no path", "Secret"),
+		Entry("no kind", ":.data"),
+		Entry("invalid path", "Secret:.data["),
+	)
+})
+
+var _ = Describe("Redactor", func() {
+	It("masks values at the sensitive paths, keeping keys and other fields", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+
+		result := redactor.Redact(newSecret(map[string]interface{}{"password": "cGFzc3dvcmQ="}))
+
+		password, _, _ := unstructured.NestedString(result.Object, "data", "password")
+		Expect(password).To(HavePrefix(RedactedValuePrefix))
+		Expect(result.GetName()).To(Equal("secret"))
+	})
+
+	It("doesn't modify the original object", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+		secret := newSecret(map[string]interface{}{"password": "cGFzc3dvcmQ="})
+
+		redactor.Redact(secret)
+
+		password, _, _ := unstructured.NestedString(secret.Object, "data", "password")
+		Expect(password).To(Equal("cGFzc3dvcmQ="))
+	})
+
+	It("masks equal values equally and different values differently", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+
+		first := redactor.Redact(newSecret(map[string]interface{}{"a": "dmFsdWU=", "b": "dmFsdWU=", "c": "b3RoZXI="}))
+
+		a, _, _ := unstructured.NestedString(first.Object, "data", "a")
+		b, _, _ := unstructured.NestedString(first.Object, "data", "b")
+		c, _, _ := unstructured.NestedString(first.Object, "data", "c")
+		Expect(a).To(Equal(b))
+		Expect(a).NotTo(Equal(c))
+	})
+
+	It("masks paths matched by custom rules through lists", func() {
+		rule, err := ParseRule("Deployment.apps:.spec.template.spec.containers[*].env[*].value")
+		Expect(err).To(Succeed())
+		redactor := NewRedactor([]*Rule{rule}, RedactorOptions{})
+
+		deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "app"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name": "app",
+								"env": []interface{}{
+									map[string]interface{}{"name": "TOKEN", "value": "secret-token"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}}
+
+		result := redactor.Redact(deployment)
+
+		containers, _, _ := unstructured.NestedSlice(result.Object, "spec", "template", "spec", "containers")
+		env := containers[0].(map[string]interface{})["env"].([]interface{})[0].(map[string]interface{})
+		Expect(env["name"]).To(Equal("TOKEN"))
+		Expect(env["value"]).To(HavePrefix(RedactedValuePrefix))
+		Expect(containers[0].(map[string]interface{})["name"]).To(Equal("app"))
+	})
+
+	It("masks paths from the sensitive paths annotation of the related object", func() {
+		redactor := NewRedactor(nil, RedactorOptions{NoDefaultRules: true})
+
+		desired := newConfigMap(map[string]interface{}{"password": "new-password", "user": "admin"})
+		desired.SetAnnotations(map[string]string{AnnotationKeySensitivePaths: ".data.password"})
+		live := newConfigMap(map[string]interface{}{"password": "old-password", "user": "admin"})
+
+		result := redactor.Redact(live, desired)
+
+		password, _, _ := unstructured.NestedString(result.Object, "data", "password")
+		user, _, _ := unstructured.NestedString(result.Object, "data", "user")
+		Expect(password).To(HavePrefix(RedactedValuePrefix))
+		Expect(user).To(Equal("admin"))
+	})
+
+	It("masks sensitive values in the last applied configuration annotation", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+
+		secret := newSecret(map[string]interface{}{"password": "cGFzc3dvcmQ="})
+		lastApplied, err := json.Marshal(secret.Object)
+		Expect(err).To(Succeed())
+		secret.SetAnnotations(map[string]string{annotationKeyLastAppliedConfiguration: string(lastApplied)})
+
+		result := redactor.Redact(secret)
+
+		annotation := result.GetAnnotations()[annotationKeyLastAppliedConfiguration]
+		Expect(annotation).NotTo(ContainSubstring("cGFzc3dvcmQ="))
+
+		var redactedLastApplied map[string]interface{}
+		Expect(json.Unmarshal([]byte(annotation), &redactedLastApplied)).To(Succeed())
+		password, _, _ := unstructured.NestedString(redactedLastApplied, "data", "password")
+		Expect(password).To(HavePrefix(RedactedValuePrefix))
+	})
+
+	It("masks the whole last applied configuration annotation if it can't be parsed", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+
+		secret := newSecret(map[string]interface{}{"password": "cGFzc3dvcmQ="})
+		secret.SetAnnotations(map[string]string{annotationKeyLastAppliedConfiguration: `{"data": {"password": "cGFzc3dvcmQ="`})
+
+		result := redactor.Redact(secret)
+
+		Expect(result.GetAnnotations()[annotationKeyLastAppliedConfiguration]).To(HavePrefix(RedactedValuePrefix))
+	})
+})
+
+var _ = Describe("TextRedactor", func() {
+	It("masks sensitive values in text the same way as in objects", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+		secret := newSecret(map[string]interface{}{"password": "cGFzc3dvcmQ="})
+
+		textRedactor := redactor.TextRedactor(secret)
+		text := textRedactor.Redact(`Secret "secret" is invalid: data[password]: Invalid value: "cGFzc3dvcmQ="`)
+
+		redactedPassword, _, _ := unstructured.NestedString(redactor.Redact(secret).Object, "data", "password")
+		Expect(text).NotTo(ContainSubstring("cGFzc3dvcmQ="))
+		Expect(text).To(ContainSubstring(redactedPassword))
+		Expect(text).To(ContainSubstring("data[password]"))
+	})
+
+	It("doesn't mask too short values", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+
+		textRedactor := redactor.TextRedactor(newSecret(map[string]interface{}{"flag": "MQ="}))
+
+		Expect(textRedactor.Redact("value MQ= is fine")).To(Equal("value MQ= is fine"))
+	})
+
+	It("masks values containing other values completely", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+
+		textRedactor := redactor.TextRedactor(newSecret(map[string]interface{}{"a": "c2VjcmV0", "b": "c2VjcmV0LWxvbmdlcg=="}))
+
+		Expect(textRedactor.Redact("c2VjcmV0LWxvbmdlcg==")).NotTo(ContainSubstring("c2VjcmV0"))
+	})
+
+	It("keeps the original error wrapped", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+		textRedactor := redactor.TextRedactor(newSecret(map[string]interface{}{"password": "cGFzc3dvcmQ="}))
+		origErr := errors.New("invalid value cGFzc3dvcmQ=")
+
+		err := textRedactor.RedactError(origErr)
+
+		Expect(err.Error()).NotTo(ContainSubstring("cGFzc3dvcmQ="))
+		Expect(errors.Is(err, origErr)).To(BeTrue())
+	})
+
+	It("returns text as is when nil", func() {
+		var textRedactor *TextRedactor
+
+		Expect(textRedactor.Redact("cGFzc3dvcmQ=")).To(Equal("cGFzc3dvcmQ="))
+		Expect(textRedactor.RedactError(nil)).To(BeNil())
+	})
+
+	It("doesn't mask anything without sensitive values", func() {
+		redactor := NewRedactor(nil, RedactorOptions{})
+
+		textRedactor := redactor.TextRedactor(newConfigMap(map[string]interface{}{"user": "admin"}))
+
+		Expect(textRedactor.Redact(strings.Repeat("admin ", 2))).To(Equal("admin admin "))
+	})
+})
+
+func newSecret(data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "secret"},
+		"data":       data,
+	}}
+}
+
+func newConfigMap(data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "config"},
+		"data":       data,
+	}}
+}
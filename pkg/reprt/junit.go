@@ -0,0 +1,197 @@
+package reprt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+)
+
+type junitTestSuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	Name       string            `xml:"name,attr"`
+	Tests      int               `xml:"tests,attr"`
+	Failures   int               `xml:"failures,attr"`
+	Skipped    int               `xml:"skipped,attr"`
+	Time       string            `xml:"time,attr"`
+	TestSuites []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties []*junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []*junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// Represents worthy operations as JUnit test cases: completed operations pass, failed
+// operations fail with the operation error and canceled operations are skipped. Failed
+// readiness tracking test cases get the captured logs of the tracked resource attached.
+func (r *Report) JUnit() ([]byte, error) {
+	suite := &junitTestSuite{
+		Name: fmt.Sprintf("%s/%s", r.release.Namespace(), r.release.Name()),
+		Time: formatJUnitDuration(r.totalDuration),
+		Properties: []*junitProperty{
+			{Name: "release", Value: r.release.Name()},
+			{Name: "namespace", Value: r.release.Namespace()},
+			{Name: "revision", Value: fmt.Sprint(r.release.Revision())},
+			{Name: "status", Value: string(r.release.Status())},
+		},
+	}
+
+	if r.interruptReason != "" {
+		suite.Properties = append(suite.Properties, &junitProperty{Name: "interruptReason", Value: r.interruptReason})
+	}
+
+	for _, op := range r.completedOps {
+		suite.TestCases = append(suite.TestCases, newJUnitTestCase(op))
+	}
+
+	for _, op := range r.failedOps {
+		testCase := newJUnitTestCase(op)
+
+		message := "operation failed"
+		if op.Err() != nil {
+			message = r.textRedactor.Redact(op.Err().Error())
+		}
+
+		testCase.Failure = &junitFailure{
+			Message: message,
+			Type:    string(op.Type()),
+			Text:    message,
+		}
+
+		if op.Type() == opertn.TypeTrackResourceReadinessOperation {
+			testCase.SystemOut = r.resourceLogs(op)
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Failures++
+	}
+
+	for _, op := range r.canceledOps {
+		testCase := newJUnitTestCase(op)
+		testCase.Skipped = &junitSkipped{Message: "operation canceled"}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Skipped++
+	}
+
+	suite.Tests = len(suite.TestCases)
+
+	suites := &junitTestSuites{
+		Name:       suite.Name,
+		Tests:      suite.Tests,
+		Failures:   suite.Failures,
+		Skipped:    suite.Skipped,
+		Time:       suite.Time,
+		TestSuites: []*junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling JUnit report: %w", err)
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func (r *Report) SaveJUnit(path string) error {
+	data, err := r.JUnit()
+	if err != nil {
+		return fmt.Errorf("error constructing JUnit report: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing JUnit report file at %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func (r *Report) resourceLogs(op opertn.Operation) string {
+	if r.logStore == nil {
+		return ""
+	}
+
+	res := operationResourceV3(op)
+	if res == nil {
+		return ""
+	}
+
+	var result strings.Builder
+	r.logStore.RTransaction(func(ls *logstore.LogStore) {
+		for _, crl := range ls.ResourcesLogs() {
+			crl.RTransaction(func(rl *logstore.ResourceLogs) {
+				if rl.Name() != res.Name ||
+					rl.GroupVersionKind().Kind != res.Kind ||
+					rl.GroupVersionKind().Group != res.Group ||
+					(res.Namespace != "" && rl.Namespace() != res.Namespace) {
+					return
+				}
+
+				logLines := rl.LogLines()
+
+				sources := make([]string, 0, len(logLines))
+				for source := range logLines {
+					sources = append(sources, source)
+				}
+				sort.Strings(sources)
+
+				for _, source := range sources {
+					fmt.Fprintf(&result, "Logs for %s/%s, %s:\n", rl.GroupVersionKind().Kind, rl.Name(), source)
+					for _, logLine := range logLines[source] {
+						result.WriteString(logLine.Line + "\n")
+					}
+				}
+			})
+		}
+	})
+
+	return r.textRedactor.Redact(result.String())
+}
+
+func newJUnitTestCase(op opertn.Operation) *junitTestCase {
+	return &junitTestCase{
+		Name:      op.HumanID(),
+		ClassName: string(op.Type()),
+		Time:      formatJUnitDuration(opertn.Duration(op)),
+	}
+}
+
+func formatJUnitDuration(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
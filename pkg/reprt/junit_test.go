@@ -0,0 +1,64 @@
+package reprt_test
+
+import (
+	"encoding/xml"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+)
+
+type junitReport struct {
+	Tests      int `xml:"tests,attr"`
+	Failures   int `xml:"failures,attr"`
+	Skipped    int `xml:"skipped,attr"`
+	TestSuites []struct {
+		Name      string `xml:"name,attr"`
+		TestCases []struct {
+			Name      string `xml:"name,attr"`
+			ClassName string `xml:"classname,attr"`
+			Time      string `xml:"time,attr"`
+			Failure   *struct {
+				Message string `xml:"message,attr"`
+			} `xml:"failure"`
+			Skipped *struct {
+				Message string `xml:"message,attr"`
+			} `xml:"skipped"`
+		} `xml:"testcase"`
+	} `xml:"testsuite"`
+}
+
+var _ = Describe("JUnit report", func() {
+	It("should represent operations as test cases", func() {
+		data, err := newTestReport(0).JUnit()
+		Expect(err).To(Succeed())
+		Expect(string(data)).To(HavePrefix(xml.Header))
+
+		var report junitReport
+		Expect(xml.Unmarshal(data, &report)).To(Succeed())
+
+		Expect(report.Tests).To(Equal(3))
+		Expect(report.Failures).To(Equal(1))
+		Expect(report.Skipped).To(Equal(1))
+		Expect(report.TestSuites).To(HaveLen(1))
+
+		suite := report.TestSuites[0]
+		Expect(suite.Name).To(Equal("test-namespace/test-release"))
+		Expect(suite.TestCases).To(HaveLen(3))
+
+		completed, failed, canceled := suite.TestCases[0], suite.TestCases[1], suite.TestCases[2]
+
+		Expect(completed.ClassName).To(Equal(opertn.TypeCreateResourceOperation))
+		Expect(completed.Time).To(Equal("1.500"))
+		Expect(completed.Failure).To(BeNil())
+		Expect(completed.Skipped).To(BeNil())
+
+		Expect(failed.ClassName).To(Equal(opertn.TypeTrackResourceReadinessOperation))
+		Expect(failed.Failure).NotTo(BeNil())
+		Expect(failed.Failure.Message).To(Equal("job failed"))
+
+		Expect(canceled.Skipped).NotTo(BeNil())
+		Expect(canceled.Skipped.Message).To(Equal("operation canceled"))
+	})
+})
@@ -6,19 +6,34 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/gookit/color"
 	"github.com/samber/lo"
 
 	"github.com/werf/3p-helm-for-werf-helm/pkg/release"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	kdutil "github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
 
 	"github.com/werf/nelm-for-werf-helm/pkg/log"
 	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
 	"github.com/werf/nelm-for-werf-helm/pkg/rls"
 	"github.com/werf/nelm-for-werf-helm/pkg/utls"
 )
 
-func NewReport(completedOps, canceledOps, failedOps []opertn.Operation, release *rls.Release) *Report {
+const slowestOpsLimit = 5
+
+const (
+	ReportVersionV2      = 2
+	ReportVersionV3      = 3
+	DefaultReportVersion = ReportVersionV2
+)
+
+var SupportedReportVersions = []int{ReportVersionV2, ReportVersionV3}
+
+func NewReport(completedOps, canceledOps, failedOps []opertn.Operation, release *rls.Release, opts ReportOptions) *Report {
 	sort.Slice(completedOps, func(i, j int) bool {
 		return completedOps[i].HumanID() < completedOps[j].HumanID()
 	})
@@ -29,24 +44,90 @@ func NewReport(completedOps, canceledOps, failedOps []opertn.Operation, release
 		return failedOps[i].HumanID() < failedOps[j].HumanID()
 	})
 
+	var totalDuration time.Duration
+	if len(opts.ExecutedOperations) > 0 {
+		startedAt := lo.MinBy(opts.ExecutedOperations, func(a, b opertn.Operation) bool {
+			return a.StartedAt().Before(b.StartedAt())
+		}).StartedAt()
+		endedAt := lo.MaxBy(opts.ExecutedOperations, func(a, b opertn.Operation) bool {
+			return a.EndedAt().After(b.EndedAt())
+		}).EndedAt()
+
+		totalDuration = endedAt.Sub(startedAt)
+	}
+
+	slowestOps := lo.Filter(opts.ExecutedOperations, func(op opertn.Operation, _ int) bool {
+		return !op.Empty()
+	})
+	sort.SliceStable(slowestOps, func(i, j int) bool {
+		return opertn.Duration(slowestOps[i]) > opertn.Duration(slowestOps[j])
+	})
+	if len(slowestOps) > slowestOpsLimit {
+		slowestOps = slowestOps[:slowestOpsLimit]
+	}
+
+	retriedOps := lo.Filter(opts.ExecutedOperations, func(op opertn.Operation, _ int) bool {
+		return op.Attempts() > 1
+	})
+	sort.Slice(retriedOps, func(i, j int) bool {
+		return retriedOps[i].HumanID() < retriedOps[j].HumanID()
+	})
+
+	version := opts.Version
+	if version == 0 {
+		version = DefaultReportVersion
+	}
+
 	return &Report{
-		completedOps: completedOps,
-		failedOps:    failedOps,
-		canceledOps:  canceledOps,
-		release:      release,
+		version:         version,
+		allOps:          opts.Operations,
+		logStore:        opts.LogStore,
+		completedOps:    completedOps,
+		failedOps:       failedOps,
+		canceledOps:     canceledOps,
+		release:         release,
+		interruptReason: opts.TextRedactor.Redact(opts.InterruptReason),
+		textRedactor:    opts.TextRedactor,
+		totalDuration:   totalDuration,
+		criticalPath:    opts.CriticalPath,
+		slowestOps:      slowestOps,
+		retriedOps:      retriedOps,
 	}
 }
 
+type ReportOptions struct {
+	CriticalPath       []opertn.Operation
+	ExecutedOperations []opertn.Operation
+	InterruptReason    string
+	// Logs of the tracked resources, attached to failed readiness operations in JUnit report.
+	LogStore *kdutil.Concurrent[*logstore.LogStore]
+	// All operations of the plan, used to summarize tracking outcomes.
+	Operations []opertn.Operation
+	// Masks sensitive values in operation errors and resource logs.
+	TextRedactor *redactr.TextRedactor
+	// Version of the JSON report, DefaultReportVersion if not set.
+	Version int
+}
+
 type Report struct {
-	completedOps []opertn.Operation
-	failedOps    []opertn.Operation
-	canceledOps  []opertn.Operation
-	release      *rls.Release
+	version         int
+	allOps          []opertn.Operation
+	logStore        *kdutil.Concurrent[*logstore.LogStore]
+	completedOps    []opertn.Operation
+	failedOps       []opertn.Operation
+	canceledOps     []opertn.Operation
+	release         *rls.Release
+	interruptReason string
+	textRedactor    *redactr.TextRedactor
+	totalDuration   time.Duration
+	criticalPath    []opertn.Operation
+	slowestOps      []opertn.Operation
+	retriedOps      []opertn.Operation
 }
 
 func (r *Report) Print(ctx context.Context) {
 	totalOpsLen := len(r.completedOps) + len(r.failedOps) + len(r.canceledOps)
-	if totalOpsLen == 0 {
+	if totalOpsLen == 0 && r.interruptReason == "" {
 		return
 	}
 
@@ -73,27 +154,66 @@ func (r *Report) Print(ctx context.Context) {
 			}
 		})
 	}
+
+	if r.interruptReason != "" {
+		log.Default.InfoBlock(ctx, failedStyle("Interrupted")).Do(func() {
+			log.Default.Info(ctx, utls.Capitalize(r.interruptReason))
+		})
+	}
+
+	if summary := r.Summary(); summary != "" {
+		log.Default.InfoBlock(ctx, summaryStyle("Summary")).Do(func() {
+			log.Default.Info(ctx, strings.TrimSuffix(summary, "\n"))
+		})
+	}
+}
+
+// Plain text summary of how long the execution took and what delayed it.
+func (r *Report) Summary() string {
+	if r.totalDuration == 0 && len(r.criticalPath) == 0 && len(r.slowestOps) == 0 && len(r.retriedOps) == 0 {
+		return ""
+	}
+
+	var summary strings.Builder
+
+	fmt.Fprintf(&summary, "Total duration: %s\n", formatDuration(r.totalDuration))
+
+	if len(r.criticalPath) > 0 {
+		summary.WriteString("Critical path:\n")
+		for _, op := range r.criticalPath {
+			fmt.Fprintf(&summary, "  %s (%s%s)\n", utls.Capitalize(op.HumanID()), formatDuration(opertn.Duration(op)), formatAttempts(op))
+		}
+	}
+
+	if len(r.slowestOps) > 0 {
+		summary.WriteString("Slowest operations:\n")
+		for _, op := range r.slowestOps {
+			fmt.Fprintf(&summary, "  %s (%s%s)\n", utls.Capitalize(op.HumanID()), formatDuration(opertn.Duration(op)), formatAttempts(op))
+		}
+	}
+
+	if len(r.retriedOps) > 0 {
+		summary.WriteString("Retried operations:\n")
+		for _, op := range r.retriedOps {
+			fmt.Fprintf(&summary, "  %s (%s%s)\n", utls.Capitalize(op.HumanID()), formatDuration(opertn.Duration(op)), formatAttempts(op))
+		}
+	}
+
+	return summary.String()
 }
 
 func (r *Report) JSON() ([]byte, error) {
-	reportv2 := reportV2{
-		Version:   2,
-		Release:   r.release.Name(),
-		Namespace: r.release.Namespace(),
-		Revision:  r.release.Revision(),
-		Status:    r.release.Status(),
-		CompletedOperations: lo.Map(r.completedOps, func(op opertn.Operation, _ int) string {
-			return op.ID()
-		}),
-		CanceledOperations: lo.Map(r.canceledOps, func(op opertn.Operation, _ int) string {
-			return op.ID()
-		}),
-		FailedOperations: lo.Map(r.failedOps, func(op opertn.Operation, _ int) string {
-			return op.ID()
-		}),
+	var report any
+	switch r.version {
+	case ReportVersionV2:
+		report = r.reportV2()
+	case ReportVersionV3:
+		report = r.reportV3()
+	default:
+		return nil, fmt.Errorf("unsupported report version %d", r.version)
 	}
 
-	data, err := json.MarshalIndent(reportv2, "", "\t")
+	data, err := json.MarshalIndent(report, "", "\t")
 	if err != nil {
 		return nil, fmt.Errorf("error marshalling report: %w", err)
 	}
@@ -114,6 +234,18 @@ func (r *Report) Save(path string) error {
 	return nil
 }
 
+func formatDuration(duration time.Duration) string {
+	return duration.Round(time.Millisecond).String()
+}
+
+func formatAttempts(op opertn.Operation) string {
+	if op.Attempts() <= 1 {
+		return ""
+	}
+
+	return fmt.Sprintf(", %d attempts", op.Attempts())
+}
+
 func completedStyle(text string) string {
 	return color.Style{color.Bold, color.Green}.Render(text)
 }
@@ -126,13 +258,73 @@ func failedStyle(text string) string {
 	return color.Style{color.Bold, color.Red}.Render(text)
 }
 
+func summaryStyle(text string) string {
+	return color.Style{color.Bold, color.Blue}.Render(text)
+}
+
+func (r *Report) reportV2() *reportV2 {
+	report := &reportV2{
+		Version:   ReportVersionV2,
+		Release:   r.release.Name(),
+		Namespace: r.release.Namespace(),
+		Revision:  r.release.Revision(),
+		Status:    r.release.Status(),
+		CompletedOperations: lo.Map(r.completedOps, func(op opertn.Operation, _ int) string {
+			return op.ID()
+		}),
+		CanceledOperations: lo.Map(r.canceledOps, func(op opertn.Operation, _ int) string {
+			return op.ID()
+		}),
+		FailedOperations: lo.Map(r.failedOps, func(op opertn.Operation, _ int) string {
+			return op.ID()
+		}),
+		Interrupted:       r.interruptReason != "",
+		InterruptReason:   r.interruptReason,
+		CriticalPath:      lo.Map(r.criticalPath, newOperationTiming),
+		SlowestOperations: lo.Map(r.slowestOps, newOperationTiming),
+		RetriedOperations: lo.Map(r.retriedOps, newOperationTiming),
+	}
+
+	if r.totalDuration > 0 {
+		report.Duration = formatDuration(r.totalDuration)
+	}
+
+	return report
+}
+
+// Kept as is for backward compatibility. Operations fields share the same JSON key, so
+// encoding/json drops all of them, use ReportV3 instead.
 type reportV2 struct {
-	Version             int            `json:"version,omitempty"`
-	Release             string         `json:"release,omitempty"`
-	Namespace           string         `json:"namespace,omitempty"`
-	Revision            int            `json:"revision,omitempty"`
-	Status              release.Status `json:"status,omitempty"`
-	CompletedOperations []string       `json:"operations,omitempty"`
-	CanceledOperations  []string       `json:"operations,omitempty"`
-	FailedOperations    []string       `json:"operations,omitempty"`
+	Version             int               `json:"version,omitempty"`
+	Release             string            `json:"release,omitempty"`
+	Namespace           string            `json:"namespace,omitempty"`
+	Revision            int               `json:"revision,omitempty"`
+	Status              release.Status    `json:"status,omitempty"`
+	CompletedOperations []string          `json:"operations,omitempty"`
+	CanceledOperations  []string          `json:"operations,omitempty"`
+	FailedOperations    []string          `json:"operations,omitempty"`
+	Interrupted         bool              `json:"interrupted,omitempty"`
+	InterruptReason     string            `json:"interruptReason,omitempty"`
+	Duration            string            `json:"duration,omitempty"`
+	CriticalPath        []operationTiming `json:"criticalPath,omitempty"`
+	SlowestOperations   []operationTiming `json:"slowestOperations,omitempty"`
+	RetriedOperations   []operationTiming `json:"retriedOperations,omitempty"`
+}
+
+func newOperationTiming(op opertn.Operation, _ int) operationTiming {
+	return operationTiming{
+		ID:        op.ID(),
+		StartedAt: op.StartedAt(),
+		EndedAt:   op.EndedAt(),
+		Duration:  formatDuration(opertn.Duration(op)),
+		Attempts:  op.Attempts(),
+	}
+}
+
+type operationTiming struct {
+	ID        string    `json:"id"`
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt"`
+	Duration  string    `json:"duration"`
+	Attempts  int       `json:"attempts"`
 }
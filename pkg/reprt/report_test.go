@@ -0,0 +1,158 @@
+package reprt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+	"github.com/werf/nelm-for-werf-helm/pkg/reprt"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+)
+
+var startedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type fakeOperation struct {
+	id        string
+	opType    opertn.Type
+	status    opertn.Status
+	err       error
+	attempts  int
+	startedAt time.Time
+	endedAt   time.Time
+}
+
+func (o *fakeOperation) Execute(ctx context.Context) error { return nil }
+func (o *fakeOperation) ID() string                        { return string(o.opType) + "/" + o.id }
+func (o *fakeOperation) HumanID() string                   { return string(o.opType) + ": " + o.id }
+func (o *fakeOperation) Status() opertn.Status             { return o.status }
+func (o *fakeOperation) Type() opertn.Type                 { return o.opType }
+func (o *fakeOperation) Empty() bool                       { return false }
+func (o *fakeOperation) StartedAt() time.Time              { return o.startedAt }
+func (o *fakeOperation) EndedAt() time.Time                { return o.endedAt }
+func (o *fakeOperation) Attempts() int                     { return o.attempts }
+func (o *fakeOperation) Err() error                        { return o.err }
+func (o *fakeOperation) SetErr(err error)                  { o.err = err }
+
+type fakeResourceOperation struct {
+	fakeOperation
+
+	resID *resrcid.ResourceID
+}
+
+func (o *fakeResourceOperation) ResourceID() *resrcid.ResourceID { return o.resID }
+
+// The resource ID contains colons in the name on purpose: it must be taken as is instead of
+// being parsed from the operation ID.
+func newResourceOperation(opType opertn.Type, status opertn.Status, err error) *fakeResourceOperation {
+	resID := resrcid.NewResourceID("my:job", "test-namespace", schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, resrcid.ResourceIDOptions{})
+
+	return &fakeResourceOperation{
+		fakeOperation: fakeOperation{
+			id:        resID.ID(),
+			opType:    opType,
+			status:    status,
+			err:       err,
+			attempts:  1,
+			startedAt: startedAt,
+			endedAt:   startedAt.Add(1500 * time.Millisecond),
+		},
+		resID: resID,
+	}
+}
+
+func newTestRelease() *rls.Release {
+	rel, err := rls.NewRelease("test-release", "test-namespace", 2, nil, nil, nil, nil, "", rls.ReleaseOptions{
+		Status:     release.StatusFailed,
+		DeployType: common.DeployTypeUpgrade,
+	})
+	Expect(err).To(Succeed())
+
+	return rel
+}
+
+func newTestReport(version int) *reprt.Report {
+	completedOp := newResourceOperation(opertn.TypeCreateResourceOperation, opertn.StatusCompleted, nil)
+	failedOp := newResourceOperation(opertn.TypeTrackResourceReadinessOperation, opertn.StatusFailed, errors.New("job failed"))
+	canceledOp := &fakeOperation{id: "pending", opType: opertn.TypeStageOperation, status: opertn.StatusUnknown}
+	allOps := []opertn.Operation{completedOp, failedOp, canceledOp}
+
+	return reprt.NewReport(
+		[]opertn.Operation{completedOp},
+		[]opertn.Operation{canceledOp},
+		[]opertn.Operation{failedOp},
+		newTestRelease(),
+		reprt.ReportOptions{
+			ExecutedOperations: []opertn.Operation{completedOp, failedOp},
+			Operations:         allOps,
+			Version:            version,
+		},
+	)
+}
+
+var _ = Describe("Report", func() {
+	It("should produce report of version 2 by default", func() {
+		data, err := newTestReport(0).JSON()
+		Expect(err).To(Succeed())
+
+		var report map[string]any
+		Expect(json.Unmarshal(data, &report)).To(Succeed())
+		Expect(report["version"]).To(BeEquivalentTo(reprt.ReportVersionV2))
+		Expect(report).NotTo(HaveKey("completedOperations"))
+	})
+
+	It("should produce report of version 3 if requested", func() {
+		data, err := newTestReport(reprt.ReportVersionV3).JSON()
+		Expect(err).To(Succeed())
+
+		var report reprt.ReportV3
+		Expect(json.Unmarshal(data, &report)).To(Succeed())
+
+		Expect(report.Version).To(Equal(reprt.ReportVersionV3))
+		Expect(report.Release).To(Equal("test-release"))
+		Expect(report.Namespace).To(Equal("test-namespace"))
+		Expect(report.Revision).To(Equal(2))
+		Expect(report.Status).To(Equal(release.StatusFailed))
+		Expect(report.DeployType).To(Equal(common.DeployTypeUpgrade))
+		Expect(report.Duration).To(Equal("1.5s"))
+
+		expectedResource := &reprt.ResourceV3{
+			Group:     "batch",
+			Kind:      "Job",
+			Namespace: "test-namespace",
+			Name:      "my:job",
+		}
+
+		Expect(report.CompletedOperations).To(HaveLen(1))
+		Expect(report.CompletedOperations[0].Status).To(Equal(reprt.OperationStatusV3Completed))
+		Expect(report.CompletedOperations[0].Resource).To(Equal(expectedResource))
+		Expect(report.CompletedOperations[0].Duration).To(Equal("1.5s"))
+
+		Expect(report.FailedOperations).To(HaveLen(1))
+		Expect(report.FailedOperations[0].Status).To(Equal(reprt.OperationStatusV3Failed))
+		Expect(report.FailedOperations[0].Error).To(Equal("job failed"))
+		Expect(report.FailedOperations[0].Resource).To(Equal(expectedResource))
+
+		Expect(report.CanceledOperations).To(HaveLen(1))
+		Expect(report.CanceledOperations[0].Status).To(Equal(reprt.OperationStatusV3Canceled))
+		Expect(report.CanceledOperations[0].Resource).To(BeNil())
+
+		Expect(report.Tracking).To(Equal(&reprt.TrackingSummaryV3{
+			Readiness: reprt.TrackingOutcomesV3{Failed: 1},
+		}))
+	})
+
+	It("should fail on unsupported report version", func() {
+		_, err := newTestReport(1).JSON()
+		Expect(err).To(HaveOccurred())
+	})
+})
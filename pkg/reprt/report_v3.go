@@ -0,0 +1,193 @@
+package reprt
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/opertn"
+)
+
+// ReportV3 is the schema of the report of version 3. Import it to parse saved reports.
+type ReportV3 struct {
+	Version             int                `json:"version"`
+	Release             string             `json:"release"`
+	Namespace           string             `json:"namespace"`
+	Revision            int                `json:"revision"`
+	Status              release.Status     `json:"status"`
+	DeployType          common.DeployType  `json:"deployType,omitempty"`
+	Chart               *ChartV3           `json:"chart,omitempty"`
+	Interrupted         bool               `json:"interrupted"`
+	InterruptReason     string             `json:"interruptReason,omitempty"`
+	Duration            string             `json:"duration,omitempty"`
+	CompletedOperations []*OperationV3     `json:"completedOperations"`
+	CanceledOperations  []*OperationV3     `json:"canceledOperations"`
+	FailedOperations    []*OperationV3     `json:"failedOperations"`
+	CriticalPath        []*OperationV3     `json:"criticalPath,omitempty"`
+	SlowestOperations   []*OperationV3     `json:"slowestOperations,omitempty"`
+	RetriedOperations   []*OperationV3     `json:"retriedOperations,omitempty"`
+	Tracking            *TrackingSummaryV3 `json:"tracking,omitempty"`
+}
+
+type ChartV3 struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
+type OperationV3 struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	HumanID   string      `json:"humanId"`
+	Resource  *ResourceV3 `json:"resource,omitempty"`
+	Status    string      `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	StartedAt *time.Time  `json:"startedAt,omitempty"`
+	EndedAt   *time.Time  `json:"endedAt,omitempty"`
+	Duration  string      `json:"duration,omitempty"`
+	Attempts  int         `json:"attempts,omitempty"`
+}
+
+type ResourceV3 struct {
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+type TrackingSummaryV3 struct {
+	Readiness TrackingOutcomesV3 `json:"readiness"`
+	Presence  TrackingOutcomesV3 `json:"presence"`
+	Absence   TrackingOutcomesV3 `json:"absence"`
+}
+
+type TrackingOutcomesV3 struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Canceled  int `json:"canceled"`
+}
+
+const (
+	OperationStatusV3Completed = "completed"
+	OperationStatusV3Failed    = "failed"
+	OperationStatusV3Canceled  = "canceled"
+)
+
+func (r *Report) reportV3() *ReportV3 {
+	report := &ReportV3{
+		Version:             ReportVersionV3,
+		Release:             r.release.Name(),
+		Namespace:           r.release.Namespace(),
+		Revision:            r.release.Revision(),
+		Status:              r.release.Status(),
+		DeployType:          r.release.DeployType(),
+		Interrupted:         r.interruptReason != "",
+		InterruptReason:     r.interruptReason,
+		CompletedOperations: lo.Map(r.completedOps, r.newOperationV3),
+		CanceledOperations:  lo.Map(r.canceledOps, r.newOperationV3),
+		FailedOperations:    lo.Map(r.failedOps, r.newOperationV3),
+		CriticalPath:        lo.Map(r.criticalPath, r.newOperationV3),
+		SlowestOperations:   lo.Map(r.slowestOps, r.newOperationV3),
+		RetriedOperations:   lo.Map(r.retriedOps, r.newOperationV3),
+	}
+
+	if chart := r.release.LegacyChart(); chart != nil && chart.Metadata != nil {
+		report.Chart = &ChartV3{
+			Name:       chart.Metadata.Name,
+			Version:    chart.Metadata.Version,
+			AppVersion: chart.Metadata.AppVersion,
+		}
+	}
+
+	if r.totalDuration > 0 {
+		report.Duration = formatDuration(r.totalDuration)
+	}
+
+	if len(r.allOps) > 0 {
+		report.Tracking = newTrackingSummaryV3(r.allOps)
+	}
+
+	return report
+}
+
+func (r *Report) newOperationV3(op opertn.Operation, _ int) *OperationV3 {
+	result := &OperationV3{
+		ID:       op.ID(),
+		Type:     string(op.Type()),
+		HumanID:  op.HumanID(),
+		Resource: operationResourceV3(op),
+		Attempts: op.Attempts(),
+	}
+
+	switch op.Status() {
+	case opertn.StatusCompleted:
+		result.Status = OperationStatusV3Completed
+	case opertn.StatusFailed:
+		result.Status = OperationStatusV3Failed
+	default:
+		result.Status = OperationStatusV3Canceled
+	}
+
+	if op.Err() != nil {
+		result.Error = r.textRedactor.Redact(op.Err().Error())
+	}
+
+	if !op.StartedAt().IsZero() {
+		result.StartedAt = lo.ToPtr(op.StartedAt())
+	}
+
+	if !op.EndedAt().IsZero() {
+		result.EndedAt = lo.ToPtr(op.EndedAt())
+		result.Duration = formatDuration(opertn.Duration(op))
+	}
+
+	return result
+}
+
+func operationResourceV3(op opertn.Operation) *ResourceV3 {
+	resOp, ok := op.(opertn.ResourceOperation)
+	if !ok {
+		return nil
+	}
+
+	resID := resOp.ResourceID()
+
+	return &ResourceV3{
+		Group:     resID.GroupVersionKind().Group,
+		Kind:      resID.GroupVersionKind().Kind,
+		Namespace: resID.Namespace(),
+		Name:      resID.Name(),
+	}
+}
+
+func newTrackingSummaryV3(ops []opertn.Operation) *TrackingSummaryV3 {
+	summary := &TrackingSummaryV3{}
+
+	for _, op := range ops {
+		var outcomes *TrackingOutcomesV3
+		switch op.Type() {
+		case opertn.TypeTrackResourceReadinessOperation:
+			outcomes = &summary.Readiness
+		case opertn.TypeTrackResourcePresenceOperation:
+			outcomes = &summary.Presence
+		case opertn.TypeTrackResourceAbsenceOperation:
+			outcomes = &summary.Absence
+		default:
+			continue
+		}
+
+		switch op.Status() {
+		case opertn.StatusCompleted:
+			outcomes.Succeeded++
+		case opertn.StatusFailed:
+			outcomes.Failed++
+		default:
+			outcomes.Canceled++
+		}
+	}
+
+	return summary
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/common"
 	"github.com/werf/nelm-for-werf-helm/pkg/depnd"
 	"github.com/werf/nelm-for-werf-helm/pkg/depnddetctr"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
 	"github.com/werf/nelm-for-werf-helm/pkg/utls"
 
 	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/rollout/multitrack"
@@ -100,6 +102,24 @@ var annotationKeyPatternWeight = regexp.MustCompile(`^werf.io/weight$`)
 var annotationKeyHumanHookWeight = "helm.sh/hook-weight"
 var annotationKeyPatternHookWeight = regexp.MustCompile(`^helm.sh/hook-weight$`)
 
+var annotationKeyHumanSensitivePaths = redactr.AnnotationKeySensitivePaths
+var annotationKeyPatternSensitivePaths = regexp.MustCompile(`^werf.io/sensitive-paths$`)
+
+var annotationKeyHumanAdopt = "werf.io/adopt"
+var annotationKeyPatternAdopt = regexp.MustCompile(`^werf.io/adopt$`)
+
+var annotationKeyHumanReadyWhen = "werf.io/ready-when"
+var annotationKeyPatternReadyWhen = regexp.MustCompile(`^werf.io/ready-when$`)
+
+var annotationKeyHumanFailedWhen = "werf.io/failed-when"
+var annotationKeyPatternFailedWhen = regexp.MustCompile(`^werf.io/failed-when$`)
+
+var annotationKeyHumanTransferTo = "werf.io/transfer-to"
+var annotationKeyPatternTransferTo = regexp.MustCompile(`^werf.io/transfer-to$`)
+
+// Set on resources stored in the release to record their previous owner on adoption.
+const AnnotationKeyAdoptedFrom = "werf.io/adopted-from"
+
 var annotationKeyHumanDeployDependency = "werf.io/deploy-dependency-<name>"
 var annotationKeyPatternDeployDependency = regexp.MustCompile(`^werf.io/deploy-dependency-(?P<id>.+)$`)
 
@@ -109,6 +129,9 @@ var annotationKeyPatternDependency = regexp.MustCompile(`^(?P<id>.+).dependency.
 var annotationKeyHumanExternalDependency = "<name>.external-dependency.werf.io"
 var annotationKeyPatternExternalDependency = regexp.MustCompile(`^(?P<id>.+).external-dependency.werf.io$`)
 
+var annotationKeyHumanExternalDeployDependency = "werf.io/external-dependency-<name>"
+var annotationKeyPatternExternalDeployDependency = regexp.MustCompile(`^werf.io/external-dependency-(?P<id>.+)$`)
+
 var annotationKeyHumanLegacyExternalDependencyResource = "<name>.external-dependency.werf.io/resource"
 var annotationKeyPatternLegacyExternalDependencyResource = regexp.MustCompile(`^(?P<id>.+).external-dependency.werf.io/resource$`)
 
@@ -252,6 +275,26 @@ func validateReplicasOnCreation(unstruct *unstructured.Unstructured) error {
 	return nil
 }
 
+func validateSensitivePaths(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternSensitivePaths); found {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty comma-separated list of paths", value, key)
+		}
+
+		for _, path := range strings.Split(value, ",") {
+			if strings.TrimSpace(path) == "" {
+				return fmt.Errorf("invalid value %q for annotation %q, one of the comma-separated values is empty", value, key)
+			}
+
+			if err := redactr.ValidatePath(path); err != nil {
+				return fmt.Errorf("invalid value %q for annotation %q: %w", value, key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func validateTrack(unstruct *unstructured.Unstructured) error {
 	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternFailMode); found {
 		if value == "" {
@@ -428,6 +471,55 @@ func validateTrack(unstruct *unstructured.Unstructured) error {
 	return nil
 }
 
+func validateAdopt(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternAdopt); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty boolean value", value, key)
+		}
+
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q, expected boolean value", value, key)
+		}
+	}
+
+	return nil
+}
+
+func validateReadinessRule(unstruct *unstructured.Unstructured) error {
+	readyKey, readyValue, readyFound := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReadyWhen)
+	if readyFound {
+		if _, err := readinessPredicate(readyValue); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q: %w", readyValue, readyKey, err)
+		}
+	}
+
+	if failedKey, failedValue, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternFailedWhen); found {
+		if !readyFound {
+			return fmt.Errorf("annotation %q can't be used without annotation %q", failedKey, annotationKeyHumanReadyWhen)
+		}
+
+		if _, err := readinessPredicate(failedValue); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %q: %w", failedValue, failedKey, err)
+		}
+	}
+
+	return nil
+}
+
+func validateTransferTo(unstruct *unstructured.Unstructured) error {
+	if key, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternTransferTo); found {
+		if value == "" {
+			return fmt.Errorf("invalid value %q for annotation %q, expected non-empty string value", value, key)
+		}
+
+		if _, _, ok := parseTransferTo(value, ""); !ok {
+			return fmt.Errorf("invalid value %q for annotation %q, expected \"<release name>\" or \"<release namespace>/<release name>\"", value, key)
+		}
+	}
+
+	return nil
+}
+
 func validateDeployDependencies(unstruct *unstructured.Unstructured) error {
 	if annotations, found := FindAnnotationsOrLabelsByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternDeployDependency); found {
 		for key, value := range annotations {
@@ -555,6 +647,72 @@ func validateExternalDependencies(unstruct *unstructured.Unstructured) error {
 		}
 	}
 
+	if annotations, found := FindAnnotationsOrLabelsByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternExternalDeployDependency); found {
+		for key, value := range annotations {
+			keyMatches := annotationKeyPatternExternalDeployDependency.FindStringSubmatch(key)
+			if keyMatches == nil {
+				return fmt.Errorf("invalid key for annotation %q", key)
+			}
+
+			idSubexpIndex := annotationKeyPatternExternalDeployDependency.SubexpIndex("id")
+			if idSubexpIndex == -1 {
+				return fmt.Errorf("invalid regexp pattern %q for annotation %q", annotationKeyPatternExternalDeployDependency.String(), key)
+			}
+
+			if len(keyMatches) < idSubexpIndex+1 {
+				return fmt.Errorf("can't parse external dependency id from annotation key %q", key)
+			}
+
+			if value == "" {
+				return fmt.Errorf("invalid value %q for annotation %q, expected non-empty string value", value, key)
+			}
+
+			properties, err := utls.ParseProperties(context.TODO(), value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for annotation %q: %w", value, key, err)
+			}
+
+			for _, requiredKey := range []string{"version", "kind", "name", "state"} {
+				if _, found := properties[requiredKey]; !found {
+					return fmt.Errorf(`invalid value %q for annotation %q, %q property must be set`, value, key, requiredKey)
+				}
+			}
+
+			for propKey, propVal := range properties {
+				switch propKey {
+				case "group", "version", "kind", "name", "namespace":
+					switch pv := propVal.(type) {
+					case string:
+						if pv == "" {
+							return fmt.Errorf("invalid value %q for property %q, expected non-empty string value", pv, propKey)
+						}
+					case bool:
+						return fmt.Errorf("invalid boolean value %t for property %q, expected string value", pv, propKey)
+					default:
+						panic(fmt.Sprintf("unexpected type %T for property %q", pv, propKey))
+					}
+				case "state":
+					switch pv := propVal.(type) {
+					case string:
+						switch pv {
+						case string(depnd.ResourceStatePresent), string(depnd.ResourceStateReady), string(depnd.ResourceStateAbsent):
+						case "":
+							return fmt.Errorf("invalid value %q for property %q, expected non-empty string value", pv, propKey)
+						default:
+							return fmt.Errorf("unknown value %q for property %q", pv, propKey)
+						}
+					case bool:
+						return fmt.Errorf("invalid boolean value %t for property %q, expected string value", pv, propKey)
+					default:
+						panic(fmt.Sprintf("unexpected type %T for property %q", pv, propKey))
+					}
+				default:
+					return fmt.Errorf("unknown property %q in value of annotation %q", propKey, key)
+				}
+			}
+		}
+	}
+
 	if annotations, found := FindAnnotationsOrLabelsByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternLegacyExternalDependencyResource); found {
 		for key, value := range annotations {
 			keyMatches := annotationKeyPatternLegacyExternalDependencyResource.FindStringSubmatch(key)
@@ -920,6 +1078,118 @@ func adoptableBy(unstruct *unstructured.Unstructured, releaseName, releaseNamesp
 	return len(nonAdoptableReasons) == 0, nonAdoptableReason
 }
 
+func adopt(unstruct *unstructured.Unstructured) bool {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternAdopt)
+	if !found {
+		return false
+	}
+
+	adopt := lo.Must(strconv.ParseBool(value))
+
+	return adopt
+}
+
+func releaseOwner(unstruct *unstructured.Unstructured) (releaseName, releaseNamespace string, found bool) {
+	_, releaseName, found = FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReleaseName)
+	if !found {
+		return "", "", false
+	}
+
+	_, releaseNamespace, _ = FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReleaseNamespace)
+
+	return releaseName, releaseNamespace, true
+}
+
+func readinessRule(unstruct *unstructured.Unstructured) (rule *rdnsrule.ReadinessRule, set bool) {
+	_, readyValue, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternReadyWhen)
+	if !found {
+		return nil, false
+	}
+
+	var failed *rdnsrule.Predicate
+	if _, failedValue, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternFailedWhen); found {
+		failed = lo.Must(readinessPredicate(failedValue))
+	}
+
+	gvk := unstruct.GroupVersionKind()
+
+	return rdnsrule.NewReadinessRule(gvk.Group, gvk.Version, gvk.Kind, lo.Must(readinessPredicate(readyValue)), rdnsrule.ReadinessRuleOptions{
+		Failed: failed,
+	}), true
+}
+
+// Parses "condition=<type>[,status=<status>]" or "jsonpath=<expression>[,value=<value>]".
+func readinessPredicate(value string) (*rdnsrule.Predicate, error) {
+	if value == "" {
+		return nil, fmt.Errorf("expected non-empty string value")
+	}
+
+	properties, err := utls.ParseProperties(context.TODO(), value)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing properties: %w", err)
+	}
+
+	var opts rdnsrule.PredicateOptions
+	for propKey, propVal := range properties {
+		pv, ok := propVal.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid boolean value for property %q, expected string value", propKey)
+		}
+
+		switch propKey {
+		case "condition":
+			opts.ConditionType = pv
+		case "status":
+			opts.ConditionStatus = pv
+		case "jsonpath":
+			opts.JSONPath = pv
+		case "value":
+			opts.Value = pv
+		default:
+			return nil, fmt.Errorf("unknown property %q", propKey)
+		}
+	}
+
+	predicate, err := rdnsrule.NewPredicate(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing predicate: %w", err)
+	}
+
+	return predicate, nil
+}
+
+// If the release namespace is not specified in the annotation, defaultNamespace is used.
+func transferTo(unstruct *unstructured.Unstructured, defaultNamespace string) (releaseName, releaseNamespace string, set bool) {
+	_, value, found := FindAnnotationOrLabelByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternTransferTo)
+	if !found {
+		return "", "", false
+	}
+
+	return parseTransferTo(value, defaultNamespace)
+}
+
+func parseTransferTo(value, defaultNamespace string) (releaseName, releaseNamespace string, ok bool) {
+	split := strings.Split(value, "/")
+
+	switch len(split) {
+	case 1:
+		releaseName, releaseNamespace = split[0], defaultNamespace
+	case 2:
+		releaseNamespace, releaseName = split[0], split[1]
+		if releaseNamespace == "" {
+			return "", "", false
+		}
+	default:
+		return "", "", false
+	}
+
+	if releaseName == "" {
+		return "", "", false
+	}
+
+	return releaseName, releaseNamespace, true
+}
+
 func fixManagedFields(unstruct *unstructured.Unstructured) (changed bool, err error) {
 	managedFields := unstruct.GetManagedFields()
 	if len(managedFields) == 0 {
@@ -1237,7 +1507,7 @@ func externalDependencies(unstruct *unstructured.Unstructured, defaultNamespace
 
 	duplResult := lo.Values(lo.Assign(legacyExtDeps, deps))
 	uniqResult := lo.UniqBy(duplResult, func(d *depnd.ExternalDependency) string {
-		return d.ID()
+		return d.ID() + "/" + string(d.ResourceState)
 	})
 
 	return uniqResult, len(uniqResult) > 0, nil
@@ -1288,6 +1558,42 @@ func externalDeps(unstruct *unstructured.Unstructured, defaultNamespace string,
 		}
 	}
 
+	if annotations, found := FindAnnotationsOrLabelsByKeyPattern(unstruct.GetAnnotations(), annotationKeyPatternExternalDeployDependency); found {
+		for key, value := range annotations {
+			matches := annotationKeyPatternExternalDeployDependency.FindStringSubmatch(key)
+			idSubexpIndex := annotationKeyPatternExternalDeployDependency.SubexpIndex("id")
+			depID := matches[idSubexpIndex]
+			properties := lo.Must(utls.ParseProperties(context.TODO(), value))
+
+			var depGroup string
+			if group, found := properties["group"]; found {
+				depGroup = group.(string)
+			}
+
+			var depNamespace string
+			if namespace, found := properties["namespace"]; found {
+				depNamespace = namespace.(string)
+			}
+
+			dep := depnd.NewExternalDependency(
+				properties["name"].(string),
+				depNamespace,
+				schema.GroupVersionKind{
+					Group:   depGroup,
+					Version: properties["version"].(string),
+					Kind:    properties["kind"].(string),
+				},
+				depnd.ExternalDependencyOptions{
+					DefaultNamespace: defaultNamespace,
+					Mapper:           mapper,
+					ResourceState:    depnd.ResourceState(properties["state"].(string)),
+				},
+			)
+
+			deps[depID] = dep
+		}
+	}
+
 	return deps
 }
 
@@ -0,0 +1,117 @@
+package resrc_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/depnd"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+const annotationKeyExternalDependency = "werf.io/external-dependency-db"
+
+var _ = Describe("External dependency annotation", func() {
+	DescribeTable("should be parsed if valid",
+		func(value string, expectedGVK schema.GroupVersionKind, expectedName, expectedNamespace string, expectedState depnd.ResourceState) {
+			res := resourceWithAnnotations(map[string]interface{}{
+				annotationKeyExternalDependency: value,
+			})
+			Expect(res.Validate()).To(Succeed())
+
+			deps, set, err := res.ExternalDependencies()
+			Expect(err).To(Succeed())
+			Expect(set).To(BeTrue())
+			Expect(deps).To(HaveLen(1))
+			Expect(deps[0].GroupVersionKind()).To(Equal(expectedGVK))
+			Expect(deps[0].Name()).To(Equal(expectedName))
+			Expect(deps[0].Namespace()).To(Equal(expectedNamespace))
+			Expect(deps[0].ResourceState).To(Equal(expectedState))
+		},
+		Entry("present core resource in the release namespace",
+			"version=v1,kind=Secret,name=db-credentials,state=present",
+			schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, "db-credentials", "test-namespace", depnd.ResourceStatePresent,
+		),
+		Entry("ready resource of a group in another namespace",
+			"group=apps,version=v1,kind=StatefulSet,name=postgres,namespace=database,state=ready",
+			schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}, "postgres", "database", depnd.ResourceStateReady,
+		),
+		Entry("absent resource",
+			"version=v1,kind=ConfigMap,name=migration-lock,state=absent",
+			schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "migration-lock", "test-namespace", depnd.ResourceStateAbsent,
+		),
+		Entry("with spaces and quoted values",
+			`version = v1, kind = Secret, name = "db-credentials", state = 'ready'`,
+			schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, "db-credentials", "test-namespace", depnd.ResourceStateReady,
+		),
+	)
+
+	DescribeTable("should fail validation if invalid",
+		func(value, expectedErr string) {
+			res := resourceWithAnnotations(map[string]interface{}{
+				annotationKeyExternalDependency: value,
+			})
+
+			Expect(res.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("empty", "", `invalid value "" for annotation "werf.io/external-dependency-db", expected non-empty string value`),
+		Entry("without state", "version=v1,kind=Secret,name=db-credentials", `"state" property must be set`),
+		Entry("without target properties", "state=ready", `"version" property must be set`),
+		Entry("without kind", "version=v1,name=db-credentials,state=ready", `"kind" property must be set`),
+		Entry("without name", "version=v1,kind=Secret,state=ready", `"name" property must be set`),
+		Entry("with unknown state", "version=v1,kind=Secret,name=db-credentials,state=running", `unknown value "running" for property "state"`),
+		Entry("with empty state", "version=v1,kind=Secret,name=db-credentials,state=''", `invalid value "" for property "state", expected non-empty string value`),
+		Entry("with empty name", "version=v1,kind=Secret,state=ready,name=''", `invalid value "" for property "name", expected non-empty string value`),
+		Entry("with property without value", "version=v1,kind=Secret,name,state=ready", `invalid boolean value true for property "name", expected string value`),
+		Entry("with unknown property", "version=v1,kind=Secret,name=db-credentials,state=ready,timeout=5m", `unknown property "timeout" in value of annotation "werf.io/external-dependency-db"`),
+	)
+
+	It("should allow multiple dependencies", func() {
+		res := resourceWithAnnotations(map[string]interface{}{
+			"werf.io/external-dependency-db":    "version=v1,kind=Secret,name=db-credentials,state=present",
+			"werf.io/external-dependency-cache": "group=apps,version=v1,kind=Deployment,name=redis,state=ready",
+		})
+		Expect(res.Validate()).To(Succeed())
+
+		deps, set, err := res.ExternalDependencies()
+		Expect(err).To(Succeed())
+		Expect(set).To(BeTrue())
+		Expect(deps).To(ConsistOf(
+			HaveField("ResourceState", depnd.ResourceStatePresent),
+			HaveField("ResourceState", depnd.ResourceStateReady),
+		))
+	})
+
+	It("should report no dependencies if there are no annotations", func() {
+		res := resourceWithAnnotations(nil)
+		Expect(res.Validate()).To(Succeed())
+
+		deps, set, err := res.ExternalDependencies()
+		Expect(err).To(Succeed())
+		Expect(set).To(BeFalse())
+		Expect(deps).To(BeEmpty())
+	})
+})
+
+func resourceWithAnnotations(annotations map[string]interface{}) *resrc.GeneralResource {
+	metadata := map[string]interface{}{
+		"name":      "migrate",
+		"namespace": "test-namespace",
+	}
+	if annotations != nil {
+		metadata["annotations"] = annotations
+	}
+
+	return resrc.NewGeneralResource(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   metadata,
+		},
+	}, resrc.GeneralResourceOptions{
+		DefaultNamespace: "test-namespace",
+		Mapper:           tstng.NewFakeMapper(tstng.DefaultFakeKinds),
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 
 	"github.com/werf/nelm-for-werf-helm/pkg/depnd"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 
 	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/rollout/multitrack"
@@ -117,6 +118,22 @@ func (r *GeneralResource) Validate() error {
 		return fmt.Errorf("error validating external dependencies for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateSensitivePaths(r.unstruct); err != nil {
+		return fmt.Errorf("error validating sensitive paths for resource %q: %w", r.HumanID(), err)
+	}
+
+	if err := validateReadinessRule(r.unstruct); err != nil {
+		return fmt.Errorf("error validating readiness rule for resource %q: %w", r.HumanID(), err)
+	}
+
+	if err := validateAdopt(r.unstruct); err != nil {
+		return fmt.Errorf("error validating adopt annotation for resource %q: %w", r.HumanID(), err)
+	}
+
+	if err := validateTransferTo(r.unstruct); err != nil {
+		return fmt.Errorf("error validating transfer annotation for resource %q: %w", r.HumanID(), err)
+	}
+
 	return nil
 }
 
@@ -152,6 +169,14 @@ func (r *GeneralResource) KeepOnDelete() bool {
 	return keepOnDelete(r.unstruct)
 }
 
+func (r *GeneralResource) Adopt() bool {
+	return adopt(r.unstruct)
+}
+
+func (r *GeneralResource) TransferTo() (releaseName, releaseNamespace string, set bool) {
+	return transferTo(r.unstruct, r.defaultNamespace)
+}
+
 func (r *GeneralResource) FailMode() multitrack.FailMode {
 	return failMode(r.unstruct)
 }
@@ -184,6 +209,10 @@ func (r *GeneralResource) ShowServiceMessages() bool {
 	return showServiceMessages(r.unstruct)
 }
 
+func (r *GeneralResource) ReadinessRule() (rule *rdnsrule.ReadinessRule, set bool) {
+	return readinessRule(r.unstruct)
+}
+
 func (r *GeneralResource) SkipLogs() bool {
 	return skipLogs(r.unstruct)
 }
@@ -12,6 +12,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 
 	"github.com/werf/nelm-for-werf-helm/pkg/depnd"
+	"github.com/werf/nelm-for-werf-helm/pkg/rdnsrule"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 
 	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/rollout/multitrack"
@@ -121,6 +122,14 @@ func (r *HookResource) Validate() error {
 		return fmt.Errorf("error validating external dependencies for resource %q: %w", r.HumanID(), err)
 	}
 
+	if err := validateSensitivePaths(r.unstruct); err != nil {
+		return fmt.Errorf("error validating sensitive paths for resource %q: %w", r.HumanID(), err)
+	}
+
+	if err := validateReadinessRule(r.unstruct); err != nil {
+		return fmt.Errorf("error validating readiness rule for resource %q: %w", r.HumanID(), err)
+	}
+
 	return nil
 }
 
@@ -188,6 +197,10 @@ func (r *HookResource) ShowServiceMessages() bool {
 	return showServiceMessages(r.unstruct)
 }
 
+func (r *HookResource) ReadinessRule() (rule *rdnsrule.ReadinessRule, set bool) {
+	return readinessRule(r.unstruct)
+}
+
 func (r *HookResource) SkipLogs() bool {
 	return skipLogs(r.unstruct)
 }
@@ -50,6 +50,26 @@ func (r *RemoteResource) AdoptableBy(releaseName, releaseNamespace string) (adop
 	return adoptableBy(r.unstruct, releaseName, releaseNamespace)
 }
 
+// Returns the release the resource belongs to according to its release annotations.
+func (r *RemoteResource) ReleaseOwner() (releaseName, releaseNamespace string, found bool) {
+	return releaseOwner(r.unstruct)
+}
+
+// The release namespace defaults to the namespace of the release currently owning the
+// resource, or to fallbackNamespace if there is no owner.
+func (r *RemoteResource) TransferTo(fallbackNamespace string) (releaseName, releaseNamespace string, set bool) {
+	defaultNamespace := fallbackNamespace
+	if _, ownerNamespace, found := releaseOwner(r.unstruct); found && ownerNamespace != "" {
+		defaultNamespace = ownerNamespace
+	}
+
+	return transferTo(r.unstruct, defaultNamespace)
+}
+
+func (r *RemoteResource) Orphaned(releaseName string, releaseNamespace string) bool {
+	return orphaned(r.unstruct, releaseName, releaseNamespace)
+}
+
 func (r *RemoteResource) KeepOnDelete(releaseName string, releaseNamespace string) bool {
 	if err := validateResourcePolicy(r.unstruct); err != nil {
 		return true
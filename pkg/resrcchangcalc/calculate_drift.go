@@ -0,0 +1,164 @@
+package resrcchangcalc
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/utls"
+)
+
+const (
+	ChangeReasonModifiedOutOfBand = "live resource differs from the deployed one"
+	ChangeReasonDeletedOutOfBand  = "resource of the deployed release not found in the cluster"
+	ChangeReasonDryApplyFailed    = "dry-apply of the deployed resource failed"
+)
+
+// Compares the live resources with the resources of the deployed release, dry-applied
+// to the cluster. Resources taken over by other releases are skipped. Resources which failed
+// to dry-apply can't be compared, their drift is reported as unknown.
+func CalculateDrift(
+	releaseName string,
+	releaseNamespace string,
+	generalResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo,
+	opts CalculateDriftOptions,
+) (
+	modifiedDrifts []*ModifiedResourceDrift,
+	deletedDrifts []*DeletedResourceDrift,
+	extraManagersDrifts []*ExtraManagersResourceDrift,
+	unknownDrifts []*UnknownResourceDrift,
+	anyDriftDetected bool,
+	err error,
+) {
+	redactor := opts.Redactor
+	if redactor == nil {
+		redactor = redactr.NewRedactor(nil, redactr.RedactorOptions{})
+	}
+
+	for _, info := range generalResourcesInfos {
+		liveRes := info.LiveResource()
+		if liveRes == nil {
+			deletedDrifts = append(deletedDrifts, &DeletedResourceDrift{
+				ResourceID: info.ResourceID,
+				Reason:     ChangeReasonDeletedOutOfBand,
+			})
+
+			continue
+		}
+
+		if liveRes.Orphaned(releaseName, releaseNamespace) {
+			continue
+		}
+
+		if managers := extraFieldManagers(liveRes, opts.IgnoreFieldManagers); len(managers) > 0 {
+			extraManagersDrifts = append(extraManagersDrifts, &ExtraManagersResourceDrift{
+				ResourceID: info.ResourceID,
+				Managers:   managers,
+			})
+		}
+
+		dryApplyRes := info.DryApplyResource()
+		if dryApplyRes == nil {
+			unknownDrift := &UnknownResourceDrift{
+				ResourceID: info.ResourceID,
+				Reason:     ChangeReasonDryApplyFailed,
+			}
+
+			if dryApplyErr := info.DryApplyErr(); dryApplyErr != nil {
+				unknownDrift.Err = redactor.TextRedactor(info.Resource().Unstructured(), liveRes.Unstructured()).RedactError(dryApplyErr)
+			}
+
+			unknownDrifts = append(unknownDrifts, unknownDrift)
+
+			continue
+		}
+
+		differ, err := utls.ResourcesReallyDiffer(liveRes.Unstructured(), dryApplyRes.Unstructured())
+		if err != nil {
+			return nil, nil, nil, nil, false, fmt.Errorf("error diffing live and dry-apply versions of resource %q: %w", info.HumanID(), err)
+		} else if !differ {
+			continue
+		}
+
+		var uDiff string
+		if resrc.IsCRDFromGK(info.ResourceID.GroupVersionKind().GroupKind()) {
+			uDiff = HiddenInsignificantOutput
+		} else {
+			var nonEmptyDiff bool
+			uDiff, nonEmptyDiff = utls.ColoredUnifiedDiff(
+				diffableResource(redactor.Redact(dryApplyRes.Unstructured(), liveRes.Unstructured())),
+				diffableResource(redactor.Redact(liveRes.Unstructured(), dryApplyRes.Unstructured())),
+			)
+			if !nonEmptyDiff {
+				uDiff = HiddenInsignificantChanges
+			}
+		}
+
+		modifiedDrifts = append(modifiedDrifts, &ModifiedResourceDrift{
+			ResourceID: info.ResourceID,
+			Reason:     ChangeReasonModifiedOutOfBand,
+			Udiff:      uDiff,
+		})
+	}
+
+	anyDriftDetected = len(modifiedDrifts)+len(deletedDrifts)+len(extraManagersDrifts) > 0
+
+	return modifiedDrifts, deletedDrifts, extraManagersDrifts, unknownDrifts, anyDriftDetected, nil
+}
+
+type CalculateDriftOptions struct {
+	// Field managers which are expected to modify the resources, e.g. controllers.
+	IgnoreFieldManagers []string
+	Redactor            *redactr.Redactor
+}
+
+type ModifiedResourceDrift struct {
+	*resrcid.ResourceID
+
+	Reason string
+	// The diff is from the deployed resource to the live one.
+	Udiff string
+}
+
+type DeletedResourceDrift struct {
+	*resrcid.ResourceID
+
+	Reason string
+}
+
+type UnknownResourceDrift struct {
+	*resrcid.ResourceID
+
+	Reason string
+	Err    error
+}
+
+type ExtraManagersResourceDrift struct {
+	*resrcid.ResourceID
+
+	Managers []string
+}
+
+func extraFieldManagers(res *resrc.RemoteResource, ignoreManagers []string) []string {
+	var managers []string
+	for _, entry := range res.Unstructured().GetManagedFields() {
+		if entry.Subresource != "" ||
+			entry.Manager == common.DefaultFieldManager ||
+			lo.Contains(ignoreManagers, entry.Manager) {
+			continue
+		}
+
+		managers = append(managers, entry.Manager)
+	}
+
+	managers = lo.Uniq(managers)
+	sort.Strings(managers)
+
+	return managers
+}
@@ -0,0 +1,165 @@
+package resrcchangcalc_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+type driftCase struct {
+	// Resources of the deployed release.
+	generalResources    []*unstructured.Unstructured
+	liveObjects         []*unstructured.Unstructured
+	ignoreFieldManagers []string
+
+	// Each drift is "<name>: <reason>".
+	expectModified []string
+	expectDeleted  []string
+	// Each drift is the name and the managers, e.g. "config: [manager]".
+	expectExtraManagers []string
+	expectAnyDrift      bool
+}
+
+var _ = DescribeTable("CalculateDrift",
+	func(c driftCase) {
+		ctx := tstng.NewContext(GinkgoWriter)
+
+		// Live objects without managed fields are considered applied by us.
+		liveObjects := lo.Map(c.liveObjects, func(obj *unstructured.Unstructured, _ int) *unstructured.Unstructured {
+			if len(obj.GetManagedFields()) == 0 {
+				return withManagedFields(obj, managedFieldsEntry("helm", ""))
+			}
+
+			return obj
+		})
+
+		cluster, err := tstng.NewFakeCluster(tstng.FakeClusterOptions{
+			Objects: liveObjects,
+		})
+		Expect(err).To(Succeed())
+
+		var infos []*resrcinfo.DeployableGeneralResourceInfo
+		for _, obj := range c.generalResources {
+			res := resrc.NewGeneralResource(obj, resrc.GeneralResourceOptions{
+				DefaultNamespace: releaseNamespace,
+				Mapper:           cluster.Mapper(),
+				DiscoveryClient:  cluster.Discovery(),
+			})
+
+			info, err := resrcinfo.NewDeployableGeneralResourceInfo(ctx, res, releaseNamespace, cluster.KubeClient(), cluster.Mapper())
+			Expect(err).To(Succeed())
+
+			infos = append(infos, info)
+		}
+
+		modified, deleted, extraManagers, unknown, anyDrift, err := resrcchangcalc.CalculateDrift(releaseName, releaseNamespace, infos, resrcchangcalc.CalculateDriftOptions{
+			IgnoreFieldManagers: c.ignoreFieldManagers,
+		})
+		Expect(err).To(Succeed())
+
+		Expect(summarize(modified, func(d *resrcchangcalc.ModifiedResourceDrift) (*resrcid.ResourceID, string) {
+			return d.ResourceID, d.Reason
+		})).To(ConsistOf(c.expectModified))
+		Expect(summarize(deleted, func(d *resrcchangcalc.DeletedResourceDrift) (*resrcid.ResourceID, string) {
+			return d.ResourceID, d.Reason
+		})).To(ConsistOf(c.expectDeleted))
+		Expect(summarize(extraManagers, func(d *resrcchangcalc.ExtraManagersResourceDrift) (*resrcid.ResourceID, string) {
+			return d.ResourceID, formatManagers(d.Managers)
+		})).To(ConsistOf(c.expectExtraManagers))
+		Expect(unknown).To(BeEmpty())
+		Expect(anyDrift).To(Equal(c.expectAnyDrift))
+	},
+	Entry("no drift", driftCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+	}),
+	Entry("resource modified out of band", driftCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		liveObjects:      []*unstructured.Unstructured{withData(configMap("config", ownedAnnotations()), "key", "changed")},
+		expectModified:   []string{"config: " + resrcchangcalc.ChangeReasonModifiedOutOfBand},
+		expectAnyDrift:   true,
+	}),
+	Entry("resource deleted out of band", driftCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", ownedAnnotations()), configMap("deleted", ownedAnnotations())},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		expectDeleted:    []string{"deleted: " + resrcchangcalc.ChangeReasonDeletedOutOfBand},
+		expectAnyDrift:   true,
+	}),
+	Entry("resource taken over by another release is skipped", driftCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		liveObjects: []*unstructured.Unstructured{withManagedFields(
+			withData(configMap("config", map[string]interface{}{
+				"meta.helm.sh/release-name":      "other-release",
+				"meta.helm.sh/release-namespace": releaseNamespace,
+			}), "key", "changed"),
+			managedFieldsEntry("kubectl-edit", ""),
+		)},
+	}),
+	Entry("resource modified by other field managers", driftCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		liveObjects: []*unstructured.Unstructured{withManagedFields(
+			configMap("config", ownedAnnotations()),
+			managedFieldsEntry("kubectl-client-side-apply", ""),
+			managedFieldsEntry("kube-controller-manager", ""),
+			managedFieldsEntry("kubectl-client-side-apply", ""),
+			managedFieldsEntry("helm", ""),
+		)},
+		expectExtraManagers: []string{"config: [kube-controller-manager kubectl-client-side-apply]"},
+		expectAnyDrift:      true,
+	}),
+	Entry("resource modified by ignored field managers and only in subresources", driftCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		liveObjects: []*unstructured.Unstructured{withManagedFields(
+			configMap("config", ownedAnnotations()),
+			managedFieldsEntry("vpa-recommender", ""),
+			managedFieldsEntry("kube-controller-manager", "status"),
+			managedFieldsEntry("helm", ""),
+		)},
+		ignoreFieldManagers: []string{"vpa-recommender"},
+	}),
+)
+
+func formatManagers(managers []string) string {
+	return fmt.Sprint(managers)
+}
+
+func withData(obj *unstructured.Unstructured, key, value string) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	lo.Must0(unstructured.SetNestedField(obj.Object, value, "data", key))
+
+	return obj
+}
+
+func withManagedFields(obj *unstructured.Unstructured, entries ...metav1.ManagedFieldsEntry) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	obj.SetManagedFields(entries)
+
+	return obj
+}
+
+// Entry of the manager owning no fields. Only the helm manager applies, others update.
+func managedFieldsEntry(manager, subresource string) metav1.ManagedFieldsEntry {
+	operation := metav1.ManagedFieldsOperationUpdate
+	if manager == "helm" {
+		operation = metav1.ManagedFieldsOperationApply
+	}
+
+	return metav1.ManagedFieldsEntry{
+		Manager:     manager,
+		Operation:   operation,
+		APIVersion:  "v1",
+		FieldsType:  "FieldsV1",
+		FieldsV1:    &metav1.FieldsV1{Raw: []byte("{}")},
+		Subresource: subresource,
+	}
+}
@@ -1,6 +1,7 @@
 package resrcchangcalc
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/samber/lo"
@@ -9,15 +10,24 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/werf/nelm-for-werf-helm/pkg/plnbuilder"
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/utls"
 )
 
 const HiddenInsignificantOutput = "<hidden insignificant output>"
 const HiddenInsignificantChanges = "<hidden insignificant changes>"
-const HiddenSensitiveOutput = "<hidden sensitive output>"
+
+const (
+	ChangeReasonNotFound           = "resource not found in the cluster"
+	ChangeReasonRecreateRequested  = "recreation requested by the delete policy"
+	ChangeReasonOutdated           = "live resource differs from the desired one"
+	ChangeReasonUpToDateUnknown    = "unable to determine whether live resource is up to date"
+	ChangeReasonRemovedFromRelease = "resource removed from the release"
+)
 
 func CalculatePlannedChanges(
 	releaseName string,
@@ -27,31 +37,48 @@ func CalculatePlannedChanges(
 	generalResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo,
 	prevReleaseGeneralResourceInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo,
 	prevRelFailed bool,
+	opts CalculatePlannedChangesOptions,
 ) (
 	createdChanges []*CreatedResourceChange,
 	recreatedChanges []*RecreatedResourceChange,
 	updatedChanges []*UpdatedResourceChange,
 	appliedChanges []*AppliedResourceChange,
 	deletedChanges []*DeletedResourceChange,
+	transferredChanges []*TransferredResourceChange,
 	anyChangesPlanned bool,
 ) {
 	curReleaseExistResourcesUIDs, _ := plnbuilder.CurrentReleaseExistingResourcesUIDs(standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos)
 
+	standaloneCRDsInfos, hookResourcesInfos, generalResourcesInfos, prevReleaseGeneralResourceInfos, _ = plnbuilder.SelectResourcesInfos(
+		opts.ResourceSelector,
+		releaseName,
+		releaseNamespace,
+		standaloneCRDsInfos,
+		hookResourcesInfos,
+		generalResourcesInfos,
+		prevReleaseGeneralResourceInfos,
+	)
+
+	redactor := opts.Redactor
+	if redactor == nil {
+		redactor = redactr.NewRedactor(nil, redactr.RedactorOptions{})
+	}
+
 	allChanges := make([]any, 0)
 
-	if changes, present := standaloneCRDChanges(standaloneCRDsInfos); present {
+	if changes, present := standaloneCRDChanges(standaloneCRDsInfos, redactor); present {
 		allChanges = append(allChanges, changes...)
 	}
 
-	if changes, present := hookResourcesChanges(hookResourcesInfos, prevRelFailed, releaseName, releaseNamespace); present {
+	if changes, present := hookResourcesChanges(hookResourcesInfos, prevRelFailed, releaseName, releaseNamespace, redactor); present {
 		allChanges = append(allChanges, changes...)
 	}
 
-	if changes, present := generalResourcesChanges(generalResourcesInfos, prevRelFailed, releaseName, releaseNamespace); present {
+	if changes, present := generalResourcesChanges(generalResourcesInfos, prevRelFailed, releaseName, releaseNamespace, redactor); present {
 		allChanges = append(allChanges, changes...)
 	}
 
-	if changes, present := prevReleaseGeneralResourcesChanges(prevReleaseGeneralResourceInfos, curReleaseExistResourcesUIDs, releaseName, releaseNamespace); present {
+	if changes, present := prevReleaseGeneralResourcesChanges(prevReleaseGeneralResourceInfos, curReleaseExistResourcesUIDs, releaseName, releaseNamespace, redactor); present {
 		allChanges = append(allChanges, changes...)
 	}
 
@@ -67,19 +94,26 @@ func CalculatePlannedChanges(
 			appliedChanges = append(appliedChanges, ch)
 		case *DeletedResourceChange:
 			deletedChanges = append(deletedChanges, ch)
+		case *TransferredResourceChange:
+			transferredChanges = append(transferredChanges, ch)
 		default:
 			panic("unexpected type")
 		}
 	}
 
 	if len(allChanges) == 0 {
-		return nil, nil, nil, nil, nil, false
+		return nil, nil, nil, nil, nil, nil, false
 	}
 
-	return createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, true
+	return createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, transferredChanges, true
 }
 
-func standaloneCRDChanges(infos []*resrcinfo.DeployableStandaloneCRDInfo) (changes []any, present bool) {
+type CalculatePlannedChangesOptions struct {
+	ResourceSelector *resrcmatcher.ResourceSelector
+	Redactor         *redactr.Redactor
+}
+
+func standaloneCRDChanges(infos []*resrcinfo.DeployableStandaloneCRDInfo, redactor *redactr.Redactor) (changes []any, present bool) {
 	for _, info := range infos {
 		create := info.ShouldCreate()
 		update := info.ShouldUpdate()
@@ -90,16 +124,21 @@ func standaloneCRDChanges(infos []*resrcinfo.DeployableStandaloneCRDInfo) (chang
 
 			changes = append(changes, &CreatedResourceChange{
 				ResourceID: info.ResourceID,
+				Reason:     ChangeReasonNotFound,
 				Udiff:      uDiff,
 			})
 		} else if update {
-			uDiff, nonEmptyDiff := utls.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), diffableResource(info.DryApplyResource().Unstructured()))
+			uDiff, nonEmptyDiff := utls.ColoredUnifiedDiff(
+				diffableResource(redactor.Redact(info.LiveResource().Unstructured(), info.DryApplyResource().Unstructured())),
+				diffableResource(redactor.Redact(info.DryApplyResource().Unstructured(), info.LiveResource().Unstructured())),
+			)
 			if !nonEmptyDiff {
 				uDiff = HiddenInsignificantChanges
 			}
 
 			changes = append(changes, &UpdatedResourceChange{
 				ResourceID: info.ResourceID,
+				Reason:     ChangeReasonOutdated,
 				Udiff:      uDiff,
 			})
 		} else if apply {
@@ -107,6 +146,7 @@ func standaloneCRDChanges(infos []*resrcinfo.DeployableStandaloneCRDInfo) (chang
 
 			changes = append(changes, &AppliedResourceChange{
 				ResourceID: info.ResourceID,
+				Reason:     ChangeReasonUpToDateUnknown,
 				Udiff:      uDiff,
 			})
 		}
@@ -115,10 +155,9 @@ func standaloneCRDChanges(infos []*resrcinfo.DeployableStandaloneCRDInfo) (chang
 	return changes, len(changes) > 0
 }
 
-func hookResourcesChanges(infos []*resrcinfo.DeployableHookResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string) (changes []any, present bool) {
+func hookResourcesChanges(infos []*resrcinfo.DeployableHookResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string, redactor *redactr.Redactor) (changes []any, present bool) {
 	for _, info := range infos {
 		isCrd := resrc.IsCRDFromGK(info.ResourceID.GroupVersionKind().GroupKind())
-		isSecret := resrc.IsSecret(info.ResourceID.GroupVersionKind().GroupKind())
 		create := info.ShouldCreate()
 		recreate := info.ShouldRecreate()
 		update := info.ShouldUpdate()
@@ -130,14 +169,13 @@ func hookResourcesChanges(infos []*resrcinfo.DeployableHookResourceInfo, prevRel
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSecret {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(redactor.Redact(info.Resource().Unstructured()))))
 			}
 
 			changes = append(changes, &CreatedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonNotFound,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
@@ -146,32 +184,29 @@ func hookResourcesChanges(infos []*resrcinfo.DeployableHookResourceInfo, prevRel
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSecret {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(redactor.Redact(info.Resource().Unstructured()))))
 			}
 
 			changes = append(changes, &RecreatedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonRecreateRequested,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
 			})
 		} else if update {
-			var uDiff string
-			if isSecret {
-				uDiff = HiddenSensitiveOutput
-			} else {
-				if ud, nonEmpty := utls.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), diffableResource(info.DryApplyResource().Unstructured())); nonEmpty {
-					uDiff = ud
-				} else {
-					uDiff = HiddenInsignificantChanges
-				}
+			uDiff, nonEmptyDiff := utls.ColoredUnifiedDiff(
+				diffableResource(redactor.Redact(info.LiveResource().Unstructured(), info.DryApplyResource().Unstructured())),
+				diffableResource(redactor.Redact(info.DryApplyResource().Unstructured(), info.LiveResource().Unstructured())),
+			)
+			if !nonEmptyDiff {
+				uDiff = HiddenInsignificantChanges
 			}
 
 			changes = append(changes, &UpdatedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonOutdated,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
@@ -180,14 +215,13 @@ func hookResourcesChanges(infos []*resrcinfo.DeployableHookResourceInfo, prevRel
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSecret {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(redactor.Redact(info.Resource().Unstructured()))))
 			}
 
 			changes = append(changes, &AppliedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonUpToDateUnknown,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
@@ -198,29 +232,28 @@ func hookResourcesChanges(infos []*resrcinfo.DeployableHookResourceInfo, prevRel
 	return changes, len(changes) > 0
 }
 
-func generalResourcesChanges(infos []*resrcinfo.DeployableGeneralResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string) (changes []any, present bool) {
+func generalResourcesChanges(infos []*resrcinfo.DeployableGeneralResourceInfo, prevRelFailed bool, releaseName, releaseNamespace string, redactor *redactr.Redactor) (changes []any, present bool) {
 	for _, info := range infos {
 		isCrd := resrc.IsCRDFromGK(info.ResourceID.GroupVersionKind().GroupKind())
-		isSecret := resrc.IsSecret(info.ResourceID.GroupVersionKind().GroupKind())
 		create := info.ShouldCreate()
 		recreate := info.ShouldRecreate()
 		update := info.ShouldUpdate()
 		apply := info.ShouldApply()
 		cleanup := info.ShouldCleanup(releaseName, releaseNamespace)
 		cleanupOnFailure := info.ShouldCleanupOnFailed(prevRelFailed, releaseName, releaseNamespace)
+		ownershipChange := ownershipChange(info.LiveResource(), releaseName, releaseNamespace)
 
 		if create {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSecret {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(redactor.Redact(info.Resource().Unstructured()))))
 			}
 
 			changes = append(changes, &CreatedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonNotFound,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
@@ -229,51 +262,50 @@ func generalResourcesChanges(infos []*resrcinfo.DeployableGeneralResourceInfo, p
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSecret {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(redactor.Redact(info.Resource().Unstructured()))))
 			}
 
 			changes = append(changes, &RecreatedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonRecreateRequested,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
+				OwnershipChange:    ownershipChange,
 			})
 		} else if update {
-			var uDiff string
-			if isSecret {
-				uDiff = HiddenSensitiveOutput
-			} else {
-				if ud, nonEmpty := utls.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), diffableResource(info.DryApplyResource().Unstructured())); nonEmpty {
-					uDiff = ud
-				} else {
-					uDiff = HiddenInsignificantChanges
-				}
+			uDiff, nonEmptyDiff := utls.ColoredUnifiedDiff(
+				diffableResource(redactor.Redact(info.LiveResource().Unstructured(), info.DryApplyResource().Unstructured())),
+				diffableResource(redactor.Redact(info.DryApplyResource().Unstructured(), info.LiveResource().Unstructured())),
+			)
+			if !nonEmptyDiff {
+				uDiff = HiddenInsignificantChanges
 			}
 
 			changes = append(changes, &UpdatedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonOutdated,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
+				OwnershipChange:    ownershipChange,
 			})
 		} else if apply {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSecret {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(info.Resource().Unstructured())))
+				uDiff = lo.Must(utls.ColoredUnifiedDiff("", diffableResource(redactor.Redact(info.Resource().Unstructured()))))
 			}
 
 			changes = append(changes, &AppliedResourceChange{
 				ResourceID:         info.ResourceID,
+				Reason:             ChangeReasonUpToDateUnknown,
 				Udiff:              uDiff,
 				CleanedUpOnSuccess: cleanup,
 				CleanedUpOnFailure: cleanupOnFailure,
+				OwnershipChange:    ownershipChange,
 			})
 		}
 	}
@@ -281,33 +313,61 @@ func generalResourcesChanges(infos []*resrcinfo.DeployableGeneralResourceInfo, p
 	return changes, len(changes) > 0
 }
 
-func prevReleaseGeneralResourcesChanges(infos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo, curReleaseExistResourcesUIDs []types.UID, releaseName, releaseNamespace string) (changes []any, present bool) {
+func prevReleaseGeneralResourcesChanges(infos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo, curReleaseExistResourcesUIDs []types.UID, releaseName, releaseNamespace string, redactor *redactr.Redactor) (changes []any, present bool) {
 	for _, info := range infos {
 		isCrd := resrc.IsCRDFromGK(info.ResourceID.GroupVersionKind().GroupKind())
-		isSecret := resrc.IsSecret(info.ResourceID.GroupVersionKind().GroupKind())
 		delete := info.ShouldDelete(curReleaseExistResourcesUIDs, releaseName, releaseNamespace)
 
 		if delete {
 			var uDiff string
 			if isCrd {
 				uDiff = HiddenInsignificantOutput
-			} else if isSecret {
-				uDiff = HiddenSensitiveOutput
 			} else {
-				uDiff = lo.Must(utls.ColoredUnifiedDiff(diffableResource(info.LiveResource().Unstructured()), ""))
+				uDiff = lo.Must(utls.ColoredUnifiedDiff(diffableResource(redactor.Redact(info.LiveResource().Unstructured())), ""))
 			}
 
 			changes = append(changes, &DeletedResourceChange{
 				ResourceID: info.ResourceID,
+				Reason:     ChangeReasonRemovedFromRelease,
 				Udiff:      uDiff,
 			})
+		} else if info.LiveResource() != nil && !lo.Contains(curReleaseExistResourcesUIDs, info.LiveResource().Unstructured().GetUID()) {
+			if newReleaseName, newReleaseNamespace, transfer := info.TransferTo(releaseName, releaseNamespace); transfer {
+				changes = append(changes, &TransferredResourceChange{
+					ResourceID: info.ResourceID,
+					Reason:     transferReason(newReleaseName, newReleaseNamespace),
+				})
+			}
 		}
 	}
 
 	return changes, len(changes) > 0
 }
 
+func transferReason(newReleaseName, newReleaseNamespace string) string {
+	return fmt.Sprintf("transferred to release %q (namespace: %q)", newReleaseName, newReleaseNamespace)
+}
+
+// Empty if the live resource already belongs to the release.
+func ownershipChange(liveResource *resrc.RemoteResource, releaseName, releaseNamespace string) string {
+	if liveResource == nil {
+		return ""
+	}
+
+	if adoptable, _ := liveResource.AdoptableBy(releaseName, releaseNamespace); adoptable {
+		return ""
+	}
+
+	if ownerName, ownerNamespace, found := liveResource.ReleaseOwner(); found {
+		return fmt.Sprintf("take over from release %q (namespace: %q)", ownerName, ownerNamespace)
+	}
+
+	return "take over, not managed by any release"
+}
+
 func diffableResource(unstruct *unstructured.Unstructured) string {
+	unstruct = unstruct.DeepCopy()
+
 	unstructured.RemoveNestedField(unstruct.Object, "metadata", "creationTimestamp")
 	unstructured.RemoveNestedField(unstruct.Object, "metadata", "generation")
 	unstructured.RemoveNestedField(unstruct.Object, "metadata", "resourceVersion")
@@ -351,6 +411,7 @@ type CreatedResourceChange struct {
 	*resrcid.ResourceID
 
 	Udiff              string
+	Reason             string
 	CleanedUpOnSuccess bool
 	CleanedUpOnFailure bool
 }
@@ -359,28 +420,43 @@ type RecreatedResourceChange struct {
 	*resrcid.ResourceID
 
 	Udiff              string
+	Reason             string
 	CleanedUpOnSuccess bool
 	CleanedUpOnFailure bool
+	OwnershipChange    string
 }
 
 type UpdatedResourceChange struct {
 	*resrcid.ResourceID
 
 	Udiff              string
+	Reason             string
 	CleanedUpOnSuccess bool
 	CleanedUpOnFailure bool
+	OwnershipChange    string
 }
 
 type AppliedResourceChange struct {
 	*resrcid.ResourceID
 
 	Udiff              string
+	Reason             string
 	CleanedUpOnSuccess bool
 	CleanedUpOnFailure bool
+	OwnershipChange    string
 }
 
 type DeletedResourceChange struct {
 	*resrcid.ResourceID
 
-	Udiff string
+	Udiff  string
+	Reason string
+}
+
+// The resource is removed from the release, but kept in the cluster to be adopted by another
+// release.
+type TransferredResourceChange struct {
+	*resrcid.ResourceID
+
+	Reason string
 }
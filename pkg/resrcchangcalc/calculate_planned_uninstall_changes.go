@@ -0,0 +1,182 @@
+package resrcchangcalc
+
+import (
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/redactr"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/utls"
+)
+
+const (
+	ChangeReasonReleaseUninstalled      = "release uninstalled"
+	ChangeReasonReleaseNamespaceDeleted = "release namespace deletion requested"
+	ChangeReasonHookDeletionRequested   = "hooks deletion requested"
+	ChangeReasonKeptByResourcePolicy    = "kept by the resource policy"
+	ChangeReasonNotManagedByHelm        = "resource is not managed by Helm"
+	ChangeReasonDeleteHookNotCleanedUp  = "delete hook is not cleaned up after execution"
+)
+
+// Calculates what uninstalling the release will do. Besides the deleted resources, also
+// returns the resources of the release which won't be deleted, either because of the
+// resource policy or because they are not owned by the release.
+func CalculatePlannedUninstallChanges(
+	releaseName string,
+	releaseNamespace string,
+	hookResourcesInfos []*resrcinfo.DeployableHookResourceInfo,
+	prevReleaseGeneralResourceInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo,
+	opts CalculatePlannedUninstallChangesOptions,
+) (
+	createdChanges []*CreatedResourceChange,
+	recreatedChanges []*RecreatedResourceChange,
+	updatedChanges []*UpdatedResourceChange,
+	appliedChanges []*AppliedResourceChange,
+	deletedChanges []*DeletedResourceChange,
+	keptChanges []*KeptResourceChange,
+	notOwnedChanges []*NotOwnedResourceChange,
+	anyChangesPlanned bool,
+) {
+	redactor := opts.Redactor
+	if redactor == nil {
+		redactor = redactr.NewRedactor(nil, redactr.RedactorOptions{})
+	}
+
+	deleteHookResourcesInfos := lo.Filter(hookResourcesInfos, func(info *resrcinfo.DeployableHookResourceInfo, _ int) bool {
+		return info.Resource().OnPreDelete() || info.Resource().OnPostDelete()
+	})
+
+	allChanges := make([]any, 0)
+
+	if changes, present := hookResourcesChanges(deleteHookResourcesInfos, false, releaseName, releaseNamespace, redactor); present {
+		allChanges = append(allChanges, changes...)
+	}
+
+	for _, info := range prevReleaseGeneralResourceInfos {
+		if info.LiveResource() == nil {
+			continue
+		}
+
+		if info.LiveResource().Orphaned(releaseName, releaseNamespace) {
+			allChanges = append(allChanges, newNotOwnedResourceChange(info.ResourceID, info.LiveResource(), releaseName, releaseNamespace))
+		} else if newReleaseName, newReleaseNamespace, transfer := info.TransferTo(releaseName, releaseNamespace); transfer {
+			allChanges = append(allChanges, &KeptResourceChange{
+				ResourceID: info.ResourceID,
+				Reason:     transferReason(newReleaseName, newReleaseNamespace),
+			})
+		} else if info.ShouldKeepOnDelete(releaseName, releaseNamespace) {
+			allChanges = append(allChanges, &KeptResourceChange{
+				ResourceID: info.ResourceID,
+				Reason:     ChangeReasonKeptByResourcePolicy,
+			})
+		} else {
+			allChanges = append(allChanges, newDeletedResourceChange(info.ResourceID, info.LiveResource(), ChangeReasonReleaseUninstalled, redactor))
+		}
+	}
+
+	if opts.DeleteHooks {
+		for _, info := range hookResourcesInfos {
+			isDeleteHook := info.Resource().OnPreDelete() || info.Resource().OnPostDelete()
+
+			if info.ShouldKeepOnDelete(releaseName, releaseNamespace) {
+				if info.LiveResource() != nil || isDeleteHook {
+					allChanges = append(allChanges, &KeptResourceChange{
+						ResourceID: info.ResourceID,
+						Reason:     ChangeReasonKeptByResourcePolicy,
+					})
+				}
+			} else if isDeleteHook {
+				if !info.ShouldCleanup(releaseName, releaseNamespace) {
+					allChanges = append(allChanges, &DeletedResourceChange{
+						ResourceID: info.ResourceID,
+						Reason:     ChangeReasonDeleteHookNotCleanedUp,
+						Udiff:      HiddenInsignificantOutput,
+					})
+				}
+			} else if info.LiveResource() != nil {
+				allChanges = append(allChanges, newDeletedResourceChange(info.ResourceID, info.LiveResource(), ChangeReasonHookDeletionRequested, redactor))
+			}
+		}
+	}
+
+	if opts.ReleaseNamespaceID != nil {
+		allChanges = append(allChanges, &DeletedResourceChange{
+			ResourceID: opts.ReleaseNamespaceID,
+			Reason:     ChangeReasonReleaseNamespaceDeleted,
+			Udiff:      HiddenInsignificantOutput,
+		})
+	}
+
+	for _, change := range allChanges {
+		switch ch := change.(type) {
+		case *CreatedResourceChange:
+			createdChanges = append(createdChanges, ch)
+		case *RecreatedResourceChange:
+			recreatedChanges = append(recreatedChanges, ch)
+		case *UpdatedResourceChange:
+			updatedChanges = append(updatedChanges, ch)
+		case *AppliedResourceChange:
+			appliedChanges = append(appliedChanges, ch)
+		case *DeletedResourceChange:
+			deletedChanges = append(deletedChanges, ch)
+		case *KeptResourceChange:
+			keptChanges = append(keptChanges, ch)
+		case *NotOwnedResourceChange:
+			notOwnedChanges = append(notOwnedChanges, ch)
+		default:
+			panic("unexpected type")
+		}
+	}
+
+	anyChangesPlanned = len(createdChanges)+len(recreatedChanges)+len(updatedChanges)+len(appliedChanges)+len(deletedChanges) > 0
+
+	return createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, keptChanges, notOwnedChanges, anyChangesPlanned
+}
+
+type CalculatePlannedUninstallChangesOptions struct {
+	// Delete all hooks of the release, not only delete hooks which are cleaned up.
+	DeleteHooks bool
+	// If set, the release namespace is planned for deletion.
+	ReleaseNamespaceID *resrcid.ResourceID
+	Redactor           *redactr.Redactor
+}
+
+func newDeletedResourceChange(resID *resrcid.ResourceID, liveResource *resrc.RemoteResource, reason string, redactor *redactr.Redactor) *DeletedResourceChange {
+	var uDiff string
+	if resrc.IsCRDFromGK(resID.GroupVersionKind().GroupKind()) {
+		uDiff = HiddenInsignificantOutput
+	} else {
+		uDiff = lo.Must(utls.ColoredUnifiedDiff(diffableResource(redactor.Redact(liveResource.Unstructured())), ""))
+	}
+
+	return &DeletedResourceChange{
+		ResourceID: resID,
+		Reason:     reason,
+		Udiff:      uDiff,
+	}
+}
+
+func newNotOwnedResourceChange(resID *resrcid.ResourceID, liveResource *resrc.RemoteResource, releaseName, releaseNamespace string) *NotOwnedResourceChange {
+	reason := ChangeReasonNotManagedByHelm
+	if adoptable, nonAdoptableReason := liveResource.AdoptableBy(releaseName, releaseNamespace); !adoptable {
+		reason = nonAdoptableReason
+	}
+
+	return &NotOwnedResourceChange{
+		ResourceID: resID,
+		Reason:     reason,
+	}
+}
+
+type KeptResourceChange struct {
+	*resrcid.ResourceID
+
+	Reason string
+}
+
+type NotOwnedResourceChange struct {
+	*resrcid.ResourceID
+
+	Reason string
+}
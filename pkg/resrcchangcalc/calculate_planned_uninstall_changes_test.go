@@ -0,0 +1,264 @@
+package resrcchangcalc_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/tstng"
+)
+
+const (
+	releaseName      = "test-release"
+	releaseNamespace = "test-namespace"
+)
+
+type uninstallChangesCase struct {
+	// Resources of the release being uninstalled.
+	generalResources []*unstructured.Unstructured
+	hookResources    []*unstructured.Unstructured
+	// Objects in the cluster.
+	liveObjects     []*unstructured.Unstructured
+	deleteHooks     bool
+	deleteNamespace bool
+
+	// Each change is "<name>: <reason>".
+	expectCreated    []string
+	expectDeleted    []string
+	expectKept       []string
+	expectNotOwned   []string
+	expectAnyChanges bool
+}
+
+var _ = DescribeTable("CalculatePlannedUninstallChanges",
+	func(c uninstallChangesCase) {
+		ctx := tstng.NewContext(GinkgoWriter)
+
+		cluster, err := tstng.NewFakeCluster(tstng.FakeClusterOptions{
+			Objects: c.liveObjects,
+		})
+		Expect(err).To(Succeed())
+
+		var hookInfos []*resrcinfo.DeployableHookResourceInfo
+		for _, obj := range c.hookResources {
+			res := resrc.NewHookResource(obj, resrc.HookResourceOptions{
+				DefaultNamespace: releaseNamespace,
+				Mapper:           cluster.Mapper(),
+				DiscoveryClient:  cluster.Discovery(),
+			})
+
+			info, err := resrcinfo.NewDeployableHookResourceInfo(ctx, res, releaseNamespace, cluster.KubeClient(), cluster.Mapper())
+			Expect(err).To(Succeed())
+
+			hookInfos = append(hookInfos, info)
+		}
+
+		var generalInfos []*resrcinfo.DeployablePrevReleaseGeneralResourceInfo
+		for _, obj := range c.generalResources {
+			res := resrc.NewGeneralResource(obj, resrc.GeneralResourceOptions{
+				DefaultNamespace: releaseNamespace,
+				Mapper:           cluster.Mapper(),
+				DiscoveryClient:  cluster.Discovery(),
+			})
+
+			info, err := resrcinfo.NewDeployablePrevReleaseGeneralResourceInfo(ctx, res, releaseNamespace, cluster.KubeClient(), cluster.Mapper())
+			Expect(err).To(Succeed())
+
+			generalInfos = append(generalInfos, info)
+		}
+
+		opts := resrcchangcalc.CalculatePlannedUninstallChangesOptions{
+			DeleteHooks: c.deleteHooks,
+		}
+		if c.deleteNamespace {
+			opts.ReleaseNamespaceID = resrcid.NewResourceIDFromUnstruct(namespace(releaseNamespace), resrcid.ResourceIDOptions{
+				Mapper: cluster.Mapper(),
+			})
+		}
+
+		created, recreated, updated, applied, deleted, kept, notOwned, anyChanges := resrcchangcalc.CalculatePlannedUninstallChanges(releaseName, releaseNamespace, hookInfos, generalInfos, opts)
+
+		Expect(summarize(created, func(ch *resrcchangcalc.CreatedResourceChange) (*resrcid.ResourceID, string) {
+			return ch.ResourceID, ch.Reason
+		})).To(ConsistOf(c.expectCreated))
+		Expect(recreated).To(BeEmpty())
+		Expect(updated).To(BeEmpty())
+		Expect(applied).To(BeEmpty())
+		Expect(summarize(deleted, func(ch *resrcchangcalc.DeletedResourceChange) (*resrcid.ResourceID, string) {
+			return ch.ResourceID, ch.Reason
+		})).To(ConsistOf(c.expectDeleted))
+		Expect(summarize(kept, func(ch *resrcchangcalc.KeptResourceChange) (*resrcid.ResourceID, string) {
+			return ch.ResourceID, ch.Reason
+		})).To(ConsistOf(c.expectKept))
+		Expect(summarize(notOwned, func(ch *resrcchangcalc.NotOwnedResourceChange) (*resrcid.ResourceID, string) {
+			return ch.ResourceID, ch.Reason
+		})).To(ConsistOf(c.expectNotOwned))
+		Expect(anyChanges).To(Equal(c.expectAnyChanges))
+	},
+	Entry("nothing to uninstall", uninstallChangesCase{}),
+	Entry("resource of the release is deleted", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", nil)},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		expectDeleted:    []string{"config: " + resrcchangcalc.ChangeReasonReleaseUninstalled},
+		expectAnyChanges: true,
+	}),
+	Entry("already missing resource is not mentioned", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", nil), configMap("missing", nil)},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		expectDeleted:    []string{"config: " + resrcchangcalc.ChangeReasonReleaseUninstalled},
+		expectAnyChanges: true,
+	}),
+	Entry("resource kept by the resource policy of the release", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", map[string]interface{}{"helm.sh/resource-policy": "keep"})},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		expectKept:       []string{"config: " + resrcchangcalc.ChangeReasonKeptByResourcePolicy},
+	}),
+	Entry("resource kept by the resource policy of the live resource", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", nil)},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations("helm.sh/resource-policy", "keep"))},
+		expectKept:       []string{"config: " + resrcchangcalc.ChangeReasonKeptByResourcePolicy},
+	}),
+	Entry("resource transferred to another release", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", nil)},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations("werf.io/transfer-to", "other-release"))},
+		expectKept:       []string{`config: transferred to release "other-release" (namespace: "test-namespace")`},
+	}),
+	Entry("resource not managed by Helm", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", nil)},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", nil)},
+		expectNotOwned: []string{
+			`config: annotation "meta.helm.sh/release-name" not found, must be set to "test-release", annotation "meta.helm.sh/release-namespace" not found, must be set to "test-namespace"`,
+		},
+	}),
+	Entry("resource owned by another release", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", nil)},
+		liveObjects: []*unstructured.Unstructured{configMap("config", map[string]interface{}{
+			"meta.helm.sh/release-name":      "other-release",
+			"meta.helm.sh/release-namespace": releaseNamespace,
+		})},
+		expectNotOwned: []string{`config: annotation "meta.helm.sh/release-name=other-release" must have value "test-release"`},
+	}),
+	Entry("non-delete hooks are kept unless hooks deletion requested", uninstallChangesCase{
+		hookResources: []*unstructured.Unstructured{configMap("hook", map[string]interface{}{"helm.sh/hook": "pre-install"})},
+		liveObjects:   []*unstructured.Unstructured{configMap("hook", ownedAnnotations("helm.sh/hook", "pre-install"))},
+	}),
+	Entry("non-delete hooks are deleted if hooks deletion requested", uninstallChangesCase{
+		hookResources: []*unstructured.Unstructured{
+			configMap("hook", map[string]interface{}{"helm.sh/hook": "pre-install"}),
+			configMap("missing-hook", map[string]interface{}{"helm.sh/hook": "pre-install"}),
+			configMap("kept-hook", map[string]interface{}{"helm.sh/hook": "pre-install", "helm.sh/resource-policy": "keep"}),
+		},
+		liveObjects: []*unstructured.Unstructured{
+			configMap("hook", ownedAnnotations("helm.sh/hook", "pre-install")),
+			configMap("kept-hook", ownedAnnotations("helm.sh/hook", "pre-install")),
+		},
+		deleteHooks:      true,
+		expectDeleted:    []string{"hook: " + resrcchangcalc.ChangeReasonHookDeletionRequested},
+		expectKept:       []string{"kept-hook: " + resrcchangcalc.ChangeReasonKeptByResourcePolicy},
+		expectAnyChanges: true,
+	}),
+	Entry("delete hooks are run", uninstallChangesCase{
+		hookResources: []*unstructured.Unstructured{
+			configMap("cleaned-up-hook", map[string]interface{}{"helm.sh/hook": "pre-delete", "helm.sh/hook-delete-policy": "hook-succeeded"}),
+			configMap("left-hook", map[string]interface{}{"helm.sh/hook": "post-delete", "helm.sh/hook-delete-policy": "before-hook-creation"}),
+		},
+		expectCreated: []string{
+			"cleaned-up-hook: " + resrcchangcalc.ChangeReasonNotFound,
+			"left-hook: " + resrcchangcalc.ChangeReasonNotFound,
+		},
+		expectAnyChanges: true,
+	}),
+	Entry("delete hooks left after execution are deleted if hooks deletion requested", uninstallChangesCase{
+		hookResources: []*unstructured.Unstructured{
+			configMap("cleaned-up-hook", map[string]interface{}{"helm.sh/hook": "pre-delete", "helm.sh/hook-delete-policy": "hook-succeeded"}),
+			configMap("left-hook", map[string]interface{}{"helm.sh/hook": "post-delete", "helm.sh/hook-delete-policy": "before-hook-creation"}),
+		},
+		deleteHooks: true,
+		expectCreated: []string{
+			"cleaned-up-hook: " + resrcchangcalc.ChangeReasonNotFound,
+			"left-hook: " + resrcchangcalc.ChangeReasonNotFound,
+		},
+		expectDeleted:    []string{"left-hook: " + resrcchangcalc.ChangeReasonDeleteHookNotCleanedUp},
+		expectAnyChanges: true,
+	}),
+	Entry("release namespace is deleted if requested", uninstallChangesCase{
+		generalResources: []*unstructured.Unstructured{configMap("config", map[string]interface{}{"helm.sh/resource-policy": "keep"})},
+		liveObjects:      []*unstructured.Unstructured{configMap("config", ownedAnnotations())},
+		deleteNamespace:  true,
+		expectDeleted:    []string{"test-namespace: " + resrcchangcalc.ChangeReasonReleaseNamespaceDeleted},
+		expectKept:       []string{"config: " + resrcchangcalc.ChangeReasonKeptByResourcePolicy},
+		expectAnyChanges: true,
+	}),
+)
+
+func summarize[T any](changes []T, idAndReason func(change T) (*resrcid.ResourceID, string)) []string {
+	result := []string{}
+	for _, change := range changes {
+		id, reason := idAndReason(change)
+		result = append(result, fmt.Sprintf("%s: %s", id.Name(), reason))
+	}
+
+	return result
+}
+
+// Release annotations and labels of resources owned by the release, with extra annotations as
+// key-value pairs.
+func ownedAnnotations(extra ...string) map[string]interface{} {
+	annotations := map[string]interface{}{
+		"meta.helm.sh/release-name":      releaseName,
+		"meta.helm.sh/release-namespace": releaseNamespace,
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		annotations[extra[i]] = extra[i+1]
+	}
+
+	return annotations
+}
+
+// Release labels are added if the ConfigMap has release annotations.
+func configMap(name string, annotations map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": releaseNamespace,
+	}
+
+	if annotations != nil {
+		metadata["annotations"] = annotations
+
+		if _, found := annotations["meta.helm.sh/release-name"]; found {
+			metadata["labels"] = map[string]interface{}{
+				"app.kubernetes.io/managed-by": "Helm",
+			}
+		}
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   metadata,
+			"data": map[string]interface{}{
+				"key": "value",
+			},
+		},
+	}
+}
+
+func namespace(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]interface{}{
+				"name": name,
+			},
+		},
+	}
+}
@@ -4,4 +4,6 @@ import "github.com/pkg/errors"
 
 var (
 	ErrChangesPlanned = errors.New("changes planned")
+	ErrDriftDetected  = errors.New("drift detected")
+	ErrDriftUnknown   = errors.New("drift unknown")
 )
@@ -0,0 +1,13 @@
+package resrcchangcalc_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestResourceChangeCalculator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "resource change calculator suite")
+}
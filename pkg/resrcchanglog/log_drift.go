@@ -0,0 +1,75 @@
+package resrcchanglog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gookit/color"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
+)
+
+func LogDrift(
+	ctx context.Context,
+	releaseName string,
+	releaseNamespace string,
+	revision int,
+	modifiedDrifts []*resrcchangcalc.ModifiedResourceDrift,
+	deletedDrifts []*resrcchangcalc.DeletedResourceDrift,
+	extraManagersDrifts []*resrcchangcalc.ExtraManagersResourceDrift,
+	unknownDrifts []*resrcchangcalc.UnknownResourceDrift,
+) {
+	if len(modifiedDrifts)+len(deletedDrifts)+len(extraManagersDrifts)+len(unknownDrifts) == 0 {
+		log.Default.Info(ctx, color.Style{color.Bold, color.Green}.Render(fmt.Sprintf("No drift detected for release %q (namespace: %q, revision: %d)", releaseName, releaseNamespace, revision)))
+		return
+	}
+
+	log.Default.Info(ctx, "")
+
+	for _, drift := range modifiedDrifts {
+		log.Default.InfoBlock(ctx, updateStyle("Modified ")+resourceStyle(drift.ResourceID.HumanID())).Do(
+			func() {
+				log.Default.Info(ctx, "%s", drift.Udiff)
+			},
+		)
+	}
+
+	for _, drift := range deletedDrifts {
+		log.Default.Info(ctx, deleteStyle("Deleted ")+resourceStyle(drift.ResourceID.HumanID())+": %s", drift.Reason)
+	}
+
+	for _, drift := range extraManagersDrifts {
+		log.Default.Info(ctx, resourceStyle(drift.ResourceID.HumanID())+keepStyle(" also managed by ")+"%s", strings.Join(drift.Managers, ", "))
+	}
+
+	for _, drift := range unknownDrifts {
+		if drift.Err != nil {
+			log.Default.Info(ctx, unknownStyle("Unknown ")+resourceStyle(drift.ResourceID.HumanID())+": %s: %s", drift.Reason, drift.Err)
+		} else {
+			log.Default.Info(ctx, unknownStyle("Unknown ")+resourceStyle(drift.ResourceID.HumanID())+": %s", drift.Reason)
+		}
+	}
+
+	log.Default.Info(ctx, "")
+
+	log.Default.Info(ctx, color.Bold.Render("Drift summary")+" for release %q (namespace: %q, revision: %d):", releaseName, releaseNamespace, revision)
+	if len(modifiedDrifts) > 0 {
+		log.Default.Info(ctx, "- "+updateStyle("modified:")+" %d resource(s)", len(modifiedDrifts))
+	}
+	if len(deletedDrifts) > 0 {
+		log.Default.Info(ctx, "- "+deleteStyle("deleted:")+" %d resource(s)", len(deletedDrifts))
+	}
+	if len(extraManagersDrifts) > 0 {
+		log.Default.Info(ctx, "- "+keepStyle("extra field managers:")+" %d resource(s)", len(extraManagersDrifts))
+	}
+	if len(unknownDrifts) > 0 {
+		log.Default.Info(ctx, "- "+unknownStyle("drift unknown:")+" %d resource(s)", len(unknownDrifts))
+	}
+	log.Default.Info(ctx, "")
+}
+
+func unknownStyle(text string) string {
+	return color.Style{color.Bold, color.Magenta}.Render(text)
+}
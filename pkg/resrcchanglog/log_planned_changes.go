@@ -20,8 +20,9 @@ func LogPlannedChanges(
 	updatedChanges []*resrcchangcalc.UpdatedResourceChange,
 	appliedChanges []*resrcchangcalc.AppliedResourceChange,
 	deletedChanges []*resrcchangcalc.DeletedResourceChange,
+	transferredChanges []*resrcchangcalc.TransferredResourceChange,
 ) {
-	totalChangesLen := len(createdChanges) + len(recreatedChanges) + len(updatedChanges) + len(appliedChanges) + len(deletedChanges)
+	totalChangesLen := len(createdChanges) + len(recreatedChanges) + len(updatedChanges) + len(appliedChanges) + len(deletedChanges) + len(transferredChanges)
 
 	if totalChangesLen == 0 {
 		if releaseChangesPlanned {
@@ -44,7 +45,7 @@ func LogPlannedChanges(
 	}
 
 	for _, change := range recreatedChanges {
-		log.Default.InfoBlock(ctx, recreateStyle("Recreate ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)).Do(
+		log.Default.InfoBlock(ctx, recreateStyle("Recreate ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)+ownershipChangeEnding(change.OwnershipChange)).Do(
 			func() {
 				log.Default.Info(ctx, "%s", change.Udiff)
 			},
@@ -52,7 +53,7 @@ func LogPlannedChanges(
 	}
 
 	for _, change := range updatedChanges {
-		log.Default.InfoBlock(ctx, updateStyle("Update ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)).Do(
+		log.Default.InfoBlock(ctx, updateStyle("Update ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)+ownershipChangeEnding(change.OwnershipChange)).Do(
 			func() {
 				log.Default.Info(ctx, "%s", change.Udiff)
 			},
@@ -60,7 +61,7 @@ func LogPlannedChanges(
 	}
 
 	for _, change := range appliedChanges {
-		log.Default.InfoBlock(ctx, applyStyle("Blindly apply ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)).Do(
+		log.Default.InfoBlock(ctx, applyStyle("Blindly apply ")+resourceStyle(change.ResourceID.HumanID())+ending(change.CleanedUpOnSuccess, change.CleanedUpOnFailure)+ownershipChangeEnding(change.OwnershipChange)).Do(
 			func() {
 				log.Default.Info(ctx, "%s", change.Udiff)
 			},
@@ -75,6 +76,11 @@ func LogPlannedChanges(
 		)
 	}
 
+	for _, change := range transferredChanges {
+		log.Default.Info(ctx, keepStyle("Transfer ")+resourceStyle(change.ResourceID.HumanID())+" ("+adoptStyle(change.Reason)+")")
+		log.Default.Info(ctx, "")
+	}
+
 	log.Default.Info(ctx, color.Bold.Render("Planned changes summary")+" for release %q (namespace: %q):", releaseName, releaseNamespace)
 	if len(createdChanges) > 0 {
 		log.Default.Info(ctx, "- "+createStyle("create:")+" %d resource(s)", len(createdChanges))
@@ -91,9 +97,54 @@ func LogPlannedChanges(
 	if len(deletedChanges) > 0 {
 		log.Default.Info(ctx, "- "+deleteStyle("delete:")+" %d resource(s)", len(deletedChanges))
 	}
+	if len(transferredChanges) > 0 {
+		log.Default.Info(ctx, "- "+keepStyle("transfer:")+" %d resource(s)", len(transferredChanges))
+	}
 	log.Default.Info(ctx, "")
 }
 
+func LogPlannedUninstallChanges(
+	ctx context.Context,
+	releaseName string,
+	releaseNamespace string,
+	createdChanges []*resrcchangcalc.CreatedResourceChange,
+	recreatedChanges []*resrcchangcalc.RecreatedResourceChange,
+	updatedChanges []*resrcchangcalc.UpdatedResourceChange,
+	appliedChanges []*resrcchangcalc.AppliedResourceChange,
+	deletedChanges []*resrcchangcalc.DeletedResourceChange,
+	keptChanges []*resrcchangcalc.KeptResourceChange,
+	notOwnedChanges []*resrcchangcalc.NotOwnedResourceChange,
+) {
+	totalChangesLen := len(createdChanges) + len(recreatedChanges) + len(updatedChanges) + len(appliedChanges) + len(deletedChanges)
+
+	if totalChangesLen == 0 && len(keptChanges) == 0 && len(notOwnedChanges) == 0 {
+		log.Default.Info(ctx, color.Style{color.Bold, color.Yellow}.Render(fmt.Sprintf("No resources to delete, but will delete release %q (namespace: %q)", releaseName, releaseNamespace)))
+		return
+	}
+
+	if totalChangesLen > 0 {
+		LogPlannedChanges(ctx, releaseName, releaseNamespace, true, createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, nil)
+	} else {
+		log.Default.Info(ctx, "")
+	}
+
+	if len(keptChanges) > 0 {
+		log.Default.Info(ctx, color.Bold.Render("Will be kept")+" in the cluster after uninstalling release %q (namespace: %q):", releaseName, releaseNamespace)
+		for _, change := range keptChanges {
+			log.Default.Info(ctx, "- "+keepStyle(change.ResourceID.HumanID())+": %s", change.Reason)
+		}
+		log.Default.Info(ctx, "")
+	}
+
+	if len(notOwnedChanges) > 0 {
+		log.Default.Info(ctx, color.Bold.Render("Won't be deleted")+", not owned by release %q (namespace: %q):", releaseName, releaseNamespace)
+		for _, change := range notOwnedChanges {
+			log.Default.Info(ctx, "- "+keepStyle(change.ResourceID.HumanID())+": %s", change.Reason)
+		}
+		log.Default.Info(ctx, "")
+	}
+}
+
 func createStyle(text string) string {
 	return color.Style{color.Bold, color.Green}.Render(text)
 }
@@ -114,10 +165,26 @@ func deleteStyle(text string) string {
 	return color.Style{color.Bold, color.Red}.Render(text)
 }
 
+func keepStyle(text string) string {
+	return color.Style{color.Bold, color.Blue}.Render(text)
+}
+
+func adoptStyle(text string) string {
+	return color.Style{color.Bold, color.Magenta}.Render(text)
+}
+
 func resourceStyle(text string) string {
 	return color.Style{color.Bold}.Render(text)
 }
 
+func ownershipChangeEnding(ownershipChange string) string {
+	if ownershipChange == "" {
+		return ""
+	}
+
+	return " (" + adoptStyle(ownershipChange) + ")"
+}
+
 func ending(cleanupOnSuccess, cleanupOnFailure bool) string {
 	if cleanupOnSuccess && cleanupOnFailure {
 		return " and " + deleteStyle("delete") + " it"
@@ -0,0 +1,207 @@
+package resrcchanglog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gookit/color"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcchangcalc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+)
+
+type ChangeType string
+
+const (
+	ChangeTypeCreate   ChangeType = "create"
+	ChangeTypeRecreate ChangeType = "recreate"
+	ChangeTypeUpdate   ChangeType = "update"
+	ChangeTypeApply    ChangeType = "apply"
+	ChangeTypeDelete   ChangeType = "delete"
+	ChangeTypeTransfer ChangeType = "transfer"
+	ChangeTypeKeep     ChangeType = "keep"
+	ChangeTypeNotOwned ChangeType = "not-owned"
+)
+
+func NewPlannedChangesReport(
+	releaseName string,
+	releaseNamespace string,
+	releaseChangesPlanned bool,
+	createdChanges []*resrcchangcalc.CreatedResourceChange,
+	recreatedChanges []*resrcchangcalc.RecreatedResourceChange,
+	updatedChanges []*resrcchangcalc.UpdatedResourceChange,
+	appliedChanges []*resrcchangcalc.AppliedResourceChange,
+	deletedChanges []*resrcchangcalc.DeletedResourceChange,
+	transferredChanges []*resrcchangcalc.TransferredResourceChange,
+) *PlannedChangesReport {
+	report := &PlannedChangesReport{
+		Release:               releaseName,
+		Namespace:             releaseNamespace,
+		ReleaseChangesPlanned: releaseChangesPlanned,
+		Changes:               []*PlannedChange{},
+	}
+
+	for _, change := range createdChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeCreate, change.Reason, change.Udiff, change.CleanedUpOnSuccess, change.CleanedUpOnFailure, ""))
+	}
+
+	for _, change := range recreatedChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeRecreate, change.Reason, change.Udiff, change.CleanedUpOnSuccess, change.CleanedUpOnFailure, change.OwnershipChange))
+	}
+
+	for _, change := range updatedChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeUpdate, change.Reason, change.Udiff, change.CleanedUpOnSuccess, change.CleanedUpOnFailure, change.OwnershipChange))
+	}
+
+	for _, change := range appliedChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeApply, change.Reason, change.Udiff, change.CleanedUpOnSuccess, change.CleanedUpOnFailure, change.OwnershipChange))
+	}
+
+	for _, change := range deletedChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeDelete, change.Reason, change.Udiff, false, false, ""))
+	}
+
+	for _, change := range transferredChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeTransfer, change.Reason, "", false, false, ""))
+	}
+
+	return report
+}
+
+func NewPlannedUninstallChangesReport(
+	releaseName string,
+	releaseNamespace string,
+	createdChanges []*resrcchangcalc.CreatedResourceChange,
+	recreatedChanges []*resrcchangcalc.RecreatedResourceChange,
+	updatedChanges []*resrcchangcalc.UpdatedResourceChange,
+	appliedChanges []*resrcchangcalc.AppliedResourceChange,
+	deletedChanges []*resrcchangcalc.DeletedResourceChange,
+	keptChanges []*resrcchangcalc.KeptResourceChange,
+	notOwnedChanges []*resrcchangcalc.NotOwnedResourceChange,
+) *PlannedChangesReport {
+	report := NewPlannedChangesReport(releaseName, releaseNamespace, true, createdChanges, recreatedChanges, updatedChanges, appliedChanges, deletedChanges, nil)
+	report.Uninstall = true
+
+	for _, change := range keptChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeKeep, change.Reason, "", false, false, ""))
+	}
+
+	for _, change := range notOwnedChanges {
+		report.Changes = append(report.Changes, newPlannedChange(change.ResourceID, ChangeTypeNotOwned, change.Reason, "", false, false, ""))
+	}
+
+	return report
+}
+
+type PlannedChangesReport struct {
+	Release               string           `json:"release"`
+	Namespace             string           `json:"namespace"`
+	ReleaseChangesPlanned bool             `json:"releaseChangesPlanned"`
+	Uninstall             bool             `json:"uninstall,omitempty"`
+	Changes               []*PlannedChange `json:"changes"`
+}
+
+type PlannedChange struct {
+	ID                 string     `json:"id"`
+	HumanID            string     `json:"humanId"`
+	Group              string     `json:"group,omitempty"`
+	Version            string     `json:"version"`
+	Kind               string     `json:"kind"`
+	Namespace          string     `json:"namespace,omitempty"`
+	Name               string     `json:"name"`
+	Type               ChangeType `json:"type"`
+	Reason             string     `json:"reason"`
+	Diff               string     `json:"diff,omitempty"`
+	CleanedUpOnSuccess bool       `json:"cleanedUpOnSuccess,omitempty"`
+	CleanedUpOnFailure bool       `json:"cleanedUpOnFailure,omitempty"`
+	OwnershipChange    string     `json:"ownershipChange,omitempty"`
+}
+
+func (r *PlannedChangesReport) Count(changeType ChangeType) int {
+	var count int
+	for _, change := range r.Changes {
+		if change.Type == changeType {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (r *PlannedChangesReport) Markdown() string {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "### Planned changes for release `%s` (namespace: `%s`)\n\n", r.Release, r.Namespace)
+
+	if len(r.Changes) == 0 {
+		if r.Uninstall {
+			out.WriteString("No resource changes planned, but the release will be deleted.\n")
+		} else if r.ReleaseChangesPlanned {
+			out.WriteString("No resource changes planned, but a new release revision will be created.\n")
+		} else {
+			out.WriteString("No changes planned.\n")
+		}
+
+		return out.String()
+	}
+
+	out.WriteString("| Change | Resources |\n")
+	out.WriteString("| --- | --- |\n")
+	for _, changeType := range []ChangeType{ChangeTypeCreate, ChangeTypeRecreate, ChangeTypeUpdate, ChangeTypeApply, ChangeTypeDelete, ChangeTypeTransfer, ChangeTypeKeep, ChangeTypeNotOwned} {
+		if count := r.Count(changeType); count > 0 {
+			fmt.Fprintf(&out, "| %s | %d |\n", changeType, count)
+		}
+	}
+
+	for _, change := range r.Changes {
+		out.WriteString("\n<details>\n")
+		if change.OwnershipChange != "" {
+			fmt.Fprintf(&out, "<summary><b>%s</b> <code>%s</code>: %s, %s</summary>\n\n", change.Type, change.HumanID, change.Reason, change.OwnershipChange)
+		} else {
+			fmt.Fprintf(&out, "<summary><b>%s</b> <code>%s</code>: %s</summary>\n\n", change.Type, change.HumanID, change.Reason)
+		}
+		if change.Diff != "" {
+			fence := markdownCodeFence(change.Diff)
+			fmt.Fprintf(&out, "%sdiff\n%s\n%s\n", fence, change.Diff, fence)
+		}
+		out.WriteString("</details>\n")
+	}
+
+	return out.String()
+}
+
+// Returns a code fence longer than the longest run of backticks in the content, so that the
+// content can't close the code block early.
+func markdownCodeFence(content string) string {
+	var longestRun, currentRun int
+	for _, r := range content {
+		if r == '`' {
+			currentRun++
+			longestRun = max(longestRun, currentRun)
+		} else {
+			currentRun = 0
+		}
+	}
+
+	return strings.Repeat("`", max(3, longestRun+1))
+}
+
+func newPlannedChange(id *resrcid.ResourceID, changeType ChangeType, reason, uDiff string, cleanedUpOnSuccess, cleanedUpOnFailure bool, ownershipChange string) *PlannedChange {
+	gvk := id.GroupVersionKind()
+
+	return &PlannedChange{
+		ID:                 id.ID(),
+		HumanID:            id.HumanID(),
+		Group:              gvk.Group,
+		Version:            gvk.Version,
+		Kind:               gvk.Kind,
+		Namespace:          id.Namespace(),
+		Name:               id.Name(),
+		Type:               changeType,
+		Reason:             reason,
+		Diff:               color.ClearCode(uDiff),
+		CleanedUpOnSuccess: cleanedUpOnSuccess,
+		CleanedUpOnFailure: cleanedUpOnFailure,
+		OwnershipChange:    ownershipChange,
+	}
+}
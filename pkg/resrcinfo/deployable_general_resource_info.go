@@ -109,6 +109,10 @@ func (i *DeployableGeneralResourceInfo) DryApplyResource() *resrc.RemoteResource
 	return i.dryApplyResource
 }
 
+func (i *DeployableGeneralResourceInfo) DryApplyErr() error {
+	return i.dryApplyErr
+}
+
 func (i *DeployableGeneralResourceInfo) ShouldCreate() bool {
 	return !i.exists
 }
@@ -58,9 +58,33 @@ func (i *DeployablePrevReleaseGeneralResourceInfo) LiveResource() *resrc.RemoteR
 }
 
 func (i *DeployablePrevReleaseGeneralResourceInfo) ShouldKeepOnDelete(releaseName string, releaseNamespace string) bool {
+	if _, _, transfer := i.TransferTo(releaseName, releaseNamespace); transfer {
+		return true
+	}
+
 	return i.resource.KeepOnDelete() || (i.exists && i.getResource.KeepOnDelete(releaseName, releaseNamespace))
 }
 
+// Returns the release the resource is transferred to, as specified by the transfer annotation
+// either in the previous release or on the live resource. Transfers to the release itself are
+// ignored.
+func (i *DeployablePrevReleaseGeneralResourceInfo) TransferTo(releaseName string, releaseNamespace string) (newReleaseName string, newReleaseNamespace string, transfer bool) {
+	var set bool
+	if i.exists {
+		newReleaseName, newReleaseNamespace, set = i.getResource.TransferTo(releaseNamespace)
+	}
+
+	if !set {
+		newReleaseName, newReleaseNamespace, set = i.resource.TransferTo()
+	}
+
+	if !set || (newReleaseName == releaseName && newReleaseNamespace == releaseNamespace) {
+		return "", "", false
+	}
+
+	return newReleaseName, newReleaseNamespace, true
+}
+
 func (i *DeployablePrevReleaseGeneralResourceInfo) ShouldDelete(curReleaseExistingResourcesUIDs []types.UID, releaseName string, releaseNamespace string) bool {
 	if !i.exists {
 		return false
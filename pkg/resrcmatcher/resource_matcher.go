@@ -1,6 +1,10 @@
 package resrcmatcher
 
 import (
+	"path"
+
+	"k8s.io/apimachinery/pkg/labels"
+
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 	"github.com/werf/nelm-for-werf-helm/pkg/utls"
 )
@@ -17,11 +21,13 @@ func NewResourceMatcher(names, namespaces, groups, versions, kinds []string, opt
 		groups:     groups,
 		versions:   versions,
 		kinds:      kinds,
+		labels:     opts.LabelSelector,
 	}
 }
 
 type ResourceMatcherOptions struct {
 	DefaultNamespace string
+	LabelSelector    labels.Selector
 }
 
 type ResourceMatcher struct {
@@ -30,6 +36,7 @@ type ResourceMatcher struct {
 	groups     []string
 	versions   []string
 	kinds      []string
+	labels     labels.Selector
 }
 
 func (s *ResourceMatcher) Match(resource *resrcid.ResourceID) bool {
@@ -38,7 +45,7 @@ func (s *ResourceMatcher) Match(resource *resrcid.ResourceID) bool {
 		nameMatch = true
 	} else {
 		for _, name := range s.names {
-			if resource.Name() == name {
+			if matchValue(name, resource.Name()) {
 				nameMatch = true
 				break
 			}
@@ -53,7 +60,7 @@ func (s *ResourceMatcher) Match(resource *resrcid.ResourceID) bool {
 		namespaceMatch = true
 	} else {
 		for _, namespace := range s.namespaces {
-			if resource.Namespace() == namespace {
+			if matchValue(namespace, resource.Namespace()) {
 				namespaceMatch = true
 				break
 			}
@@ -68,7 +75,7 @@ func (s *ResourceMatcher) Match(resource *resrcid.ResourceID) bool {
 		groupMatch = true
 	} else {
 		for _, group := range s.groups {
-			if resource.GroupVersionKind().Group == group {
+			if matchValue(group, resource.GroupVersionKind().Group) {
 				groupMatch = true
 				break
 			}
@@ -83,7 +90,7 @@ func (s *ResourceMatcher) Match(resource *resrcid.ResourceID) bool {
 		versionMatch = true
 	} else {
 		for _, version := range s.versions {
-			if resource.GroupVersionKind().Version == version {
+			if matchValue(version, resource.GroupVersionKind().Version) {
 				versionMatch = true
 				break
 			}
@@ -98,7 +105,7 @@ func (s *ResourceMatcher) Match(resource *resrcid.ResourceID) bool {
 		kindMatch = true
 	} else {
 		for _, kind := range s.kinds {
-			if resource.GroupVersionKind().Kind == kind {
+			if matchValue(kind, resource.GroupVersionKind().Kind) {
 				kindMatch = true
 				break
 			}
@@ -110,3 +117,23 @@ func (s *ResourceMatcher) Match(resource *resrcid.ResourceID) bool {
 
 	return true
 }
+
+func (s *ResourceMatcher) MatchWithLabels(resource *resrcid.ResourceID, resourceLabels map[string]string) bool {
+	if !s.Match(resource) {
+		return false
+	}
+
+	if s.labels == nil {
+		return true
+	}
+
+	return s.labels.Matches(labels.Set(resourceLabels))
+}
+
+func matchValue(pattern, value string) bool {
+	if matched, err := path.Match(pattern, value); err == nil && matched {
+		return true
+	}
+
+	return pattern == value
+}
@@ -0,0 +1,127 @@
+package resrcmatcher
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+)
+
+// Selector format: "key=value;key=value;...", where keys are: kind, name, namespace, group,
+// version, labels. Values except labels support globs. Labels value is a regular label
+// selector, e.g. "labels=app=web,tier notin (db)".
+func ParseResourceSelector(selector string, opts ResourceSelectorOptions) (*ResourceMatcher, error) {
+	var names, namespaces, groups, versions, kinds []string
+	var labelSelector labels.Selector
+
+	for _, part := range strings.Split(selector, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid resource selector part %q, expected key=value", part)
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "name":
+			names = append(names, value)
+		case "namespace":
+			namespaces = append(namespaces, value)
+		case "group":
+			groups = append(groups, value)
+		case "version":
+			versions = append(versions, value)
+		case "kind":
+			kinds = append(kinds, value)
+		case "labels":
+			var err error
+			labelSelector, err = labels.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing label selector %q: %w", value, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown resource selector key %q", key)
+		}
+	}
+
+	if names == nil && namespaces == nil && groups == nil && versions == nil && kinds == nil && labelSelector == nil {
+		return nil, fmt.Errorf("empty resource selector %q", selector)
+	}
+
+	return NewResourceMatcher(names, namespaces, groups, versions, kinds, ResourceMatcherOptions{
+		DefaultNamespace: opts.DefaultNamespace,
+		LabelSelector:    labelSelector,
+	}), nil
+}
+
+func NewResourceSelector(includeSelectors, excludeSelectors []string, opts ResourceSelectorOptions) (*ResourceSelector, error) {
+	var include, exclude []*ResourceMatcher
+
+	for _, selector := range includeSelectors {
+		matcher, err := ParseResourceSelector(selector, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing include resource selector: %w", err)
+		}
+
+		include = append(include, matcher)
+	}
+
+	for _, selector := range excludeSelectors {
+		matcher, err := ParseResourceSelector(selector, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing exclude resource selector: %w", err)
+		}
+
+		exclude = append(exclude, matcher)
+	}
+
+	return &ResourceSelector{
+		include: include,
+		exclude: exclude,
+	}, nil
+}
+
+type ResourceSelectorOptions struct {
+	DefaultNamespace string
+}
+
+type ResourceSelector struct {
+	include []*ResourceMatcher
+	exclude []*ResourceMatcher
+}
+
+func (s *ResourceSelector) Empty() bool {
+	return len(s.include) == 0 && len(s.exclude) == 0
+}
+
+func (s *ResourceSelector) Select(resource *resrcid.ResourceID, resourceLabels map[string]string) bool {
+	if len(s.include) > 0 {
+		var included bool
+		for _, matcher := range s.include {
+			if matcher.MatchWithLabels(resource, resourceLabels) {
+				included = true
+				break
+			}
+		}
+
+		if !included {
+			return false
+		}
+	}
+
+	for _, matcher := range s.exclude {
+		if matcher.MatchWithLabels(resource, resourceLabels) {
+			return false
+		}
+	}
+
+	return true
+}
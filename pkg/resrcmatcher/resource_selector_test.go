@@ -0,0 +1,93 @@
+package resrcmatcher_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
+)
+
+var (
+	configMapID = resrcid.NewResourceID("config", "", schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, resrcid.ResourceIDOptions{
+		DefaultNamespace: "app",
+	})
+	deploymentID = resrcid.NewResourceID("web-frontend", "app", schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, resrcid.ResourceIDOptions{})
+	jobID        = resrcid.NewResourceID("migrate", "other", schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, resrcid.ResourceIDOptions{})
+)
+
+var _ = Describe("ParseResourceSelector", func() {
+	DescribeTable("should match resources",
+		func(selector string, resource *resrcid.ResourceID, resourceLabels map[string]string, expectedMatch bool) {
+			matcher, err := resrcmatcher.ParseResourceSelector(selector, resrcmatcher.ResourceSelectorOptions{
+				DefaultNamespace: "app",
+			})
+			Expect(err).To(Succeed())
+			Expect(matcher.MatchWithLabels(resource, resourceLabels)).To(Equal(expectedMatch))
+		},
+		Entry("by kind", "kind=ConfigMap", configMapID, nil, true),
+		Entry("by other kind", "kind=Deployment", configMapID, nil, false),
+		Entry("by name glob", "name=web-*", deploymentID, nil, true),
+		Entry("by name glob not matching", "name=api-*", deploymentID, nil, false),
+		Entry("by any of repeated keys", "kind=Job;kind=Deployment", deploymentID, nil, true),
+		Entry("by all of different keys", "kind=Deployment;group=apps;version=v1;name=web-frontend", deploymentID, nil, true),
+		Entry("by all of different keys, one not matching", "kind=Deployment;group=batch", deploymentID, nil, false),
+		Entry("by empty group of core resources", "group=;kind=ConfigMap", configMapID, nil, true),
+		Entry("by namespace", "namespace=other", jobID, nil, true),
+		Entry("by empty namespace, defaulting to the release namespace", "namespace=", configMapID, nil, true),
+		Entry("by resource namespace defaulting to the release namespace", "namespace=app", configMapID, nil, true),
+		Entry("by labels", "labels=tier=web,env in (prod,stage)", deploymentID, map[string]string{"tier": "web", "env": "prod"}, true),
+		Entry("by labels not matching", "labels=tier=web,env in (prod,stage)", deploymentID, map[string]string{"tier": "web", "env": "dev"}, false),
+		Entry("by kind and labels", "kind=Deployment;labels=tier=web", deploymentID, map[string]string{"tier": "web"}, true),
+		Entry("with spaces and empty parts", " kind = Job ; ; name = migrate ", jobID, nil, true),
+	)
+
+	DescribeTable("should fail on invalid selector",
+		func(selector, expectedErr string) {
+			_, err := resrcmatcher.ParseResourceSelector(selector, resrcmatcher.ResourceSelectorOptions{})
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("without value", "kind", `invalid resource selector part "kind", expected key=value`),
+		Entry("with unknown key", "type=ConfigMap", `unknown resource selector key "type"`),
+		Entry("with invalid labels", "labels=tier in (", "error parsing label selector"),
+		Entry("empty", "", `empty resource selector ""`),
+		Entry("with empty parts only", " ; ", "empty resource selector"),
+	)
+})
+
+var _ = Describe("ResourceSelector", func() {
+	DescribeTable("should select resources",
+		func(include, exclude []string, expectedSelected []*resrcid.ResourceID) {
+			selector, err := resrcmatcher.NewResourceSelector(include, exclude, resrcmatcher.ResourceSelectorOptions{
+				DefaultNamespace: "app",
+			})
+			Expect(err).To(Succeed())
+
+			var selected []*resrcid.ResourceID
+			for _, resource := range []*resrcid.ResourceID{configMapID, deploymentID, jobID} {
+				if selector.Select(resource, map[string]string{"name": resource.Name()}) {
+					selected = append(selected, resource)
+				}
+			}
+
+			Expect(selected).To(Equal(expectedSelected))
+		},
+		Entry("everything without selectors", nil, nil, []*resrcid.ResourceID{configMapID, deploymentID, jobID}),
+		Entry("resources matching any include selector", []string{"kind=ConfigMap", "kind=Job"}, nil, []*resrcid.ResourceID{configMapID, jobID}),
+		Entry("resources not matching any exclude selector", nil, []string{"kind=ConfigMap", "namespace=other"}, []*resrcid.ResourceID{deploymentID}),
+		Entry("included resources not excluded", []string{"namespace=app"}, []string{"labels=name=config"}, []*resrcid.ResourceID{deploymentID}),
+		Entry("nothing if everything is excluded", nil, []string{"name=*"}, nil),
+	)
+
+	It("should be empty without selectors", func() {
+		selector, err := resrcmatcher.NewResourceSelector(nil, nil, resrcmatcher.ResourceSelectorOptions{})
+		Expect(err).To(Succeed())
+		Expect(selector.Empty()).To(BeTrue())
+	})
+
+	It("should fail on invalid exclude selector", func() {
+		_, err := resrcmatcher.NewResourceSelector([]string{"kind=Job"}, []string{"kind"}, resrcmatcher.ResourceSelectorOptions{})
+		Expect(err).To(MatchError(ContainSubstring("error parsing exclude resource selector")))
+	})
+})
@@ -0,0 +1,13 @@
+package resrcmatcher_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestResourceMatcher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "resource matcher suite")
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrcpatcher"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrctransfrmr"
 	"github.com/werf/nelm-for-werf-helm/pkg/utls"
@@ -63,6 +64,7 @@ func NewDeployableResourcesProcessor(
 		deployableStandaloneCRDsPatchers:  deployableStandaloneCRDsPatchers,
 		deployableHookResourcePatchers:    deployableHookResourcePatchers,
 		deployableGeneralResourcePatchers: deployableGeneralResourcePatchers,
+		adoptResourceMatchers:             opts.AdoptResourceMatchers,
 	}
 }
 
@@ -79,6 +81,9 @@ type DeployableResourcesProcessorOptions struct {
 	Mapper                            meta.ResettableRESTMapper
 	DiscoveryClient                   discovery.CachedDiscoveryInterface
 	AllowClusterAccess                bool
+	// Resources matching any of these are adopted even if they belong to another release or
+	// to no release at all, same as resources annotated with "werf.io/adopt=true".
+	AdoptResourceMatchers []*resrcmatcher.ResourceMatcher
 }
 
 type DeployableResourcesProcessor struct {
@@ -105,6 +110,8 @@ type DeployableResourcesProcessor struct {
 	deployableHookResourcePatchers    []resrcpatcher.ResourcePatcher
 	deployableGeneralResourcePatchers []resrcpatcher.ResourcePatcher
 
+	adoptResourceMatchers []*resrcmatcher.ResourceMatcher
+
 	releasableHookResources    []*resrc.HookResource
 	releasableGeneralResources []*resrc.GeneralResource
 
@@ -739,9 +746,65 @@ func (p *DeployableResourcesProcessor) validateAdoptableResources() error {
 		}
 
 		if adoptable, nonAdoptableReason := genResInfo.LiveResource().AdoptableBy(p.releaseName, p.releaseNamespace); !adoptable {
-			errs = append(errs, fmt.Errorf("resource %q is not adoptable: %s", genResInfo.HumanID(), nonAdoptableReason))
+			if p.adoptionRequested(genResInfo) {
+				p.recordAdoption(genResInfo)
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf("resource %q is not adoptable: %s; annotate it with \"werf.io/adopt=true\" or select it for adoption to take it over", genResInfo.HumanID(), nonAdoptableReason))
 		}
 	}
 
 	return utls.Multierrorf("adoption validation failed", errs)
 }
+
+// Adoption is requested either by the resource itself or by the previous owner transferring
+// the resource to this release.
+func (p *DeployableResourcesProcessor) adoptionRequested(genResInfo *resrcinfo.DeployableGeneralResourceInfo) bool {
+	res := genResInfo.Resource()
+	if res.Adopt() {
+		return true
+	}
+
+	if newReleaseName, newReleaseNamespace, set := genResInfo.LiveResource().TransferTo(p.releaseNamespace); set && newReleaseName == p.releaseName && newReleaseNamespace == p.releaseNamespace {
+		return true
+	}
+
+	for _, matcher := range p.adoptResourceMatchers {
+		if matcher.MatchWithLabels(res.ResourceID, res.Unstructured().GetLabels()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Records the previous owner of the adopted resource in the releasable resource, so that
+// the takeover can be found in the release history.
+func (p *DeployableResourcesProcessor) recordAdoption(genResInfo *resrcinfo.DeployableGeneralResourceInfo) {
+	adoptedFrom := "unmanaged"
+	if ownerName, ownerNamespace, found := genResInfo.LiveResource().ReleaseOwner(); found {
+		adoptedFrom = ownerNamespace + "/" + ownerName
+	}
+
+	for i, res := range p.releasableGeneralResources {
+		if res.ID() != genResInfo.ID() {
+			continue
+		}
+
+		unstruct := res.Unstructured().DeepCopy()
+		annotations := unstruct.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[resrc.AnnotationKeyAdoptedFrom] = adoptedFrom
+		unstruct.SetAnnotations(annotations)
+
+		p.releasableGeneralResources[i] = resrc.NewGeneralResource(unstruct, resrc.GeneralResourceOptions{
+			FilePath:         res.FilePath(),
+			DefaultNamespace: p.releaseNamespace,
+			Mapper:           p.mapper,
+			DiscoveryClient:  p.discoveryClient,
+		})
+	}
+}
@@ -9,9 +9,14 @@ import (
 	"github.com/werf/3p-helm-for-werf-helm/pkg/release"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/time"
 
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
 	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
 )
 
+// Stored in release labels, since Info.Description is free text that Helm and users may
+// overwrite.
+const LegacyReleaseLabelDeployType = "werf.io/deploy-type"
+
 func NewLegacyReleaseFromRelease(rel *Release) (*release.Release, error) {
 	var legacyHooks []*release.Hook
 	for _, res := range rel.HookResources() {
@@ -50,9 +55,27 @@ func NewLegacyReleaseFromRelease(rel *Release) (*release.Release, error) {
 		Chart:    rel.LegacyChart(),
 	}
 
+	if rel.DeployType() != "" {
+		legacyRel.Labels = map[string]string{
+			LegacyReleaseLabelDeployType: string(rel.DeployType()),
+		}
+	}
+
 	return legacyRel, nil
 }
 
+func deployTypeFromLegacyLabels(labels map[string]string) common.DeployType {
+	switch deployType := common.DeployType(labels[LegacyReleaseLabelDeployType]); deployType {
+	case common.DeployTypeInitial,
+		common.DeployTypeInstall,
+		common.DeployTypeUpgrade,
+		common.DeployTypeRollback:
+		return deployType
+	}
+
+	return ""
+}
+
 func hookResourceToLegacyHook(res *resrc.HookResource) (*release.Hook, error) {
 	var deletePolicies []release.HookDeletePolicy
 	if res.Recreate() {
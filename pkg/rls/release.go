@@ -57,6 +57,7 @@ func NewRelease(name, namespace string, revision int, values map[string]interfac
 		notes:            notes,
 		firstDeployed:    opts.FirstDeployed,
 		lastDeployed:     opts.LastDeployed,
+		deployType:       opts.DeployType,
 	}, nil
 }
 
@@ -64,6 +65,7 @@ type ReleaseOptions struct {
 	Status        release.Status
 	FirstDeployed time.Time
 	LastDeployed  time.Time
+	DeployType    common.DeployType
 	Mapper        meta.ResettableRESTMapper
 }
 
@@ -99,6 +101,7 @@ func NewReleaseFromLegacyRelease(legacyRelease *release.Release, opts ReleaseFro
 		Status:        legacyRelease.Info.Status,
 		FirstDeployed: legacyRelease.Info.FirstDeployed.Time,
 		LastDeployed:  legacyRelease.Info.LastDeployed.Time,
+		DeployType:    deployTypeFromLegacyLabels(legacyRelease.Labels),
 		Mapper:        opts.Mapper,
 	})
 	if err != nil {
@@ -124,6 +127,7 @@ type Release struct {
 	status        release.Status
 	firstDeployed time.Time
 	lastDeployed  time.Time
+	deployType    common.DeployType
 
 	hookResources    []*resrc.HookResource
 	generalResources []*resrc.GeneralResource
@@ -174,6 +178,10 @@ func (r *Release) LastDeployed() time.Time {
 	return r.lastDeployed
 }
 
+func (r *Release) DeployType() common.DeployType {
+	return r.deployType
+}
+
 func (r *Release) ID() string {
 	return fmt.Sprintf("%s:%s:%d", r.namespace, r.name, r.revision)
 }
@@ -221,6 +229,7 @@ func (r *Release) Failed() bool {
 
 func (r *Release) Pend(deployType common.DeployType) {
 	r.status = release.StatusPendingInstall
+	r.deployType = deployType
 
 	switch deployType {
 	case common.DeployTypeInitial,
@@ -239,6 +248,10 @@ func (r *Release) Pend(deployType common.DeployType) {
 	r.lastDeployed = now
 }
 
+func (r *Release) PendUninstall() {
+	r.status = release.StatusUninstalling
+}
+
 func (r *Release) Skip() {
 	r.status = release.StatusSkipped
 }
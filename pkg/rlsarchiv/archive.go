@@ -0,0 +1,185 @@
+package rlsarchiv
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+const FormatVersion = 1
+
+const (
+	metadataFileName = "metadata.json"
+	releasesDirName  = "releases"
+)
+
+var releaseFileNameRegex = regexp.MustCompile(`^v([0-9]+)\.json$`)
+
+type Metadata struct {
+	FormatVersion    int       `json:"formatVersion"`
+	ReleaseName      string    `json:"releaseName"`
+	ReleaseNamespace string    `json:"releaseNamespace"`
+	ExportedAt       time.Time `json:"exportedAt"`
+	Revisions        []int     `json:"revisions"`
+}
+
+// Portable release history: all revisions of a single release, sorted by revision.
+type Archive struct {
+	Metadata *Metadata
+	Releases []*legacyRelease.Release
+}
+
+// Writes the archive as tar.gz with "metadata.json" and "releases/v<revision>.json" entries.
+func Write(w io.Writer, releaseName, releaseNamespace string, legacyRels []*legacyRelease.Release, exportedAt time.Time) error {
+	legacyRels = sortedByRevision(legacyRels)
+
+	metadata := &Metadata{
+		FormatVersion:    FormatVersion,
+		ReleaseName:      releaseName,
+		ReleaseNamespace: releaseNamespace,
+		ExportedAt:       exportedAt,
+	}
+	for _, legacyRel := range legacyRels {
+		metadata.Revisions = append(metadata.Revisions, legacyRel.Version)
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	metadataData, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling archive metadata: %w", err)
+	}
+
+	if err := writeTarFile(tarWriter, metadataFileName, metadataData, exportedAt); err != nil {
+		return fmt.Errorf("error writing %q: %w", metadataFileName, err)
+	}
+
+	for _, legacyRel := range legacyRels {
+		data, err := rlsstor.MarshalRelease(legacyRel)
+		if err != nil {
+			return fmt.Errorf("error marshalling revision %d: %w", legacyRel.Version, err)
+		}
+
+		fileName := path.Join(releasesDirName, "v"+strconv.Itoa(legacyRel.Version)+".json")
+		if err := writeTarFile(tarWriter, fileName, data, exportedAt); err != nil {
+			return fmt.Errorf("error writing %q: %w", fileName, err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("error closing tar writer: %w", err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("error closing gzip writer: %w", err)
+	}
+
+	return nil
+}
+
+func Read(r io.Reader) (*Archive, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing gzip reader: %w", err)
+	}
+	defer gzipReader.Close()
+
+	archive := &Archive{}
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %q: %w", header.Name, err)
+		}
+
+		switch dir, fileName := path.Split(path.Clean(header.Name)); {
+		case dir == "" && fileName == metadataFileName:
+			var metadata Metadata
+			if err := json.Unmarshal(data, &metadata); err != nil {
+				return nil, fmt.Errorf("error unmarshalling archive metadata: %w", err)
+			}
+
+			archive.Metadata = &metadata
+		case dir == releasesDirName+"/" && releaseFileNameRegex.MatchString(fileName):
+			legacyRel, err := rlsstor.UnmarshalRelease(data)
+			if err != nil {
+				return nil, fmt.Errorf("error reading %q: %w", header.Name, err)
+			}
+
+			archive.Releases = append(archive.Releases, legacyRel)
+		}
+	}
+
+	if archive.Metadata == nil {
+		return nil, fmt.Errorf("%q not found in archive", metadataFileName)
+	}
+
+	if archive.Metadata.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version %d, expected %d", archive.Metadata.FormatVersion, FormatVersion)
+	}
+
+	archive.Releases = sortedByRevision(archive.Releases)
+
+	if len(archive.Releases) != len(archive.Metadata.Revisions) {
+		return nil, fmt.Errorf("expected %d revisions in archive, but found %d", len(archive.Metadata.Revisions), len(archive.Releases))
+	}
+
+	for _, legacyRel := range archive.Releases {
+		if legacyRel.Name != archive.Metadata.ReleaseName || legacyRel.Namespace != archive.Metadata.ReleaseNamespace {
+			return nil, fmt.Errorf("revision %d belongs to release %q (namespace: %q), expected release %q (namespace: %q)", legacyRel.Version, legacyRel.Name, legacyRel.Namespace, archive.Metadata.ReleaseName, archive.Metadata.ReleaseNamespace)
+		}
+	}
+
+	return archive, nil
+}
+
+func writeTarFile(tarWriter *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o600,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	}); err != nil {
+		return fmt.Errorf("error writing tar header: %w", err)
+	}
+
+	if _, err := tarWriter.Write(data); err != nil {
+		return fmt.Errorf("error writing tar entry: %w", err)
+	}
+
+	return nil
+}
+
+func sortedByRevision(legacyRels []*legacyRelease.Release) []*legacyRelease.Release {
+	result := append([]*legacyRelease.Release{}, legacyRels...)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result
+}
@@ -0,0 +1,180 @@
+package rlsarchiv_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsarchiv"
+)
+
+var exportedAt = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var _ = Describe("Archive", func() {
+	It("should read back written releases as they were", func() {
+		var buf bytes.Buffer
+		Expect(rlsarchiv.Write(&buf, "test-release", "test-namespace", []*legacyRelease.Release{
+			newLegacyRelease(2, legacyRelease.StatusDeployed),
+			newLegacyRelease(1, legacyRelease.StatusSuperseded),
+		}, exportedAt)).To(Succeed())
+
+		archive, err := rlsarchiv.Read(&buf)
+		Expect(err).To(Succeed())
+
+		Expect(archive.Metadata).To(Equal(&rlsarchiv.Metadata{
+			FormatVersion:    rlsarchiv.FormatVersion,
+			ReleaseName:      "test-release",
+			ReleaseNamespace: "test-namespace",
+			ExportedAt:       exportedAt,
+			Revisions:        []int{1, 2},
+		}))
+
+		Expect(archive.Releases).To(HaveLen(2))
+		for i, rel := range archive.Releases {
+			expected := newLegacyRelease(i+1, []legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusDeployed}[i])
+			Expect(rel.Version).To(Equal(expected.Version))
+			Expect(rel.Info.Status).To(Equal(expected.Info.Status))
+			Expect(rel.Info.Description).To(Equal(expected.Info.Description))
+			Expect(rel.Manifest).To(Equal(expected.Manifest))
+			Expect(rel.Hooks).To(Equal(expected.Hooks))
+			Expect(rel.Labels).To(Equal(expected.Labels))
+		}
+	})
+
+	It("should write entries readable only by owner", func() {
+		var buf bytes.Buffer
+		Expect(rlsarchiv.Write(&buf, "test-release", "test-namespace", []*legacyRelease.Release{
+			newLegacyRelease(1, legacyRelease.StatusDeployed),
+		}, exportedAt)).To(Succeed())
+
+		gzipReader, err := gzip.NewReader(&buf)
+		Expect(err).To(Succeed())
+
+		var names []string
+		tarReader := tar.NewReader(gzipReader)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).To(Succeed())
+
+			Expect(header.Mode).To(Equal(int64(0o600)), "mode of %q", header.Name)
+			Expect(header.ModTime).To(BeTemporally("==", exportedAt))
+			names = append(names, header.Name)
+		}
+
+		Expect(names).To(Equal([]string{"metadata.json", "releases/v1.json"}))
+	})
+
+	DescribeTable("should fail to read invalid archives",
+		func(files map[string]string, expectedErr string) {
+			_, err := rlsarchiv.Read(bytes.NewReader(tarGz(files)))
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("without metadata", map[string]string{
+			"releases/v1.json": releaseJSON,
+		}, `"metadata.json" not found in archive`),
+		Entry("with invalid metadata", map[string]string{
+			"metadata.json": "{",
+		}, "error unmarshalling archive metadata"),
+		Entry("of unsupported format version", map[string]string{
+			"metadata.json": `{"formatVersion": 2, "releaseName": "test-release", "releaseNamespace": "test-namespace", "revisions": [1]}`,
+		}, "unsupported archive format version 2, expected 1"),
+		Entry("with missing revisions", map[string]string{
+			"metadata.json":    `{"formatVersion": 1, "releaseName": "test-release", "releaseNamespace": "test-namespace", "revisions": [1, 2]}`,
+			"releases/v1.json": releaseJSON,
+		}, "expected 2 revisions in archive, but found 1"),
+		Entry("with invalid revision", map[string]string{
+			"metadata.json":    `{"formatVersion": 1, "releaseName": "test-release", "releaseNamespace": "test-namespace", "revisions": [1]}`,
+			"releases/v1.json": "{}",
+		}, `error reading "releases/v1.json": no release found`),
+		Entry("with revision of another release", map[string]string{
+			"metadata.json":    `{"formatVersion": 1, "releaseName": "other-release", "releaseNamespace": "test-namespace", "revisions": [1]}`,
+			"releases/v1.json": releaseJSON,
+		}, `revision 1 belongs to release "test-release" (namespace: "test-namespace"), expected release "other-release" (namespace: "test-namespace")`),
+	)
+
+	It("should ignore unknown files", func() {
+		archive, err := rlsarchiv.Read(bytes.NewReader(tarGz(map[string]string{
+			"metadata.json":      `{"formatVersion": 1, "releaseName": "test-release", "releaseNamespace": "test-namespace", "revisions": [1]}`,
+			"releases/v1.json":   releaseJSON,
+			"releases/notes.txt": "notes",
+			"README.md":          "readme",
+		})))
+		Expect(err).To(Succeed())
+		Expect(archive.Releases).To(HaveLen(1))
+	})
+
+	It("should fail to read not gzipped data", func() {
+		_, err := rlsarchiv.Read(bytes.NewReader([]byte("not an archive")))
+		Expect(err).To(MatchError(ContainSubstring("error constructing gzip reader")))
+	})
+})
+
+const releaseJSON = `{"release": {"name": "test-release", "namespace": "test-namespace", "version": 1, "info": {"status": "deployed"}}}`
+
+func newLegacyRelease(revision int, status legacyRelease.Status) *legacyRelease.Release {
+	return &legacyRelease.Release{
+		Name:      "test-release",
+		Namespace: "test-namespace",
+		Version:   revision,
+		Info: &legacyRelease.Info{
+			Status:      status,
+			Description: "Upgrade complete",
+		},
+		// Unusual formatting must survive the archiving.
+		Manifest: "---\n# Source: chart/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: config\ndata:\n    key:   value\n",
+		Hooks: []*legacyRelease.Hook{
+			{
+				Name:     "migrate",
+				Kind:     "Job",
+				Path:     "chart/templates/job.yaml",
+				Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n",
+				Events:   []legacyRelease.HookEvent{legacyRelease.HookPreUpgrade},
+				Weight:   -5,
+			},
+		},
+		Labels: map[string]string{
+			"custom": "label",
+		},
+	}
+}
+
+// Entries are written in the order of names, so that the archive is always the same.
+func tarGz(files map[string]string) []byte {
+	GinkgoHelper()
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	names := lo.Keys(files)
+	sort.Strings(names)
+
+	for _, name := range names {
+		Expect(tarWriter.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0o600,
+			Size:     int64(len(files[name])),
+		})).To(Succeed())
+
+		_, err := tarWriter.Write([]byte(files[name]))
+		Expect(err).To(Succeed())
+	}
+
+	Expect(tarWriter.Close()).To(Succeed())
+	Expect(gzipWriter.Close()).To(Succeed())
+
+	return buf.Bytes()
+}
@@ -0,0 +1,104 @@
+package rlsarchiv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+)
+
+const annotationKeyReleaseNamespace = "meta.helm.sh/release-namespace"
+
+var manifestSeparatorRegex = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// Moves the release to another namespace. Namespace fields and release namespace annotations
+// of the release resources are rewritten only if they point to the original release
+// namespace, so resources deployed to other namespaces stay where they were.
+func RewriteNamespace(legacyRel *legacyRelease.Release, newNamespace string) error {
+	oldNamespace := legacyRel.Namespace
+	if oldNamespace == newNamespace {
+		return nil
+	}
+
+	manifest, err := rewriteManifestNamespace(legacyRel.Manifest, oldNamespace, newNamespace)
+	if err != nil {
+		return fmt.Errorf("error rewriting namespace in manifests of revision %d: %w", legacyRel.Version, err)
+	}
+	legacyRel.Manifest = manifest
+
+	for _, hook := range legacyRel.Hooks {
+		manifest, err := rewriteManifestNamespace(hook.Manifest, oldNamespace, newNamespace)
+		if err != nil {
+			return fmt.Errorf("error rewriting namespace in hook %q of revision %d: %w", hook.Name, legacyRel.Version, err)
+		}
+		hook.Manifest = manifest
+	}
+
+	legacyRel.Namespace = newNamespace
+
+	return nil
+}
+
+// Only changed documents are re-marshalled, everything else, including "# Source:" comments,
+// is kept as is.
+func rewriteManifestNamespace(manifest, oldNamespace, newNamespace string) (string, error) {
+	docs := manifestSeparatorRegex.Split(manifest, -1)
+
+	for i, doc := range docs {
+		var commentLines []string
+		var bodyLines []string
+		for _, line := range strings.Split(doc, "\n") {
+			if len(bodyLines) == 0 && (strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "") {
+				commentLines = append(commentLines, line)
+			} else {
+				bodyLines = append(bodyLines, line)
+			}
+		}
+
+		if len(bodyLines) == 0 {
+			continue
+		}
+
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(strings.Join(bodyLines, "\n")), &obj); err != nil {
+			return "", fmt.Errorf("error unmarshalling manifest: %w", err)
+		}
+
+		if !rewriteObjectNamespace(obj, oldNamespace, newNamespace) {
+			continue
+		}
+
+		body, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling manifest: %w", err)
+		}
+
+		docs[i] = strings.Join(append(commentLines, string(body)), "\n")
+	}
+
+	return strings.Join(docs, "---"), nil
+}
+
+func rewriteObjectNamespace(obj map[string]interface{}, oldNamespace, newNamespace string) (changed bool) {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if namespace, ok := metadata["namespace"].(string); ok && namespace == oldNamespace {
+		metadata["namespace"] = newNamespace
+		changed = true
+	}
+
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		if namespace, ok := annotations[annotationKeyReleaseNamespace].(string); ok && namespace == oldNamespace {
+			annotations[annotationKeyReleaseNamespace] = newNamespace
+			changed = true
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,90 @@
+package rlsarchiv_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsarchiv"
+)
+
+var _ = Describe("RewriteNamespace", func() {
+	DescribeTable("should move resources of the release namespace to the new namespace",
+		func(manifest, expected string) {
+			rel := &legacyRelease.Release{
+				Name:      "test-release",
+				Namespace: "old",
+				Version:   1,
+				Manifest:  manifest,
+			}
+
+			Expect(rlsarchiv.RewriteNamespace(rel, "new")).To(Succeed())
+			Expect(rel.Namespace).To(Equal("new"))
+			Expect(rel.Manifest).To(Equal(expected))
+		},
+		Entry("resource in the release namespace",
+			"---\n# Source: chart/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: old\n",
+			"---\n# Source: chart/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: new\n",
+		),
+		Entry("release namespace annotation",
+			"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  annotations:\n    meta.helm.sh/release-namespace: old\n  name: config\n",
+			"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  annotations:\n    meta.helm.sh/release-namespace: new\n  name: config\n",
+		),
+		Entry("resource in another namespace is kept as is",
+			"---\n# Source: chart/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: config\n    namespace: other\n",
+			"---\n# Source: chart/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: config\n    namespace: other\n",
+		),
+		Entry("resource without namespace is kept as is",
+			"---\napiVersion: v1\nkind: Namespace\nmetadata:\n    name: old\n",
+			"---\napiVersion: v1\nkind: Namespace\nmetadata:\n    name: old\n",
+		),
+		Entry("only changed documents are reformatted",
+			"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: first\n    namespace: other\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: second\n    namespace: old\n",
+			"---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: first\n    namespace: other\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: second\n  namespace: new\n",
+		),
+		Entry("empty manifest", "", ""),
+	)
+
+	It("should rewrite hooks", func() {
+		rel := &legacyRelease.Release{
+			Name:      "test-release",
+			Namespace: "old",
+			Version:   1,
+			Hooks: []*legacyRelease.Hook{
+				{
+					Name:     "migrate",
+					Manifest: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n  namespace: old\n",
+				},
+			},
+		}
+
+		Expect(rlsarchiv.RewriteNamespace(rel, "new")).To(Succeed())
+		Expect(rel.Hooks[0].Manifest).To(Equal("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: migrate\n  namespace: new\n"))
+	})
+
+	It("should do nothing if the namespace is the same", func() {
+		manifest := "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n    name: config\n    namespace: old\n"
+		rel := &legacyRelease.Release{
+			Name:      "test-release",
+			Namespace: "old",
+			Version:   1,
+			Manifest:  manifest,
+		}
+
+		Expect(rlsarchiv.RewriteNamespace(rel, "old")).To(Succeed())
+		Expect(rel.Manifest).To(Equal(manifest))
+	})
+
+	It("should fail on invalid manifests", func() {
+		rel := &legacyRelease.Release{
+			Name:      "test-release",
+			Namespace: "old",
+			Version:   3,
+			Manifest:  "---\nmetadata: [\n",
+		}
+
+		Expect(rlsarchiv.RewriteNamespace(rel, "new")).To(MatchError(ContainSubstring("error rewriting namespace in manifests of revision 3")))
+		Expect(rel.Namespace).To(Equal("old"))
+	})
+})
@@ -0,0 +1,13 @@
+package rlsarchiv_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReleaseArchive(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "release archive suite")
+}
@@ -0,0 +1,62 @@
+package rlshistor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+)
+
+// What the history tells about the next deploy of the release: the previous releases, the
+// revision and the deploy type of the new release.
+func NewDeployInfo(history Historier) (*DeployInfo, error) {
+	prevRelease, prevReleaseFound, err := history.LastRelease()
+	if err != nil {
+		return nil, fmt.Errorf("error getting last release: %w", err)
+	}
+
+	prevDeployedRelease, prevDeployedReleaseFound, err := history.LastDeployedRelease()
+	if err != nil {
+		return nil, fmt.Errorf("error getting last deployed release: %w", err)
+	}
+
+	var newRevision int
+	var firstDeployed time.Time
+	if prevReleaseFound {
+		newRevision = prevRelease.Revision() + 1
+		firstDeployed = prevRelease.FirstDeployed()
+	} else {
+		newRevision = 1
+	}
+
+	var deployType common.DeployType
+	if prevReleaseFound && prevDeployedReleaseFound {
+		deployType = common.DeployTypeUpgrade
+	} else if prevReleaseFound {
+		deployType = common.DeployTypeInstall
+	} else {
+		deployType = common.DeployTypeInitial
+	}
+
+	return &DeployInfo{
+		PrevRelease:              prevRelease,
+		PrevReleaseFound:         prevReleaseFound,
+		PrevDeployedRelease:      prevDeployedRelease,
+		PrevDeployedReleaseFound: prevDeployedReleaseFound,
+		NewRevision:              newRevision,
+		FirstDeployed:            firstDeployed,
+		DeployType:               deployType,
+	}, nil
+}
+
+type DeployInfo struct {
+	PrevRelease              *rls.Release
+	PrevReleaseFound         bool
+	PrevDeployedRelease      *rls.Release
+	PrevDeployedReleaseFound bool
+	NewRevision              int
+	// Zero if there were no previous releases.
+	FirstDeployed time.Time
+	DeployType    common.DeployType
+}
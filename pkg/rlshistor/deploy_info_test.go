@@ -0,0 +1,71 @@
+package rlshistor
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/chart"
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+var _ = Describe("DeployInfo", func() {
+	DescribeTable("should be derived from the history",
+		func(statuses []legacyRelease.Status, expectedRevision int, expectedDeployType common.DeployType, expectedPrevDeployedRevision int) {
+			ctx := context.Background()
+			storage := rlsstor.NewMemoryReleaseStorage()
+
+			for i, status := range statuses {
+				Expect(storage.Create(ctx, &legacyRelease.Release{
+					Name:      "test-release",
+					Namespace: "test-namespace",
+					Version:   i + 1,
+					Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "test", Version: "0.1.0"}},
+					Info: &legacyRelease.Info{
+						Status: status,
+					},
+				})).To(Succeed())
+			}
+
+			history, err := NewHistory(ctx, "test-release", "test-namespace", storage, HistoryOptions{})
+			Expect(err).To(Succeed())
+
+			deployInfo, err := NewDeployInfo(history)
+			Expect(err).To(Succeed())
+			Expect(deployInfo.NewRevision).To(Equal(expectedRevision))
+			Expect(deployInfo.DeployType).To(Equal(expectedDeployType))
+			Expect(deployInfo.PrevReleaseFound).To(Equal(len(statuses) > 0))
+
+			if expectedPrevDeployedRevision == 0 {
+				Expect(deployInfo.PrevDeployedReleaseFound).To(BeFalse())
+			} else {
+				Expect(deployInfo.PrevDeployedReleaseFound).To(BeTrue())
+				Expect(deployInfo.PrevDeployedRelease.Revision()).To(Equal(expectedPrevDeployedRevision))
+			}
+		},
+		Entry("without releases",
+			nil,
+			1, common.DeployTypeInitial, 0,
+		),
+		Entry("after failed first release",
+			[]legacyRelease.Status{legacyRelease.StatusFailed},
+			2, common.DeployTypeInstall, 0,
+		),
+		Entry("after deployed release",
+			[]legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusDeployed},
+			3, common.DeployTypeUpgrade, 2,
+		),
+		Entry("after failed upgrade",
+			[]legacyRelease.Status{legacyRelease.StatusDeployed, legacyRelease.StatusFailed},
+			3, common.DeployTypeUpgrade, 1,
+		),
+		Entry("after uninstall",
+			[]legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusUninstalled},
+			3, common.DeployTypeInstall, 0,
+		),
+	)
+})
@@ -2,6 +2,7 @@ package rlshistor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -11,16 +12,16 @@ import (
 
 	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
 	"github.com/werf/3p-helm-for-werf-helm/pkg/releaseutil"
-	"github.com/werf/3p-helm-for-werf-helm/pkg/storage/driver"
 
 	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
 )
 
 var _ Historier = (*History)(nil)
 
-func NewHistory(releaseName, releaseNamespace string, historyStorage LegacyStorage, opts HistoryOptions) (*History, error) {
-	legacyRels, err := historyStorage.Query(map[string]string{"name": releaseName, "owner": "helm"})
-	if err != nil && err != driver.ErrReleaseNotFound {
+func NewHistory(ctx context.Context, releaseName, releaseNamespace string, historyStorage rlsstor.ReleaseStorager, opts HistoryOptions) (*History, error) {
+	legacyRels, err := historyStorage.Releases(ctx, releaseName)
+	if err != nil {
 		return nil, fmt.Errorf("error querying releases for release %q (namespace: %q): %w", releaseName, releaseNamespace, err)
 	}
 	releaseutil.SortByRevision(legacyRels)
@@ -32,21 +33,31 @@ func NewHistory(releaseName, releaseNamespace string, historyStorage LegacyStora
 		storage:          historyStorage,
 		mapper:           opts.Mapper,
 		discoveryClient:  opts.DiscoveryClient,
+		succeededLimit:   opts.SucceededRevisionsLimit,
+		failedLimit:      opts.FailedRevisionsLimit,
 	}, nil
 }
 
 type HistoryOptions struct {
 	Mapper          meta.ResettableRESTMapper
 	DiscoveryClient discovery.CachedDiscoveryInterface
+	// Max number of deployed, superseded and uninstalled revisions kept when creating a new
+	// revision. No limit if 0.
+	SucceededRevisionsLimit int
+	// Max number of failed, pending and unknown revisions kept when creating a new
+	// revision. No limit if 0.
+	FailedRevisionsLimit int
 }
 
 type History struct {
 	releaseName      string
 	releaseNamespace string
 	legacyReleases   []*legacyRelease.Release
-	storage          LegacyStorage
+	storage          rlsstor.ReleaseStorager
 	mapper           meta.ResettableRESTMapper
 	discoveryClient  discovery.CachedDiscoveryInterface
+	succeededLimit   int
+	failedLimit      int
 	updateLock       sync.Mutex
 }
 
@@ -69,6 +80,23 @@ func (h *History) Release(revision int) (rel *rls.Release, found bool, err error
 	return rel, true, nil
 }
 
+func (h *History) Releases() ([]*rls.Release, error) {
+	var rels []*rls.Release
+	for _, legacyRel := range h.legacyReleases {
+		rel, err := rls.NewReleaseFromLegacyRelease(legacyRel, rls.ReleaseFromLegacyReleaseOptions{
+			Mapper:          h.mapper,
+			DiscoveryClient: h.discoveryClient,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error constructing release from legacy release: %w", err)
+		}
+
+		rels = append(rels, rel)
+	}
+
+	return rels, nil
+}
+
 func (h *History) LastRelease() (rel *rls.Release, found bool, err error) {
 	if h.Empty() {
 		return nil, false, nil
@@ -140,7 +168,11 @@ func (h *History) CreateRelease(ctx context.Context, rel *rls.Release) error {
 		return fmt.Errorf("error constructing legacy release from release: %w", err)
 	}
 
-	if err := h.storage.Create(legacyRel); err != nil {
+	if err := h.prune(ctx); err != nil {
+		return fmt.Errorf("error pruning history of release %q (namespace: %q): %w", legacyRel.Name, legacyRel.Namespace, err)
+	}
+
+	if err := h.storage.Create(ctx, legacyRel); err != nil {
 		return fmt.Errorf("error creating release %q (namespace: %q, revision: %q): %w", legacyRel.Name, legacyRel.Namespace, legacyRel.Version, err)
 	}
 
@@ -158,7 +190,7 @@ func (h *History) UpdateRelease(ctx context.Context, rel *rls.Release) error {
 		return fmt.Errorf("error constructing legacy release from release: %w", err)
 	}
 
-	if err := h.storage.Update(legacyRel); err != nil {
+	if err := h.storage.Update(ctx, legacyRel); err != nil {
 		return fmt.Errorf("error updating release %q (namespace: %q, revision: %q): %w", legacyRel.Name, legacyRel.Namespace, legacyRel.Version, err)
 	}
 
@@ -173,17 +205,106 @@ func (h *History) UpdateRelease(ctx context.Context, rel *rls.Release) error {
 	return nil
 }
 
-type LegacyStorage interface {
-	Create(rls *legacyRelease.Release) error
-	Update(rls *legacyRelease.Release) error
-	Query(labels map[string]string) ([]*legacyRelease.Release, error)
+func (h *History) DeleteRelease(ctx context.Context, rel *rls.Release) error {
+	h.updateLock.Lock()
+	defer h.updateLock.Unlock()
+
+	if err := h.storage.Delete(ctx, rel.Name(), rel.Revision()); err != nil {
+		return fmt.Errorf("error deleting release %q (namespace: %q, revision: %d): %w", rel.Name(), rel.Namespace(), rel.Revision(), err)
+	}
+
+	h.legacyReleases = lo.Reject(h.legacyReleases, func(r *legacyRelease.Release, _ int) bool {
+		return r.Version == rel.Revision()
+	})
+
+	return nil
+}
+
+// Makes room for a new revision, which is expected to succeed. The last deployed or
+// superseded revision and the last uninstalled one are never deleted, otherwise the next
+// deploy would be treated as the first install.
+func (h *History) prune(ctx context.Context) error {
+	if h.succeededLimit <= 0 && h.failedLimit <= 0 {
+		return nil
+	}
+
+	retained := make(map[int]bool)
+	for i := len(h.legacyReleases) - 1; i >= 0; i-- {
+		if status := h.legacyReleases[i].Info.Status; status == legacyRelease.StatusDeployed || status == legacyRelease.StatusSuperseded {
+			retained[h.legacyReleases[i].Version] = true
+			break
+		}
+	}
+	for i := len(h.legacyReleases) - 1; i >= 0; i-- {
+		if h.legacyReleases[i].Info.Status == legacyRelease.StatusUninstalled {
+			retained[h.legacyReleases[i].Version] = true
+			break
+		}
+	}
+
+	var succeeded, failed []*legacyRelease.Release
+	for _, legacyRel := range h.legacyReleases {
+		switch legacyRel.Info.Status {
+		case legacyRelease.StatusDeployed,
+			legacyRelease.StatusSuperseded,
+			legacyRelease.StatusUninstalled:
+			succeeded = append(succeeded, legacyRel)
+		default:
+			failed = append(failed, legacyRel)
+		}
+	}
+
+	var toDelete []*legacyRelease.Release
+	if h.succeededLimit > 0 {
+		toDelete = append(toDelete, oldestExcessReleases(succeeded, h.succeededLimit-1, retained)...)
+	}
+	if h.failedLimit > 0 {
+		toDelete = append(toDelete, oldestExcessReleases(failed, h.failedLimit, retained)...)
+	}
+
+	for _, legacyRel := range toDelete {
+		if err := h.storage.Delete(ctx, legacyRel.Name, legacyRel.Version); err != nil && !errors.Is(err, rlsstor.ErrReleaseNotFound) {
+			return fmt.Errorf("error deleting release %q (namespace: %q, revision: %d): %w", legacyRel.Name, legacyRel.Namespace, legacyRel.Version, err)
+		}
+
+		h.legacyReleases = lo.Reject(h.legacyReleases, func(r *legacyRelease.Release, _ int) bool {
+			return r.Version == legacyRel.Version
+		})
+	}
+
+	return nil
+}
+
+// Releases must be sorted by revision.
+func oldestExcessReleases(legacyRels []*legacyRelease.Release, keep int, retained map[int]bool) []*legacyRelease.Release {
+	excess := len(legacyRels) - keep
+	if excess <= 0 {
+		return nil
+	}
+
+	var result []*legacyRelease.Release
+	for _, legacyRel := range legacyRels {
+		if len(result) == excess {
+			break
+		}
+
+		if retained[legacyRel.Version] {
+			continue
+		}
+
+		result = append(result, legacyRel)
+	}
+
+	return result
 }
 
 type Historier interface {
 	Release(revision int) (rel *rls.Release, found bool, err error)
+	Releases() ([]*rls.Release, error)
 	LastRelease() (rel *rls.Release, found bool, err error)
 	LastDeployedRelease() (rel *rls.Release, found bool, err error)
 	Empty() bool
 	CreateRelease(ctx context.Context, rel *rls.Release) error
 	UpdateRelease(ctx context.Context, rel *rls.Release) error
+	DeleteRelease(ctx context.Context, rel *rls.Release) error
 }
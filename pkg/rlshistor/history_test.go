@@ -0,0 +1,94 @@
+package rlshistor
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+var _ = Describe("History", func() {
+	DescribeTable("prune should make room for a new revision",
+		func(statuses []legacyRelease.Status, succeededLimit, failedLimit int, expectedRevisions []int) {
+			ctx := context.Background()
+			storage := rlsstor.NewMemoryReleaseStorage()
+
+			var legacyRels []*legacyRelease.Release
+			for i, status := range statuses {
+				legacyRel := &legacyRelease.Release{
+					Name:      "test-release",
+					Namespace: "test-namespace",
+					Version:   i + 1,
+					Info: &legacyRelease.Info{
+						Status: status,
+					},
+				}
+				Expect(storage.Create(ctx, legacyRel)).To(Succeed())
+
+				legacyRels = append(legacyRels, legacyRel)
+			}
+
+			history := &History{
+				releaseName:      "test-release",
+				releaseNamespace: "test-namespace",
+				legacyReleases:   legacyRels,
+				storage:          storage,
+				succeededLimit:   succeededLimit,
+				failedLimit:      failedLimit,
+			}
+			Expect(history.prune(ctx)).To(Succeed())
+
+			revisions := func(legacyRels []*legacyRelease.Release) []int {
+				return lo.Map(legacyRels, func(r *legacyRelease.Release, _ int) int {
+					return r.Version
+				})
+			}
+
+			Expect(revisions(history.legacyReleases)).To(Equal(expectedRevisions))
+
+			storedRels, err := storage.Releases(ctx, "test-release")
+			Expect(err).To(Succeed())
+			Expect(revisions(storedRels)).To(ConsistOf(expectedRevisions))
+		},
+		Entry("without limits",
+			[]legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusFailed, legacyRelease.StatusSuperseded, legacyRelease.StatusDeployed},
+			0, 0,
+			[]int{1, 2, 3, 4},
+		),
+		Entry("over the succeeded limit, leaving room for the new revision",
+			[]legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusSuperseded, legacyRelease.StatusSuperseded, legacyRelease.StatusSuperseded, legacyRelease.StatusDeployed},
+			3, 0,
+			[]int{4, 5},
+		),
+		Entry("within the succeeded limit",
+			[]legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusDeployed},
+			3, 0,
+			[]int{1, 2},
+		),
+		Entry("over the failed limit",
+			[]legacyRelease.Status{legacyRelease.StatusFailed, legacyRelease.StatusFailed, legacyRelease.StatusPendingUpgrade, legacyRelease.StatusFailed, legacyRelease.StatusDeployed},
+			0, 2,
+			[]int{3, 4, 5},
+		),
+		Entry("over both limits",
+			[]legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusFailed, legacyRelease.StatusSuperseded, legacyRelease.StatusFailed, legacyRelease.StatusDeployed},
+			2, 1,
+			[]int{4, 5},
+		),
+		Entry("keeping the last deployed revision over the succeeded limit",
+			[]legacyRelease.Status{legacyRelease.StatusDeployed, legacyRelease.StatusFailed, legacyRelease.StatusFailed, legacyRelease.StatusFailed},
+			1, 1,
+			[]int{1, 4},
+		),
+		Entry("keeping the last uninstalled revision over the succeeded limit",
+			[]legacyRelease.Status{legacyRelease.StatusSuperseded, legacyRelease.StatusUninstalled, legacyRelease.StatusDeployed},
+			1, 0,
+			[]int{2, 3},
+		),
+	)
+})
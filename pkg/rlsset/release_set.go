@@ -0,0 +1,194 @@
+package rlsset
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dominikbraun/graph"
+	"sigs.k8s.io/yaml"
+)
+
+// Release from the release set manifest. Relative chart and values paths in the manifest are
+// relative to the manifest's directory, LoadManifest prefixes them with it.
+type Release struct {
+	Name             string
+	Namespace        string
+	ChartDirPath     string
+	ValuesFilesPaths []string
+	ValuesSets       []string
+	ValuesStringSets []string
+	// IDs of releases which must be deployed before this one.
+	DependsOn []string
+}
+
+// "<namespace>/<name>"
+func (r *Release) ID() string {
+	return r.Namespace + "/" + r.Name
+}
+
+// Releases deployed together, ordered by their dependencies on each other.
+type ReleaseSet struct {
+	releases []*Release
+	graph    graph.Graph[string, *Release]
+}
+
+// Builds the release set from releases in the order they should be reported in. Fails if
+// releases are duplicated, depend on unknown releases or depend on each other cyclically.
+func NewReleaseSet(releases []*Release) (*ReleaseSet, error) {
+	releaseGraph := graph.New(func(r *Release) string { return r.ID() }, graph.Directed(), graph.Acyclic(), graph.PreventCycles())
+
+	for _, rel := range releases {
+		if err := releaseGraph.AddVertex(rel); err != nil {
+			if errors.Is(err, graph.ErrVertexAlreadyExists) {
+				return nil, fmt.Errorf("release %q specified more than once", rel.ID())
+			}
+
+			return nil, fmt.Errorf("error adding release %q: %w", rel.ID(), err)
+		}
+	}
+
+	for _, rel := range releases {
+		for _, depID := range rel.DependsOn {
+			if _, err := releaseGraph.Vertex(depID); err != nil {
+				return nil, fmt.Errorf("release %q depends on unknown release %q", rel.ID(), depID)
+			}
+
+			if err := releaseGraph.AddEdge(depID, rel.ID()); err != nil {
+				if errors.Is(err, graph.ErrEdgeCreatesCycle) {
+					return nil, fmt.Errorf("dependency of release %q on release %q creates a cycle", rel.ID(), depID)
+				} else if errors.Is(err, graph.ErrEdgeAlreadyExists) {
+					continue
+				}
+
+				return nil, fmt.Errorf("error adding dependency of release %q on release %q: %w", rel.ID(), depID, err)
+			}
+		}
+	}
+
+	return &ReleaseSet{
+		releases: releases,
+		graph:    releaseGraph,
+	}, nil
+}
+
+func (s *ReleaseSet) Releases() []*Release {
+	return s.releases
+}
+
+// Reads the release set manifest like:
+//
+//	releases:
+//	- name: database
+//	  namespace: production
+//	  chart: ./charts/database
+//	  values:
+//	  - ./values/database.yaml
+//	- name: backend
+//	  namespace: production
+//	  chart: ./charts/backend
+//	  set:
+//	  - replicas=3
+//	  dependsOn:
+//	  - database
+//
+// Dependencies are specified as "[<namespace>/]<name>", namespace defaults to the namespace
+// of the dependent release, which defaults to the default namespace. Relative chart and
+// values paths are relative to the manifest.
+func LoadManifest(path, defaultNamespace string) ([]*Release, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading release set manifest %q: %w", path, err)
+	}
+
+	var manifest manifestFile
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error unmarshalling release set manifest %q: %w", path, err)
+	}
+
+	baseDir := filepath.Dir(path)
+	resolvePath := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+
+		return filepath.Join(baseDir, p)
+	}
+
+	var releases []*Release
+	for i, r := range manifest.Releases {
+		if r.Name == "" {
+			return nil, fmt.Errorf("error in release %d: name must be specified", i)
+		}
+
+		if r.Chart == "" {
+			return nil, fmt.Errorf("error in release %q: chart must be specified", r.Name)
+		}
+
+		namespace := r.Namespace
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+
+		rel := &Release{
+			Name:             r.Name,
+			Namespace:        namespace,
+			ChartDirPath:     resolvePath(r.Chart),
+			ValuesSets:       r.Set,
+			ValuesStringSets: r.SetString,
+		}
+
+		for _, valuesFilePath := range r.Values {
+			rel.ValuesFilesPaths = append(rel.ValuesFilesPaths, resolvePath(valuesFilePath))
+		}
+
+		for _, dep := range r.DependsOn {
+			depID, ok := parseReleaseID(dep, namespace)
+			if !ok {
+				return nil, fmt.Errorf("error in release %q: invalid dependency %q, expected \"[<namespace>/]<name>\"", r.Name, dep)
+			}
+
+			rel.DependsOn = append(rel.DependsOn, depID)
+		}
+
+		releases = append(releases, rel)
+	}
+
+	return releases, nil
+}
+
+func parseReleaseID(value, defaultNamespace string) (id string, ok bool) {
+	split := strings.Split(value, "/")
+
+	var name, namespace string
+	switch len(split) {
+	case 1:
+		name, namespace = split[0], defaultNamespace
+	case 2:
+		namespace, name = split[0], split[1]
+	default:
+		return "", false
+	}
+
+	if name == "" || namespace == "" {
+		return "", false
+	}
+
+	return namespace + "/" + name, true
+}
+
+type manifestFile struct {
+	Releases []manifestFileRelease `json:"releases"`
+}
+
+type manifestFileRelease struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Chart     string   `json:"chart"`
+	Values    []string `json:"values"`
+	Set       []string `json:"set"`
+	SetString []string `json:"setString"`
+	DependsOn []string `json:"dependsOn"`
+}
@@ -0,0 +1,184 @@
+package rlsset_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsset"
+)
+
+var _ = Describe("LoadManifest", func() {
+	It("should resolve paths against the manifest's directory and dependencies against namespaces", func() {
+		manifestPath := writeManifest(`
+releases:
+- name: database
+  chart: ./charts/database
+  values:
+  - values/database.yaml
+  - /etc/values/common.yaml
+  set:
+  - replicas=1
+- name: backend
+  namespace: production
+  chart: /charts/backend
+  setString:
+  - tag=1.0
+  dependsOn:
+  - default-ns/database
+  - cache
+- name: cache
+  namespace: production
+  chart: ../charts/cache
+`)
+		manifestDir := filepath.Dir(manifestPath)
+
+		releases, err := rlsset.LoadManifest(manifestPath, "default-ns")
+		Expect(err).To(Succeed())
+		Expect(releases).To(Equal([]*rlsset.Release{
+			{
+				Name:             "database",
+				Namespace:        "default-ns",
+				ChartDirPath:     filepath.Join(manifestDir, "charts", "database"),
+				ValuesFilesPaths: []string{filepath.Join(manifestDir, "values", "database.yaml"), "/etc/values/common.yaml"},
+				ValuesSets:       []string{"replicas=1"},
+			},
+			{
+				Name:             "backend",
+				Namespace:        "production",
+				ChartDirPath:     "/charts/backend",
+				ValuesStringSets: []string{"tag=1.0"},
+				DependsOn:        []string{"default-ns/database", "production/cache"},
+			},
+			{
+				Name:         "cache",
+				Namespace:    "production",
+				ChartDirPath: filepath.Join(filepath.Dir(manifestDir), "charts", "cache"),
+			},
+		}))
+	})
+
+	DescribeTable("should fail on invalid manifest",
+		func(manifest, expectedErr string) {
+			_, err := rlsset.LoadManifest(writeManifest(manifest), "default")
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("without release name", `
+releases:
+- chart: ./chart
+`, "error in release 0: name must be specified"),
+		Entry("without chart", `
+releases:
+- name: app
+`, `error in release "app": chart must be specified`),
+		Entry("with invalid dependency", `
+releases:
+- name: app
+  chart: ./chart
+  dependsOn:
+  - a/b/c
+`, `error in release "app": invalid dependency "a/b/c"`),
+		Entry("with empty dependency namespace", `
+releases:
+- name: app
+  chart: ./chart
+  dependsOn:
+  - /db
+`, `error in release "app": invalid dependency "/db"`),
+		Entry("with unknown field", `
+releases:
+- name: app
+  chart: ./chart
+  depends: [db]
+`, "error unmarshalling release set manifest"),
+	)
+
+	It("should fail if manifest doesn't exist", func() {
+		_, err := rlsset.LoadManifest(filepath.Join(GinkgoT().TempDir(), "missing.yaml"), "default")
+		Expect(err).To(MatchError(ContainSubstring("error reading release set manifest")))
+	})
+})
+
+var _ = Describe("NewReleaseSet", func() {
+	DescribeTable("should validate dependencies",
+		func(manifest, expectedErr string) {
+			releases, err := rlsset.LoadManifest(writeManifest(manifest), "default")
+			Expect(err).To(Succeed())
+
+			_, err = rlsset.NewReleaseSet(releases)
+			if expectedErr == "" {
+				Expect(err).To(Succeed())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("without dependencies", `
+releases:
+- name: a
+  chart: ./a
+- name: b
+  chart: ./b
+`, ""),
+		Entry("with duplicated dependency", `
+releases:
+- name: a
+  chart: ./a
+- name: b
+  chart: ./b
+  dependsOn: [a, default/a]
+`, ""),
+		Entry("with unknown dependency", `
+releases:
+- name: a
+  chart: ./a
+  dependsOn: [b]
+`, `release "default/a" depends on unknown release "default/b"`),
+		Entry("with dependency on release in another namespace", `
+releases:
+- name: a
+  namespace: other
+  chart: ./a
+- name: b
+  chart: ./b
+  dependsOn: [a]
+`, `release "default/b" depends on unknown release "default/a"`),
+		Entry("with dependency on itself", `
+releases:
+- name: a
+  chart: ./a
+  dependsOn: [a]
+`, "creates a cycle"),
+		Entry("with cyclic dependencies", `
+releases:
+- name: a
+  chart: ./a
+  dependsOn: [c]
+- name: b
+  chart: ./b
+  dependsOn: [a]
+- name: c
+  chart: ./c
+  dependsOn: [b]
+`, "creates a cycle"),
+		Entry("with duplicated release", `
+releases:
+- name: a
+  chart: ./a
+- name: a
+  namespace: default
+  chart: ./b
+`, `release "default/a" specified more than once`),
+	)
+})
+
+func writeManifest(manifest string) string {
+	GinkgoHelper()
+
+	path := filepath.Join(GinkgoT().TempDir(), "sets", "releases.yaml")
+	Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+	Expect(os.WriteFile(path, []byte(manifest), 0o644)).To(Succeed())
+
+	return path
+}
@@ -0,0 +1,156 @@
+package rlsset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+type ResultStatus string
+
+const (
+	ResultStatusSucceeded ResultStatus = "succeeded"
+	ResultStatusFailed    ResultStatus = "failed"
+	ResultStatusSkipped   ResultStatus = "skipped"
+)
+
+type Result struct {
+	Release *Release
+	Status  ResultStatus
+	// Why the release failed or was skipped.
+	Err error
+}
+
+// Runs fn for each release once all releases it depends on succeeded, running at most
+// parallelism releases at a time. If a release fails, all releases depending on it, directly
+// or not, are skipped, while unrelated releases keep running. Once the context is canceled, no
+// more releases are started. Results are in the order of releases in the set.
+func (s *ReleaseSet) Run(ctx context.Context, parallelism int, fn func(ctx context.Context, rel *Release) error) ([]*Result, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	adjacencyMap, err := s.graph.AdjacencyMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting adjacency map: %w", err)
+	}
+
+	predecessorMap, err := s.graph.PredecessorMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting predecessor map: %w", err)
+	}
+
+	releasesByID := map[string]*Release{}
+	indexes := map[string]int{}
+	remainingDeps := map[string]int{}
+	var ready []*Release
+	for i, rel := range s.releases {
+		releasesByID[rel.ID()] = rel
+		indexes[rel.ID()] = i
+		remainingDeps[rel.ID()] = len(predecessorMap[rel.ID()])
+
+		if remainingDeps[rel.ID()] == 0 {
+			ready = append(ready, rel)
+		}
+	}
+
+	results := map[string]*Result{}
+
+	var skipDependents func(releaseID string, reason error)
+	skipDependents = func(releaseID string, reason error) {
+		for depID := range adjacencyMap[releaseID] {
+			if _, done := results[depID]; done {
+				continue
+			}
+
+			results[depID] = &Result{
+				Release: releasesByID[depID],
+				Status:  ResultStatusSkipped,
+				Err:     reason,
+			}
+
+			skipDependents(depID, reason)
+		}
+	}
+
+	type finishedRelease struct {
+		release *Release
+		err     error
+	}
+
+	finishedCh := make(chan finishedRelease)
+	var running int
+
+	for len(ready) > 0 || running > 0 {
+		for len(ready) > 0 && running < parallelism {
+			rel := ready[0]
+			ready = ready[1:]
+
+			if ctx.Err() != nil {
+				reason := fmt.Errorf("not started: %w", context.Cause(ctx))
+				results[rel.ID()] = &Result{
+					Release: rel,
+					Status:  ResultStatusSkipped,
+					Err:     reason,
+				}
+				skipDependents(rel.ID(), reason)
+
+				continue
+			}
+
+			running++
+			go func() {
+				finishedCh <- finishedRelease{
+					release: rel,
+					err:     fn(ctx, rel),
+				}
+			}()
+		}
+
+		if running == 0 {
+			break
+		}
+
+		finished := <-finishedCh
+		running--
+
+		id := finished.release.ID()
+
+		if finished.err != nil {
+			results[id] = &Result{
+				Release: finished.release,
+				Status:  ResultStatusFailed,
+				Err:     finished.err,
+			}
+			skipDependents(id, fmt.Errorf("upstream release %q failed", id))
+
+			continue
+		}
+
+		results[id] = &Result{
+			Release: finished.release,
+			Status:  ResultStatusSucceeded,
+		}
+
+		var newlyReady []*Release
+		for depID := range adjacencyMap[id] {
+			remainingDeps[depID]--
+
+			if _, done := results[depID]; !done && remainingDeps[depID] == 0 {
+				newlyReady = append(newlyReady, releasesByID[depID])
+			}
+		}
+
+		sort.Slice(newlyReady, func(i, j int) bool {
+			return indexes[newlyReady[i].ID()] < indexes[newlyReady[j].ID()]
+		})
+		ready = append(ready, newlyReady...)
+	}
+
+	var orderedResults []*Result
+	for _, rel := range s.releases {
+		orderedResults = append(orderedResults, results[rel.ID()])
+	}
+
+	return orderedResults, nil
+}
@@ -0,0 +1,168 @@
+package rlsset_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsset"
+)
+
+var _ = Describe("ReleaseSet.Run", func() {
+	It("should run releases after their dependencies, in the order of the set otherwise", func() {
+		releaseSet := newReleaseSet(
+			release("c", "b"),
+			release("a"),
+			release("b", "a"),
+			release("d"),
+		)
+
+		var order []string
+		results, err := releaseSet.Run(context.Background(), 1, func(ctx context.Context, rel *rlsset.Release) error {
+			order = append(order, rel.Name)
+			return nil
+		})
+		Expect(err).To(Succeed())
+		Expect(order).To(Equal([]string{"a", "d", "b", "c"}))
+		Expect(resultStatuses(results)).To(Equal(map[string]rlsset.ResultStatus{
+			"c": rlsset.ResultStatusSucceeded,
+			"a": rlsset.ResultStatusSucceeded,
+			"b": rlsset.ResultStatusSucceeded,
+			"d": rlsset.ResultStatusSucceeded,
+		}))
+		Expect(lo.Map(results, func(r *rlsset.Result, _ int) string { return r.Release.Name })).To(Equal([]string{"c", "a", "b", "d"}))
+	})
+
+	It("should skip all dependents of failed release and keep running unrelated releases", func() {
+		releaseSet := newReleaseSet(
+			release("db"),
+			release("backend", "db"),
+			release("frontend", "backend"),
+			release("cache"),
+			release("worker", "cache"),
+		)
+
+		failure := errors.New("deploy failed")
+
+		var mu sync.Mutex
+		var started []string
+		results, err := releaseSet.Run(context.Background(), 2, func(ctx context.Context, rel *rlsset.Release) error {
+			mu.Lock()
+			started = append(started, rel.Name)
+			mu.Unlock()
+
+			if rel.Name == "db" {
+				return failure
+			}
+
+			return nil
+		})
+		Expect(err).To(Succeed())
+		Expect(started).To(ConsistOf("db", "cache", "worker"))
+		Expect(resultStatuses(results)).To(Equal(map[string]rlsset.ResultStatus{
+			"db":       rlsset.ResultStatusFailed,
+			"backend":  rlsset.ResultStatusSkipped,
+			"frontend": rlsset.ResultStatusSkipped,
+			"cache":    rlsset.ResultStatusSucceeded,
+			"worker":   rlsset.ResultStatusSucceeded,
+		}))
+		Expect(results[0].Err).To(MatchError(failure))
+		Expect(results[1].Err).To(MatchError(`upstream release "default/db" failed`))
+		Expect(results[2].Err).To(MatchError(`upstream release "default/db" failed`))
+	})
+
+	DescribeTable("should run no more releases at a time than the parallelism limit",
+		func(parallelism, expectedMaxRunning int) {
+			releaseSet := newReleaseSet(
+				release("a"),
+				release("b"),
+				release("c"),
+				release("d"),
+				release("e"),
+				release("f"),
+			)
+
+			var mu sync.Mutex
+			var running, maxRunning int
+			results, err := releaseSet.Run(context.Background(), parallelism, func(ctx context.Context, rel *rlsset.Release) error {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+
+				return nil
+			})
+			Expect(err).To(Succeed())
+			Expect(results).To(HaveLen(6))
+			Expect(maxRunning).To(Equal(expectedMaxRunning))
+		},
+		Entry("of 1", 1, 1),
+		Entry("of 2", 2, 2),
+		Entry("higher than the number of releases", 10, 6),
+		Entry("of 0, treated as 1", 0, 1),
+	)
+
+	It("should not start releases once context is canceled", func() {
+		releaseSet := newReleaseSet(
+			release("a"),
+			release("b", "a"),
+			release("c"),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var started []string
+		results, err := releaseSet.Run(ctx, 1, func(ctx context.Context, rel *rlsset.Release) error {
+			started = append(started, rel.Name)
+			cancel()
+
+			return nil
+		})
+		Expect(err).To(Succeed())
+		Expect(started).To(Equal([]string{"a"}))
+		Expect(resultStatuses(results)).To(Equal(map[string]rlsset.ResultStatus{
+			"a": rlsset.ResultStatusSucceeded,
+			"b": rlsset.ResultStatusSkipped,
+			"c": rlsset.ResultStatusSkipped,
+		}))
+		Expect(results[1].Err).To(MatchError(ContainSubstring("not started")))
+	})
+})
+
+func release(name string, dependsOn ...string) *rlsset.Release {
+	return &rlsset.Release{
+		Name:         name,
+		Namespace:    "default",
+		ChartDirPath: name,
+		DependsOn: lo.Map(dependsOn, func(dep string, _ int) string {
+			return "default/" + dep
+		}),
+	}
+}
+
+func newReleaseSet(releases ...*rlsset.Release) *rlsset.ReleaseSet {
+	GinkgoHelper()
+
+	releaseSet, err := rlsset.NewReleaseSet(releases)
+	Expect(err).To(Succeed())
+
+	return releaseSet
+}
+
+func resultStatuses(results []*rlsset.Result) map[string]rlsset.ResultStatus {
+	return lo.SliceToMap(results, func(r *rlsset.Result) (string, rlsset.ResultStatus) {
+		return r.Release.Name, r.Status
+	})
+}
@@ -0,0 +1,13 @@
+package rlsset_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReleaseSet(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "release set suite")
+}
@@ -0,0 +1,392 @@
+package rlsstor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	api_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/log"
+)
+
+const (
+	ChunkedSecretType = "nelm.werf.io/release.v1"
+	// Leaves enough room for metadata below the 1 MiB object size limit.
+	DefaultChunkSize = 512 * 1024
+
+	chunkedSecretDataKey = "release"
+	// Differs from the owner of the Helm secrets driver, so that Helm doesn't pick up our Secrets.
+	chunkedSecretOwner = "nelm"
+
+	labelKeyChunkIndex  = "nelm.werf.io/chunk"
+	labelKeyChunksCount = "nelm.werf.io/chunks"
+	labelKeyGeneration  = "nelm.werf.io/generation"
+)
+
+var chunkedSecretSystemLabels = []string{"name", "owner", "status", "version", "createdAt", "modifiedAt", labelKeyChunkIndex, labelKeyChunksCount, labelKeyGeneration}
+
+var _ ReleaseStorager = (*ChunkedSecretReleaseStorage)(nil)
+
+// Stores each release revision gzipped and split across as many chunk Secrets as needed, so that
+// releases of big charts don't hit the object size limit. Every revision has a head Secret, which
+// holds the release labels and points to the current generation of chunks. Updates write chunks
+// of a new generation first and only then switch the head to them, so that an interrupted update
+// never leaves a revision half-written.
+func NewChunkedSecretReleaseStorage(client kubernetes.Interface, releaseNamespace string, opts ChunkedSecretReleaseStorageOptions) *ChunkedSecretReleaseStorage {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return &ChunkedSecretReleaseStorage{
+		client:           client,
+		releaseNamespace: releaseNamespace,
+		chunkSize:        chunkSize,
+	}
+}
+
+type ChunkedSecretReleaseStorageOptions struct {
+	ChunkSize int
+}
+
+type ChunkedSecretReleaseStorage struct {
+	client           kubernetes.Interface
+	releaseNamespace string
+	chunkSize        int
+}
+
+// Revisions that can't be decoded are skipped with a warning, so that a single broken revision
+// doesn't make the whole release unusable.
+func (s *ChunkedSecretReleaseStorage) Releases(ctx context.Context, releaseName string) ([]*legacyRelease.Release, error) {
+	secrets, err := s.listSecrets(ctx, releaseName, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error listing release secrets: %w", err)
+	}
+
+	secretsByRevision := lo.GroupBy(secrets, func(secret corev1.Secret) string {
+		return secret.Labels["version"]
+	})
+
+	revisions := lo.Keys(secretsByRevision)
+	sort.Strings(revisions)
+
+	var rels []*legacyRelease.Release
+	for _, revision := range revisions {
+		rel, err := decodeRevision(secretsByRevision[revision])
+		if err != nil {
+			log.Default.Warn(ctx, "Skipping revision %s of release %q (namespace: %q): error decoding: %s", revision, releaseName, s.releaseNamespace, err)
+			continue
+		}
+
+		rels = append(rels, rel)
+	}
+
+	return rels, nil
+}
+
+// Chunks without a head are leftovers of an interrupted create and are deleted before creating
+// the revision again.
+func (s *ChunkedSecretReleaseStorage) Create(ctx context.Context, rel *legacyRelease.Release) error {
+	existing, err := s.listSecrets(ctx, rel.Name, rel.Version)
+	if err != nil {
+		return fmt.Errorf("error listing release secrets: %w", err)
+	}
+
+	if lo.ContainsBy(existing, func(secret corev1.Secret) bool { return !isChunkSecret(secret) }) {
+		if _, err := decodeRevision(existing); err != nil {
+			return fmt.Errorf("revision %d of release %q exists but can't be decoded, delete Secrets with labels \"%s\" to create it again: %w", rel.Version, rel.Name, revisionSelector(rel.Name, rel.Version), err)
+		}
+
+		return ErrReleaseExists
+	}
+
+	for _, secret := range existing {
+		if err := s.client.CoreV1().Secrets(s.releaseNamespace).Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !api_errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting leftover release secret %q: %w", secret.Name, err)
+		}
+	}
+
+	const generation = 1
+
+	head, chunks, err := s.encodeRevision(rel, generation, time.Now())
+	if err != nil {
+		return fmt.Errorf("error encoding release: %w", err)
+	}
+
+	if err := s.createChunks(ctx, chunks); err != nil {
+		return err
+	}
+
+	if _, err := s.client.CoreV1().Secrets(s.releaseNamespace).Create(ctx, head, metav1.CreateOptions{}); err != nil {
+		s.deleteChunks(ctx, chunks)
+
+		if api_errors.IsAlreadyExists(err) {
+			return ErrReleaseExists
+		}
+
+		return fmt.Errorf("error creating release secret %q: %w", head.Name, err)
+	}
+
+	return nil
+}
+
+func (s *ChunkedSecretReleaseStorage) Update(ctx context.Context, rel *legacyRelease.Release) error {
+	existingHead, err := s.client.CoreV1().Secrets(s.releaseNamespace).Get(ctx, headSecretName(rel.Name, rel.Version), metav1.GetOptions{})
+	if err != nil {
+		if api_errors.IsNotFound(err) {
+			return ErrReleaseNotFound
+		}
+
+		return fmt.Errorf("error getting release secret: %w", err)
+	}
+
+	generation, err := strconv.Atoi(existingHead.Labels[labelKeyGeneration])
+	if err != nil {
+		return fmt.Errorf("error parsing generation of release secret %q: %w", existingHead.Name, err)
+	}
+	generation++
+
+	head, chunks, err := s.encodeRevision(rel, generation, time.Now())
+	if err != nil {
+		return fmt.Errorf("error encoding release: %w", err)
+	}
+
+	if err := s.createChunks(ctx, chunks); err != nil {
+		return err
+	}
+
+	head.ResourceVersion = existingHead.ResourceVersion
+	if _, err := s.client.CoreV1().Secrets(s.releaseNamespace).Update(ctx, head, metav1.UpdateOptions{}); err != nil {
+		s.deleteChunks(ctx, chunks)
+		return fmt.Errorf("error updating release secret %q: %w", head.Name, err)
+	}
+
+	secrets, err := s.listSecrets(ctx, rel.Name, rel.Version)
+	if err != nil {
+		return fmt.Errorf("error listing release secrets: %w", err)
+	}
+
+	for _, secret := range secrets {
+		if !isChunkSecret(secret) || secret.Labels[labelKeyGeneration] == strconv.Itoa(generation) {
+			continue
+		}
+
+		if err := s.client.CoreV1().Secrets(s.releaseNamespace).Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !api_errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting stale release secret %q: %w", secret.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *ChunkedSecretReleaseStorage) Delete(ctx context.Context, releaseName string, revision int) error {
+	existing, err := s.listSecrets(ctx, releaseName, revision)
+	if err != nil {
+		return fmt.Errorf("error listing release secrets: %w", err)
+	} else if len(existing) == 0 {
+		return ErrReleaseNotFound
+	}
+
+	// Delete the head first, so that the revision is never seen with missing chunks.
+	sort.SliceStable(existing, func(i, j int) bool {
+		return !isChunkSecret(existing[i]) && isChunkSecret(existing[j])
+	})
+
+	for _, secret := range existing {
+		if err := s.client.CoreV1().Secrets(s.releaseNamespace).Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !api_errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting release secret %q: %w", secret.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// If revision is 0, secrets of all revisions are returned.
+func (s *ChunkedSecretReleaseStorage) listSecrets(ctx context.Context, releaseName string, revision int) ([]corev1.Secret, error) {
+	list, err := s.client.CoreV1().Secrets(s.releaseNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: revisionSelector(releaseName, revision),
+		FieldSelector: fields.OneTermEqualSelector("type", ChunkedSecretType).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+func (s *ChunkedSecretReleaseStorage) createChunks(ctx context.Context, chunks []*corev1.Secret) error {
+	for i, chunk := range chunks {
+		if _, err := s.client.CoreV1().Secrets(s.releaseNamespace).Create(ctx, chunk, metav1.CreateOptions{}); err != nil {
+			s.deleteChunks(ctx, chunks[:i])
+			return fmt.Errorf("error creating release secret %q: %w", chunk.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Best effort cleanup, leftover chunks are ignored on read and deleted with the revision or when
+// the revision is created again.
+func (s *ChunkedSecretReleaseStorage) deleteChunks(ctx context.Context, chunks []*corev1.Secret) {
+	for _, chunk := range chunks {
+		s.client.CoreV1().Secrets(s.releaseNamespace).Delete(ctx, chunk.Name, metav1.DeleteOptions{})
+	}
+}
+
+func (s *ChunkedSecretReleaseStorage) encodeRevision(rel *legacyRelease.Release, generation int, modifiedAt time.Time) (head *corev1.Secret, chunks []*corev1.Secret, err error) {
+	data, err := json.Marshal(rel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshalling release: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error constructing gzip writer: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, nil, fmt.Errorf("error compressing release: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, nil, fmt.Errorf("error compressing release: %w", err)
+	}
+
+	dataChunks := lo.Chunk(buf.Bytes(), s.chunkSize)
+
+	commonLabels := map[string]string{
+		"name":              rel.Name,
+		"owner":             chunkedSecretOwner,
+		"version":           strconv.Itoa(rel.Version),
+		labelKeyGeneration:  strconv.Itoa(generation),
+		labelKeyChunksCount: strconv.Itoa(len(dataChunks)),
+	}
+
+	head = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: headSecretName(rel.Name, rel.Version),
+			Labels: lo.Assign(rel.Labels, commonLabels, map[string]string{
+				"status":     string(rel.Info.Status),
+				"modifiedAt": strconv.FormatInt(modifiedAt.Unix(), 10),
+			}),
+		},
+		Type: ChunkedSecretType,
+	}
+
+	for i, dataChunk := range dataChunks {
+		chunks = append(chunks, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("%s.g%d.%d", head.Name, generation, i),
+				Labels: lo.Assign(commonLabels, map[string]string{
+					labelKeyChunkIndex: strconv.Itoa(i),
+				}),
+			},
+			Type: ChunkedSecretType,
+			Data: map[string][]byte{
+				chunkedSecretDataKey: dataChunk,
+			},
+		})
+	}
+
+	return head, chunks, nil
+}
+
+// Chunks not of the generation the head points to are leftovers of interrupted updates and are
+// ignored.
+func decodeRevision(secrets []corev1.Secret) (*legacyRelease.Release, error) {
+	head, found := lo.Find(secrets, func(secret corev1.Secret) bool {
+		return !isChunkSecret(secret)
+	})
+	if !found {
+		return nil, fmt.Errorf("head secret not found")
+	}
+
+	chunks := lo.Filter(secrets, func(secret corev1.Secret, _ int) bool {
+		return isChunkSecret(secret) && secret.Labels[labelKeyGeneration] == head.Labels[labelKeyGeneration]
+	})
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunkIndex(chunks[i]) < chunkIndex(chunks[j])
+	})
+
+	chunksCount, err := strconv.Atoi(head.Labels[labelKeyChunksCount])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing chunks count: %w", err)
+	}
+
+	if len(chunks) != chunksCount {
+		return nil, fmt.Errorf("expected %d chunks, but found %d", chunksCount, len(chunks))
+	}
+
+	var buf bytes.Buffer
+	for i, chunk := range chunks {
+		if chunkIndex(chunk) != i {
+			return nil, fmt.Errorf("chunk %d not found", i)
+		}
+
+		buf.Write(chunk.Data[chunkedSecretDataKey])
+	}
+
+	r, err := gzip.NewReader(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing gzip reader: %w", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing release: %w", err)
+	}
+
+	var rel legacyRelease.Release
+	if err := json.Unmarshal(data, &rel); err != nil {
+		return nil, fmt.Errorf("error unmarshalling release: %w", err)
+	}
+
+	rel.Labels = lo.OmitByKeys(head.Labels, chunkedSecretSystemLabels)
+
+	return &rel, nil
+}
+
+// If revision is 0, matches secrets of all revisions.
+func revisionSelector(releaseName string, revision int) string {
+	selector := labels.Set{"name": releaseName, "owner": chunkedSecretOwner}
+	if revision > 0 {
+		selector["version"] = strconv.Itoa(revision)
+	}
+
+	return selector.String()
+}
+
+func headSecretName(releaseName string, revision int) string {
+	return fmt.Sprintf("sh.nelm.release.v1.%s.v%d", releaseName, revision)
+}
+
+func isChunkSecret(secret corev1.Secret) bool {
+	_, found := secret.Labels[labelKeyChunkIndex]
+	return found
+}
+
+func chunkIndex(secret corev1.Secret) int {
+	index, err := strconv.Atoi(secret.Labels[labelKeyChunkIndex])
+	if err != nil {
+		return -1
+	}
+
+	return index
+}
@@ -0,0 +1,184 @@
+package rlsstor_test
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+const testNamespace = "test-namespace"
+
+func newLegacyRelease(revision int, status legacyRelease.Status, manifest string) *legacyRelease.Release {
+	return &legacyRelease.Release{
+		Name:      "test-release",
+		Namespace: testNamespace,
+		Version:   revision,
+		Info: &legacyRelease.Info{
+			Status: status,
+		},
+		Manifest: manifest,
+		Labels: map[string]string{
+			"custom": "label",
+		},
+	}
+}
+
+// Random data compresses poorly, so that the release takes multiple chunks.
+func bigManifest() string {
+	data := make([]byte, 16*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	return hex.EncodeToString(data)
+}
+
+func secretNames(client *fake.Clientset) []string {
+	list, err := client.CoreV1().Secrets(testNamespace).List(context.Background(), metav1.ListOptions{})
+	Expect(err).To(Succeed())
+
+	var names []string
+	for _, secret := range list.Items {
+		names = append(names, secret.Name)
+	}
+
+	return names
+}
+
+var _ = Describe("ChunkedSecretReleaseStorage", func() {
+	DescribeTable("should read back created and updated releases",
+		func(chunkSize int, manifest string, multipleChunks bool) {
+			ctx := context.Background()
+			client := fake.NewSimpleClientset()
+			storage := rlsstor.NewChunkedSecretReleaseStorage(client, testNamespace, rlsstor.ChunkedSecretReleaseStorageOptions{
+				ChunkSize: chunkSize,
+			})
+
+			rel := newLegacyRelease(1, legacyRelease.StatusPendingInstall, manifest)
+			Expect(storage.Create(ctx, rel)).To(Succeed())
+
+			// Head secret and chunks.
+			secretsCount := len(secretNames(client))
+			if multipleChunks {
+				Expect(secretsCount).To(BeNumerically(">", 2))
+			} else {
+				Expect(secretsCount).To(Equal(2))
+			}
+
+			rels, err := storage.Releases(ctx, rel.Name)
+			Expect(err).To(Succeed())
+			Expect(rels).To(HaveLen(1))
+			Expect(rels[0].Version).To(Equal(1))
+			Expect(rels[0].Info.Status).To(Equal(legacyRelease.StatusPendingInstall))
+			Expect(rels[0].Manifest).To(Equal(manifest))
+			Expect(rels[0].Labels).To(Equal(map[string]string{"custom": "label"}))
+
+			rel.Info.Status = legacyRelease.StatusDeployed
+			Expect(storage.Update(ctx, rel)).To(Succeed())
+			Expect(secretNames(client)).To(HaveLen(secretsCount))
+			for _, name := range secretNames(client) {
+				Expect(name).NotTo(ContainSubstring(".g1."))
+			}
+
+			rels, err = storage.Releases(ctx, rel.Name)
+			Expect(err).To(Succeed())
+			Expect(rels).To(HaveLen(1))
+			Expect(rels[0].Info.Status).To(Equal(legacyRelease.StatusDeployed))
+			Expect(rels[0].Manifest).To(Equal(manifest))
+
+			Expect(storage.Delete(ctx, rel.Name, rel.Version)).To(Succeed())
+			Expect(secretNames(client)).To(BeEmpty())
+		},
+		Entry("in a single chunk", 0, "apiVersion: v1\nkind: ConfigMap\n", false),
+		Entry("in multiple chunks", 1024, bigManifest(), true),
+	)
+
+	It("should fail to create existing revision and to update missing one", func() {
+		ctx := context.Background()
+		storage := rlsstor.NewChunkedSecretReleaseStorage(fake.NewSimpleClientset(), testNamespace, rlsstor.ChunkedSecretReleaseStorageOptions{})
+
+		Expect(storage.Update(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(MatchError(rlsstor.ErrReleaseNotFound))
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(MatchError(rlsstor.ErrReleaseExists))
+	})
+
+	It("should create revision over chunks left by an interrupted create", func() {
+		ctx := context.Background()
+		client := fake.NewSimpleClientset()
+		storage := rlsstor.NewChunkedSecretReleaseStorage(client, testNamespace, rlsstor.ChunkedSecretReleaseStorageOptions{
+			ChunkSize: 1024,
+		})
+
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusPendingInstall, bigManifest()))).To(Succeed())
+		secretsCount := len(secretNames(client))
+
+		// Interrupted before the head is created.
+		Expect(client.CoreV1().Secrets(testNamespace).Delete(ctx, "sh.nelm.release.v1.test-release.v1", metav1.DeleteOptions{})).To(Succeed())
+
+		rels, err := storage.Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(BeEmpty())
+
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusPendingInstall, "short"))).To(Succeed())
+		Expect(secretNames(client)).To(HaveLen(2))
+		Expect(secretsCount).To(BeNumerically(">", 2))
+
+		rels, err = storage.Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(HaveLen(1))
+		Expect(rels[0].Manifest).To(Equal("short"))
+	})
+
+	It("should fail to create revision over existing one that can't be decoded", func() {
+		ctx := context.Background()
+		client := fake.NewSimpleClientset()
+		storage := rlsstor.NewChunkedSecretReleaseStorage(client, testNamespace, rlsstor.ChunkedSecretReleaseStorageOptions{})
+
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+		Expect(client.CoreV1().Secrets(testNamespace).Delete(ctx, "sh.nelm.release.v1.test-release.v1.g1.0", metav1.DeleteOptions{})).To(Succeed())
+
+		err := storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))
+		Expect(err).To(MatchError(ContainSubstring(`revision 1 of release "test-release" exists but can't be decoded, delete Secrets with labels "name=test-release,owner=nelm,version=1"`)))
+		Expect(err).NotTo(MatchError(rlsstor.ErrReleaseExists))
+		Expect(secretNames(client)).To(ConsistOf("sh.nelm.release.v1.test-release.v1"))
+	})
+
+	It("should skip revisions that fail to decode", func() {
+		ctx := context.Background()
+		client := fake.NewSimpleClientset()
+		storage := rlsstor.NewChunkedSecretReleaseStorage(client, testNamespace, rlsstor.ChunkedSecretReleaseStorageOptions{})
+
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusSuperseded, "first"))).To(Succeed())
+		Expect(storage.Create(ctx, newLegacyRelease(2, legacyRelease.StatusDeployed, "second"))).To(Succeed())
+
+		chunk, err := client.CoreV1().Secrets(testNamespace).Get(ctx, "sh.nelm.release.v1.test-release.v1.g1.0", metav1.GetOptions{})
+		Expect(err).To(Succeed())
+		chunk.Data = map[string][]byte{"release": []byte("not gzip")}
+		_, err = client.CoreV1().Secrets(testNamespace).Update(ctx, chunk, metav1.UpdateOptions{})
+		Expect(err).To(Succeed())
+
+		rels, err := storage.Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(HaveLen(1))
+		Expect(rels[0].Version).To(Equal(2))
+	})
+
+	It("should not use the owner label of the Helm secrets driver", func() {
+		ctx := context.Background()
+		client := fake.NewSimpleClientset()
+		storage := rlsstor.NewChunkedSecretReleaseStorage(client, testNamespace, rlsstor.ChunkedSecretReleaseStorageOptions{})
+
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+
+		list, err := client.CoreV1().Secrets(testNamespace).List(ctx, metav1.ListOptions{LabelSelector: "owner=helm"})
+		Expect(err).To(Succeed())
+		Expect(list.Items).To(BeEmpty())
+	})
+})
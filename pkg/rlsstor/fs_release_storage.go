@@ -0,0 +1,191 @@
+package rlsstor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+)
+
+var _ ReleaseStorager = (*FSReleaseStorage)(nil)
+
+var fsReleaseFileNameRegex = regexp.MustCompile(`^v([0-9]+)\.json$`)
+
+// Stores releases as plain JSON files in "<dir>/<namespace>/<release name>/v<revision>.json".
+// Useful for offline testing and for keeping the release history in Git.
+func NewFSReleaseStorage(dirPath, releaseNamespace string) *FSReleaseStorage {
+	return &FSReleaseStorage{
+		dirPath:          dirPath,
+		releaseNamespace: releaseNamespace,
+	}
+}
+
+type FSReleaseStorage struct {
+	dirPath          string
+	releaseNamespace string
+}
+
+func (s *FSReleaseStorage) Releases(ctx context.Context, releaseName string) ([]*legacyRelease.Release, error) {
+	entries, err := os.ReadDir(s.releaseDirPath(releaseName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error reading release directory: %w", err)
+	}
+
+	var rels []*legacyRelease.Release
+	for _, entry := range entries {
+		if entry.IsDir() || !fsReleaseFileNameRegex.MatchString(entry.Name()) {
+			continue
+		}
+
+		rel, err := s.readRelease(filepath.Join(s.releaseDirPath(releaseName), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading release file %q: %w", entry.Name(), err)
+		}
+
+		rels = append(rels, rel)
+	}
+
+	return rels, nil
+}
+
+func (s *FSReleaseStorage) Create(ctx context.Context, rel *legacyRelease.Release) error {
+	path := s.releaseFilePath(rel.Name, rel.Version)
+
+	if _, err := os.Stat(path); err == nil {
+		return ErrReleaseExists
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error checking release file %q: %w", path, err)
+	}
+
+	if err := s.writeRelease(path, rel); err != nil {
+		return fmt.Errorf("error writing release file %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func (s *FSReleaseStorage) Update(ctx context.Context, rel *legacyRelease.Release) error {
+	path := s.releaseFilePath(rel.Name, rel.Version)
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrReleaseNotFound
+		}
+
+		return fmt.Errorf("error checking release file %q: %w", path, err)
+	}
+
+	if err := s.writeRelease(path, rel); err != nil {
+		return fmt.Errorf("error writing release file %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func (s *FSReleaseStorage) Delete(ctx context.Context, releaseName string, revision int) error {
+	path := s.releaseFilePath(releaseName, revision)
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrReleaseNotFound
+		}
+
+		return fmt.Errorf("error removing release file %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func (s *FSReleaseStorage) releaseDirPath(releaseName string) string {
+	return filepath.Join(s.dirPath, s.releaseNamespace, releaseName)
+}
+
+func (s *FSReleaseStorage) releaseFilePath(releaseName string, revision int) string {
+	return filepath.Join(s.releaseDirPath(releaseName), "v"+strconv.Itoa(revision)+".json")
+}
+
+func (s *FSReleaseStorage) readRelease(path string) (*legacyRelease.Release, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	return UnmarshalRelease(data)
+}
+
+// Writes to a temporary file first, so that an interrupted write doesn't corrupt the stored
+// release.
+func (s *FSReleaseStorage) writeRelease(path string, rel *legacyRelease.Release) error {
+	data, err := MarshalRelease(rel)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating release directory: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(append(data, '\n')); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error writing temporary file: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("error closing temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("error renaming temporary file: %w", err)
+	}
+
+	return nil
+}
+
+// Serializes the release as indented JSON. Release labels are not serialized with the
+// release, so they are stored alongside.
+func MarshalRelease(rel *legacyRelease.Release) ([]byte, error) {
+	data, err := json.MarshalIndent(releaseRecord{
+		Labels:  rel.Labels,
+		Release: rel,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling release: %w", err)
+	}
+
+	return data, nil
+}
+
+func UnmarshalRelease(data []byte) (*legacyRelease.Release, error) {
+	var record releaseRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("error unmarshalling release: %w", err)
+	}
+
+	if record.Release == nil {
+		return nil, fmt.Errorf("no release found")
+	}
+	record.Release.Labels = record.Labels
+
+	return record.Release, nil
+}
+
+type releaseRecord struct {
+	Labels  map[string]string      `json:"labels,omitempty"`
+	Release *legacyRelease.Release `json:"release"`
+}
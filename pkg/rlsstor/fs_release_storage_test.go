@@ -0,0 +1,139 @@
+package rlsstor_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+var _ = Describe("FSReleaseStorage", func() {
+	var (
+		ctx     context.Context
+		dirPath string
+		storage *rlsstor.FSReleaseStorage
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		dirPath = GinkgoT().TempDir()
+		storage = rlsstor.NewFSReleaseStorage(dirPath, testNamespace)
+	})
+
+	releaseFilePath := func(revision string) string {
+		return filepath.Join(dirPath, testNamespace, "test-release", revision+".json")
+	}
+
+	It("should read back created and updated releases", func() {
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusSuperseded, "first"))).To(Succeed())
+		Expect(storage.Create(ctx, newLegacyRelease(2, legacyRelease.StatusPendingUpgrade, "second"))).To(Succeed())
+		Expect(releaseFilePath("v1")).To(BeARegularFile())
+		Expect(releaseFilePath("v2")).To(BeARegularFile())
+
+		rel := newLegacyRelease(2, legacyRelease.StatusDeployed, "second")
+		Expect(storage.Update(ctx, rel)).To(Succeed())
+
+		rels, err := storage.Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(HaveLen(2))
+		Expect(rels).To(ContainElement(And(
+			HaveField("Version", 1),
+			HaveField("Info.Status", legacyRelease.StatusSuperseded),
+			HaveField("Manifest", "first"),
+			HaveField("Labels", map[string]string{"custom": "label"}),
+		)))
+		Expect(rels).To(ContainElement(And(
+			HaveField("Version", 2),
+			HaveField("Info.Status", legacyRelease.StatusDeployed),
+			HaveField("Manifest", "second"),
+			HaveField("Labels", map[string]string{"custom": "label"}),
+		)))
+
+		Expect(storage.Delete(ctx, "test-release", 1)).To(Succeed())
+		Expect(releaseFilePath("v1")).NotTo(BeAnExistingFile())
+
+		rels, err = storage.Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(HaveLen(1))
+		Expect(rels[0].Version).To(Equal(2))
+	})
+
+	It("should return no releases if there are none", func() {
+		rels, err := storage.Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(BeEmpty())
+	})
+
+	It("should keep releases of different namespaces apart", func() {
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+
+		rels, err := rlsstor.NewFSReleaseStorage(dirPath, "other-namespace").Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(BeEmpty())
+	})
+
+	It("should fail to create existing revision and to update or delete missing one", func() {
+		Expect(storage.Update(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(MatchError(rlsstor.ErrReleaseNotFound))
+		Expect(storage.Delete(ctx, "test-release", 1)).To(MatchError(rlsstor.ErrReleaseNotFound))
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(MatchError(rlsstor.ErrReleaseExists))
+	})
+
+	It("should ignore files that are not releases", func() {
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+
+		releaseDirPath := filepath.Join(dirPath, testNamespace, "test-release")
+		Expect(os.WriteFile(filepath.Join(releaseDirPath, "v2.json.tmp-123"), []byte("partial"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(releaseDirPath, "README.md"), []byte("releases"), 0o644)).To(Succeed())
+		Expect(os.Mkdir(releaseFilePath("v3"), 0o755)).To(Succeed())
+
+		rels, err := storage.Releases(ctx, "test-release")
+		Expect(err).To(Succeed())
+		Expect(rels).To(HaveLen(1))
+		Expect(rels[0].Version).To(Equal(1))
+	})
+
+	It("should not leave temporary files behind", func() {
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+		Expect(storage.Update(ctx, newLegacyRelease(1, legacyRelease.StatusSuperseded, ""))).To(Succeed())
+
+		entries, err := os.ReadDir(filepath.Join(dirPath, testNamespace, "test-release"))
+		Expect(err).To(Succeed())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Name()).To(Equal("v1.json"))
+	})
+
+	It("should fail to read corrupted release files", func() {
+		Expect(storage.Create(ctx, newLegacyRelease(1, legacyRelease.StatusDeployed, ""))).To(Succeed())
+		Expect(os.WriteFile(releaseFilePath("v1"), []byte("{"), 0o644)).To(Succeed())
+
+		_, err := storage.Releases(ctx, "test-release")
+		Expect(err).To(MatchError(ContainSubstring(`error reading release file "v1.json"`)))
+	})
+})
+
+var _ = DescribeTable("UnmarshalRelease",
+	func(data string, expectedErr string) {
+		_, err := rlsstor.UnmarshalRelease([]byte(data))
+		Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+	},
+	Entry("invalid JSON", "{", "error unmarshalling release"),
+	Entry("no release", `{"labels": {"custom": "label"}}`, "no release found"),
+)
+
+var _ = It("MarshalRelease should keep labels of the release", func() {
+	data, err := rlsstor.MarshalRelease(newLegacyRelease(1, legacyRelease.StatusDeployed, "manifest"))
+	Expect(err).To(Succeed())
+
+	rel, err := rlsstor.UnmarshalRelease(data)
+	Expect(err).To(Succeed())
+	Expect(rel.Name).To(Equal("test-release"))
+	Expect(rel.Manifest).To(Equal("manifest"))
+	Expect(rel.Labels).To(Equal(map[string]string{"custom": "label"}))
+})
@@ -0,0 +1,22 @@
+package rlsstor
+
+import (
+	"context"
+	"errors"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+)
+
+var (
+	ErrReleaseExists   = errors.New("release already exists")
+	ErrReleaseNotFound = errors.New("release not found")
+)
+
+// Stores revisions of releases of a single namespace.
+type ReleaseStorager interface {
+	// Returns all revisions of the release, in no particular order.
+	Releases(ctx context.Context, releaseName string) ([]*legacyRelease.Release, error)
+	Create(ctx context.Context, rel *legacyRelease.Release) error
+	Update(ctx context.Context, rel *legacyRelease.Release) error
+	Delete(ctx context.Context, releaseName string, revision int) error
+}
@@ -0,0 +1,79 @@
+package rlsstor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/storage/driver"
+)
+
+var _ ReleaseStorager = (*LegacyReleaseStorage)(nil)
+
+// Stores releases through the Helm storage, e.g. in Secrets, ConfigMaps or SQL.
+func NewLegacyReleaseStorage(storage LegacyStorage) *LegacyReleaseStorage {
+	return &LegacyReleaseStorage{
+		storage: storage,
+	}
+}
+
+type LegacyReleaseStorage struct {
+	storage LegacyStorage
+}
+
+func (s *LegacyReleaseStorage) Releases(ctx context.Context, releaseName string) ([]*legacyRelease.Release, error) {
+	rels, err := s.storage.Query(map[string]string{"name": releaseName, "owner": "helm"})
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("error querying releases: %w", err)
+	}
+
+	return rels, nil
+}
+
+func (s *LegacyReleaseStorage) Create(ctx context.Context, rel *legacyRelease.Release) error {
+	if err := s.storage.Create(rel); err != nil {
+		if errors.Is(err, driver.ErrReleaseExists) {
+			return ErrReleaseExists
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *LegacyReleaseStorage) Update(ctx context.Context, rel *legacyRelease.Release) error {
+	if err := s.storage.Update(rel); err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return ErrReleaseNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *LegacyReleaseStorage) Delete(ctx context.Context, releaseName string, revision int) error {
+	if _, err := s.storage.Delete(releaseName, revision); err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return ErrReleaseNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+type LegacyStorage interface {
+	Create(rls *legacyRelease.Release) error
+	Update(rls *legacyRelease.Release) error
+	Delete(name string, version int) (*legacyRelease.Release, error)
+	Query(labels map[string]string) ([]*legacyRelease.Release, error)
+}
@@ -0,0 +1,90 @@
+package rlsstor
+
+import (
+	"context"
+	"sync"
+
+	legacyRelease "github.com/werf/3p-helm-for-werf-helm/pkg/release"
+)
+
+var _ ReleaseStorager = (*MemoryReleaseStorage)(nil)
+
+// Keeps releases in memory. Releases are stored serialized, so that modifying a release after
+// storing or retrieving it doesn't affect the stored one.
+func NewMemoryReleaseStorage() *MemoryReleaseStorage {
+	return &MemoryReleaseStorage{
+		releases: map[string]map[int][]byte{},
+	}
+}
+
+type MemoryReleaseStorage struct {
+	releases map[string]map[int][]byte
+	mu       sync.Mutex
+}
+
+func (s *MemoryReleaseStorage) Releases(ctx context.Context, releaseName string) ([]*legacyRelease.Release, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rels []*legacyRelease.Release
+	for _, data := range s.releases[releaseName] {
+		rel, err := UnmarshalRelease(data)
+		if err != nil {
+			return nil, err
+		}
+
+		rels = append(rels, rel)
+	}
+
+	return rels, nil
+}
+
+func (s *MemoryReleaseStorage) Create(ctx context.Context, rel *legacyRelease.Release) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.releases[rel.Name][rel.Version]; found {
+		return ErrReleaseExists
+	}
+
+	data, err := MarshalRelease(rel)
+	if err != nil {
+		return err
+	}
+
+	if s.releases[rel.Name] == nil {
+		s.releases[rel.Name] = map[int][]byte{}
+	}
+	s.releases[rel.Name][rel.Version] = data
+
+	return nil
+}
+
+func (s *MemoryReleaseStorage) Update(ctx context.Context, rel *legacyRelease.Release) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.releases[rel.Name][rel.Version]; !found {
+		return ErrReleaseNotFound
+	}
+
+	data, err := MarshalRelease(rel)
+	if err != nil {
+		return err
+	}
+	s.releases[rel.Name][rel.Version] = data
+
+	return nil
+}
+
+func (s *MemoryReleaseStorage) Delete(ctx context.Context, releaseName string, revision int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.releases[releaseName][revision]; !found {
+		return ErrReleaseNotFound
+	}
+	delete(s.releases[releaseName], revision)
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package rlsstor_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestReleaseStorage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "release storage suite")
+}
@@ -0,0 +1,13 @@
+package tstng
+
+import (
+	"context"
+	"io"
+
+	"github.com/werf/logboek"
+)
+
+// Most of the code logs through the logger bound to the context, so it panics without one.
+func NewContext(logOut io.Writer) context.Context {
+	return logboek.NewContext(context.Background(), logboek.NewLogger(logOut, logOut))
+}
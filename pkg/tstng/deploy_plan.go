@@ -0,0 +1,202 @@
+package tstng
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/werf/3p-helm-for-werf-helm/pkg/action"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/chartutil"
+	"github.com/werf/3p-helm-for-werf-helm/pkg/cli"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
+	kubeutil "github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm-for-werf-helm/pkg/chrttree"
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnbuilder"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcmatcher"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcprocssr"
+	"github.com/werf/nelm-for-werf-helm/pkg/rls"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+const (
+	defaultReleaseName      = "test-release"
+	defaultReleaseNamespace = "test-namespace"
+)
+
+// Goes through the same steps as "plan" and "release install" commands up to building the
+// deploy plan: renders the chart, processes its resources against the fake cluster and the
+// release history in the release storage, then builds the plan. Nothing is deployed.
+func BuildDeployPlan(ctx context.Context, chartDirPath string, cluster *FakeCluster, releaseStorage rlsstor.ReleaseStorager, opts BuildDeployPlanOptions) (*DeployPlan, error) {
+	if opts.ReleaseName == "" {
+		opts.ReleaseName = defaultReleaseName
+	}
+
+	if opts.ReleaseNamespace == "" {
+		opts.ReleaseNamespace = defaultReleaseNamespace
+	}
+
+	helmActionConfig := &action.Configuration{
+		Capabilities: chartutil.DefaultCapabilities.Copy(),
+		Log:          func(format string, a ...interface{}) {},
+	}
+
+	if opts.KubeVersion != "" {
+		kubeVersion, err := chartutil.ParseKubeVersion(opts.KubeVersion)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing kube version %q: %w", opts.KubeVersion, err)
+		}
+
+		helmActionConfig.Capabilities.KubeVersion = *kubeVersion
+	}
+
+	history, err := rlshistor.NewHistory(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		releaseStorage,
+		rlshistor.HistoryOptions{
+			Mapper:          cluster.Mapper(),
+			DiscoveryClient: cluster.Discovery(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing release history: %w", err)
+	}
+
+	deployInfo, err := rlshistor.NewDeployInfo(history)
+	if err != nil {
+		return nil, fmt.Errorf("error getting deploy info: %w", err)
+	}
+
+	prevRelease, prevReleaseFound := deployInfo.PrevRelease, deployInfo.PrevReleaseFound
+	newRevision, firstDeployed, deployType := deployInfo.NewRevision, deployInfo.FirstDeployed, deployInfo.DeployType
+
+	chartTree, err := chrttree.NewChartTree(
+		ctx,
+		chartDirPath,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newRevision,
+		deployType,
+		helmActionConfig,
+		chrttree.ChartTreeOptions{
+			SetValues:       opts.ValuesSets,
+			ValuesFiles:     opts.ValuesFilesPaths,
+			Mapper:          cluster.Mapper(),
+			DiscoveryClient: cluster.Discovery(),
+			HelmSettings:    cli.New(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing chart tree: %w", err)
+	}
+
+	var prevRelGeneralResources []*resrc.GeneralResource
+	if prevReleaseFound {
+		prevRelGeneralResources = prevRelease.GeneralResources()
+	}
+
+	resProcessor := resrcprocssr.NewDeployableResourcesProcessor(
+		deployType,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		chartTree.StandaloneCRDs(),
+		chartTree.HookResources(),
+		chartTree.GeneralResources(),
+		prevRelGeneralResources,
+		resrcprocssr.DeployableResourcesProcessorOptions{
+			KubeClient:         cluster.KubeClient(),
+			Mapper:             cluster.Mapper(),
+			DiscoveryClient:    cluster.Discovery(),
+			AllowClusterAccess: true,
+		},
+	)
+
+	if err := resProcessor.Process(ctx); err != nil {
+		return nil, fmt.Errorf("error processing resources: %w", err)
+	}
+
+	newRel, err := rls.NewRelease(
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		newRevision,
+		chartTree.ReleaseValues(),
+		chartTree.LegacyChart(),
+		resProcessor.ReleasableHookResources(),
+		resProcessor.ReleasableGeneralResources(),
+		chartTree.Notes(),
+		rls.ReleaseOptions{
+			FirstDeployed: firstDeployed,
+			Mapper:        cluster.Mapper(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing new release: %w", err)
+	}
+
+	resourceSelector, err := resrcmatcher.NewResourceSelector(nil, nil, resrcmatcher.ResourceSelectorOptions{
+		DefaultNamespace: opts.ReleaseNamespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error constructing resource selector: %w", err)
+	}
+
+	plan, err := plnbuilder.NewDeployPlanBuilder(
+		opts.ReleaseNamespace,
+		deployType,
+		statestore.NewTaskStore(),
+		kubeutil.NewConcurrent(
+			logstore.NewLogStore(),
+		),
+		resProcessor.DeployableStandaloneCRDsInfos(),
+		resProcessor.DeployableHookResourcesInfos(),
+		resProcessor.DeployableGeneralResourcesInfos(),
+		resProcessor.DeployablePrevReleaseGeneralResourcesInfos(),
+		newRel,
+		history,
+		cluster.KubeClient(),
+		cluster.Static(),
+		cluster.Dynamic(),
+		cluster.Discovery(),
+		cluster.Mapper(),
+		plnbuilder.DeployPlanBuilderOptions{
+			PrevRelease:         prevRelease,
+			PrevDeployedRelease: deployInfo.PrevDeployedRelease,
+			ResourceSelector:    resourceSelector,
+		},
+	).Build(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error building deploy plan: %w", err)
+	}
+
+	return &DeployPlan{
+		Plan:                  plan,
+		Release:               newRel,
+		DeployType:            deployType,
+		HookResourcesInfos:    resProcessor.DeployableHookResourcesInfos(),
+		GeneralResourcesInfos: resProcessor.DeployableGeneralResourcesInfos(),
+	}, nil
+}
+
+type BuildDeployPlanOptions struct {
+	// Defaults to Helm default capabilities.
+	KubeVersion      string
+	ReleaseName      string
+	ReleaseNamespace string
+	ValuesFilesPaths []string
+	ValuesSets       []string
+}
+
+type DeployPlan struct {
+	Plan       *pln.Plan
+	Release    *rls.Release
+	DeployType common.DeployType
+	// Resources of the release as they are going to be deployed, with release metadata.
+	HookResourcesInfos    []*resrcinfo.DeployableHookResourceInfo
+	GeneralResourcesInfos []*resrcinfo.DeployableGeneralResourceInfo
+}
@@ -0,0 +1,273 @@
+package tstng
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/common"
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+)
+
+var fakeClusterEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// In-memory cluster for tests, backed by client-go fake clients. Server-side apply is emulated
+// by merging the applied object into the live one, so removed fields are not pruned and
+// field ownership is not tracked. Dynamic and static clients don't share objects.
+func NewFakeCluster(opts FakeClusterOptions) (*FakeCluster, error) {
+	kinds := append([]FakeKind{}, DefaultFakeKinds...)
+	kinds = append(kinds, opts.ExtraKinds...)
+
+	mapper := NewFakeMapper(kinds)
+
+	gvrToListKind := map[schema.GroupVersionResource]string{}
+	for _, kind := range kinds {
+		gvr, _ := meta.UnsafeGuessKindToResource(kind.GroupVersionKind)
+		gvrToListKind[gvr] = kind.GroupVersionKind.Kind + "List"
+	}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), gvrToListKind)
+	discoveryClient := newFakeDiscovery(kinds)
+	staticClient := kubefake.NewSimpleClientset()
+
+	cluster := &FakeCluster{
+		staticClient:    staticClient,
+		dynamicClient:   dynamicClient,
+		discoveryClient: discoveryClient,
+		mapper:          mapper,
+	}
+
+	dynamicClient.PrependReactor("patch", "*", cluster.applyReactor)
+
+	cluster.kubeClient = &FakeKubeClient{
+		KubeClient: kubeclnt.NewKubeClient(staticClient, dynamicClient, discoveryClient, mapper),
+		cluster:    cluster,
+	}
+
+	for _, obj := range opts.Objects {
+		if err := cluster.AddObject(obj); err != nil {
+			return nil, fmt.Errorf("error adding object %q: %w", obj.GetName(), err)
+		}
+	}
+
+	return cluster, nil
+}
+
+type FakeClusterOptions struct {
+	// Kinds known to the cluster in addition to DefaultFakeKinds, e.g. kinds of custom resources.
+	ExtraKinds []FakeKind
+	// Objects existing in the cluster from the start.
+	Objects []*unstructured.Unstructured
+}
+
+type FakeCluster struct {
+	kubeClient      *FakeKubeClient
+	staticClient    *kubefake.Clientset
+	dynamicClient   *dynamicfake.FakeDynamicClient
+	discoveryClient discovery.CachedDiscoveryInterface
+	mapper          *FakeMapper
+
+	mu         sync.Mutex
+	uidCounter int
+}
+
+func (c *FakeCluster) KubeClient() kubeclnt.KubeClienter {
+	return c.kubeClient
+}
+
+func (c *FakeCluster) Static() kubernetes.Interface {
+	return c.staticClient
+}
+
+func (c *FakeCluster) Dynamic() dynamic.Interface {
+	return c.dynamicClient
+}
+
+func (c *FakeCluster) Discovery() discovery.CachedDiscoveryInterface {
+	return c.discoveryClient
+}
+
+func (c *FakeCluster) Mapper() meta.ResettableRESTMapper {
+	return c.mapper
+}
+
+// Stores the object as is, without emulating server-side apply. Fails if the object exists.
+func (c *FakeCluster) AddObject(obj *unstructured.Unstructured) error {
+	gvr, namespace, err := c.objectLocation(obj.GroupVersionKind(), obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	obj = obj.DeepCopy()
+	obj.SetNamespace(namespace)
+	c.setServerFields(obj, nil)
+
+	if err := c.dynamicClient.Tracker().Create(gvr, obj, namespace); err != nil {
+		return fmt.Errorf("error creating object: %w", err)
+	}
+
+	return nil
+}
+
+// Returns a copy of the live object.
+func (c *FakeCluster) Object(gvk schema.GroupVersionKind, namespace, name string) (obj *unstructured.Unstructured, found bool, err error) {
+	gvr, namespace, err := c.objectLocation(gvk, namespace)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return c.liveObject(gvr, namespace, name)
+}
+
+func (c *FakeCluster) objectLocation(gvk schema.GroupVersionKind, namespace string) (gvr schema.GroupVersionResource, ns string, err error) {
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, "", fmt.Errorf("error getting REST mapping for %q: %w", gvk, err)
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		namespace = ""
+	} else if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	return mapping.Resource, namespace, nil
+}
+
+func (c *FakeCluster) liveObject(gvr schema.GroupVersionResource, namespace, name string) (obj *unstructured.Unstructured, found bool, err error) {
+	liveObj, err := c.dynamicClient.Tracker().Get(gvr, namespace, name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("error getting live object: %w", err)
+	}
+
+	unstruct, ok := liveObj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected live object type %T", liveObj)
+	}
+
+	return unstruct.DeepCopy(), true, nil
+}
+
+// Persists the result of server-side apply, unlike the default fake dynamic client reactor,
+// which fails on applying non-existent objects.
+func (c *FakeCluster) applyReactor(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+	patchAction, ok := action.(clienttesting.PatchAction)
+	if !ok || patchAction.GetPatchType() != types.ApplyPatchType {
+		return false, nil, nil
+	}
+
+	applied := &unstructured.Unstructured{}
+	if err := json.Unmarshal(patchAction.GetPatch(), &applied.Object); err != nil {
+		return true, nil, errors.NewBadRequest(fmt.Sprintf("error unmarshalling applied object: %s", err))
+	}
+
+	gvr := patchAction.GetResource()
+	namespace := patchAction.GetNamespace()
+
+	live, found, err := c.liveObject(gvr, namespace, patchAction.GetName())
+	if err != nil {
+		return true, nil, err
+	}
+
+	result := c.apply(live, applied, namespace)
+
+	if found {
+		err = c.dynamicClient.Tracker().Update(gvr, result, namespace)
+	} else {
+		err = c.dynamicClient.Tracker().Create(gvr, result, namespace)
+	}
+	if err != nil {
+		return true, nil, err
+	}
+
+	return true, result.DeepCopy(), nil
+}
+
+// Returns what server-side apply of the object would result in. Live object is nil if it
+// doesn't exist.
+func (c *FakeCluster) apply(live, applied *unstructured.Unstructured, namespace string) *unstructured.Unstructured {
+	var result *unstructured.Unstructured
+	if live == nil {
+		result = applied.DeepCopy()
+	} else {
+		result = live.DeepCopy()
+		mergeObjects(result.Object, applied.DeepCopy().Object)
+	}
+
+	if namespace != "" {
+		result.SetNamespace(namespace)
+	}
+
+	// Entries of other managers are kept as is, only ours is replaced.
+	var managedFields []metav1.ManagedFieldsEntry
+	if live != nil {
+		managedFields = lo.Filter(live.GetManagedFields(), func(entry metav1.ManagedFieldsEntry, _ int) bool {
+			return entry.Manager != common.DefaultFieldManager || entry.Operation != metav1.ManagedFieldsOperationApply
+		})
+	}
+	result.SetManagedFields(append(managedFields, metav1.ManagedFieldsEntry{
+		Manager:    common.DefaultFieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: result.GetAPIVersion(),
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte("{}")},
+	}))
+	c.setServerFields(result, live)
+
+	return result
+}
+
+func (c *FakeCluster) setServerFields(obj, live *unstructured.Unstructured) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if live == nil {
+		c.uidCounter++
+		obj.SetUID(types.UID(fmt.Sprintf("00000000-0000-0000-0000-%012d", c.uidCounter)))
+		obj.SetResourceVersion("1")
+		// Time is frozen, so that objects created in tests are always the same.
+		obj.SetCreationTimestamp(metav1.NewTime(fakeClusterEpoch.Add(time.Duration(c.uidCounter) * time.Second)))
+		obj.SetGeneration(1)
+	} else {
+		obj.SetUID(live.GetUID())
+		obj.SetCreationTimestamp(live.GetCreationTimestamp())
+
+		resourceVersion, _ := strconv.Atoi(live.GetResourceVersion())
+		obj.SetResourceVersion(strconv.Itoa(resourceVersion + 1))
+	}
+}
+
+// Maps are merged recursively, everything else is replaced.
+func mergeObjects(dst, src map[string]interface{}) {
+	for key, srcValue := range src {
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+		if srcIsMap && dstIsMap {
+			mergeObjects(dstMap, srcMap)
+		} else {
+			dst[key] = srcValue
+		}
+	}
+}
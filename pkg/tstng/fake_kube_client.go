@@ -0,0 +1,49 @@
+package tstng
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/kubeclnt"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcid"
+)
+
+var _ kubeclnt.KubeClienter = (*FakeKubeClient)(nil)
+
+// The regular kube client on top of the fake cluster. Fake dynamic client knows nothing about
+// dry-run, so dry-run applies are emulated without going through it.
+type FakeKubeClient struct {
+	*kubeclnt.KubeClient
+	cluster *FakeCluster
+}
+
+func (c *FakeKubeClient) Apply(ctx context.Context, resource *resrcid.ResourceID, unstruct *unstructured.Unstructured, opts kubeclnt.KubeClientApplyOptions) (*unstructured.Unstructured, error) {
+	if !opts.DryRun {
+		return c.KubeClient.Apply(ctx, resource, unstruct, opts)
+	}
+
+	gvr, err := resource.GroupVersionResource()
+	if err != nil {
+		return nil, fmt.Errorf("error getting GroupVersionResource: %w", err)
+	}
+
+	namespaced, err := resource.Namespaced()
+	if err != nil {
+		return nil, fmt.Errorf("error checking if resource is namespaced: %w", err)
+	}
+
+	var namespace string
+	if namespaced {
+		namespace = resource.Namespace()
+	}
+
+	live, _, err := c.cluster.liveObject(gvr, namespace, resource.Name())
+	if err != nil {
+		return nil, fmt.Errorf("error server-side dry-run applying resource %q: %w", resource.HumanID(), errors.NewInternalError(err))
+	}
+
+	return c.cluster.apply(live, unstruct, namespace), nil
+}
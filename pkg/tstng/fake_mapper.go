@@ -0,0 +1,100 @@
+package tstng
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var _ meta.ResettableRESTMapper = (*FakeMapper)(nil)
+
+type FakeKind struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespaced       bool
+}
+
+// Kinds known to the fake cluster unless other kinds are specified.
+var DefaultFakeKinds = []FakeKind{
+	{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}},
+	{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Service"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"}},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"}, Namespaced: true},
+	{GroupVersionKind: schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}},
+}
+
+// REST mapper for a fixed set of kinds. Resource names are guessed from kinds the same way
+// kubectl does it for unknown kinds.
+func NewFakeMapper(kinds []FakeKind) *FakeMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, kind := range kinds {
+		if kind.Namespaced {
+			mapper.Add(kind.GroupVersionKind, meta.RESTScopeNamespace)
+		} else {
+			mapper.Add(kind.GroupVersionKind, meta.RESTScopeRoot)
+		}
+	}
+
+	return &FakeMapper{
+		DefaultRESTMapper: mapper,
+	}
+}
+
+type FakeMapper struct {
+	*meta.DefaultRESTMapper
+}
+
+// Kinds are fixed, so there is nothing to reset.
+func (m *FakeMapper) Reset() {}
+
+func newFakeDiscovery(kinds []FakeKind) discovery.CachedDiscoveryInterface {
+	resourceLists := map[schema.GroupVersion]*metav1.APIResourceList{}
+	var groupVersions []schema.GroupVersion
+	for _, kind := range kinds {
+		gv := kind.GroupVersionKind.GroupVersion()
+
+		resourceList, found := resourceLists[gv]
+		if !found {
+			resourceList = &metav1.APIResourceList{
+				GroupVersion: gv.String(),
+			}
+			resourceLists[gv] = resourceList
+			groupVersions = append(groupVersions, gv)
+		}
+
+		plural, singular := meta.UnsafeGuessKindToResource(kind.GroupVersionKind)
+		resourceList.APIResources = append(resourceList.APIResources, metav1.APIResource{
+			Name:         plural.Resource,
+			SingularName: singular.Resource,
+			Namespaced:   kind.Namespaced,
+			Group:        gv.Group,
+			Version:      gv.Version,
+			Kind:         kind.GroupVersionKind.Kind,
+			Verbs:        metav1.Verbs{"create", "delete", "get", "list", "patch", "update", "watch"},
+		})
+	}
+
+	fake := &clienttesting.Fake{}
+	for _, gv := range groupVersions {
+		fake.Resources = append(fake.Resources, resourceLists[gv])
+	}
+
+	return memory.NewMemCacheClient(&fakediscovery.FakeDiscovery{Fake: fake})
+}
@@ -0,0 +1,105 @@
+package tstng
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+)
+
+// If set, golden files are rewritten with actual results instead of being compared with them.
+const UpdateGoldenEnvVar = "NELM_UPDATE_GOLDEN"
+
+// Lists operations of the plan sorted by ID, each followed by the sorted IDs of operations it
+// depends on:
+//
+//	create/test-namespace::ConfigMap:config
+//	  after: stage/general-resources/weight:0/start
+func PlanOperations(plan *pln.Plan) ([]byte, error) {
+	predecessorMap, err := plan.PredecessorMap()
+	if err != nil {
+		return nil, fmt.Errorf("error getting predecessor map: %w", err)
+	}
+
+	var opIDs []string
+	for opID := range predecessorMap {
+		opIDs = append(opIDs, opID)
+	}
+	sort.Strings(opIDs)
+
+	b := &bytes.Buffer{}
+	for _, opID := range opIDs {
+		fmt.Fprintln(b, opID)
+
+		var depIDs []string
+		for depID := range predecessorMap[opID] {
+			depIDs = append(depIDs, depID)
+		}
+		sort.Strings(depIDs)
+
+		for _, depID := range depIDs {
+			fmt.Fprintf(b, "  after: %s\n", depID)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// Same as the DOT graph of the plan, but with vertices and edges sorted, since their order in
+// the graph isn't stable.
+func PlanDOT(plan *pln.Plan) ([]byte, error) {
+	dot, err := plan.DOT()
+	if err != nil {
+		return nil, fmt.Errorf("error getting DOT graph: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(dot), "\n"), "\n")
+
+	var header, statements, footer []string
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "\t\""):
+			statements = append(statements, line)
+		case len(statements) == 0:
+			header = append(header, line)
+		default:
+			footer = append(footer, line)
+		}
+	}
+	sort.Strings(statements)
+
+	result := append(append(header, statements...), footer...)
+
+	return []byte(strings.Join(result, "\n") + "\n"), nil
+}
+
+// Compares the actual result with the golden file, or rewrites the golden file if the
+// NELM_UPDATE_GOLDEN environment variable is set.
+func CompareGolden(goldenPath string, actual []byte) error {
+	if os.Getenv(UpdateGoldenEnvVar) != "" {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			return fmt.Errorf("error creating golden file directory: %w", err)
+		}
+
+		if err := os.WriteFile(goldenPath, actual, 0o644); err != nil {
+			return fmt.Errorf("error writing golden file %q: %w", goldenPath, err)
+		}
+
+		return nil
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		return fmt.Errorf("error reading golden file %q (set %s=1 to create it): %w", goldenPath, UpdateGoldenEnvVar, err)
+	}
+
+	if !bytes.Equal(expected, actual) {
+		return fmt.Errorf("result differs from golden file %q (set %s=1 to update it):\n--- expected\n%s\n--- actual\n%s", goldenPath, UpdateGoldenEnvVar, expected, actual)
+	}
+
+	return nil
+}
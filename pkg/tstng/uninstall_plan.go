@@ -0,0 +1,106 @@
+package tstng
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/logstore"
+	"github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/statestore"
+	kubeutil "github.com/werf/kubedog-for-werf-helm/pkg/trackers/dyntracker/util"
+	"github.com/werf/nelm-for-werf-helm/pkg/pln"
+	"github.com/werf/nelm-for-werf-helm/pkg/plnbuilder"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrc"
+	"github.com/werf/nelm-for-werf-helm/pkg/resrcinfo"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlshistor"
+	"github.com/werf/nelm-for-werf-helm/pkg/rlsstor"
+)
+
+// Goes through the same steps as "release uninstall" command up to building the uninstall plan
+// of the last release in the release storage. Nothing is deleted.
+func BuildUninstallPlan(ctx context.Context, cluster *FakeCluster, releaseStorage rlsstor.ReleaseStorager, opts BuildUninstallPlanOptions) (*pln.Plan, error) {
+	if opts.ReleaseName == "" {
+		opts.ReleaseName = defaultReleaseName
+	}
+
+	if opts.ReleaseNamespace == "" {
+		opts.ReleaseNamespace = defaultReleaseNamespace
+	}
+
+	history, err := rlshistor.NewHistory(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		releaseStorage,
+		rlshistor.HistoryOptions{
+			Mapper:          cluster.Mapper(),
+			DiscoveryClient: cluster.Discovery(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing release history: %w", err)
+	}
+
+	lastRelease, found, err := history.LastRelease()
+	if err != nil {
+		return nil, fmt.Errorf("error getting last release: %w", err)
+	} else if !found {
+		return nil, fmt.Errorf("no release %q (namespace: %q) found", opts.ReleaseName, opts.ReleaseNamespace)
+	}
+
+	hookResources := lastRelease.HookResources()
+	if !opts.DeleteHooks {
+		hookResources = lo.Filter(hookResources, func(res *resrc.HookResource, _ int) bool {
+			return res.OnPreDelete() || res.OnPostDelete()
+		})
+	}
+
+	_, _, hookResourcesInfos, _, prevReleaseGeneralResourceInfos, err := resrcinfo.BuildDeployableResourceInfos(
+		ctx,
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		nil,
+		hookResources,
+		nil,
+		lastRelease.GeneralResources(),
+		cluster.KubeClient(),
+		cluster.Mapper(),
+		1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error building deployable resources infos: %w", err)
+	}
+
+	plan, err := plnbuilder.NewUninstallPlanBuilder(
+		opts.ReleaseName,
+		opts.ReleaseNamespace,
+		statestore.NewTaskStore(),
+		kubeutil.NewConcurrent(
+			logstore.NewLogStore(),
+		),
+		hookResourcesInfos,
+		prevReleaseGeneralResourceInfos,
+		lastRelease,
+		history,
+		cluster.KubeClient(),
+		cluster.Static(),
+		cluster.Dynamic(),
+		cluster.Discovery(),
+		cluster.Mapper(),
+		plnbuilder.UninstallPlanBuilderOptions{
+			DeleteHooks: opts.DeleteHooks,
+		},
+	).Build(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error building uninstall plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+type BuildUninstallPlanOptions struct {
+	DeleteHooks      bool
+	ReleaseName      string
+	ReleaseNamespace string
+}